@@ -0,0 +1,97 @@
+package backfill
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+	notificationmiloapiscomv1alpha1 "go.miloapis.com/milo/pkg/apis/notification/v1alpha1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	k8sconfig "sigs.k8s.io/controller-runtime/pkg/client/config"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/log/zap"
+
+	backfill "go.miloapis.com/email-provider-loops/internal/backfill"
+	loops "go.miloapis.com/email-provider-loops/pkg/loops"
+)
+
+// CreateBackfillCommand returns a cobra command grouping the commands that backfill Milo objects from Loops.
+func CreateBackfillCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "backfill",
+		Short: "Backfill Milo objects from the state stored in Loops",
+	}
+
+	cmd.AddCommand(createMembershipsCommand())
+
+	return cmd
+}
+
+func createMembershipsCommand() *cobra.Command {
+	var (
+		dryRun      bool
+		loopsCAFile string
+	)
+
+	cmd := &cobra.Command{
+		Use:   "memberships",
+		Short: "Create the ContactGroupMemberships missing for the Loops mailing lists contacts are subscribed to",
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			logf.SetLogger(zap.New(zap.JSONEncoder()))
+			log := logf.Log.WithName("backfill")
+			ctx := logf.IntoContext(cmd.Context(), log)
+
+			// Setup Kubernetes client
+			restConfig, err := k8sconfig.GetConfig()
+			if err != nil {
+				return fmt.Errorf("failed to get rest config: %w", err)
+			}
+
+			runtimeScheme := runtime.NewScheme()
+			if err := notificationmiloapiscomv1alpha1.AddToScheme(runtimeScheme); err != nil {
+				return fmt.Errorf("failed to add notificationmiloapiscomv1alpha1 scheme: %w", err)
+			}
+
+			k8sClient, err := client.New(restConfig, client.Options{Scheme: runtimeScheme})
+			if err != nil {
+				return fmt.Errorf("failed to create kubernetes client: %w", err)
+			}
+
+			// Setup Loops client
+			loopsAPIKey := os.Getenv("LOOPS_API_KEY")
+			if loopsAPIKey == "" {
+				return fmt.Errorf("LOOPS_API_KEY environment variable is required")
+			}
+			var loopsOpts []loops.ClientOption
+			if loopsCAFile != "" {
+				caCertPool, err := loops.LoadCACertPool(loopsCAFile)
+				if err != nil {
+					return fmt.Errorf("invalid --loops-ca-file: %w", err)
+				}
+				loopsOpts = append(loopsOpts, loops.WithCACertPool(caCertPool))
+			}
+			loopsClient, err := loops.NewSDK(loopsAPIKey, loopsOpts...)
+			if err != nil {
+				return fmt.Errorf("failed to create Loops client: %w", err)
+			}
+
+			result, err := backfill.Memberships(ctx, k8sClient, loopsClient, backfill.MembershipsOptions{DryRun: dryRun})
+			if result != nil {
+				log.Info("Backfill finished", "dryRun", dryRun, "contactsScanned", result.ContactsScanned,
+					"missing", len(result.Missing))
+			}
+			if err != nil {
+				return fmt.Errorf("failed to backfill contact group memberships: %w", err)
+			}
+
+			return nil
+		},
+	}
+
+	cmd.Flags().BoolVar(&dryRun, "dry-run", false, "Report the missing memberships without creating them")
+	cmd.Flags().StringVar(&loopsCAFile, "loops-ca-file", "",
+		"Path to a PEM bundle of the CAs trusted when connecting to Loops, instead of the system ones.")
+
+	return cmd
+}
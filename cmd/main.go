@@ -5,7 +5,9 @@ import (
 	"os"
 
 	"github.com/spf13/cobra"
+	backfill "go.miloapis.com/email-provider-loops/cmd/backfill"
 	manager "go.miloapis.com/email-provider-loops/cmd/manager"
+	report "go.miloapis.com/email-provider-loops/cmd/report"
 	version "go.miloapis.com/email-provider-loops/cmd/version"
 	"go.miloapis.com/email-provider-loops/cmd/webhook"
 )
@@ -20,6 +22,8 @@ func main() {
 	rootCmd.AddCommand(manager.CreateManagerCommand())
 	rootCmd.AddCommand(version.NewVersionCommand())
 	rootCmd.AddCommand(webhook.CreateWebhookCommand())
+	rootCmd.AddCommand(backfill.CreateBackfillCommand())
+	rootCmd.AddCommand(report.CreateReportCommand())
 
 	if err := rootCmd.Execute(); err != nil {
 		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
@@ -0,0 +1,52 @@
+package manager
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/go-logr/logr"
+)
+
+// reloader reloads a credential, e.g. a loops.FileAPIKey.
+type reloader interface {
+	Reload() error
+}
+
+// apiKeyReloader reloads the Loops API key when the manager receives a SIGHUP, so that a rotated key is picked up
+// without a restart.
+type apiKeyReloader struct {
+	log     logr.Logger
+	key     reloader
+	signals chan os.Signal
+}
+
+// newAPIKeyReloader subscribes to SIGHUP right away, so that no signal is missed before the manager starts it.
+func newAPIKeyReloader(log logr.Logger, key reloader) *apiKeyReloader {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGHUP)
+	return &apiKeyReloader{log: log, key: key, signals: signals}
+}
+
+// Start reloads the API key on each SIGHUP until ctx is done. A failed reload keeps the previous key.
+func (r *apiKeyReloader) Start(ctx context.Context) error {
+	defer signal.Stop(r.signals)
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-r.signals:
+			if err := r.key.Reload(); err != nil {
+				r.log.Error(err, "failed to reload the Loops API key, keeping the previous one")
+				continue
+			}
+			r.log.Info("reloaded the Loops API key")
+		}
+	}
+}
+
+// NeedLeaderElection makes every replica reload its API key, not only the leader.
+func (r *apiKeyReloader) NeedLeaderElection() bool {
+	return false
+}
@@ -0,0 +1,50 @@
+package manager
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/go-logr/logr"
+	loops "go.miloapis.com/email-provider-loops/pkg/loops"
+)
+
+func TestAPIKeyReloader_SIGHUP(t *testing.T) {
+	keyFile := filepath.Join(t.TempDir(), "api-key")
+	if err := os.WriteFile(keyFile, []byte("old-key"), 0o600); err != nil {
+		t.Fatalf("Failed to write key file: %v", err)
+	}
+	key, err := loops.NewFileAPIKey(keyFile)
+	if err != nil {
+		t.Fatalf("NewFileAPIKey() failed: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	r := newAPIKeyReloader(logr.Discard(), key)
+	done := make(chan error, 1)
+	go func() { done <- r.Start(ctx) }()
+
+	if err := os.WriteFile(keyFile, []byte("new-key"), 0o600); err != nil {
+		t.Fatalf("Failed to write key file: %v", err)
+	}
+	if err := syscall.Kill(os.Getpid(), syscall.SIGHUP); err != nil {
+		t.Fatalf("Failed to send SIGHUP: %v", err)
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for key.APIKey() != "new-key" {
+		if time.Now().After(deadline) {
+			t.Fatalf("Expected the key to be reloaded to %q, got %q", "new-key", key.APIKey())
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	cancel()
+	if err := <-done; err != nil {
+		t.Errorf("Start() failed: %v", err)
+	}
+}
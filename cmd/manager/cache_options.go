@@ -0,0 +1,35 @@
+package manager
+
+import (
+	"fmt"
+
+	notificationmiloapiscomv1alpha1 "go.miloapis.com/milo/pkg/apis/notification/v1alpha1"
+	"k8s.io/apimachinery/pkg/labels"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// cacheOptions restricts the cache of the objects the controllers reconcile to the ones matching the label selector.
+// Objects without a matching label are neither cached nor reconciled, and are missing from the cached client reads
+// too, so the contacts and contact groups referenced by matching memberships must match it as well. The memberships
+// and removals created by the controllers and the webhook inherit the labels of their contact, so they match it
+// whenever the contact does. An empty selector caches all objects.
+func cacheOptions(labelSelector string) (cache.Options, error) {
+	if labelSelector == "" {
+		return cache.Options{}, nil
+	}
+
+	selector, err := labels.Parse(labelSelector)
+	if err != nil {
+		return cache.Options{}, fmt.Errorf("failed to parse label selector %q: %w", labelSelector, err)
+	}
+
+	return cache.Options{
+		ByObject: map[client.Object]cache.ByObject{
+			&notificationmiloapiscomv1alpha1.Contact{}:                       {Label: selector},
+			&notificationmiloapiscomv1alpha1.ContactGroup{}:                  {Label: selector},
+			&notificationmiloapiscomv1alpha1.ContactGroupMembership{}:        {Label: selector},
+			&notificationmiloapiscomv1alpha1.ContactGroupMembershipRemoval{}: {Label: selector},
+		},
+	}, nil
+}
@@ -0,0 +1,82 @@
+package manager
+
+import (
+	"testing"
+
+	notificationmiloapiscomv1alpha1 "go.miloapis.com/milo/pkg/apis/notification/v1alpha1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
+)
+
+// contactSelector returns the label selector the cache lists and watches the Contacts with, nil if there is none.
+func contactSelector(t *testing.T, opts cache.Options) labels.Selector {
+	t.Helper()
+	for obj, byObject := range opts.ByObject {
+		if _, ok := obj.(*notificationmiloapiscomv1alpha1.Contact); ok {
+			return byObject.Label
+		}
+	}
+	return nil
+}
+
+func TestCacheOptions(t *testing.T) {
+	labeled := &notificationmiloapiscomv1alpha1.Contact{
+		ObjectMeta: metav1.ObjectMeta{Name: "jane", Labels: map[string]string{"provider": "loops"}},
+	}
+	unlabeled := &notificationmiloapiscomv1alpha1.Contact{ObjectMeta: metav1.ObjectMeta{Name: "john"}}
+
+	tests := []struct {
+		name             string
+		labelSelector    string
+		expectedErr      bool
+		expectedSelected []*notificationmiloapiscomv1alpha1.Contact
+		expectedIgnored  []*notificationmiloapiscomv1alpha1.Contact
+	}{
+		{
+			name:             "no selector",
+			expectedSelected: []*notificationmiloapiscomv1alpha1.Contact{labeled, unlabeled},
+		},
+		{
+			name:             "selector",
+			labelSelector:    "provider=loops",
+			expectedSelected: []*notificationmiloapiscomv1alpha1.Contact{labeled},
+			expectedIgnored:  []*notificationmiloapiscomv1alpha1.Contact{unlabeled},
+		},
+		{
+			name:          "invalid selector",
+			labelSelector: "provider==loops==",
+			expectedErr:   true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts, err := cacheOptions(tt.labelSelector)
+			if (err != nil) != tt.expectedErr {
+				t.Fatalf("Expected error %t, got %v", tt.expectedErr, err)
+			}
+			if tt.expectedErr {
+				return
+			}
+			if tt.labelSelector != "" && len(opts.ByObject) != 4 {
+				t.Errorf("Expected the 4 reconciled kinds to be restricted, got %d", len(opts.ByObject))
+			}
+
+			selector := contactSelector(t, opts)
+			if selector == nil {
+				selector = labels.Everything()
+			}
+			for _, contact := range tt.expectedSelected {
+				if !selector.Matches(labels.Set(contact.Labels)) {
+					t.Errorf("Expected Contact %s to be cached and reconciled", contact.Name)
+				}
+			}
+			for _, contact := range tt.expectedIgnored {
+				if selector.Matches(labels.Set(contact.Labels)) {
+					t.Errorf("Expected Contact %s to be ignored", contact.Name)
+				}
+			}
+		})
+	}
+}
@@ -1,11 +1,13 @@
 package manager
 
 import (
+	"context"
 	"crypto/tls"
 	"flag"
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 	"time"
 
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
@@ -17,6 +19,7 @@ import (
 	notificationmiloapiscomv1alpha1 "go.miloapis.com/milo/pkg/apis/notification/v1alpha1"
 
 	"github.com/spf13/cobra"
+	"go.opentelemetry.io/otel/trace"
 	"k8s.io/apimachinery/pkg/runtime"
 	_ "k8s.io/client-go/plugin/pkg/client/auth"
 
@@ -24,11 +27,19 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/certwatcher"
 	"sigs.k8s.io/controller-runtime/pkg/healthz"
 	"sigs.k8s.io/controller-runtime/pkg/log/zap"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
 	"sigs.k8s.io/controller-runtime/pkg/metrics/filters"
 	metricsserver "sigs.k8s.io/controller-runtime/pkg/metrics/server"
 	"sigs.k8s.io/controller-runtime/pkg/webhook"
 )
 
+const (
+	// debugBufferSize is the number of requests to the email provider kept by --enable-debug-buffer
+	debugBufferSize = 100
+	// debugBufferPath is the metrics server path serving the requests kept by --enable-debug-buffer
+	debugBufferPath = "/debug/loops"
+)
+
 // nolint:gocyclo
 func CreateManagerCommand() *cobra.Command {
 	var (
@@ -42,6 +53,36 @@ func CreateManagerCommand() *cobra.Command {
 		leaderElectionID, leaderElectionNamespace, leaderElectionResourceLock string
 		leaseDuration, renewDeadline, retryPeriod                             time.Duration
 		newsLetterContactGroupName, newsLetterContactGroupNamespace           string
+		deleteStrategy                                                        string
+		providerCallTimeout                                                   time.Duration
+		requireProviderOnStart                                                bool
+		instanceID                                                            string
+		previousInstanceIDs                                                   []string
+		contactSource                                                         string
+		providerMaxRetries                                                    int
+		providerRetryBackoff                                                  time.Duration
+		providerCircuitBreakerThreshold                                       int
+		providerCircuitBreakerCooldown                                        time.Duration
+		enableTracing                                                         bool
+		recreateDeletedContacts                                               bool
+		verifyListRemoval                                                     bool
+		mailingListSource                                                     string
+		syncedFields                                                          string
+		enableDebugBuffer                                                     bool
+		enableNewsletterAutoMembership                                        bool
+		maxInflightRequests                                                   int
+		loopsCAFile                                                           string
+		membershipNameHashLength                                              int
+		syncDegradedThreshold                                                 int
+		loopsAPIKeyFile                                                       string
+		loopsBaseURL                                                          string
+		mailingListUpdatePath                                                 string
+		labelSelector                                                         string
+		newsletterBatchWindow                                                 time.Duration
+		mailingListContactsPath                                               string
+		enableGC                                                              bool
+		gcInterval                                                            time.Duration
+		gcDryRun                                                              bool
 	)
 
 	cmd := &cobra.Command{
@@ -51,6 +92,44 @@ func CreateManagerCommand() *cobra.Command {
 		RunE: func(_ *cobra.Command, _ []string) error {
 			setupLog := ctrl.Log.WithName("setup")
 
+			switch controller.DeleteStrategy(deleteStrategy) {
+			case controller.DeleteStrategyDelete, controller.DeleteStrategyUnsubscribe:
+			default:
+				return fmt.Errorf("invalid --delete-strategy %q, must be one of: delete, unsubscribe", deleteStrategy)
+			}
+
+			switch controller.MailingListSource(mailingListSource) {
+			case controller.MailingListSourceMemberships, controller.MailingListSourceLabels:
+			default:
+				return fmt.Errorf("invalid --mailing-list-source %q, must be one of: memberships, labels", mailingListSource)
+			}
+
+			parsedContactSource, err := controller.ParseContactSource(contactSource)
+			if err != nil {
+				return fmt.Errorf("invalid --contact-source: %w", err)
+			}
+
+			parsedSyncedFields, err := controller.ParseSyncedFields(syncedFields)
+			if err != nil {
+				return fmt.Errorf("invalid --synced-fields: %w", err)
+			}
+
+			// Loops contacts can only be listed through the mailing lists, with a single source to recognize them by
+			if enableGC {
+				if mailingListContactsPath == "" {
+					return fmt.Errorf("--enable-gc requires --loops-mailing-list-contacts-path")
+				}
+				if strings.Contains(contactSource, "{{") {
+					return fmt.Errorf("--enable-gc requires a --contact-source without template actions, got %q", contactSource)
+				}
+			}
+
+			// The debug buffer is served by the metrics server, behind the same authn/authz filter as the metrics
+			if enableDebugBuffer && !secureMetrics {
+				return fmt.Errorf("--enable-debug-buffer requires --metrics-secure, so that %s is authenticated and authorized",
+					debugBufferPath)
+			}
+
 			var tlsOpts []func(*tls.Config)
 
 			disableHTTP2 := func(c *tls.Config) {
@@ -141,8 +220,17 @@ func CreateManagerCommand() *cobra.Command {
 			utilruntime.Must(iammiloapiscomv1alpha1.AddToScheme(scheme))
 			utilruntime.Must(notificationmiloapiscomv1alpha1.AddToScheme(scheme))
 
+			cacheOpts, err := cacheOptions(labelSelector)
+			if err != nil {
+				return fmt.Errorf("invalid --label-selector: %w", err)
+			}
+			if labelSelector != "" {
+				setupLog.Info("Only reconciling the objects matching the label selector", "labelSelector", labelSelector)
+			}
+
 			mgr, err := ctrl.NewManager(ctrl.GetConfigOrDie(), ctrl.Options{
 				Scheme:                     scheme,
+				Cache:                      cacheOpts,
 				Metrics:                    metricsServerOptions,
 				WebhookServer:              webhookServer,
 				HealthProbeBindAddress:     probeAddr,
@@ -186,34 +274,181 @@ func CreateManagerCommand() *cobra.Command {
 
 			// Setup Loops client
 			loopsAPIKey := os.Getenv("LOOPS_API_KEY")
-			if loopsAPIKey == "" {
-				return fmt.Errorf("LOOPS_API_KEY environment variable is required")
+			if loopsAPIKey == "" && loopsAPIKeyFile == "" {
+				return fmt.Errorf("LOOPS_API_KEY environment variable or --loops-api-key-file is required")
+			}
+			loopsOpts := []loops.ClientOption{
+				loops.WithRetries(providerMaxRetries, providerRetryBackoff),
+				loops.WithConcurrencyLimiter(loops.NewConcurrencyLimiter(maxInflightRequests)),
+				loops.WithClientName("manager"),
+				loops.WithMetricsRegisterer(metrics.Registry),
+				loops.WithLogger(ctrl.Log.WithName("loops")),
+			}
+			if providerCircuitBreakerThreshold > 0 {
+				loopsOpts = append(loopsOpts,
+					loops.WithCircuitBreaker(providerCircuitBreakerThreshold, providerCircuitBreakerCooldown))
+			}
+			if loopsAPIKeyFile != "" {
+				fileAPIKey, err := loops.NewFileAPIKey(loopsAPIKeyFile)
+				if err != nil {
+					return fmt.Errorf("invalid --loops-api-key-file: %w", err)
+				}
+				loopsOpts = append(loopsOpts, loops.WithAPIKeyProvider(fileAPIKey))
+				if err := mgr.Add(newAPIKeyReloader(ctrl.Log.WithName("api-key-reloader"), fileAPIKey)); err != nil {
+					setupLog.Error(err, "unable to set up API key reloading")
+					return fmt.Errorf("unable to set up API key reloading: %w", err)
+				}
+			}
+
+			// Setup tracing
+			var tracerProvider trace.TracerProvider
+			if enableTracing {
+				sdkTracerProvider, err := setupTracing(context.Background())
+				if err != nil {
+					setupLog.Error(err, "unable to set up tracing")
+					return err
+				}
+				defer func() {
+					shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+					defer cancel()
+					if err := sdkTracerProvider.Shutdown(shutdownCtx); err != nil {
+						setupLog.Error(err, "failed to flush traces")
+					}
+				}()
+				tracerProvider = sdkTracerProvider
+				loopsOpts = append(loopsOpts, loops.WithTracerProvider(tracerProvider))
+			}
+
+			if enableDebugBuffer {
+				loopsOpts = append(loopsOpts, loops.WithDebugBuffer(debugBufferSize))
+			}
+			if loopsCAFile != "" {
+				caCertPool, err := loops.LoadCACertPool(loopsCAFile)
+				if err != nil {
+					return fmt.Errorf("invalid --loops-ca-file: %w", err)
+				}
+				loopsOpts = append(loopsOpts, loops.WithCACertPool(caCertPool))
+			}
+			if mailingListUpdatePath != "" {
+				loopsOpts = append(loopsOpts, loops.WithMailingListUpdate(mailingListUpdatePath))
+			}
+			if mailingListContactsPath != "" {
+				loopsOpts = append(loopsOpts, loops.WithMailingListContacts(mailingListContactsPath))
 			}
-			loopsClient, err := loops.NewSDK(loopsAPIKey)
+
+			loopsClient, err := newLoopsClient(loopsAPIKey, loopsBaseURL, loopsOpts...)
 			if err != nil {
 				return fmt.Errorf("failed to create Loops client: %w", err)
 			}
 
+			if enableDebugBuffer {
+				if err := mgr.AddMetricsServerExtraHandler(debugBufferPath, loopsClient.DebugHandler()); err != nil {
+					setupLog.Error(err, "unable to add debug buffer endpoint")
+					return fmt.Errorf("unable to add debug buffer endpoint: %w", err)
+				}
+			}
+
+			// The controllers share the Loops rate limit, so they back off together after a 429
+			rateLimitGate := controller.NewRateLimitGate()
 			if err = (&controller.LoopsContactController{
 				Client:                          mgr.GetClient(),
 				Loops:                           loopsClient,
 				NewsLetterContactGroupName:      newsLetterContactGroupName,
 				NewsLetterContactGroupNamespace: newsLetterContactGroupNamespace,
+				DeleteStrategy:                  controller.DeleteStrategy(deleteStrategy),
+				ProviderCallTimeout:             providerCallTimeout,
+				InstanceID:                      instanceID,
+				PreviousInstanceIDs:             previousInstanceIDs,
+				ContactSource:                   parsedContactSource,
+				TracerProvider:                  tracerProvider,
+				RateLimitGate:                   rateLimitGate,
+				RecreateDeletedContacts:         recreateDeletedContacts,
+				MailingListSource:               controller.MailingListSource(mailingListSource),
+				DisableNewsletterAutoMembership: !enableNewsletterAutoMembership,
+				MembershipNameHashLength:        membershipNameHashLength,
+				SyncDegradedThreshold:           syncDegradedThreshold,
+				NewsletterBatchWindow:           newsletterBatchWindow,
+				SyncedFields:                    parsedSyncedFields,
+				Recorder:                        mgr.GetEventRecorderFor("loopscontact-controller"),
 			}).SetupWithManager(mgr); err != nil {
 				setupLog.Error(err, "unable to create controller", "controller", "LoopsContact")
 				return err
 			}
 
-			if err = (&controller.LoopsContactGroupMembershipController{
-				Client: mgr.GetClient(),
-				Loops:  loopsClient,
-			}).SetupWithManager(mgr); err != nil {
-				setupLog.Error(err, "unable to create controller", "controller", "LoopsContactGroupMembership")
-				return err
+			// Mailing lists synced from Contact labels would conflict with the ones synced from memberships
+			if controller.MailingListSource(mailingListSource) == controller.MailingListSourceMemberships {
+				if err = (&controller.LoopsContactGroupMembershipController{
+					Client:              mgr.GetClient(),
+					Loops:               loopsClient,
+					ProviderCallTimeout: providerCallTimeout,
+					InstanceID:          instanceID,
+					PreviousInstanceIDs: previousInstanceIDs,
+					TracerProvider:      tracerProvider,
+					RateLimitGate:       rateLimitGate,
+					VerifyListRemoval:   verifyListRemoval,
+				}).SetupWithManager(mgr); err != nil {
+					setupLog.Error(err, "unable to create controller", "controller", "LoopsContactGroupMembership")
+					return err
+				}
+
+				if err = (&controller.LoopsContactGroupMembershipRemovalController{
+					Client:              mgr.GetClient(),
+					Loops:               loopsClient,
+					ProviderCallTimeout: providerCallTimeout,
+					TracerProvider:      tracerProvider,
+					RateLimitGate:       rateLimitGate,
+					InstanceID:          instanceID,
+					PreviousInstanceIDs: previousInstanceIDs,
+				}).SetupWithManager(mgr); err != nil {
+					setupLog.Error(err, "unable to create controller", "controller", "LoopsContactGroupMembershipRemoval")
+					return err
+				}
+			}
+
+			// Syncing the mailing list metadata requires the mailing list update endpoint
+			if mailingListUpdatePath != "" {
+				if err = (&controller.LoopsContactGroupController{
+					Client:              mgr.GetClient(),
+					Loops:               loopsClient,
+					ProviderCallTimeout: providerCallTimeout,
+					TracerProvider:      tracerProvider,
+					RateLimitGate:       rateLimitGate,
+				}).SetupWithManager(mgr); err != nil {
+					setupLog.Error(err, "unable to create controller", "controller", "LoopsContactGroup")
+					return err
+				}
+			}
+
+			if enableGC {
+				if err := mgr.Add(&controller.ContactGarbageCollector{
+					Client:              mgr.GetClient(),
+					APIReader:           mgr.GetAPIReader(),
+					Loops:               loopsClient,
+					Source:              contactSource,
+					Interval:            gcInterval,
+					DryRun:              gcDryRun,
+					ProviderCallTimeout: providerCallTimeout,
+				}); err != nil {
+					setupLog.Error(err, "unable to add the contact garbage collector")
+					return fmt.Errorf("unable to add the contact garbage collector: %w", err)
+				}
+			}
+
+			ctx := ctrl.SetupSignalHandler()
+
+			if requireProviderOnStart {
+				keySource := "LOOPS_API_KEY"
+				if loopsAPIKeyFile != "" {
+					keySource = "--loops-api-key-file " + loopsAPIKeyFile
+				}
+				if err := checkProvider(ctx, setupLog, loopsClient, keySource); err != nil {
+					setupLog.Error(err, "email provider check failed")
+					return err
+				}
 			}
 
 			setupLog.Info("starting manager")
-			if err := mgr.Start(ctrl.SetupSignalHandler()); err != nil {
+			if err := mgr.Start(ctx); err != nil {
 				setupLog.Error(err, "problem running manager")
 				return fmt.Errorf("problem running manager: %w", err)
 			}
@@ -267,6 +502,110 @@ func CreateManagerCommand() *cobra.Command {
 		"newsletter-contact-group-name", "newsletter", "The name of the contact group for the newsletter.")
 	cmd.Flags().StringVar(&newsLetterContactGroupNamespace,
 		"newsletter-contact-group-namespace", "default", "The namespace of the contact group for the newsletter.")
+	cmd.Flags().BoolVar(&enableNewsletterAutoMembership, "enable-newsletter-automembership", true,
+		"If set, Contacts named 'newsletter-*' are added to the newsletter contact group. Disable it when the "+
+			"newsletter contact group is not configured.")
+	cmd.Flags().DurationVar(&newsletterBatchWindow, "newsletter-batch-window", 0,
+		"If set, the newsletter memberships of the Contacts are created together once per window instead of on "+
+			"each reconciliation, smoothing the writes when many newsletter Contacts are created at once. 0 creates "+
+			"them right away.")
+	cmd.Flags().IntVar(&membershipNameHashLength, "membership-name-hash-length", 0,
+		"The number of hex characters of the Contact UID hash in the names of the newsletter memberships. 0 keeps "+
+			"the full 64-character hash. Changing it creates the existing memberships again under new names.")
+	cmd.Flags().IntVar(&syncDegradedThreshold, "sync-degraded-threshold", 5,
+		"The number of consecutive failed Loops syncs of a Contact after which its SyncDegraded condition turns "+
+			"true. 0 disables the condition.")
+
+	// Contact group membership configuration flags
+	cmd.Flags().BoolVar(&verifyListRemoval, "verify-list-removal", false,
+		"If set, a ContactGroupMembership is only released once the email provider confirms the contact left "+
+			"the mailing list, retrying otherwise.")
+
+	// Instance configuration flags
+	cmd.Flags().StringVar(&instanceID, "instance-id", "",
+		"Suffix appended to the finalizer keys, required to be distinct when running several instances against "+
+			"different Loops accounts in the same cluster.")
+	cmd.Flags().StringArrayVar(&previousInstanceIDs, "previous-instance-id", nil,
+		"Instance ID this instance ran with before, whose finalizers are replaced by the ones of --instance-id so "+
+			"that changing it does not orphan the existing objects. An empty value stands for no instance ID. "+
+			"May be repeated.")
+	cmd.Flags().StringVar(&labelSelector, "label-selector", "",
+		"If set, only the Contacts, ContactGroups, ContactGroupMemberships and ContactGroupMembershipRemovals "+
+			"matching this label selector, e.g. 'notification.miloapis.com/provider=loops', are cached and "+
+			"reconciled. The others are ignored entirely.")
+
+	// Contact configuration flags
+	cmd.Flags().StringVar(&contactSource, "contact-source", controller.DefaultContactSource,
+		"The Loops source of the contacts created by the controller. It is a Go template rendered per Contact, "+
+			"exposing .Name and .Namespace, e.g. 'milo-{{.Namespace}}'.")
+
+	cmd.Flags().BoolVar(&enableGC, "enable-gc", false,
+		"If set, the leader periodically deletes the Loops contacts with the --contact-source whose userId no longer "+
+			"matches a Contact, e.g. after their finalizer was removed by hand. Contacts are found through the mailing "+
+			"lists of the ContactGroups, see --loops-mailing-list-contacts-path.")
+	cmd.Flags().DurationVar(&gcInterval, "gc-interval", controller.DefaultGarbageCollectionInterval,
+		"The interval between two runs of the contact garbage collector.")
+	cmd.Flags().BoolVar(&gcDryRun, "dry-run", false,
+		"If set, the contact garbage collector only logs the orphaned Loops contacts instead of deleting them.")
+
+	cmd.Flags().BoolVar(&recreateDeletedContacts, "recreate-deleted-contacts", false,
+		"If set, each resync of an up-to-date Contact checks that its Loops contact still exists and recreates it "+
+			"when it was deleted out-of-band. This costs a call to the email provider per reconciliation.")
+
+	cmd.Flags().StringVar(&mailingListSource, "mailing-list-source", string(controller.MailingListSourceMemberships),
+		"What Loops mailing list memberships are reconciled from. Supported options are 'memberships', which uses "+
+			"ContactGroupMemberships, and 'labels', which uses 'loops.list/<mailing list ID>=true' labels on the "+
+			"Contact and disables the ContactGroupMembership controller.")
+
+	cmd.Flags().StringVar(&syncedFields, "synced-fields", "",
+		"A comma-separated list of the contact fields pushed to Loops, e.g. 'email,subscribed' to keep names out of "+
+			"Loops. Supported fields are email, firstName, lastName, source, subscribed and mailingLists. The others are "+
+			"left untouched in Loops. Loops contacts are only created when email is synced. Empty syncs every field.")
+
+	// Contact deletion configuration flags
+	cmd.Flags().StringVar(&deleteStrategy, "delete-strategy", string(controller.DeleteStrategyDelete),
+		"How the Loops contact is handled when its Contact is deleted. Supported options are 'delete' and "+
+			"'unsubscribe', which unsubscribes and retains the contact.")
+
+	// Email provider configuration flags
+	cmd.Flags().BoolVar(&enableDebugBuffer, "enable-debug-buffer", false,
+		fmt.Sprintf("If set, the last %d requests to the email provider are kept in memory, with credentials and personal "+
+			"data redacted, and served on %s of the metrics server. Requires --metrics-secure: the endpoint is "+
+			"protected by the same authentication and authorization as the metrics.", debugBufferSize, debugBufferPath))
+	cmd.Flags().DurationVar(&providerCallTimeout, "provider-call-timeout", 30*time.Second,
+		"The maximum duration of a single call to the email provider. Use 0 to disable the per-call timeout.")
+	cmd.Flags().IntVar(&providerMaxRetries, "provider-max-retries", 0,
+		"The number of times a call to the email provider failing with a network error, a 429 or a 5xx is retried.")
+	cmd.Flags().DurationVar(&providerRetryBackoff, "provider-retry-backoff", 500*time.Millisecond,
+		"The wait before the first retry of a call to the email provider, doubled on each subsequent retry.")
+	cmd.Flags().IntVar(&providerCircuitBreakerThreshold, "provider-circuit-breaker-threshold", 0,
+		"The number of consecutive calls to the email provider failing with a network error, a 429 or a 5xx, "+
+			"after their retries, that stop the calls for --provider-circuit-breaker-cooldown. Zero disables it.")
+	cmd.Flags().DurationVar(&providerCircuitBreakerCooldown, "provider-circuit-breaker-cooldown", 30*time.Second,
+		"How long the calls to the email provider are stopped once the circuit breaker opens.")
+	cmd.Flags().StringVar(&loopsBaseURL, "loops-base-url", loops.DefaultBaseURL,
+		"The base URL of the email provider API, e.g. to point the controllers at a staging endpoint.")
+	cmd.Flags().StringVar(&loopsAPIKeyFile, "loops-api-key-file", "",
+		"Path to a file holding the email provider API key, used instead of LOOPS_API_KEY. The file is read "+
+			"again when the manager receives a SIGHUP, to rotate the key without a restart.")
+	cmd.Flags().StringVar(&loopsCAFile, "loops-ca-file", "",
+		"Path to a PEM bundle of the CAs trusted when connecting to the email provider, instead of the system ones, "+
+			"e.g. for an internal CA fronting it through a proxy.")
+	cmd.Flags().StringVar(&mailingListUpdatePath, "loops-mailing-list-update-path", "",
+		"Path of the email provider endpoint updating mailing lists, e.g. '/lists', for accounts offering it. When "+
+			"set, the display name and visibility of the ContactGroups are synced to their mailing list.")
+	cmd.Flags().StringVar(&mailingListContactsPath, "loops-mailing-list-contacts-path", "",
+		"Path of the email provider endpoint listing the contacts of mailing lists, e.g. '/lists', for accounts "+
+			"offering it. Required by --enable-gc.")
+	cmd.Flags().IntVar(&maxInflightRequests, "max-inflight-requests", 0,
+		"The maximum number of concurrent calls to the email provider across all controllers. Use 0 for no limit.")
+	cmd.Flags().BoolVar(&requireProviderOnStart, "require-provider-on-start", true,
+		"If set, the email provider is checked before starting the manager, failing fast on an invalid API key.")
+
+	// Observability configuration flags
+	cmd.Flags().BoolVar(&enableTracing, "enable-tracing", false,
+		"If set, OpenTelemetry spans are recorded for each reconciliation and email provider call and exported over "+
+			"OTLP/gRPC, configured through the standard OTEL_EXPORTER_OTLP_* environment variables.")
 
 	opts := zap.Options{
 		Development: true,
@@ -0,0 +1,10 @@
+package manager
+
+import (
+	loops "go.miloapis.com/email-provider-loops/pkg/loops"
+)
+
+// newLoopsClient creates the Loops client of the controllers, calling the Loops API at baseURL.
+func newLoopsClient(apiKey, baseURL string, opts ...loops.ClientOption) (*loops.Client, error) {
+	return loops.NewSDK(apiKey, append([]loops.ClientOption{loops.WithBaseURL(baseURL)}, opts...)...)
+}
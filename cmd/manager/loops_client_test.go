@@ -0,0 +1,45 @@
+package manager
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	loops "go.miloapis.com/email-provider-loops/pkg/loops"
+)
+
+func TestNewLoopsClient_BaseURL(t *testing.T) {
+	var gotPath string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotPath = r.URL.Path
+		if _, err := w.Write([]byte(`{"success":true}`)); err != nil {
+			t.Errorf("Failed to write response: %v", err)
+		}
+	}))
+	defer ts.Close()
+
+	// CreateManagerCommand binds the zap flags to the global flag set, so it can only be called once per test binary
+	cmd := CreateManagerCommand()
+	if got := cmd.Flags().Lookup("loops-base-url").DefValue; got != loops.DefaultBaseURL {
+		t.Errorf("Expected the default %q, got %q", loops.DefaultBaseURL, got)
+	}
+	if err := cmd.Flags().Parse([]string{"--loops-base-url", ts.URL + "/staging"}); err != nil {
+		t.Fatalf("Parse() failed: %v", err)
+	}
+	baseURL, err := cmd.Flags().GetString("loops-base-url")
+	if err != nil {
+		t.Fatalf("GetString() failed: %v", err)
+	}
+
+	client, err := newLoopsClient("test-key", baseURL)
+	if err != nil {
+		t.Fatalf("newLoopsClient() failed: %v", err)
+	}
+	if err := client.Ping(context.Background()); err != nil {
+		t.Fatalf("Ping() failed: %v", err)
+	}
+	if gotPath != "/staging/api-key" {
+		t.Errorf("Expected the request to reach /staging/api-key, got %q", gotPath)
+	}
+}
@@ -0,0 +1,35 @@
+package manager
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/go-logr/logr"
+	loops "go.miloapis.com/email-provider-loops/pkg/loops"
+)
+
+// apiKeyValidator checks that the email provider is reachable and accepts our credentials.
+type apiKeyValidator interface {
+	ValidateAPIKey(ctx context.Context) error
+}
+
+// checkProvider gates the manager start on the email provider. An invalid API key fails fast, naming keySource the
+// key was read from, while a transient unreachability is only logged so the manager can still start and retry on
+// reconciliation.
+func checkProvider(ctx context.Context, log logr.Logger, provider apiKeyValidator, keySource string) error {
+	err := provider.ValidateAPIKey(ctx)
+	switch {
+	case err == nil:
+		log.Info("email provider is reachable")
+		return nil
+	case errors.Is(err, loops.ErrInvalidAPIKey):
+		return fmt.Errorf("email provider rejected the API key, check %s: %w", keySource, err)
+	case errors.Is(err, loops.ErrUnreachable):
+		log.Error(err, "email provider is unreachable, starting anyway")
+		return nil
+	default:
+		log.Error(err, "email provider check returned an unexpected response, starting anyway")
+		return nil
+	}
+}
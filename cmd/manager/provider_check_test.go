@@ -0,0 +1,59 @@
+package manager
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/go-logr/logr"
+	loops "go.miloapis.com/email-provider-loops/pkg/loops"
+)
+
+type fakeValidator struct {
+	err error
+}
+
+func (f fakeValidator) ValidateAPIKey(context.Context) error {
+	return f.err
+}
+
+func TestCheckProvider(t *testing.T) {
+	tests := []struct {
+		name      string
+		err       error
+		keySource string
+		wantErr   string
+	}{
+		{
+			name: "Reachable",
+		},
+		{
+			name:      "Unauthorized fails fast",
+			err:       fmt.Errorf("%w: %w", loops.ErrInvalidAPIKey, &loops.Error{StatusCode: http.StatusUnauthorized}),
+			keySource: "LOOPS_API_KEY",
+			wantErr:   "check LOOPS_API_KEY",
+		},
+		{
+			name:      "Unauthorized names the key file",
+			err:       fmt.Errorf("%w: %w", loops.ErrInvalidAPIKey, &loops.Error{StatusCode: http.StatusUnauthorized}),
+			keySource: "--loops-api-key-file /etc/loops/api-key",
+			wantErr:   "check --loops-api-key-file /etc/loops/api-key",
+		},
+		{
+			name: "Unreachable continues",
+			err:  fmt.Errorf("%w: %w", loops.ErrUnreachable, errors.New("connection refused")),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := checkProvider(context.Background(), logr.Discard(), fakeValidator{err: tt.err}, tt.keySource)
+			if (err != nil) != (tt.wantErr != "") || (err != nil && !strings.Contains(err.Error(), tt.wantErr)) {
+				t.Errorf("checkProvider() error = %v, wantErr %q", err, tt.wantErr)
+			}
+		})
+	}
+}
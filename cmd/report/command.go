@@ -0,0 +1,156 @@
+package report
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+	notificationmiloapiscomv1alpha1 "go.miloapis.com/milo/pkg/apis/notification/v1alpha1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	k8sconfig "sigs.k8s.io/controller-runtime/pkg/client/config"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/log/zap"
+
+	controller "go.miloapis.com/email-provider-loops/internal"
+	report "go.miloapis.com/email-provider-loops/internal/report"
+	loops "go.miloapis.com/email-provider-loops/pkg/loops"
+)
+
+// CreateReportCommand returns a cobra command grouping the commands that report on the Loops state of Milo objects.
+func CreateReportCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "report",
+		Short: "Report on the state stored in Loops for Milo objects, without changing anything",
+	}
+
+	cmd.AddCommand(createDriftCommand())
+
+	return cmd
+}
+
+func createDriftCommand() *cobra.Command {
+	var (
+		output            string
+		loopsCAFile       string
+		mailingListSource string
+		syncedFields      string
+	)
+
+	cmd := &cobra.Command{
+		Use:   "drift",
+		Short: "Print the differences between the desired Loops state of each Contact and the one stored in Loops",
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			if output != "text" && output != "json" {
+				return fmt.Errorf("unsupported output format: %s", output)
+			}
+			switch controller.MailingListSource(mailingListSource) {
+			case controller.MailingListSourceMemberships, controller.MailingListSourceLabels:
+			default:
+				return fmt.Errorf("invalid --mailing-list-source %q, must be one of: memberships, labels", mailingListSource)
+			}
+			parsedSyncedFields, err := controller.ParseSyncedFields(syncedFields)
+			if err != nil {
+				return fmt.Errorf("invalid --synced-fields: %w", err)
+			}
+
+			// Logs go to stderr, keeping stdout for the report
+			logf.SetLogger(zap.New(zap.JSONEncoder(), zap.WriteTo(os.Stderr)))
+			log := logf.Log.WithName("report")
+			ctx := logf.IntoContext(cmd.Context(), log)
+
+			// Setup Kubernetes client
+			restConfig, err := k8sconfig.GetConfig()
+			if err != nil {
+				return fmt.Errorf("failed to get rest config: %w", err)
+			}
+
+			runtimeScheme := runtime.NewScheme()
+			if err := notificationmiloapiscomv1alpha1.AddToScheme(runtimeScheme); err != nil {
+				return fmt.Errorf("failed to add notificationmiloapiscomv1alpha1 scheme: %w", err)
+			}
+
+			k8sClient, err := client.New(restConfig, client.Options{Scheme: runtimeScheme})
+			if err != nil {
+				return fmt.Errorf("failed to create kubernetes client: %w", err)
+			}
+
+			// Setup Loops client
+			loopsAPIKey := os.Getenv("LOOPS_API_KEY")
+			if loopsAPIKey == "" {
+				return fmt.Errorf("LOOPS_API_KEY environment variable is required")
+			}
+			var loopsOpts []loops.ClientOption
+			if loopsCAFile != "" {
+				caCertPool, err := loops.LoadCACertPool(loopsCAFile)
+				if err != nil {
+					return fmt.Errorf("invalid --loops-ca-file: %w", err)
+				}
+				loopsOpts = append(loopsOpts, loops.WithCACertPool(caCertPool))
+			}
+			loopsClient, err := loops.NewSDK(loopsAPIKey, loopsOpts...)
+			if err != nil {
+				return fmt.Errorf("failed to create Loops client: %w", err)
+			}
+
+			result, err := report.Drift(ctx, k8sClient, loopsClient, report.DriftOptions{
+				MailingListSource: controller.MailingListSource(mailingListSource),
+				SyncedFields:      parsedSyncedFields,
+			})
+			if result != nil {
+				if printErr := printDrift(cmd.OutOrStdout(), output, result); printErr != nil {
+					return printErr
+				}
+			}
+			if err != nil {
+				return fmt.Errorf("failed to report the drift of some contacts: %w", err)
+			}
+
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVarP(&output, "output", "o", "text", "Output format (text, json)")
+	cmd.Flags().StringVar(&loopsCAFile, "loops-ca-file", "",
+		"Path to a PEM bundle of the CAs trusted when connecting to Loops, instead of the system ones.")
+	cmd.Flags().StringVar(&mailingListSource, "mailing-list-source", string(controller.MailingListSourceMemberships),
+		"What the manager syncs the Loops mailing lists from, to compare them accordingly: 'memberships' or 'labels'.")
+	cmd.Flags().StringVar(&syncedFields, "synced-fields", "",
+		"The --synced-fields of the manager, the fields it does not sync are not compared. Empty compares every field.")
+
+	return cmd
+}
+
+// printDrift prints the drift report in the output format, either a table of the drifted fields or JSON.
+func printDrift(w io.Writer, output string, result *report.DriftReport) error {
+	if output == "json" {
+		data, err := json.MarshalIndent(result, "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to marshal drift report: %w", err)
+		}
+		_, err = fmt.Fprintln(w, string(data))
+		return err
+	}
+
+	if len(result.Drifted) == 0 {
+		_, err := fmt.Fprintf(w, "No drift found in %d contacts\n", result.ContactsScanned)
+		return err
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "CONTACT\tUSER ID\tFIELD\tDESIRED\tACTUAL")
+	for _, drift := range result.Drifted {
+		contact := drift.Namespace + "/" + drift.Name
+		if drift.Missing {
+			fmt.Fprintf(tw, "%s\t%s\t-\tpresent\tmissing\n", contact, drift.UserID)
+			continue
+		}
+		for _, field := range drift.Fields {
+			fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\n", contact, drift.UserID, field.Field, field.Desired, field.Actual)
+		}
+	}
+	return tw.Flush()
+}
@@ -3,6 +3,8 @@ package webhook
 import (
 	"fmt"
 	"os"
+	"strings"
+	"time"
 
 	"github.com/spf13/cobra"
 	notificationmiloapiscomv1alpha1 "go.miloapis.com/milo/pkg/apis/notification/v1alpha1"
@@ -24,6 +26,17 @@ func CreateWebhookCommand() *cobra.Command {
 		webhookPort                                     int
 		webhookCertDir, webhookCertFile, webhookKeyFile string
 		metricsBindAddress                              string
+		probeAddr                                       string
+		unknownEventResponse                            string
+		routePrefix                                     string
+		enableMembershipValidation                      bool
+		requireJSONContentType                          bool
+		userAgent                                       string
+		insecureSkipSignatureVerification               bool
+		unsignedTestEventName                           string
+		unsignedTestEventHeader                         string
+		signingSecretFile                               string
+		timestampTolerance                              time.Duration
 	)
 
 	cmd := &cobra.Command{
@@ -33,6 +46,12 @@ func CreateWebhookCommand() *cobra.Command {
 			logf.SetLogger(zap.New(zap.JSONEncoder()))
 			log := logf.Log.WithName("webhook")
 
+			switch webhook.UnknownEventResponseMode(unknownEventResponse) {
+			case webhook.UnknownEventResponseOK, webhook.UnknownEventResponseBadRequest:
+			default:
+				return fmt.Errorf("invalid --unknown-event-response %q, must be one of: ok, badrequest", unknownEventResponse)
+			}
+
 			log.Info("Starting webhook server",
 				"cert_dir", webhookCertDir,
 				"cert_file", webhookCertFile,
@@ -49,6 +68,8 @@ func CreateWebhookCommand() *cobra.Command {
 			if err != nil {
 				return fmt.Errorf("failed to get rest config: %w", err)
 			}
+			// Identify the webhook in the API server audit logs
+			restConfig.UserAgent = userAgent
 
 			runtimeScheme := runtime.NewScheme()
 			if err := notificationmiloapiscomv1alpha1.AddToScheme(runtimeScheme); err != nil {
@@ -61,6 +82,7 @@ func CreateWebhookCommand() *cobra.Command {
 				Metrics: server.Options{
 					BindAddress: metricsBindAddress,
 				},
+				HealthProbeBindAddress: probeAddr,
 				WebhookServer: ctrlwebhook.NewServer(ctrlwebhook.Options{
 					CertDir:  webhookCertDir,
 					CertName: webhookCertFile,
@@ -72,18 +94,70 @@ func CreateWebhookCommand() *cobra.Command {
 				return fmt.Errorf("failed to create manager: %w", err)
 			}
 
+			if err := addHealthChecks(mgr); err != nil {
+				return err
+			}
+
 			log.Info("Loading signing secret")
 			signingSecret := os.Getenv("LOOPS_SIGNING_SECRET")
-			if signingSecret == "" {
-				return fmt.Errorf("LOOPS_SIGNING_SECRET is required but not set")
+			var fileSigningSecret *webhook.FileSigningSecret
+			if signingSecretFile != "" {
+				fileSigningSecret, err = webhook.NewFileSigningSecret(signingSecretFile)
+				if err != nil {
+					return fmt.Errorf("failed to load --signing-secret-file: %w", err)
+				}
+				if err := mgr.Add(fileSigningSecret); err != nil {
+					return fmt.Errorf("failed to add signing secret reloader: %w", err)
+				}
+				signingSecret = fileSigningSecret.SigningSecret()
+			}
+			if insecureSkipSignatureVerification {
+				log.Info("WARNING: --insecure-skip-signature-verification is set, webhook signatures are NOT verified " +
+					"and anyone able to reach the webhook can forge Loops events. Never use it in production.")
+			} else if signingSecret == "" {
+				return fmt.Errorf("LOOPS_SIGNING_SECRET or --signing-secret-file is required but not set")
+			}
+
+			var unsignedTestEvent *webhook.UnsignedTestEventAllowance
+			if unsignedTestEventName != "" || unsignedTestEventHeader != "" {
+				header, value, ok := strings.Cut(unsignedTestEventHeader, "=")
+				if unsignedTestEventName == "" || !ok || header == "" || value == "" {
+					return fmt.Errorf("--unsigned-test-event-name and --unsigned-test-event-header, as name=value, " +
+						"must be set together")
+				}
+				unsignedTestEvent = &webhook.UnsignedTestEventAllowance{
+					EventName:   unsignedTestEventName,
+					Header:      header,
+					HeaderValue: value,
+				}
+				log.Info("WARNING: unsigned test events are acknowledged without being handled",
+					"eventName", unsignedTestEventName, "header", header)
 			}
 
 			log.Info("Setting up webhook")
 			webhookv1 := webhook.NewLoopsContactGroupMembershipWebhookV1(mgr.GetClient(), signingSecret)
+			webhookv1.UnknownEventResponse = webhook.UnknownEventResponseMode(unknownEventResponse)
+			webhookv1.RoutePrefix = routePrefix
+			webhookv1.RequireJSONContentType = requireJSONContentType
+			webhookv1.InsecureSkipSignatureVerification = insecureSkipSignatureVerification
+			webhookv1.UnsignedTestEvent = unsignedTestEvent
+			webhookv1.TimestampTolerance = timestampTolerance
+			if fileSigningSecret != nil {
+				webhookv1.SigningSecretProvider = fileSigningSecret
+			}
+			log.Info("Serving webhook, the Loops webhook URL must use this path", "path", webhookv1.Path())
 			if err := webhookv1.SetupWithManager(mgr); err != nil {
 				return fmt.Errorf("failed to setup webhook: %w", err)
 			}
 
+			if enableMembershipValidation {
+				log.Info("Setting up ContactGroupMembership validating webhook")
+				validator := &webhook.ContactGroupMembershipValidator{Client: mgr.GetClient()}
+				if err := validator.SetupWithManager(mgr); err != nil {
+					return fmt.Errorf("failed to setup ContactGroupMembership validating webhook: %w", err)
+				}
+			}
+
 			log.Info("Starting manager")
 			return mgr.Start(cmd.Context())
 
@@ -97,8 +171,45 @@ func CreateWebhookCommand() *cobra.Command {
 	cmd.Flags().StringVar(&webhookCertFile, "cert-file", "", "Filename in the directory that contains the TLS cert")
 	cmd.Flags().StringVar(&webhookKeyFile, "key-file", "", "Filename in the directory that contains the TLS private key")
 
+	cmd.Flags().StringVar(&routePrefix, "route-prefix", "",
+		"Prefix prepended to the webhook path, e.g. '/providers/loops', to host several provider webhooks "+
+			"behind one server. The webhook URL configured in Loops must include it.")
+
+	cmd.Flags().StringVar(&userAgent, "user-agent", "email-provider-loops-webhook",
+		"User-Agent of the requests to the Kubernetes API, identifying the webhook in the audit logs.")
+
+	// Event handling flags.
+	cmd.Flags().StringVar(&unknownEventResponse, "unknown-event-response", string(webhook.UnknownEventResponseOK),
+		"Response to events with an unknown name. 'ok' acknowledges them so that Loops stops retrying, "+
+			"'badrequest' rejects them to surface misconfigured event subscriptions.")
+	cmd.Flags().BoolVar(&requireJSONContentType, "require-json-content-type", false,
+		"If set, requests whose Content-Type is not application/json are rejected with a 415 before being parsed.")
+
+	// Security flags.
+	cmd.Flags().BoolVar(&insecureSkipSignatureVerification, "insecure-skip-signature-verification", false,
+		"INSECURE: accept webhook requests without verifying their signature, for local testing behind proxies "+
+			"that strip headers. LOOPS_SIGNING_SECRET is then optional. Never use it in production.")
+	cmd.Flags().StringVar(&signingSecretFile, "signing-secret-file", "",
+		"Path to a file holding the Loops signing secret, used instead of LOOPS_SIGNING_SECRET. The file is read "+
+			"again periodically, to rotate the secret without a restart.")
+	cmd.Flags().DurationVar(&timestampTolerance, "timestamp-tolerance", 5*time.Minute,
+		"Maximum difference between the webhook-timestamp of a request and the current time, beyond which the "+
+			"request is rejected as a possible replay. Zero accepts any timestamp.")
+	cmd.Flags().StringVar(&unsignedTestEventName, "unsigned-test-event-name", "",
+		"Name of the test event sent by the Loops dashboard that is acknowledged without a signature, and without "+
+			"being handled. Requires --unsigned-test-event-header.")
+	cmd.Flags().StringVar(&unsignedTestEventHeader, "unsigned-test-event-header", "",
+		"Header, as name=value, that unsigned test events must carry to be acknowledged. "+
+			"Requires --unsigned-test-event-name.")
+
+	// Admission flags.
+	cmd.Flags().BoolVar(&enableMembershipValidation, "enable-membership-validation", false,
+		"If set, a validating admission webhook rejects ContactGroupMemberships referencing a Contact or "+
+			"ContactGroup that does not exist. It requires a ValidatingWebhookConfiguration pointing at this server.")
+
 	// Metrics flags.
 	cmd.Flags().StringVar(&metricsBindAddress, "metrics-bind-address", ":8080", "address the metrics endpoint binds to")
+	cmd.Flags().StringVar(&probeAddr, "health-probe-bind-address", ":8081", "The address the probe endpoint binds to.")
 
 	return cmd
 }
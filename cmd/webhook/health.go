@@ -0,0 +1,20 @@
+package webhook
+
+import (
+	"fmt"
+
+	"sigs.k8s.io/controller-runtime/pkg/healthz"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+)
+
+// addHealthChecks registers the liveness and readiness checks of the webhook server. The webhook is ready once its
+// server accepts TLS connections on its port.
+func addHealthChecks(mgr manager.Manager) error {
+	if err := mgr.AddHealthzCheck("healthz", healthz.Ping); err != nil {
+		return fmt.Errorf("unable to set up health check: %w", err)
+	}
+	if err := mgr.AddReadyzCheck("readyz", mgr.GetWebhookServer().StartedChecker()); err != nil {
+		return fmt.Errorf("unable to set up ready check: %w", err)
+	}
+	return nil
+}
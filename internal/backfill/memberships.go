@@ -0,0 +1,148 @@
+package backfill
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"go.miloapis.com/email-provider-loops/internal/util"
+	loops "go.miloapis.com/email-provider-loops/pkg/loops"
+	notificationmiloapiscomv1alpha1 "go.miloapis.com/milo/pkg/apis/notification/v1alpha1"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// MembershipsOptions configures a ContactGroupMemberships backfill.
+type MembershipsOptions struct {
+	// DryRun reports the missing memberships without creating them
+	DryRun bool
+}
+
+// MembershipsResult summarizes a ContactGroupMemberships backfill.
+type MembershipsResult struct {
+	// ContactsScanned is the number of contacts whose Loops mailing lists were read
+	ContactsScanned int
+	// Missing are the memberships that did not exist in Milo. They are created unless running in dry-run mode.
+	Missing []notificationmiloapiscomv1alpha1.ContactGroupMembership
+}
+
+// Memberships creates the ContactGroupMemberships that are missing in Milo for the mailing lists contacts are
+// subscribed to in Loops. Contact groups are matched by their Loops provider ID. Running it again is a no-op.
+func Memberships(ctx context.Context, k8sClient client.Client, loopsAPI loops.API, opts MembershipsOptions) (*MembershipsResult, error) {
+	log := logf.FromContext(ctx).WithValues("backfill", "ContactGroupMemberships", "dryRun", opts.DryRun)
+
+	// Index contact groups by their Loops mailing list ID
+	var groupList notificationmiloapiscomv1alpha1.ContactGroupList
+	if err := k8sClient.List(ctx, &groupList); err != nil {
+		return nil, fmt.Errorf("failed to list contact groups: %w", err)
+	}
+	groupsByListID := map[string]*notificationmiloapiscomv1alpha1.ContactGroup{}
+	for i := range groupList.Items {
+		for _, provider := range groupList.Items[i].Spec.Providers {
+			if provider.Name == "Loops" && provider.ID != "" {
+				groupsByListID[provider.ID] = &groupList.Items[i]
+			}
+		}
+	}
+
+	// Index existing memberships by contact and group
+	var membershipList notificationmiloapiscomv1alpha1.ContactGroupMembershipList
+	if err := k8sClient.List(ctx, &membershipList); err != nil {
+		return nil, fmt.Errorf("failed to list contact group memberships: %w", err)
+	}
+	existing := map[string]bool{}
+	for _, membership := range membershipList.Items {
+		existing[membershipKey(membership.Spec.ContactRef, membership.Spec.ContactGroupRef)] = true
+	}
+
+	var contactList notificationmiloapiscomv1alpha1.ContactList
+	if err := k8sClient.List(ctx, &contactList); err != nil {
+		return nil, fmt.Errorf("failed to list contacts: %w", err)
+	}
+
+	result := &MembershipsResult{}
+	var errs []error
+	for i := range contactList.Items {
+		contact := &contactList.Items[i]
+
+		mailingLists, err := loopsAPI.GetContactMailingLists(ctx, loopsContactID(contact))
+		if err != nil {
+			if loops.IsNotFound(err) {
+				log.Info("Contact not found in Loops, skipping", "contactName", contact.Name, "contactNamespace", contact.Namespace)
+				continue
+			}
+			log.Error(err, "Failed to get Loops mailing lists", "contactName", contact.Name, "contactNamespace", contact.Namespace)
+			errs = append(errs, fmt.Errorf("failed to get Loops mailing lists for contact %s/%s: %w", contact.Namespace, contact.Name, err))
+			continue
+		}
+		result.ContactsScanned++
+
+		for listID, subscribed := range mailingLists {
+			if !subscribed {
+				continue
+			}
+
+			group, ok := groupsByListID[listID]
+			if !ok {
+				log.Info("No contact group found for Loops mailing list, skipping", "listID", listID)
+				continue
+			}
+
+			membership := newContactGroupMembership(contact, group)
+			key := membershipKey(membership.Spec.ContactRef, membership.Spec.ContactGroupRef)
+			if existing[key] {
+				continue
+			}
+
+			log.Info("Missing contact group membership", "contactName", contact.Name, "contactNamespace", contact.Namespace, "groupName", group.Name, "groupNamespace", group.Namespace)
+			if !opts.DryRun {
+				if err := k8sClient.Create(ctx, membership); err != nil {
+					log.Error(err, "Failed to create contact group membership", "contactName", contact.Name, "groupName", group.Name)
+					errs = append(errs, fmt.Errorf("failed to create contact group membership for contact %s/%s and group %s/%s: %w", contact.Namespace, contact.Name, group.Namespace, group.Name, err))
+					continue
+				}
+			}
+
+			existing[key] = true
+			result.Missing = append(result.Missing, *membership)
+		}
+	}
+
+	return result, errors.Join(errs...)
+}
+
+// loopsContactID returns the Loops userId recorded in the contact status, falling back to the contact UID.
+func loopsContactID(contact *notificationmiloapiscomv1alpha1.Contact) string {
+	for _, provider := range contact.Status.Providers {
+		if provider.Name == "Loops" && provider.ID != "" {
+			return provider.ID
+		}
+	}
+	return string(contact.UID)
+}
+
+func membershipKey(contactRef notificationmiloapiscomv1alpha1.ContactReference, groupRef notificationmiloapiscomv1alpha1.ContactGroupReference) string {
+	return fmt.Sprintf("%s-%s-%s-%s", contactRef.Name, contactRef.Namespace, groupRef.Name, groupRef.Namespace)
+}
+
+func newContactGroupMembership(contact *notificationmiloapiscomv1alpha1.Contact, group *notificationmiloapiscomv1alpha1.ContactGroup) *notificationmiloapiscomv1alpha1.ContactGroupMembership {
+	return &notificationmiloapiscomv1alpha1.ContactGroupMembership{
+		ObjectMeta: metav1.ObjectMeta{
+			GenerateName: fmt.Sprintf("%s-%s", group.Name, contact.Name),
+			Namespace:    group.Namespace,
+			Labels:       util.InheritedLabels(contact.Labels, nil),
+		},
+		Spec: notificationmiloapiscomv1alpha1.ContactGroupMembershipSpec{
+			ContactRef: notificationmiloapiscomv1alpha1.ContactReference{
+				Name:      contact.Name,
+				Namespace: contact.Namespace,
+			},
+			ContactGroupRef: notificationmiloapiscomv1alpha1.ContactGroupReference{
+				Name:      group.Name,
+				Namespace: group.Namespace,
+			},
+		},
+	}
+}
@@ -0,0 +1,114 @@
+package backfill
+
+import (
+	"context"
+	"testing"
+
+	loops "go.miloapis.com/email-provider-loops/pkg/loops"
+	notificationmiloapiscomv1alpha1 "go.miloapis.com/milo/pkg/apis/notification/v1alpha1"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+// fakeLoops serves mailing list subscriptions by userId.
+type fakeLoops struct {
+	loops.API
+	mailingLists map[string]map[string]bool
+}
+
+func (f *fakeLoops) GetContactMailingLists(_ context.Context, userID string) (map[string]bool, error) {
+	lists, ok := f.mailingLists[userID]
+	if !ok {
+		return nil, &loops.Error{StatusCode: 404, Body: "not found"}
+	}
+	return lists, nil
+}
+
+func newContact(name string) *notificationmiloapiscomv1alpha1.Contact {
+	return &notificationmiloapiscomv1alpha1.Contact{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default", UID: types.UID(name + "-uid")},
+	}
+}
+
+func newContactGroup(name, listID string) *notificationmiloapiscomv1alpha1.ContactGroup {
+	return &notificationmiloapiscomv1alpha1.ContactGroup{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default"},
+		Spec: notificationmiloapiscomv1alpha1.ContactGroupSpec{
+			Providers: []notificationmiloapiscomv1alpha1.ContactGroupProvider{{Name: "Loops", ID: listID}},
+		},
+	}
+}
+
+func TestMemberships(t *testing.T) {
+	ctx := context.Background()
+	scheme := runtime.NewScheme()
+	if err := notificationmiloapiscomv1alpha1.AddToScheme(scheme); err != nil {
+		t.Fatalf("failed to add notification scheme: %v", err)
+	}
+
+	jane := newContact("jane")
+	bob := newContact("bob")
+	ghost := newContact("ghost")
+	newsletter := newContactGroup("newsletter", "list-newsletter")
+	product := newContactGroup("product", "list-product")
+	bobNewsletter := newContactGroupMembership(bob, newsletter)
+	bobNewsletter.Name = "newsletter-bob"
+
+	k8sClient := fake.NewClientBuilder().
+		WithScheme(scheme).
+		WithObjects(jane, bob, ghost, newsletter, product, bobNewsletter).
+		Build()
+
+	loopsAPI := &fakeLoops{mailingLists: map[string]map[string]bool{
+		"jane-uid": {"list-newsletter": true, "list-product": false, "list-unknown": true},
+		"bob-uid":  {"list-newsletter": true},
+	}}
+
+	// Dry run reports the missing membership without creating it
+	result, err := Memberships(ctx, k8sClient, loopsAPI, MembershipsOptions{DryRun: true})
+	if err != nil {
+		t.Fatalf("Memberships() failed: %v", err)
+	}
+	if result.ContactsScanned != 2 {
+		t.Errorf("Expected 2 contacts scanned, got %d", result.ContactsScanned)
+	}
+	if len(result.Missing) != 1 || result.Missing[0].Spec.ContactRef.Name != "jane" || result.Missing[0].Spec.ContactGroupRef.Name != "newsletter" {
+		t.Fatalf("Expected jane's newsletter membership to be missing, got %+v", result.Missing)
+	}
+	assertMembershipCount(t, k8sClient, 1)
+
+	// Backfill creates the missing membership
+	result, err = Memberships(ctx, k8sClient, loopsAPI, MembershipsOptions{})
+	if err != nil {
+		t.Fatalf("Memberships() failed: %v", err)
+	}
+	if len(result.Missing) != 1 {
+		t.Fatalf("Expected 1 membership created, got %d", len(result.Missing))
+	}
+	assertMembershipCount(t, k8sClient, 2)
+
+	// Backfill is idempotent
+	result, err = Memberships(ctx, k8sClient, loopsAPI, MembershipsOptions{})
+	if err != nil {
+		t.Fatalf("Memberships() failed: %v", err)
+	}
+	if len(result.Missing) != 0 {
+		t.Errorf("Expected no membership created, got %d", len(result.Missing))
+	}
+	assertMembershipCount(t, k8sClient, 2)
+}
+
+func assertMembershipCount(t *testing.T, k8sClient client.Client, want int) {
+	t.Helper()
+	var memberships notificationmiloapiscomv1alpha1.ContactGroupMembershipList
+	if err := k8sClient.List(context.Background(), &memberships); err != nil {
+		t.Fatalf("List() failed: %v", err)
+	}
+	if len(memberships.Items) != want {
+		t.Errorf("Expected %d memberships, got %d", want, len(memberships.Items))
+	}
+}
@@ -4,26 +4,68 @@ import (
 	"context"
 	"crypto/sha256"
 	"fmt"
+	"maps"
+	"slices"
+	"strconv"
 	"strings"
+	"time"
 
 	"go.miloapis.com/email-provider-loops/internal/util"
 	loops "go.miloapis.com/email-provider-loops/pkg/loops"
 	notificationmiloapiscomv1alpha1 "go.miloapis.com/milo/pkg/apis/notification/v1alpha1"
 
+	"go.opentelemetry.io/otel/trace"
+	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/utils/clock"
 	"k8s.io/utils/ptr"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/event"
 	"sigs.k8s.io/controller-runtime/pkg/finalizer"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
 	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 )
 
 const (
 	loopsContactFinalizerKey = "notification.miloapis.com/loops-contact"
 )
 
+// mailingListsAnnotation holds the comma-separated, sorted IDs of the mailing lists the Loops contact is subscribed
+// to, as last echoed by Loops. The Contact status is defined by Milo and has no field for them.
+const mailingListsAnnotation = "notification.miloapis.com/loops-mailing-lists"
+
+// The sync state of the Loops contact, the error of its last failed sync and the number of consecutive failed syncs,
+// next to the Loops entry of the Contact status providers. The ContactProviderStatus is defined by Milo and has no
+// field for them.
+const (
+	providerSyncStateAnnotation           = "notification.miloapis.com/loops-sync-state"
+	providerLastSyncErrorAnnotation       = "notification.miloapis.com/loops-last-sync-error"
+	providerConsecutiveFailuresAnnotation = "notification.miloapis.com/loops-consecutive-sync-failures"
+)
+
+// Values of the providerSyncStateAnnotation
+const (
+	ProviderSyncStateSynced = "Synced"
+	ProviderSyncStateFailed = "Failed"
+)
+
+// skipLoopsSyncAnnotation opts a Contact out of the Loops sync when set to "true", e.g. for internal test accounts.
+// The Loops contact of a Contact that was synced before gaining the annotation is left as-is: it is neither updated
+// nor removed from Loops, even when the Contact is deleted. Removing the annotation syncs the Contact again.
+const skipLoopsSyncAnnotation = "notification.miloapis.com/skip-loops-sync"
+
+// maxSyncErrorLength bounds the error recorded in the providerLastSyncErrorAnnotation
+const maxSyncErrorLength = 1024
+
 const (
 	// LoopsContactReadyCondition is a condition that is set to true when the Loops contact is ready
 	LoopsContactReadyCondition = "LoopsContactReady"
@@ -37,6 +79,28 @@ const (
 	LoopsContactNotUpdatedReason = "ContactNotUpdated"
 	// LoopsContactNotFinalizedReason is a reason that is set when the Loops contact is not finalized
 	LoopsContactNotFinalizedReason = "ContactNotFinalized"
+	// LoopsContactSyncSkippedReason is a reason that is set when the Contact opted out of the Loops sync
+	LoopsContactSyncSkippedReason = "SyncSkipped"
+	// LoopsContactIDMissingReason is a reason that is set when the Contact resolves to an empty Loops userId, a
+	// terminal failure that is not retried until the Contact changes
+	LoopsContactIDMissingReason = "ContactIDMissing"
+	// LoopsContactRecreatedInProviderReason is a reason that is set when the Loops contact is recreated after being
+	// deleted out-of-band, e.g. from the Loops dashboard
+	LoopsContactRecreatedInProviderReason = "RecreatedInProvider"
+)
+
+// SyncRecoveredReason is the reason of the event recorded when a failing Loops contact sync succeeds again
+const SyncRecoveredReason = "SyncRecovered"
+
+const (
+	// SyncDegradedCondition is a condition that is set to true when the Loops contact sync failed
+	// SyncDegradedThreshold times in a row, an alerting signal distinct from a single transient failure
+	SyncDegradedCondition = "SyncDegraded"
+	// ConsecutiveSyncFailuresReason is a reason that is set when the Loops contact sync keeps failing
+	ConsecutiveSyncFailuresReason = "ConsecutiveSyncFailures"
+	// SyncHealthyReason is a reason that is set when the Loops contact sync failed fewer times in a row than the
+	// threshold
+	SyncHealthyReason = "SyncHealthy"
 )
 
 const (
@@ -46,6 +110,19 @@ const (
 	NewsLetterAddedReason = "NewsLetterAdded"
 	// NewsLetterNotAddedReason is a reason that is set when the mailing list is not added to the Loops contact
 	NewsLetterNotAddedReason = "NewsLetterNotAdded"
+	// NewsLetterOptedOutReason is a reason that is set when the contact opted out of the newsletter, i.e. a
+	// ContactGroupMembershipRemoval exists for the contact and the newsletter group
+	NewsLetterOptedOutReason = "NewsLetterOptedOut"
+)
+
+// DeleteStrategy defines how the Loops contact is handled when its Contact is deleted.
+type DeleteStrategy string
+
+const (
+	// DeleteStrategyDelete deletes the Loops contact
+	DeleteStrategyDelete DeleteStrategy = "delete"
+	// DeleteStrategyUnsubscribe unsubscribes the Loops contact and retains it
+	DeleteStrategyUnsubscribe DeleteStrategy = "unsubscribe"
 )
 
 // LoopsContactReconciler reconciles a LoopsContact object
@@ -55,12 +132,65 @@ type LoopsContactController struct {
 	Loops                           loops.API
 	NewsLetterContactGroupName      string
 	NewsLetterContactGroupNamespace string
+	// ContactIDResolver resolves the Loops userId of a Contact. Defaults to the Contact UID.
+	ContactIDResolver ContactIDResolver
+	// DeleteStrategy defines how the Loops contact is handled on Contact deletion. Defaults to DeleteStrategyDelete.
+	DeleteStrategy DeleteStrategy
+	// ProviderCallTimeout bounds each call to Loops. Zero means no per-call timeout.
+	ProviderCallTimeout time.Duration
+	// InstanceID distinguishes the finalizer key of this controller instance from other instances
+	InstanceID string
+	// PreviousInstanceIDs are the instance IDs the controller ran with before, whose finalizers it takes over
+	PreviousInstanceIDs []string
+	// ContactSource renders the Loops source of each contact. Defaults to DefaultContactSource.
+	ContactSource *ContactSource
+	// TracerProvider records a span per reconciliation when set
+	TracerProvider trace.TracerProvider
+	// RateLimitGate delays the reconciliations while Loops rate limits the controllers sharing it. Reconciliations are
+	// not delayed when nil.
+	RateLimitGate *RateLimitGate
+	// Clock is the source of the current time of the time-dependent logic, the real clock when nil. Tests set a fake one
+	// to make it deterministic.
+	Clock clock.PassiveClock
+	// RecreateDeletedContacts makes reconciliations of up-to-date contacts check that the Loops contact still exists,
+	// recreating contacts deleted out-of-band. It costs a Loops call per reconciliation.
+	RecreateDeletedContacts bool
+	// MailingListSource defines what mailing list memberships are reconciled from. With MailingListSourceLabels the
+	// mailing lists are synced from the Contact labels on upsert, and the newsletter membership is not created.
+	// Defaults to MailingListSourceMemberships.
+	MailingListSource MailingListSource
+	// DisableNewsletterAutoMembership stops newsletter- contacts from being added to the newsletter contact group, for
+	// deployments that do not configure it.
+	DisableNewsletterAutoMembership bool
+	// MembershipNameHashLength is the number of hex characters of the Contact UID hash in the names of the generated
+	// newsletter ContactGroupMemberships. Zero or a length above 64 keeps the full hash, which existing memberships are
+	// named with; changing it makes the controller create new memberships under the new names.
+	MembershipNameHashLength int
+	// Recorder records an event when a Contact recovers from a failed Loops sync. No events are recorded when nil.
+	Recorder record.EventRecorder
+	// SyncDegradedThreshold is the number of consecutive failed syncs of a Contact after which its SyncDegraded
+	// condition turns true. Zero disables the condition.
+	SyncDegradedThreshold int
+	// NewsletterBatchWindow batches the creation of the newsletter ContactGroupMemberships, created together once per
+	// window instead of on each reconciliation. Zero creates them right away.
+	NewsletterBatchWindow time.Duration
+	// SyncedFields are the fields populated when upserting a Loops contact, the others being left untouched in Loops.
+	// Every field is synced when nil.
+	SyncedFields SyncedFields
+
+	// newsletterBatcher creates the newsletter memberships when NewsletterBatchWindow is set
+	newsletterBatcher *newsletterBatcher
 }
 
-// loopsContactFinalizer is a finalizer for the Contact object
+// loopsContactFinalizer is a finalizer for the Contact object. A Contact deleted while the controller is down keeps
+// its finalizer, so its Loops contact is still removed once the controller is back. Loops contacts only get orphaned
+// when the finalizer is removed by hand, see ContactGarbageCollector.
 type loopsContactFinalizer struct {
-	Client client.Client
-	Loops  loops.API
+	Client              client.Client
+	Loops               loops.API
+	ContactIDResolver   ContactIDResolver
+	DeleteStrategy      DeleteStrategy
+	ProviderCallTimeout time.Duration
 }
 
 func (f *loopsContactFinalizer) Finalize(ctx context.Context, obj client.Object) (finalizer.Result, error) {
@@ -96,7 +226,7 @@ func (f *loopsContactFinalizer) Finalize(ctx context.Context, obj client.Object)
 			ObservedGeneration: contact.GetGeneration(),
 		})
 
-		if err := util.PatchStatusIfChanged(ctx, util.StatusPatchParams{
+		if err := util.PatchStatusWithRetry(ctx, util.StatusPatchParams{
 			Client:     f.Client,
 			Logger:     log,
 			Object:     contact,
@@ -115,14 +245,23 @@ func (f *loopsContactFinalizer) Finalize(ctx context.Context, obj client.Object)
 	return finalizer.Result{}, nil
 }
 
-// +kubebuilder:rbac:groups=notification.miloapis.com,resources=contacts,verbs=get;list;watch
+// +kubebuilder:rbac:groups=notification.miloapis.com,resources=contacts,verbs=get;list;watch;patch
 // +kubebuilder:rbac:groups=notification.miloapis.com,resources=contacts/status,verbs=get;update;patch
 // +kubebuilder:rbac:groups=notification.miloapis.com,resources=contacts/finalizers,verbs=update
-// +kubebuilder:rbac:groups=notification.miloapis.com,resources=contactgroupmemberships,verbs=get;list;watch;delete
+// +kubebuilder:rbac:groups=notification.miloapis.com,resources=contactgroupmemberships,verbs=get;list;watch;create;patch;delete
+// +kubebuilder:rbac:groups="",resources=events,verbs=create;patch
 
 // Reconcile is the main function that reconciles the Contact object.
 func (r *LoopsContactController) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	return traceReconcile(ctx, r.TracerProvider, "LoopsContactController", req,
+		func(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+			return gateReconcile(ctx, r.RateLimitGate, req, withReconcileSummary("LoopsContactController", r.Clock, r.reconcile))
+		})
+}
+
+func (r *LoopsContactController) reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
 	log := logf.FromContext(ctx).WithValues("controller", "ContactController", "trigger", req.NamespacedName)
+	summary := reconcileSummaryFrom(ctx)
 	log.Info("Starting reconciliation", "namespacedName", req.String(), "name", req.Name, "namespace", req.Namespace)
 
 	// Get Contact
@@ -137,17 +276,19 @@ func (r *LoopsContactController) Reconcile(ctx context.Context, req ctrl.Request
 	}
 
 	// Run finalizers
+	adopted := adoptPreviousFinalizers(contact, loopsContactFinalizerKey, r.InstanceID, r.PreviousInstanceIDs)
 	finalizeResult, err := r.Finalizers.Finalize(ctx, contact)
 	if err != nil {
 		log.Error(err, "Failed to run finalizers for Contact")
 		return ctrl.Result{}, fmt.Errorf("failed to run finalizers for Contact: %w", err)
 	}
-	if finalizeResult.Updated {
+	if finalizeResult.Updated || adopted {
+		summary.setAction(reconcileActionFinalize)
 		log.Info("finalizer updated the contact object, updating API server")
 		if updateErr := r.Client.Update(ctx, contact); updateErr != nil {
 			if errors.IsConflict(updateErr) {
 				log.Info("Conflict updating Contact after finalizer update; requeuing")
-				return ctrl.Result{Requeue: true}, nil
+				return conflictRequeueResult(), nil
 			}
 			log.Error(updateErr, "Failed to update Contact after finalizer update")
 			return ctrl.Result{}, updateErr
@@ -155,26 +296,44 @@ func (r *LoopsContactController) Reconcile(ctx context.Context, req ctrl.Request
 		return ctrl.Result{}, nil
 	}
 
+	// Skip creation and updates for a contact being deleted, as the Loops contact is about to be removed
+	if !contact.DeletionTimestamp.IsZero() {
+		log.Info("Contact is being deleted, skipping reconciliation")
+		return ctrl.Result{}, nil
+	}
+
+	if skipsLoopsSync(contact) {
+		log.Info("Contact opted out of the Loops sync, skipping reconciliation")
+		summary.setAction(reconcileActionSkip)
+		return ctrl.Result{}, r.markSyncSkipped(ctx, contact)
+	}
+
 	var reconcileError error
 	oldStatus := contact.Status.DeepCopy()
 	original := contact.DeepCopy()
 	readyCond := meta.FindStatusCondition(contact.Status.Conditions, LoopsContactReadyCondition)
 
 	switch {
-	// First creation – condition not present yet
-	case readyCond == nil || readyCond.Reason == LoopsContactNotCreatedReason:
+	// First creation – condition not present yet, or the contact opted back into the sync
+	case readyCond == nil || readyCond.Reason == LoopsContactNotCreatedReason ||
+		readyCond.Reason == LoopsContactSyncSkippedReason || readyCond.Reason == LoopsContactIDMissingReason:
 		log.Info("LoopsContact creation")
+		summary.setAction(reconcileActionCreate)
 
-		err := r.upsertContact(ctx, contact)
+		contactID, err := r.upsertContact(ctx, contact, false)
 		if err != nil {
 			reconcileError = err
 			log.Info("Bad Request when creating Loops contact")
+			reason := LoopsContactNotCreatedReason
+			if isEmptyContactID(err) {
+				reason = LoopsContactIDMissingReason
+			}
 			meta.SetStatusCondition(&contact.Status.Conditions, metav1.Condition{
 				Type:               LoopsContactReadyCondition,
 				Status:             metav1.ConditionFalse,
-				Reason:             LoopsContactNotCreatedReason,
+				Reason:             reason,
 				Message:            fmt.Sprintf("Loops contact not created on email provider: %s", err.Error()),
-				LastTransitionTime: metav1.Now(),
+				LastTransitionTime: r.now(),
 				ObservedGeneration: contact.GetGeneration(),
 			})
 		}
@@ -186,31 +345,37 @@ func (r *LoopsContactController) Reconcile(ctx context.Context, req ctrl.Request
 				Status:             metav1.ConditionTrue,
 				Reason:             LoopsContactCreatedReason,
 				Message:            "Loops contact created on email provider",
-				LastTransitionTime: metav1.Now(),
+				LastTransitionTime: r.now(),
 				ObservedGeneration: contact.GetGeneration(),
 			})
 			contact.Status.Providers = []notificationmiloapiscomv1alpha1.ContactProviderStatus{
 				{
 					Name: "Loops",
-					ID:   string(contact.UID),
+					ID:   contactID,
 				},
 			}
 		}
 
 	// Update – generation changed since we last processed the object
-	case readyCond.ObservedGeneration != contact.GetGeneration() || readyCond.Reason == LoopsContactNotUpdatedReason:
+	case readyCond.ObservedGeneration != contact.GetGeneration() || readyCond.Reason == LoopsContactNotUpdatedReason ||
+		(r.syncsMailingListLabels() && mailingListLabelsChanged(contact)):
 		log.Info("Contact updated")
+		summary.setAction(reconcileActionUpdate)
 
-		err := r.upsertContact(ctx, contact)
+		_, err := r.upsertContact(ctx, contact, true)
 		if err != nil {
 			reconcileError = err
 			log.Error(err, "Failed to update contact on email provider")
+			reason := LoopsContactNotUpdatedReason
+			if isEmptyContactID(err) {
+				reason = LoopsContactIDMissingReason
+			}
 			meta.SetStatusCondition(&contact.Status.Conditions, metav1.Condition{
 				Type:               LoopsContactReadyCondition,
 				Status:             metav1.ConditionFalse,
-				Reason:             LoopsContactNotUpdatedReason,
+				Reason:             reason,
 				Message:            fmt.Sprintf("Loops contact not updated on email provider: %s", err.Error()),
-				LastTransitionTime: metav1.Now(),
+				LastTransitionTime: r.now(),
 				ObservedGeneration: contact.GetGeneration(),
 			})
 		}
@@ -222,19 +387,66 @@ func (r *LoopsContactController) Reconcile(ctx context.Context, req ctrl.Request
 				Status:             metav1.ConditionTrue,
 				Reason:             LoopsContactUpdatedReason,
 				Message:            "Loops contact updated on email provider",
-				LastTransitionTime: metav1.Now(),
+				LastTransitionTime: r.now(),
 				ObservedGeneration: contact.GetGeneration(),
 			})
 		}
 	}
 
+	// Resync – the contact is up to date, recreate it if it was deleted from Loops out-of-band
+	if r.RecreateDeletedContacts && reconcileError == nil && readyCond != nil &&
+		readyCond.Status == metav1.ConditionTrue && readyCond.ObservedGeneration == contact.GetGeneration() {
+		recreated, err := r.recreateDeletedContact(ctx, contact)
+		if err != nil {
+			reconcileError = err
+			meta.SetStatusCondition(&contact.Status.Conditions, metav1.Condition{
+				Type:               LoopsContactReadyCondition,
+				Status:             metav1.ConditionFalse,
+				Reason:             LoopsContactNotCreatedReason,
+				Message:            fmt.Sprintf("Loops contact not recreated on email provider: %s", err.Error()),
+				LastTransitionTime: r.now(),
+				ObservedGeneration: contact.GetGeneration(),
+			})
+		}
+		if recreated {
+			summary.setAction(reconcileActionRecreate)
+			meta.SetStatusCondition(&contact.Status.Conditions, metav1.Condition{
+				Type:               LoopsContactReadyCondition,
+				Status:             metav1.ConditionTrue,
+				Reason:             LoopsContactRecreatedInProviderReason,
+				Message:            "Loops contact recreated on email provider after it was deleted out-of-band",
+				LastTransitionTime: r.now(),
+				ObservedGeneration: contact.GetGeneration(),
+			})
+			if r.Recorder != nil {
+				r.Recorder.Event(contact, corev1.EventTypeWarning, LoopsContactRecreatedInProviderReason,
+					"Loops contact was deleted out-of-band and has been recreated")
+			}
+		}
+	}
+
+	if r.syncsMailingListLabels() && reconcileError == nil {
+		if err := r.recordSyncedMailingLists(ctx, contact); err != nil {
+			log.Error(err, "Failed to record the synced mailing lists")
+			reconcileError = err
+		}
+	}
+
+	failures, err := r.recordProviderSyncState(ctx, contact, reconcileError)
+	if err != nil {
+		log.Error(err, "Failed to record the Loops sync state")
+	}
+	r.setSyncDegradedCondition(contact, failures)
+
 	errorAddingToNewsLetter := false
 	if r.isNewsletterContact(contact) {
 		errorAddingToNewsLetter = r.addToNewsLetterList(ctx, contact)
 	}
 
+	summary.setCondition(meta.FindStatusCondition(contact.Status.Conditions, LoopsContactReadyCondition))
+
 	// Update contact status if it changed
-	if err := util.PatchStatusIfChanged(ctx, util.StatusPatchParams{
+	if err := util.PatchStatusWithRetry(ctx, util.StatusPatchParams{
 		Client:     r.Client,
 		Logger:     log,
 		Object:     contact,
@@ -246,6 +458,19 @@ func (r *LoopsContactController) Reconcile(ctx context.Context, req ctrl.Request
 		return ctrl.Result{}, err
 	}
 
+	oldReadyCond := meta.FindStatusCondition(oldStatus.Conditions, LoopsContactReadyCondition)
+	if r.Recorder != nil && oldReadyCond != nil && oldReadyCond.Status == metav1.ConditionFalse &&
+		oldReadyCond.Reason != LoopsContactSyncSkippedReason &&
+		meta.IsStatusConditionTrue(contact.Status.Conditions, LoopsContactReadyCondition) {
+		r.Recorder.Event(contact, corev1.EventTypeNormal, SyncRecoveredReason, "Loops contact synced again after a failure")
+	}
+
+	// Retrying cannot resolve an empty userId, the Contact is reconciled again when it changes
+	if isEmptyContactID(reconcileError) {
+		log.Info("Contact resolves to an empty Loops userId, not retrying")
+		return ctrl.Result{}, nil
+	}
+
 	if reconcileError != nil {
 		return ctrl.Result{}, reconcileError
 	}
@@ -260,52 +485,390 @@ func (r *LoopsContactController) Reconcile(ctx context.Context, req ctrl.Request
 	return ctrl.Result{}, nil
 }
 
+// now returns the current time of the Clock, for the timestamps of the conditions.
+func (r *LoopsContactController) now() metav1.Time {
+	return metav1.NewTime(clockOrReal(r.Clock).Now())
+}
+
 // SetupWithManager sets up the controller with the Manager.
 func (r *LoopsContactController) SetupWithManager(mgr ctrl.Manager) error {
-	// Register finalizer
-	r.Finalizers = finalizer.NewFinalizers()
-	if err := r.Finalizers.Register(loopsContactFinalizerKey, &loopsContactFinalizer{
-		Client: r.Client,
-		Loops:  r.Loops,
-	}); err != nil {
-		return fmt.Errorf("failed to register loops contact finalizer: %w", err)
+	if err := r.setupFinalizers(); err != nil {
+		return err
+	}
+
+	if r.NewsletterBatchWindow > 0 {
+		r.newsletterBatcher = newNewsletterBatcher(r.Client, r.NewsletterBatchWindow)
+		if err := mgr.Add(r.newsletterBatcher); err != nil {
+			return fmt.Errorf("failed to add newsletter batcher: %w", err)
+		}
+	}
+
+	// Index ContactGroupMembershipRemoval objects by their contact and group references so that a contact that opted
+	// out of the newsletter is not added back to it.
+	if err := mgr.GetFieldIndexer().IndexField(
+		context.Background(),
+		&notificationmiloapiscomv1alpha1.ContactGroupMembershipRemoval{},
+		contactGroupMembershipRemovalPairIndexKey,
+		func(rawObj client.Object) []string {
+			removal := rawObj.(*notificationmiloapiscomv1alpha1.ContactGroupMembershipRemoval)
+			return []string{buildContactGroupMembershipRemovalPairIndexKey(removal)}
+		},
+	); err != nil {
+		return fmt.Errorf("failed to create contact group membership removal index for contact and group pair: %w", err)
 	}
 
 	return ctrl.NewControllerManagedBy(mgr).
-		For(&notificationmiloapiscomv1alpha1.Contact{}).
+		For(&notificationmiloapiscomv1alpha1.Contact{}, builder.WithPredicates(contactChangedPredicate())).
+		// Recreate the newsletter membership when it is deleted externally, and record the memberships created by a
+		// batch flush
+		Watches(
+			&notificationmiloapiscomv1alpha1.ContactGroupMembership{},
+			handler.EnqueueRequestsFromMapFunc(r.newsletterMembershipContact),
+			builder.WithPredicates(predicate.Funcs{
+				CreateFunc:  func(event.CreateEvent) bool { return r.newsletterBatcher != nil },
+				UpdateFunc:  func(event.UpdateEvent) bool { return false },
+				DeleteFunc:  func(event.DeleteEvent) bool { return true },
+				GenericFunc: func(event.GenericEvent) bool { return false },
+			}),
+		).
 		Named("loopscontact").
 		Complete(r)
 }
 
-func (r *LoopsContactController) upsertContact(ctx context.Context, contact *notificationmiloapiscomv1alpha1.Contact) error {
+// newsletterMembershipContact maps a ContactGroupMembership to the Contact that owns it, i.e. the Contact whose
+// newsletter membership has its deterministic name. Other memberships are ignored.
+func (r *LoopsContactController) newsletterMembershipContact(ctx context.Context, obj client.Object) []reconcile.Request {
+	cgm, ok := obj.(*notificationmiloapiscomv1alpha1.ContactGroupMembership)
+	if !ok || !r.createsNewsletterMemberships() {
+		return nil
+	}
+	if cgm.Spec.ContactGroupRef.Name != r.NewsLetterContactGroupName ||
+		cgm.Spec.ContactGroupRef.Namespace != r.NewsLetterContactGroupNamespace {
+		return nil
+	}
+
+	contact := &notificationmiloapiscomv1alpha1.Contact{}
+	contactKey := client.ObjectKey{Namespace: cgm.Spec.ContactRef.Namespace, Name: cgm.Spec.ContactRef.Name}
+	if err := r.Client.Get(ctx, contactKey, contact); err != nil {
+		if !errors.IsNotFound(err) {
+			logf.FromContext(ctx).Error(err, "Failed to get Contact of ContactGroupMembership", "contactGroupMembership", cgm.Name)
+		}
+		return nil
+	}
+	if cgm.Namespace != contact.Namespace || cgm.Name != r.generateCgmName(contact) {
+		return nil
+	}
+
+	return []reconcile.Request{{NamespacedName: contactKey}}
+}
+
+// setupFinalizers registers the contact finalizer under the instance finalizer key.
+func (r *LoopsContactController) setupFinalizers() error {
+	r.Finalizers = finalizer.NewFinalizers()
+	if err := r.Finalizers.Register(finalizerKey(loopsContactFinalizerKey, r.InstanceID), &loopsContactFinalizer{
+		Client:              r.Client,
+		Loops:               r.Loops,
+		ContactIDResolver:   r.ContactIDResolver,
+		DeleteStrategy:      r.DeleteStrategy,
+		ProviderCallTimeout: r.ProviderCallTimeout,
+	}); err != nil {
+		return fmt.Errorf("failed to register loops contact finalizer: %w", err)
+	}
+
+	return nil
+}
+
+// upsertContact creates or updates the Loops contact and returns the userId it is identified by. When clearEmptyNames
+// is set, names that are empty in the Contact spec are emptied in Loops too, so that clearing a name in Milo
+// propagates on update.
+func (r *LoopsContactController) upsertContact(ctx context.Context, contact *notificationmiloapiscomv1alpha1.Contact, clearEmptyNames bool) (string, error) {
 	log := logf.FromContext(ctx).WithValues("controller", "LoopsContactController", "trigger", contact.Name)
 	log.Info("Creating Loops contact")
 
-	// Create Loops contact
-	_, err := r.Loops.UpsertContact(ctx, loops.ContactRequest{
-		Email:      contact.Spec.Email,
-		UserID:     string(contact.UID),
+	contactID, err := resolveContactID(r.ContactIDResolver, contact)
+	if err != nil {
+		log.Error(err, "Failed to resolve Loops contact ID")
+		return "", fmt.Errorf("failed to resolve Loops contact ID: %w", err)
+	}
+
+	source, err := r.ContactSource.Render(contact)
+	if err != nil {
+		log.Error(err, "Failed to render Loops contact source")
+		return "", err
+	}
+
+	req := loops.ContactRequest{
+		Email:      loops.NormalizeEmail(contact.Spec.Email),
+		UserID:     contactID,
 		FirstName:  contact.Spec.GivenName,
 		LastName:   contact.Spec.FamilyName,
-		Source:     "email-provider-loops-k8s-controller",
+		Source:     source,
 		Subscribed: ptr.To(true),
-	})
+	}
+	if r.syncsMailingListLabels() {
+		req.MailingLists = mailingListsFromLabels(contact)
+	}
+	if clearEmptyNames {
+		if contact.Spec.GivenName == "" {
+			req.ClearFields = append(req.ClearFields, loops.ContactFieldFirstName)
+		}
+		if contact.Spec.FamilyName == "" {
+			req.ClearFields = append(req.ClearFields, loops.ContactFieldLastName)
+		}
+	}
+	r.SyncedFields.apply(&req)
+
+	// Create Loops contact
+	callCtx, cancel := withProviderCallTimeout(ctx, r.ProviderCallTimeout)
+	defer cancel()
+	resp, err := r.Loops.UpsertContact(callCtx, req)
 	if err != nil {
 		log.Error(err, "Failed to find Loops contact")
-		return fmt.Errorf("failed to find Loops contact: %w", err)
+		return "", fmt.Errorf("failed to find Loops contact: %w", err)
+	}
+
+	// Best-effort, the contact is upserted even if its mailing lists cannot be recorded
+	if err := r.recordMailingLists(ctx, contact, resp); err != nil {
+		log.Error(err, "Failed to record the Loops mailing lists of the contact")
+	}
+
+	return contactID, nil
+}
+
+// contactChangedPredicate filters out the updates that only change the status or the annotations this controller
+// records on a Contact, which would otherwise reconcile it again right away and, on a failed sync, count a failure per
+// reconciliation. Spec changes bump the generation, while the labels, the skipLoopsSyncAnnotation, the deletion
+// timestamp and finalizers are compared too, as they change what is synced.
+func contactChangedPredicate() predicate.Predicate {
+	return predicate.Funcs{
+		UpdateFunc: func(e event.UpdateEvent) bool {
+			if e.ObjectOld == nil || e.ObjectNew == nil {
+				return true
+			}
+			return e.ObjectOld.GetGeneration() != e.ObjectNew.GetGeneration() ||
+				!maps.Equal(e.ObjectOld.GetLabels(), e.ObjectNew.GetLabels()) ||
+				e.ObjectOld.GetAnnotations()[skipLoopsSyncAnnotation] != e.ObjectNew.GetAnnotations()[skipLoopsSyncAnnotation] ||
+				!e.ObjectOld.GetDeletionTimestamp().Equal(e.ObjectNew.GetDeletionTimestamp()) ||
+				!slices.Equal(e.ObjectOld.GetFinalizers(), e.ObjectNew.GetFinalizers())
+		},
+	}
+}
+
+// skipsLoopsSync returns true if the contact opted out of the Loops sync with the skipLoopsSyncAnnotation.
+func skipsLoopsSync(contact *notificationmiloapiscomv1alpha1.Contact) bool {
+	return contact.Annotations[skipLoopsSyncAnnotation] == "true"
+}
+
+// markSyncSkipped sets the ready condition of a contact that opted out of the Loops sync.
+func (r *LoopsContactController) markSyncSkipped(ctx context.Context, contact *notificationmiloapiscomv1alpha1.Contact) error {
+	oldStatus := contact.Status.DeepCopy()
+	original := contact.DeepCopy()
+	meta.SetStatusCondition(&contact.Status.Conditions, metav1.Condition{
+		Type:               LoopsContactReadyCondition,
+		Status:             metav1.ConditionFalse,
+		Reason:             LoopsContactSyncSkippedReason,
+		Message:            fmt.Sprintf("Contact is not synced to Loops as it is annotated with %s", skipLoopsSyncAnnotation),
+		LastTransitionTime: r.now(),
+		ObservedGeneration: contact.GetGeneration(),
+	})
+
+	return util.PatchStatusWithRetry(ctx, util.StatusPatchParams{
+		Client:     r.Client,
+		Logger:     logf.FromContext(ctx),
+		Object:     contact,
+		Original:   original,
+		OldStatus:  oldStatus,
+		NewStatus:  &contact.Status,
+		FieldOwner: "loopscontact-controller",
+	})
+}
+
+// recordProviderSyncState annotates the contact with the outcome of its sync to Loops and returns the number of
+// consecutive failed syncs, reset by a successful one. A failed sync records its error, which a successful one
+// clears. The annotations do not trigger a reconciliation, see contactChangedPredicate, so failures are counted once
+// per retry of the failed reconciliation.
+func (r *LoopsContactController) recordProviderSyncState(ctx context.Context, contact *notificationmiloapiscomv1alpha1.Contact, syncErr error) (int, error) {
+	state, lastError, failures := ProviderSyncStateSynced, "", 0
+	if syncErr != nil {
+		state, lastError = ProviderSyncStateFailed, syncErr.Error()
+		if len(lastError) > maxSyncErrorLength {
+			lastError = lastError[:maxSyncErrorLength]
+		}
+		// An unreadable count restarts from the current failure
+		previous, _ := strconv.Atoi(contact.Annotations[providerConsecutiveFailuresAnnotation])
+		failures = max(previous, 0) + 1
+	}
+	if contact.Annotations[providerSyncStateAnnotation] == state &&
+		contact.Annotations[providerLastSyncErrorAnnotation] == lastError &&
+		contact.Annotations[providerConsecutiveFailuresAnnotation] == consecutiveFailuresValue(failures) {
+		return failures, nil
+	}
+
+	// Patch a copy, as the patch response would otherwise overwrite the pending status changes of contact
+	annotated := contact.DeepCopy()
+	if annotated.Annotations == nil {
+		annotated.Annotations = map[string]string{}
+	}
+	annotated.Annotations[providerSyncStateAnnotation] = state
+	if lastError != "" {
+		annotated.Annotations[providerLastSyncErrorAnnotation] = lastError
+		annotated.Annotations[providerConsecutiveFailuresAnnotation] = consecutiveFailuresValue(failures)
+	} else {
+		delete(annotated.Annotations, providerLastSyncErrorAnnotation)
+		delete(annotated.Annotations, providerConsecutiveFailuresAnnotation)
+	}
+	if err := r.Client.Patch(ctx, annotated, client.MergeFrom(contact)); err != nil {
+		return failures, fmt.Errorf("failed to annotate Contact with its Loops sync state: %w", err)
+	}
+
+	return failures, nil
+}
+
+// consecutiveFailuresValue returns the providerConsecutiveFailuresAnnotation value of a failure count, empty when
+// there are none.
+func consecutiveFailuresValue(failures int) string {
+	if failures == 0 {
+		return ""
+	}
+	return strconv.Itoa(failures)
+}
+
+// setSyncDegradedCondition sets the SyncDegraded condition of the contact from its number of consecutive failed
+// syncs. The condition is not set when SyncDegradedThreshold is zero.
+func (r *LoopsContactController) setSyncDegradedCondition(contact *notificationmiloapiscomv1alpha1.Contact, failures int) {
+	if r.SyncDegradedThreshold <= 0 {
+		return
+	}
+
+	if failures >= r.SyncDegradedThreshold {
+		meta.SetStatusCondition(&contact.Status.Conditions, metav1.Condition{
+			Type:               SyncDegradedCondition,
+			Status:             metav1.ConditionTrue,
+			Reason:             ConsecutiveSyncFailuresReason,
+			Message:            fmt.Sprintf("Loops contact sync failed at least %d times in a row", r.SyncDegradedThreshold),
+			LastTransitionTime: r.now(),
+			ObservedGeneration: contact.GetGeneration(),
+		})
+		return
+	}
+
+	meta.SetStatusCondition(&contact.Status.Conditions, metav1.Condition{
+		Type:               SyncDegradedCondition,
+		Status:             metav1.ConditionFalse,
+		Reason:             SyncHealthyReason,
+		Message:            fmt.Sprintf("Loops contact sync failed fewer than %d times in a row", r.SyncDegradedThreshold),
+		LastTransitionTime: r.now(),
+		ObservedGeneration: contact.GetGeneration(),
+	})
+}
+
+// recordMailingLists annotates the contact with the mailing lists it is subscribed to when the upsert response echoes
+// them. Responses without mailing lists leave the annotation untouched.
+func (r *LoopsContactController) recordMailingLists(ctx context.Context, contact *notificationmiloapiscomv1alpha1.Contact, resp *loops.APIResponse) error {
+	if resp == nil || resp.MailingLists == nil {
+		return nil
+	}
+
+	var subscribed []string
+	for mailingListID, isSubscribed := range resp.MailingLists {
+		if isSubscribed {
+			subscribed = append(subscribed, mailingListID)
+		}
+	}
+	slices.Sort(subscribed)
+	mailingLists := strings.Join(subscribed, ",")
+	if current, ok := contact.Annotations[mailingListsAnnotation]; ok && current == mailingLists {
+		return nil
+	}
+
+	// Patch a copy, as the patch response would otherwise overwrite the pending status changes of contact
+	annotated := contact.DeepCopy()
+	if annotated.Annotations == nil {
+		annotated.Annotations = map[string]string{}
+	}
+	annotated.Annotations[mailingListsAnnotation] = mailingLists
+	if err := r.Client.Patch(ctx, annotated, client.MergeFrom(contact)); err != nil {
+		return fmt.Errorf("failed to annotate Contact with its mailing lists: %w", err)
 	}
 
 	return nil
 }
 
+// recreateDeletedContact looks the contact up in Loops by its userId and upserts it again if it is missing. It returns
+// true when the contact was recreated.
+func (r *LoopsContactController) recreateDeletedContact(ctx context.Context, contact *notificationmiloapiscomv1alpha1.Contact) (bool, error) {
+	log := logf.FromContext(ctx).WithValues("controller", "LoopsContactController", "trigger", contact.Name)
+
+	contactID, err := resolveContactID(r.ContactIDResolver, contact)
+	if err != nil {
+		log.Error(err, "Failed to resolve Loops contact ID")
+		return false, fmt.Errorf("failed to resolve Loops contact ID: %w", err)
+	}
+
+	callCtx, cancel := withProviderCallTimeout(ctx, r.ProviderCallTimeout)
+	defer cancel()
+	found, err := r.Loops.FindContact(callCtx, loops.FindContactRequest{UserID: contactID})
+	if err != nil {
+		log.Error(err, "Failed to find Loops contact")
+		return false, fmt.Errorf("failed to find Loops contact: %w", err)
+	}
+	if found != nil {
+		return false, nil
+	}
+
+	log.Info("Loops contact deleted out-of-band, recreating it")
+	if _, err := r.upsertContact(ctx, contact, false); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// DeleteContact removes the Loops contact according to the configured DeleteStrategy.
 func (f *loopsContactFinalizer) DeleteContact(ctx context.Context, contact *notificationmiloapiscomv1alpha1.Contact) error {
 	log := logf.FromContext(ctx).WithValues("controller", "LoopsContactController", "trigger", contact.Name)
+
+	if skipsLoopsSync(contact) {
+		log.Info("Contact opted out of the Loops sync, leaving its Loops contact as-is")
+		return nil
+	}
+
+	contactID, err := resolveContactID(f.ContactIDResolver, contact)
+	if isEmptyContactID(err) {
+		// Contacts without a userId are never upserted, so there is no Loops contact to remove
+		log.Info("Contact has no Loops userId, skipping Loops contact removal")
+		return nil
+	}
+	if err != nil {
+		log.Error(err, "Failed to resolve Loops contact ID")
+		return fmt.Errorf("failed to resolve Loops contact ID: %w", err)
+	}
+
+	if f.DeleteStrategy == DeleteStrategyUnsubscribe {
+		log.Info("Unsubscribing Loops contact")
+
+		// Unsubscribe Loops contact, retaining it on the email provider
+		callCtx, cancel := withProviderCallTimeout(ctx, f.ProviderCallTimeout)
+		defer cancel()
+		_, err = f.Loops.Unsubscribe(callCtx, contactID)
+		if err != nil {
+			if !isLoopsContactGone(err) {
+				log.Error(err, "Failed to unsubscribe Loops contact")
+				return fmt.Errorf("failed to unsubscribe Loops contact: %w", err)
+			}
+			log.Info("Loops contact not found, probably deleted already")
+		}
+
+		return nil
+	}
+
 	log.Info("Deleting Loops contact")
 
 	// Delete Loops contact
-	_, err := f.Loops.DeleteContact(ctx, string(contact.UID))
+	callCtx, cancel := withProviderCallTimeout(ctx, f.ProviderCallTimeout)
+	defer cancel()
+	_, err = f.Loops.DeleteContact(callCtx, contactID)
 	if err != nil {
-		if !loops.IsNotFound(err) {
+		if !isLoopsContactGone(err) {
 			log.Error(err, "Failed to delete Loops contact")
 			return fmt.Errorf("failed to delete Loops contact: %w", err)
 		}
@@ -315,6 +878,22 @@ func (f *loopsContactFinalizer) DeleteContact(ctx context.Context, contact *noti
 	return nil
 }
 
+// isLoopsContactGone returns true if err reports the Loops contact as absent, either not found or purged (410 Gone).
+func isLoopsContactGone(err error) bool {
+	return loops.IsNotFound(err) || loops.IsGone(err)
+}
+
+// syncsMailingListLabels returns true if the mailing lists are reconciled from the Contact labels.
+func (r *LoopsContactController) syncsMailingListLabels() bool {
+	return r.MailingListSource == MailingListSourceLabels
+}
+
+// createsNewsletterMemberships returns true if newsletter contacts are added to the newsletter contact group. Mailing
+// lists synced from the Contact labels do not use memberships.
+func (r *LoopsContactController) createsNewsletterMemberships() bool {
+	return !r.DisableNewsletterAutoMembership && !r.syncsMailingListLabels()
+}
+
 // isNewsletterContact returns true if the contact name starts with "newsletter-".
 func (r *LoopsContactController) isNewsletterContact(contact *notificationmiloapiscomv1alpha1.Contact) bool {
 	return strings.HasPrefix(contact.Name, "newsletter-")
@@ -322,19 +901,71 @@ func (r *LoopsContactController) isNewsletterContact(contact *notificationmiloap
 
 func (r *LoopsContactController) addToNewsLetterList(ctx context.Context, contact *notificationmiloapiscomv1alpha1.Contact) bool {
 	log := logf.FromContext(ctx).WithValues("controller", "LoopsContactController", "trigger", contact.Name)
+	if !r.createsNewsletterMemberships() {
+		log.Info("Newsletter auto-membership disabled, skipping")
+		return false
+	}
 	log.Info("Adding mailing list to Loops contact")
 
-	newsLetterCond := meta.FindStatusCondition(contact.Status.Conditions, NewsLetterAddedCondition)
-	if newsLetterCond != nil && newsLetterCond.Status == metav1.ConditionTrue {
+	// The membership name is deterministic, so an existing membership is found without creating it again. It may also
+	// have been deleted since the condition was set, in which case it is recreated.
+	existing := &notificationmiloapiscomv1alpha1.ContactGroupMembership{}
+	err := r.Client.Get(ctx, client.ObjectKey{Namespace: contact.Namespace, Name: r.generateCgmName(contact)}, existing)
+	if err == nil {
+		if !metav1.IsControlledBy(existing, contact) {
+			r.adoptNewsletterMembership(ctx, contact, existing)
+		}
 		log.Info("News letter already added")
+		r.setNewsLetterAddedCondition(contact)
+		return false
+	}
+	if !errors.IsNotFound(err) {
+		log.Error(err, "Failed to get newsletter ContactGroupMembership")
+		return true
+	}
+
+	// A membership created by the webhook or the backfill has a generated name. Creating another one would be collapsed
+	// with it, and the deletion of the duplicate would requeue the contact to create it again.
+	member, err := r.hasNewsletterMembership(ctx, contact)
+	if err != nil {
+		log.Error(err, "Failed to list the newsletter ContactGroupMemberships")
+		return true
+	}
+	if member {
+		log.Info("News letter already added by another ContactGroupMembership")
+		r.setNewsLetterAddedCondition(contact)
+		return false
+	}
+
+	// A ContactGroupMembershipRemoval is the persistent opt-out of the contact, so the membership is not recreated
+	optedOut, err := r.optedOutOfNewsletter(ctx, contact)
+	if err != nil {
+		log.Error(err, "Failed to look up the newsletter ContactGroupMembershipRemoval")
+		return true
+	}
+	if optedOut {
+		log.Info("Contact opted out of the newsletter, not adding it back")
+		meta.SetStatusCondition(&contact.Status.Conditions, metav1.Condition{
+			Type:               NewsLetterAddedCondition,
+			Status:             metav1.ConditionFalse,
+			Reason:             NewsLetterOptedOutReason,
+			Message:            "Contact opted out of the Newsletter list.",
+			LastTransitionTime: r.now(),
+			ObservedGeneration: contact.GetGeneration(),
+		})
 		return false
 	}
 
+	if meta.IsStatusConditionTrue(contact.Status.Conditions, NewsLetterAddedCondition) {
+		log.Info("Newsletter ContactGroupMembership not found, recreating it")
+	}
+
 	// Add mailing list to Loops contact
 	contactgroupmembership := notificationmiloapiscomv1alpha1.ContactGroupMembership{
 		ObjectMeta: metav1.ObjectMeta{
 			Name:      r.generateCgmName(contact),
 			Namespace: contact.Namespace,
+			Labels:    util.InheritedLabels(contact.Labels, nil),
 		},
 		Spec: notificationmiloapiscomv1alpha1.ContactGroupMembershipSpec{
 			ContactRef: notificationmiloapiscomv1alpha1.ContactReference{
@@ -347,10 +978,21 @@ func (r *LoopsContactController) addToNewsLetterList(ctx context.Context, contac
 			},
 		},
 	}
+	if err := r.setNewsletterMembershipOwner(contact, &contactgroupmembership); err != nil {
+		log.Error(err, "Failed to set the owner of the newsletter ContactGroupMembership")
+	}
+
+	if r.newsletterBatcher != nil {
+		log.Info("Newsletter ContactGroupMembership queued for the next batch")
+		r.newsletterBatcher.add(&contactgroupmembership)
+		return false
+	}
 
 	if err := r.Client.Create(ctx, &contactgroupmembership); err != nil {
+		// A membership created since the Get above
 		if errors.IsAlreadyExists(err) {
 			log.Info("ContactGroupMembership already exists")
+			r.setNewsLetterAddedCondition(contact)
 			return false
 		}
 		log.Error(err, "Failed to create ContactGroupMembership")
@@ -360,33 +1002,127 @@ func (r *LoopsContactController) addToNewsLetterList(ctx context.Context, contac
 			Status:             metav1.ConditionFalse,
 			Reason:             NewsLetterNotAddedReason,
 			Message:            fmt.Sprintf("Contact not added to Newsletter list: %s", err.Error()),
-			LastTransitionTime: metav1.Now(),
+			LastTransitionTime: r.now(),
 			ObservedGeneration: contact.GetGeneration(),
 		})
 
 		return true
 	}
 
+	r.setNewsLetterAddedCondition(contact)
+
+	log.Info("ContactGroupMembership created")
+	return false
+}
+
+// hasNewsletterMembership returns true if a ContactGroupMembership, not being deleted, exists for the contact and the
+// newsletter group, whatever its name, using the indexed field registered by the LoopsContactGroupMembershipController.
+func (r *LoopsContactController) hasNewsletterMembership(ctx context.Context, contact *notificationmiloapiscomv1alpha1.Contact) (bool, error) {
+	memberships, err := listContactGroupMemberships(ctx, r.Client, &notificationmiloapiscomv1alpha1.ContactGroupMembership{
+		Spec: notificationmiloapiscomv1alpha1.ContactGroupMembershipSpec{
+			ContactRef: notificationmiloapiscomv1alpha1.ContactReference{
+				Name:      contact.Name,
+				Namespace: contact.Namespace,
+			},
+			ContactGroupRef: notificationmiloapiscomv1alpha1.ContactGroupReference{
+				Name:      r.NewsLetterContactGroupName,
+				Namespace: r.NewsLetterContactGroupNamespace,
+			},
+		},
+	})
+	if err != nil {
+		return false, err
+	}
+	return len(memberships) > 0, nil
+}
+
+// optedOutOfNewsletter returns true if a ContactGroupMembershipRemoval exists for the contact and the newsletter
+// group, using the indexed field.
+func (r *LoopsContactController) optedOutOfNewsletter(ctx context.Context, contact *notificationmiloapiscomv1alpha1.Contact) (bool, error) {
+	removal := &notificationmiloapiscomv1alpha1.ContactGroupMembershipRemoval{
+		Spec: notificationmiloapiscomv1alpha1.ContactGroupMembershipRemovalSpec{
+			ContactRef: notificationmiloapiscomv1alpha1.ContactReference{
+				Name:      contact.Name,
+				Namespace: contact.Namespace,
+			},
+			ContactGroupRef: notificationmiloapiscomv1alpha1.ContactGroupReference{
+				Name:      r.NewsLetterContactGroupName,
+				Namespace: r.NewsLetterContactGroupNamespace,
+			},
+		},
+	}
+	var removalList notificationmiloapiscomv1alpha1.ContactGroupMembershipRemovalList
+	if err := r.Client.List(ctx, &removalList,
+		client.MatchingFields{contactGroupMembershipRemovalPairIndexKey: buildContactGroupMembershipRemovalPairIndexKey(removal)},
+	); err != nil {
+		return false, err
+	}
+	return len(removalList.Items) > 0, nil
+}
+
+// setNewsLetterAddedCondition marks the contact as added to the newsletter, once its membership exists.
+func (r *LoopsContactController) setNewsLetterAddedCondition(contact *notificationmiloapiscomv1alpha1.Contact) {
 	meta.SetStatusCondition(&contact.Status.Conditions, metav1.Condition{
 		Type:               NewsLetterAddedCondition,
 		Status:             metav1.ConditionTrue,
 		Reason:             NewsLetterAddedReason,
 		Message:            "Contact added to Newsletter list on email provider.",
-		LastTransitionTime: metav1.Now(),
+		LastTransitionTime: r.now(),
 		ObservedGeneration: contact.GetGeneration(),
 	})
-
-	log.Info("ContactGroupMembership created")
-	return false
 }
 
-// generateCgmName generates a deterministic name for a ContactGroupMembership
+// generateCgmName generates a deterministic name for a ContactGroupMembership. The name is the Contact name followed
+// by a hash of its UID, with the Contact name truncated so that the name fits the Kubernetes name length limit.
 func (r *LoopsContactController) generateCgmName(
 	contact *notificationmiloapiscomv1alpha1.Contact,
 ) string {
-	// Create a full hash for uniqueness
+	// The hash of the UID keeps the name unique when the Contact name is truncated
 	hash := sha256.Sum256([]byte(string(contact.UID)))
 	hashStr := fmt.Sprintf("%x", hash)
+	if r.MembershipNameHashLength > 0 && r.MembershipNameHashLength < len(hashStr) {
+		hashStr = hashStr[:r.MembershipNameHashLength]
+	}
 
-	return fmt.Sprintf("%s-%s", contact.Name, hashStr)
+	prefix := contact.Name
+	if maxPrefixLen := validation.DNS1123SubdomainMaxLength - len(hashStr) - 1; len(prefix) > maxPrefixLen {
+		// A truncated prefix must not end with a separator, which would make an invalid name
+		prefix = strings.TrimRight(prefix[:maxPrefixLen], "-.")
+	}
+
+	return fmt.Sprintf("%s-%s", prefix, hashStr)
+}
+
+// setNewsletterMembershipOwner makes the contact the controller owner of its newsletter membership, so that deleting
+// the contact garbage-collects the membership. Owner references cannot cross namespaces, which holds here as the
+// newsletter membership is always created in the namespace of its contact.
+func (r *LoopsContactController) setNewsletterMembershipOwner(
+	contact *notificationmiloapiscomv1alpha1.Contact,
+	cgm *notificationmiloapiscomv1alpha1.ContactGroupMembership,
+) error {
+	if cgm.Namespace != contact.Namespace {
+		return fmt.Errorf("membership namespace %s differs from contact namespace %s", cgm.Namespace, contact.Namespace)
+	}
+	return controllerutil.SetControllerReference(contact, cgm, r.Client.Scheme())
+}
+
+// adoptNewsletterMembership sets the owner of a newsletter membership created before the memberships were owned by
+// their contact. Failures are only logged, as the membership itself is in place.
+func (r *LoopsContactController) adoptNewsletterMembership(
+	ctx context.Context,
+	contact *notificationmiloapiscomv1alpha1.Contact,
+	cgm *notificationmiloapiscomv1alpha1.ContactGroupMembership,
+) {
+	log := logf.FromContext(ctx).WithValues("controller", "LoopsContactController", "trigger", contact.Name)
+
+	adopted := cgm.DeepCopy()
+	if err := r.setNewsletterMembershipOwner(contact, adopted); err != nil {
+		log.Error(err, "Failed to set the owner of the newsletter ContactGroupMembership")
+		return
+	}
+	if err := r.Client.Patch(ctx, adopted, client.MergeFrom(cgm)); err != nil {
+		log.Error(err, "Failed to adopt the newsletter ContactGroupMembership")
+		return
+	}
+	log.Info("Adopted the newsletter ContactGroupMembership")
 }
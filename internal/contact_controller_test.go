@@ -0,0 +1,1325 @@
+package controller
+
+import (
+	"context"
+	"errors"
+	"maps"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	loops "go.miloapis.com/email-provider-loops/pkg/loops"
+	notificationmiloapiscomv1alpha1 "go.miloapis.com/milo/pkg/apis/notification/v1alpha1"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+)
+
+func newTestContactController(t *testing.T, objs ...client.Object) (*LoopsContactController, *fakeLoops) {
+	t.Helper()
+	k8sClient := fake.NewClientBuilder().
+		WithScheme(newTestScheme(t)).
+		WithObjects(objs...).
+		WithStatusSubresource(&notificationmiloapiscomv1alpha1.Contact{}).
+		WithIndex(&notificationmiloapiscomv1alpha1.ContactGroupMembershipRemoval{}, contactGroupMembershipRemovalPairIndexKey, contactGroupMembershipRemovalPairIndex).
+		WithIndex(&notificationmiloapiscomv1alpha1.ContactGroupMembership{}, contactGroupMembershipPairIndexKey, contactGroupMembershipPairIndex).
+		Build()
+
+	loopsAPI := newFakeLoops()
+	r := &LoopsContactController{
+		Client:                          k8sClient,
+		Loops:                           loopsAPI,
+		NewsLetterContactGroupName:      "newsletter",
+		NewsLetterContactGroupNamespace: "default",
+	}
+
+	return r, loopsAPI
+}
+
+// contactGroupMembershipPairIndex is the index function the contact group membership controller registers for
+// memberships, which the contact controller uses to find the newsletter membership of a contact.
+func contactGroupMembershipPairIndex(rawObj client.Object) []string {
+	return []string{buildContactGroupMembershipPairIndexKey(rawObj.(*notificationmiloapiscomv1alpha1.ContactGroupMembership))}
+}
+
+// contactGroupMembershipRemovalPairIndex is the index function the contact controller registers for removals.
+func contactGroupMembershipRemovalPairIndex(rawObj client.Object) []string {
+	return []string{buildContactGroupMembershipRemovalPairIndexKey(rawObj.(*notificationmiloapiscomv1alpha1.ContactGroupMembershipRemoval))}
+}
+
+func TestReconcile_CustomContactIDResolver(t *testing.T) {
+	ctx := context.Background()
+	contact := newTestContact("jane")
+	contact.Finalizers = []string{loopsContactFinalizerKey}
+
+	r, loopsAPI := newTestContactController(t, contact)
+	r.ContactIDResolver = ContactIDResolverFunc(func(c *notificationmiloapiscomv1alpha1.Contact) (string, error) {
+		return "external-" + c.Name, nil
+	})
+	if err := r.setupFinalizers(); err != nil {
+		t.Fatalf("setupFinalizers() failed: %v", err)
+	}
+
+	req := ctrl.Request{NamespacedName: client.ObjectKeyFromObject(contact)}
+	if _, err := r.Reconcile(ctx, req); err != nil {
+		t.Fatalf("Reconcile() failed: %v", err)
+	}
+
+	if len(loopsAPI.upserts) != 1 || loopsAPI.upserts[0].UserID != "external-jane" {
+		t.Fatalf("Expected upsert with userId external-jane, got %+v", loopsAPI.upserts)
+	}
+
+	updated := &notificationmiloapiscomv1alpha1.Contact{}
+	if err := r.Client.Get(ctx, req.NamespacedName, updated); err != nil {
+		t.Fatalf("Get() failed: %v", err)
+	}
+	if len(updated.Status.Providers) != 1 || updated.Status.Providers[0].ID != "external-jane" {
+		t.Errorf("Expected status provider ID external-jane, got %+v", updated.Status.Providers)
+	}
+
+	// Deleting the contact runs the finalizer with the same ID
+	if err := r.Client.Delete(ctx, updated); err != nil {
+		t.Fatalf("Delete() failed: %v", err)
+	}
+	if _, err := r.Reconcile(ctx, req); err != nil {
+		t.Fatalf("Reconcile() failed: %v", err)
+	}
+	if len(loopsAPI.deletes) != 1 || loopsAPI.deletes[0] != "external-jane" {
+		t.Errorf("Expected delete with userId external-jane, got %v", loopsAPI.deletes)
+	}
+}
+
+func TestFinalize_DeleteStrategy(t *testing.T) {
+	tests := []struct {
+		name            string
+		strategy        DeleteStrategy
+		wantDeletes     int
+		wantUnsubscribe bool
+	}{
+		{
+			name:        "Default strategy deletes",
+			wantDeletes: 1,
+		},
+		{
+			name:        "Delete strategy deletes",
+			strategy:    DeleteStrategyDelete,
+			wantDeletes: 1,
+		},
+		{
+			name:            "Unsubscribe strategy unsubscribes",
+			strategy:        DeleteStrategyUnsubscribe,
+			wantUnsubscribe: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			contact := newTestContact("jane")
+			contact.Finalizers = []string{loopsContactFinalizerKey}
+
+			r, loopsAPI := newTestContactController(t, contact)
+			r.DeleteStrategy = tt.strategy
+			if err := r.setupFinalizers(); err != nil {
+				t.Fatalf("setupFinalizers() failed: %v", err)
+			}
+
+			if err := r.Client.Delete(ctx, contact); err != nil {
+				t.Fatalf("Delete() failed: %v", err)
+			}
+			if _, err := r.Reconcile(ctx, ctrl.Request{NamespacedName: client.ObjectKeyFromObject(contact)}); err != nil {
+				t.Fatalf("Reconcile() failed: %v", err)
+			}
+
+			if len(loopsAPI.deletes) != tt.wantDeletes {
+				t.Errorf("Expected %d deletes, got %v", tt.wantDeletes, loopsAPI.deletes)
+			}
+			unsubscribed := len(loopsAPI.unsubscribes) == 1 && loopsAPI.unsubscribes[0] == string(contact.UID)
+			if unsubscribed != tt.wantUnsubscribe {
+				t.Errorf("Expected unsubscribe %v, got unsubscribes %v", tt.wantUnsubscribe, loopsAPI.unsubscribes)
+			}
+		})
+	}
+}
+
+func TestFinalize_LoopsContactAlreadyGone(t *testing.T) {
+	tests := []struct {
+		name     string
+		strategy DeleteStrategy
+		err      error
+	}{
+		{name: "Delete of a missing contact", err: &loops.Error{StatusCode: http.StatusNotFound}},
+		{name: "Delete of a purged contact", err: &loops.Error{StatusCode: http.StatusGone}},
+		{name: "Unsubscribe of a purged contact", strategy: DeleteStrategyUnsubscribe, err: &loops.Error{StatusCode: http.StatusGone}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			contact := newTestContact("jane")
+			contact.Finalizers = []string{loopsContactFinalizerKey}
+
+			r, loopsAPI := newTestContactController(t, contact)
+			r.DeleteStrategy = tt.strategy
+			if err := r.setupFinalizers(); err != nil {
+				t.Fatalf("setupFinalizers() failed: %v", err)
+			}
+			loopsAPI.err = tt.err
+
+			if err := r.Client.Delete(ctx, contact); err != nil {
+				t.Fatalf("Delete() failed: %v", err)
+			}
+			if _, err := r.Reconcile(ctx, ctrl.Request{NamespacedName: client.ObjectKeyFromObject(contact)}); err != nil {
+				t.Fatalf("Reconcile() failed: %v", err)
+			}
+
+			err := r.Client.Get(ctx, client.ObjectKeyFromObject(contact), &notificationmiloapiscomv1alpha1.Contact{})
+			if !apierrors.IsNotFound(err) {
+				t.Errorf("Expected the contact to be released, got %v", err)
+			}
+		})
+	}
+}
+
+func TestReconcile_ProviderCallTimeout(t *testing.T) {
+	ctx := context.Background()
+	contact := newTestContact("jane")
+	contact.Finalizers = []string{loopsContactFinalizerKey}
+
+	r, loopsAPI := newTestContactController(t, contact)
+	r.ProviderCallTimeout = 50 * time.Millisecond
+	if err := r.setupFinalizers(); err != nil {
+		t.Fatalf("setupFinalizers() failed: %v", err)
+	}
+	loopsAPI.block = true
+
+	start := time.Now()
+	_, err := r.Reconcile(ctx, ctrl.Request{NamespacedName: client.ObjectKeyFromObject(contact)})
+	elapsed := time.Since(start)
+
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Expected deadline exceeded error, got %v", err)
+	}
+	if elapsed > 5*time.Second {
+		t.Errorf("Expected the call to be cancelled at the deadline, took %s", elapsed)
+	}
+}
+
+func TestReconcile_SkipsContactBeingDeleted(t *testing.T) {
+	ctx := context.Background()
+	contact := newTestContact("newsletter-jane")
+	now := metav1.Now()
+	contact.DeletionTimestamp = &now
+	// Another finalizer keeps the contact around after ours is gone
+	contact.Finalizers = []string{"example.com/other"}
+
+	r, loopsAPI := newTestContactController(t, contact)
+	if err := r.setupFinalizers(); err != nil {
+		t.Fatalf("setupFinalizers() failed: %v", err)
+	}
+
+	if _, err := r.Reconcile(ctx, ctrl.Request{NamespacedName: client.ObjectKeyFromObject(contact)}); err != nil {
+		t.Fatalf("Reconcile() failed: %v", err)
+	}
+
+	if len(loopsAPI.upserts) != 0 {
+		t.Errorf("Expected no upsert for a contact being deleted, got %+v", loopsAPI.upserts)
+	}
+
+	var memberships notificationmiloapiscomv1alpha1.ContactGroupMembershipList
+	if err := r.Client.List(ctx, &memberships); err != nil {
+		t.Fatalf("List() failed: %v", err)
+	}
+	if len(memberships.Items) != 0 {
+		t.Errorf("Expected no newsletter membership for a contact being deleted, got %d", len(memberships.Items))
+	}
+}
+
+func TestSetupFinalizers_InstanceID(t *testing.T) {
+	tests := []struct {
+		name       string
+		instanceID string
+		wantKey    string
+	}{
+		{
+			name:    "Default instance",
+			wantKey: "notification.miloapis.com/loops-contact",
+		},
+		{
+			name:       "Custom instance",
+			instanceID: "eu",
+			wantKey:    "notification.miloapis.com/loops-contact-eu",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			contact := newTestContact("jane")
+
+			r, _ := newTestContactController(t, contact)
+			r.InstanceID = tt.instanceID
+			if err := r.setupFinalizers(); err != nil {
+				t.Fatalf("setupFinalizers() failed: %v", err)
+			}
+
+			req := ctrl.Request{NamespacedName: client.ObjectKeyFromObject(contact)}
+			if _, err := r.Reconcile(ctx, req); err != nil {
+				t.Fatalf("Reconcile() failed: %v", err)
+			}
+
+			updated := &notificationmiloapiscomv1alpha1.Contact{}
+			if err := r.Client.Get(ctx, req.NamespacedName, updated); err != nil {
+				t.Fatalf("Get() failed: %v", err)
+			}
+			if len(updated.Finalizers) != 1 || updated.Finalizers[0] != tt.wantKey {
+				t.Errorf("Expected finalizers [%s], got %v", tt.wantKey, updated.Finalizers)
+			}
+		})
+	}
+}
+
+func TestReconcile_AdoptsPreviousInstanceFinalizer(t *testing.T) {
+	tests := []struct {
+		name     string
+		deleting bool
+		wantKeys []string
+	}{
+		{
+			name:     "Live contact",
+			wantKeys: []string{"notification.miloapis.com/loops-contact-eu"},
+		},
+		{
+			name:     "Contact being deleted",
+			deleting: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			contact := newTestContact("jane")
+			contact.Finalizers = []string{loopsContactFinalizerKey}
+			if tt.deleting {
+				contact.DeletionTimestamp = &metav1.Time{Time: time.Now()}
+			}
+
+			r, loopsAPI := newTestContactController(t, contact)
+			r.InstanceID = "eu"
+			r.PreviousInstanceIDs = []string{""}
+			if err := r.setupFinalizers(); err != nil {
+				t.Fatalf("setupFinalizers() failed: %v", err)
+			}
+
+			req := ctrl.Request{NamespacedName: client.ObjectKeyFromObject(contact)}
+			if _, err := r.Reconcile(ctx, req); err != nil {
+				t.Fatalf("Reconcile() failed: %v", err)
+			}
+
+			updated := &notificationmiloapiscomv1alpha1.Contact{}
+			err := r.Client.Get(ctx, req.NamespacedName, updated)
+			if tt.deleting {
+				// The adopted finalizer ran and released the contact
+				if !apierrors.IsNotFound(err) {
+					t.Errorf("Expected the contact to be deleted, got %v", err)
+				}
+				if len(loopsAPI.deletes) != 1 {
+					t.Errorf("Expected the Loops contact to be deleted, got %v", loopsAPI.deletes)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Get() failed: %v", err)
+			}
+			if !slices.Equal(updated.Finalizers, tt.wantKeys) {
+				t.Errorf("Expected finalizers %v, got %v", tt.wantKeys, updated.Finalizers)
+			}
+		})
+	}
+}
+
+func TestReconcile_ConflictRequeuesWithDelay(t *testing.T) {
+	ctx := context.Background()
+	contact := newTestContact("jane")
+
+	r, _ := newTestContactController(t)
+	r.Client = fake.NewClientBuilder().
+		WithScheme(newTestScheme(t)).
+		WithObjects(contact).
+		WithInterceptorFuncs(interceptor.Funcs{
+			Update: func(ctx context.Context, c client.WithWatch, obj client.Object, opts ...client.UpdateOption) error {
+				return apierrors.NewConflict(schema.GroupResource{Group: "notification.miloapis.com", Resource: "contacts"}, obj.GetName(), errors.New("conflict"))
+			},
+		}).
+		Build()
+	if err := r.setupFinalizers(); err != nil {
+		t.Fatalf("setupFinalizers() failed: %v", err)
+	}
+
+	result, err := r.Reconcile(ctx, ctrl.Request{NamespacedName: client.ObjectKeyFromObject(contact)})
+	if err != nil {
+		t.Fatalf("Reconcile() failed: %v", err)
+	}
+	if result.Requeue {
+		t.Error("Expected a delayed requeue, got an immediate one")
+	}
+	if result.RequeueAfter < conflictRequeueBaseDelay || result.RequeueAfter > 2*conflictRequeueBaseDelay {
+		t.Errorf("Expected RequeueAfter between %s and %s, got %s", conflictRequeueBaseDelay, 2*conflictRequeueBaseDelay, result.RequeueAfter)
+	}
+}
+
+func TestReconcile_ClearsEmptiedName(t *testing.T) {
+	ctx := context.Background()
+	contact := newTestContact("jane")
+	contact.Finalizers = []string{loopsContactFinalizerKey}
+	contact.Spec.FamilyName = "Doe"
+	// The given name was set when generation 1 was synced and has been cleared since
+	contact.Generation = 2
+	contact.Status.Conditions = []metav1.Condition{
+		{
+			Type:               LoopsContactReadyCondition,
+			Status:             metav1.ConditionTrue,
+			Reason:             LoopsContactCreatedReason,
+			LastTransitionTime: metav1.Now(),
+			ObservedGeneration: 1,
+		},
+	}
+
+	r, loopsAPI := newTestContactController(t, contact)
+	if err := r.setupFinalizers(); err != nil {
+		t.Fatalf("setupFinalizers() failed: %v", err)
+	}
+
+	if _, err := r.Reconcile(ctx, ctrl.Request{NamespacedName: client.ObjectKeyFromObject(contact)}); err != nil {
+		t.Fatalf("Reconcile() failed: %v", err)
+	}
+
+	if len(loopsAPI.upserts) != 1 {
+		t.Fatalf("Expected 1 upsert, got %d", len(loopsAPI.upserts))
+	}
+	clearFields := loopsAPI.upserts[0].ClearFields
+	if len(clearFields) != 1 || clearFields[0] != loops.ContactFieldFirstName {
+		t.Errorf("Expected only firstName to be cleared, got %v", clearFields)
+	}
+}
+
+func TestReconcile_RecreatesDeletedNewsletterMembership(t *testing.T) {
+	ctx := context.Background()
+	contact := newTestContact("newsletter-jane")
+	contact.Finalizers = []string{loopsContactFinalizerKey}
+
+	r, _ := newTestContactController(t, contact)
+	if err := r.setupFinalizers(); err != nil {
+		t.Fatalf("setupFinalizers() failed: %v", err)
+	}
+	req := ctrl.Request{NamespacedName: client.ObjectKeyFromObject(contact)}
+
+	if _, err := r.Reconcile(ctx, req); err != nil {
+		t.Fatalf("Reconcile() failed: %v", err)
+	}
+	cgm := &notificationmiloapiscomv1alpha1.ContactGroupMembership{}
+	cgmKey := client.ObjectKey{Namespace: contact.Namespace, Name: r.generateCgmName(contact)}
+	if err := r.Client.Get(ctx, cgmKey, cgm); err != nil {
+		t.Fatalf("Expected the newsletter membership to be created: %v", err)
+	}
+
+	// Deleting the membership enqueues its Contact
+	if err := r.Client.Delete(ctx, cgm); err != nil {
+		t.Fatalf("Delete() failed: %v", err)
+	}
+	requests := r.newsletterMembershipContact(ctx, cgm)
+	if len(requests) != 1 || requests[0] != req {
+		t.Fatalf("Expected the membership to map to %v, got %v", req, requests)
+	}
+
+	if _, err := r.Reconcile(ctx, requests[0]); err != nil {
+		t.Fatalf("Reconcile() failed: %v", err)
+	}
+	if err := r.Client.Get(ctx, cgmKey, &notificationmiloapiscomv1alpha1.ContactGroupMembership{}); err != nil {
+		t.Errorf("Expected the newsletter membership to be recreated: %v", err)
+	}
+}
+
+func TestReconcile_NewsletterMembershipNotRecreatedAfterOptOut(t *testing.T) {
+	ctx := context.Background()
+	contact := newTestContact("newsletter-jane")
+	contact.Finalizers = []string{loopsContactFinalizerKey}
+	removal := newTestContactGroupMembershipRemoval("jane-newsletter", contact, newTestContactGroup("newsletter", "list-1"))
+
+	r, _ := newTestContactController(t, contact, removal)
+	if err := r.setupFinalizers(); err != nil {
+		t.Fatalf("setupFinalizers() failed: %v", err)
+	}
+	req := ctrl.Request{NamespacedName: client.ObjectKeyFromObject(contact)}
+
+	if _, err := r.Reconcile(ctx, req); err != nil {
+		t.Fatalf("Reconcile() failed: %v", err)
+	}
+	cgmKey := client.ObjectKey{Namespace: contact.Namespace, Name: r.generateCgmName(contact)}
+	err := r.Client.Get(ctx, cgmKey, &notificationmiloapiscomv1alpha1.ContactGroupMembership{})
+	if !apierrors.IsNotFound(err) {
+		t.Errorf("Expected the newsletter membership not to be created, got %v", err)
+	}
+
+	updated := &notificationmiloapiscomv1alpha1.Contact{}
+	if err := r.Client.Get(ctx, req.NamespacedName, updated); err != nil {
+		t.Fatalf("Get() failed: %v", err)
+	}
+	cond := meta.FindStatusCondition(updated.Status.Conditions, NewsLetterAddedCondition)
+	if cond == nil || cond.Status != metav1.ConditionFalse || cond.Reason != NewsLetterOptedOutReason {
+		t.Errorf("Expected the %s condition to be False with reason %s, got %v", NewsLetterAddedCondition, NewsLetterOptedOutReason, cond)
+	}
+}
+
+func TestReconcile_NewsletterMembershipCreatedOnce(t *testing.T) {
+	ctx := context.Background()
+	contact := newTestContact("newsletter-jane")
+	contact.Finalizers = []string{loopsContactFinalizerKey}
+
+	creates := 0
+	r, _ := newTestContactController(t)
+	r.Client = fake.NewClientBuilder().
+		WithScheme(newTestScheme(t)).
+		WithObjects(contact).
+		WithStatusSubresource(&notificationmiloapiscomv1alpha1.Contact{}).
+		WithIndex(&notificationmiloapiscomv1alpha1.ContactGroupMembershipRemoval{}, contactGroupMembershipRemovalPairIndexKey, contactGroupMembershipRemovalPairIndex).
+		WithIndex(&notificationmiloapiscomv1alpha1.ContactGroupMembership{}, contactGroupMembershipPairIndexKey, contactGroupMembershipPairIndex).
+		WithInterceptorFuncs(interceptor.Funcs{
+			Create: func(ctx context.Context, c client.WithWatch, obj client.Object, opts ...client.CreateOption) error {
+				if _, ok := obj.(*notificationmiloapiscomv1alpha1.ContactGroupMembership); ok {
+					creates++
+				}
+				return c.Create(ctx, obj, opts...)
+			},
+		}).
+		Build()
+	if err := r.setupFinalizers(); err != nil {
+		t.Fatalf("setupFinalizers() failed: %v", err)
+	}
+	req := ctrl.Request{NamespacedName: client.ObjectKeyFromObject(contact)}
+
+	if _, err := r.Reconcile(ctx, req); err != nil {
+		t.Fatalf("Reconcile() failed: %v", err)
+	}
+	if creates != 1 {
+		t.Fatalf("Expected 1 membership create, got %d", creates)
+	}
+
+	// A lost condition is set again from the existing membership
+	if err := r.Client.Get(ctx, req.NamespacedName, contact); err != nil {
+		t.Fatalf("Get() failed: %v", err)
+	}
+	meta.RemoveStatusCondition(&contact.Status.Conditions, NewsLetterAddedCondition)
+	if err := r.Client.Status().Update(ctx, contact); err != nil {
+		t.Fatalf("Status().Update() failed: %v", err)
+	}
+
+	if _, err := r.Reconcile(ctx, req); err != nil {
+		t.Fatalf("Reconcile() failed: %v", err)
+	}
+	if creates != 1 {
+		t.Errorf("Expected no membership create on the second reconcile, got %d creates", creates)
+	}
+	if err := r.Client.Get(ctx, req.NamespacedName, contact); err != nil {
+		t.Fatalf("Get() failed: %v", err)
+	}
+	if !meta.IsStatusConditionTrue(contact.Status.Conditions, NewsLetterAddedCondition) {
+		t.Errorf("Expected the %s condition to be true, got %+v", NewsLetterAddedCondition, contact.Status.Conditions)
+	}
+}
+
+func TestReconcile_NewsletterMembershipCreatedByWebhook(t *testing.T) {
+	ctx := context.Background()
+	contact := newTestContact("newsletter-jane")
+	contact.Finalizers = []string{loopsContactFinalizerKey}
+	// The webhook and the backfill create the membership with a generated name
+	cgm := newTestContactGroupMembership("newsletter-jane-x7k2p", contact, newTestContactGroup("newsletter", "list-newsletter"),
+		time.Now())
+
+	r, _ := newTestContactController(t, contact, cgm)
+	if err := r.setupFinalizers(); err != nil {
+		t.Fatalf("setupFinalizers() failed: %v", err)
+	}
+	req := ctrl.Request{NamespacedName: client.ObjectKeyFromObject(contact)}
+	if _, err := r.Reconcile(ctx, req); err != nil {
+		t.Fatalf("Reconcile() failed: %v", err)
+	}
+
+	var memberships notificationmiloapiscomv1alpha1.ContactGroupMembershipList
+	if err := r.Client.List(ctx, &memberships); err != nil {
+		t.Fatalf("List() failed: %v", err)
+	}
+	if len(memberships.Items) != 1 || memberships.Items[0].Name != cgm.Name {
+		t.Errorf("Expected only the membership %s, got %v", cgm.Name, memberships.Items)
+	}
+	if err := r.Client.Get(ctx, req.NamespacedName, contact); err != nil {
+		t.Fatalf("Get() failed: %v", err)
+	}
+	if !meta.IsStatusConditionTrue(contact.Status.Conditions, NewsLetterAddedCondition) {
+		t.Errorf("Expected the %s condition to be true, got %+v", NewsLetterAddedCondition, contact.Status.Conditions)
+	}
+}
+
+func TestReconcile_NewsletterMembershipMatchesLabelSelector(t *testing.T) {
+	ctx := context.Background()
+	contact := newTestContact("newsletter-jane")
+	contact.Labels = map[string]string{"provider": "loops"}
+	contact.Finalizers = []string{loopsContactFinalizerKey}
+
+	r, _ := newTestContactController(t, contact)
+	if err := r.setupFinalizers(); err != nil {
+		t.Fatalf("setupFinalizers() failed: %v", err)
+	}
+	if _, err := r.Reconcile(ctx, ctrl.Request{NamespacedName: client.ObjectKeyFromObject(contact)}); err != nil {
+		t.Fatalf("Reconcile() failed: %v", err)
+	}
+
+	cgm := &notificationmiloapiscomv1alpha1.ContactGroupMembership{}
+	if err := r.Client.Get(ctx, client.ObjectKey{Namespace: contact.Namespace, Name: r.generateCgmName(contact)}, cgm); err != nil {
+		t.Fatalf("Expected the newsletter membership to be created: %v", err)
+	}
+	selector, err := labels.Parse("provider=loops")
+	if err != nil {
+		t.Fatalf("labels.Parse() failed: %v", err)
+	}
+	if !selector.Matches(labels.Set(cgm.Labels)) {
+		t.Errorf("Expected the newsletter membership to match the label selector of its contact, got labels %v", cgm.Labels)
+	}
+}
+
+func TestReconcile_NewsletterMembershipOwnedByContact(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("created membership", func(t *testing.T) {
+		contact := newTestContact("newsletter-jane")
+		contact.Finalizers = []string{loopsContactFinalizerKey}
+
+		r, _ := newTestContactController(t, contact)
+		if err := r.setupFinalizers(); err != nil {
+			t.Fatalf("setupFinalizers() failed: %v", err)
+		}
+		if _, err := r.Reconcile(ctx, ctrl.Request{NamespacedName: client.ObjectKeyFromObject(contact)}); err != nil {
+			t.Fatalf("Reconcile() failed: %v", err)
+		}
+
+		cgm := &notificationmiloapiscomv1alpha1.ContactGroupMembership{}
+		cgmKey := client.ObjectKey{Namespace: contact.Namespace, Name: r.generateCgmName(contact)}
+		if err := r.Client.Get(ctx, cgmKey, cgm); err != nil {
+			t.Fatalf("Expected the newsletter membership to be created: %v", err)
+		}
+		if !metav1.IsControlledBy(cgm, contact) {
+			t.Errorf("Expected the membership to be controlled by the contact, got owners %v", cgm.OwnerReferences)
+		}
+	})
+
+	t.Run("adopted membership", func(t *testing.T) {
+		contact := newTestContact("newsletter-jane")
+		contact.Finalizers = []string{loopsContactFinalizerKey}
+		meta.SetStatusCondition(&contact.Status.Conditions, metav1.Condition{
+			Type:   NewsLetterAddedCondition,
+			Status: metav1.ConditionTrue,
+			Reason: NewsLetterAddedReason,
+		})
+		cgm := newTestContactGroupMembership((&LoopsContactController{}).generateCgmName(contact), contact,
+			newTestContactGroup("newsletter", "list-newsletter"), time.Now())
+
+		r, _ := newTestContactController(t, contact, cgm)
+		if err := r.setupFinalizers(); err != nil {
+			t.Fatalf("setupFinalizers() failed: %v", err)
+		}
+		if _, err := r.Reconcile(ctx, ctrl.Request{NamespacedName: client.ObjectKeyFromObject(contact)}); err != nil {
+			t.Fatalf("Reconcile() failed: %v", err)
+		}
+
+		adopted := &notificationmiloapiscomv1alpha1.ContactGroupMembership{}
+		if err := r.Client.Get(ctx, client.ObjectKeyFromObject(cgm), adopted); err != nil {
+			t.Fatalf("Get() failed: %v", err)
+		}
+		if !metav1.IsControlledBy(adopted, contact) {
+			t.Errorf("Expected the membership to be adopted by the contact, got owners %v", adopted.OwnerReferences)
+		}
+	})
+}
+
+func TestNewsletterMembershipContact_IgnoresOtherMemberships(t *testing.T) {
+	ctx := context.Background()
+	contact := newTestContact("newsletter-jane")
+	newsletter := newTestContactGroup("newsletter", "list-newsletter")
+	product := newTestContactGroup("product", "list-product")
+
+	r, _ := newTestContactController(t, contact)
+
+	tests := []struct {
+		name string
+		cgm  *notificationmiloapiscomv1alpha1.ContactGroupMembership
+	}{
+		{name: "other group", cgm: newTestContactGroupMembership(r.generateCgmName(contact), contact, product, time.Now())},
+		{name: "other name", cgm: newTestContactGroupMembership("newsletter-jane-manual", contact, newsletter, time.Now())},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if requests := r.newsletterMembershipContact(ctx, tt.cgm); len(requests) != 0 {
+				t.Errorf("Expected no requests, got %v", requests)
+			}
+		})
+	}
+}
+
+func TestUpsertContact_NormalizesEmail(t *testing.T) {
+	tests := []struct {
+		name  string
+		email string
+	}{
+		{name: "mixed case", email: "Jane@Example.COM"},
+		{name: "whitespace", email: "  jane@example.com\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			contact := newTestContact("jane")
+			contact.Spec.Email = tt.email
+
+			r, loopsAPI := newTestContactController(t, contact)
+			if _, err := r.upsertContact(context.Background(), contact, false); err != nil {
+				t.Fatalf("upsertContact() failed: %v", err)
+			}
+
+			if got := loopsAPI.upserts[0].Email; got != "jane@example.com" {
+				t.Errorf("Expected email jane@example.com to be sent to Loops, got %q", got)
+			}
+			// The Milo spec keeps the original email
+			if contact.Spec.Email != tt.email {
+				t.Errorf("Expected the contact spec email to be kept as %q, got %q", tt.email, contact.Spec.Email)
+			}
+		})
+	}
+}
+
+func TestReconcile_RecreatesDeletedContact(t *testing.T) {
+	tests := []struct {
+		name            string
+		existsInLoops   bool
+		expectedUpserts int
+		expectedReason  string
+		expectedEvents  int
+	}{
+		{name: "deleted out-of-band", existsInLoops: false, expectedUpserts: 1,
+			expectedReason: LoopsContactRecreatedInProviderReason, expectedEvents: 1},
+		{name: "exists", existsInLoops: true, expectedUpserts: 0, expectedReason: LoopsContactCreatedReason},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			contact := newTestContact("jane")
+			contact.Finalizers = []string{loopsContactFinalizerKey}
+			contact.Generation = 1
+			contact.Status.Conditions = []metav1.Condition{
+				{
+					Type:               LoopsContactReadyCondition,
+					Status:             metav1.ConditionTrue,
+					Reason:             LoopsContactCreatedReason,
+					LastTransitionTime: metav1.Now(),
+					ObservedGeneration: 1,
+				},
+			}
+
+			r, loopsAPI := newTestContactController(t, contact)
+			r.RecreateDeletedContacts = true
+			recorder := record.NewFakeRecorder(10)
+			r.Recorder = recorder
+			if err := r.setupFinalizers(); err != nil {
+				t.Fatalf("setupFinalizers() failed: %v", err)
+			}
+			if tt.existsInLoops {
+				loopsAPI.contacts[string(contact.UID)] = &loops.Contact{UserID: string(contact.UID)}
+			}
+
+			if _, err := r.Reconcile(ctx, ctrl.Request{NamespacedName: client.ObjectKeyFromObject(contact)}); err != nil {
+				t.Fatalf("Reconcile() failed: %v", err)
+			}
+			if len(loopsAPI.upserts) != tt.expectedUpserts {
+				t.Errorf("Expected %d upserts, got %d", tt.expectedUpserts, len(loopsAPI.upserts))
+			}
+
+			updated := &notificationmiloapiscomv1alpha1.Contact{}
+			if err := r.Client.Get(ctx, client.ObjectKeyFromObject(contact), updated); err != nil {
+				t.Fatalf("Get() failed: %v", err)
+			}
+			cond := meta.FindStatusCondition(updated.Status.Conditions, LoopsContactReadyCondition)
+			if cond == nil || cond.Status != metav1.ConditionTrue || cond.Reason != tt.expectedReason {
+				t.Errorf("Expected a true %s condition with reason %s, got %+v",
+					LoopsContactReadyCondition, tt.expectedReason, cond)
+			}
+			if len(recorder.Events) != tt.expectedEvents {
+				t.Errorf("Expected %d events, got %d", tt.expectedEvents, len(recorder.Events))
+			}
+		})
+	}
+}
+
+func TestReconcile_MailingListLabels(t *testing.T) {
+	ctx := context.Background()
+	contact := newTestContact("jane")
+	contact.Finalizers = []string{loopsContactFinalizerKey}
+	contact.Labels = map[string]string{
+		MailingListLabelPrefix + "list-a": "true",
+		MailingListLabelPrefix + "list-b": "true",
+		MailingListLabelPrefix + "list-c": "false",
+		"app":                             "web",
+	}
+
+	r, loopsAPI := newTestContactController(t, contact)
+	r.MailingListSource = MailingListSourceLabels
+	if err := r.setupFinalizers(); err != nil {
+		t.Fatalf("setupFinalizers() failed: %v", err)
+	}
+	req := ctrl.Request{NamespacedName: client.ObjectKeyFromObject(contact)}
+
+	// Adding labels subscribes the contact to the labeled mailing lists
+	if _, err := r.Reconcile(ctx, req); err != nil {
+		t.Fatalf("Reconcile() failed: %v", err)
+	}
+	if len(loopsAPI.upserts) != 1 {
+		t.Fatalf("Expected 1 upsert, got %d", len(loopsAPI.upserts))
+	}
+	expected := loops.MailingListOps{"list-a": loops.MailingListAdd, "list-b": loops.MailingListAdd}
+	if !maps.Equal(loopsAPI.upserts[0].MailingLists, expected) {
+		t.Errorf("Expected mailing lists %v, got %v", expected, loopsAPI.upserts[0].MailingLists)
+	}
+
+	updated := &notificationmiloapiscomv1alpha1.Contact{}
+	if err := r.Client.Get(ctx, req.NamespacedName, updated); err != nil {
+		t.Fatalf("Get() failed: %v", err)
+	}
+	if synced := updated.Annotations[syncedMailingListsAnnotation]; synced != "list-a,list-b" {
+		t.Errorf("Expected synced mailing lists list-a,list-b, got %q", synced)
+	}
+
+	// An unchanged contact is not upserted again
+	if _, err := r.Reconcile(ctx, req); err != nil {
+		t.Fatalf("Reconcile() failed: %v", err)
+	}
+	if len(loopsAPI.upserts) != 1 {
+		t.Fatalf("Expected no upsert of an unchanged contact, got %d upserts", len(loopsAPI.upserts))
+	}
+
+	// Removing a label unsubscribes the contact from its mailing list
+	delete(updated.Labels, MailingListLabelPrefix+"list-b")
+	if err := r.Client.Update(ctx, updated); err != nil {
+		t.Fatalf("Update() failed: %v", err)
+	}
+	if _, err := r.Reconcile(ctx, req); err != nil {
+		t.Fatalf("Reconcile() failed: %v", err)
+	}
+	if len(loopsAPI.upserts) != 2 {
+		t.Fatalf("Expected 2 upserts, got %d", len(loopsAPI.upserts))
+	}
+	expected = loops.MailingListOps{"list-a": loops.MailingListAdd, "list-b": loops.MailingListRemove}
+	if !maps.Equal(loopsAPI.upserts[1].MailingLists, expected) {
+		t.Errorf("Expected mailing lists %v, got %v", expected, loopsAPI.upserts[1].MailingLists)
+	}
+
+	if err := r.Client.Get(ctx, req.NamespacedName, updated); err != nil {
+		t.Fatalf("Get() failed: %v", err)
+	}
+	if synced := updated.Annotations[syncedMailingListsAnnotation]; synced != "list-a" {
+		t.Errorf("Expected synced mailing lists list-a, got %q", synced)
+	}
+}
+
+func TestReconcile_MailingListLabelsIgnoredForMemberships(t *testing.T) {
+	ctx := context.Background()
+	contact := newTestContact("jane")
+	contact.Finalizers = []string{loopsContactFinalizerKey}
+	contact.Labels = map[string]string{MailingListLabelPrefix + "list-a": "true"}
+
+	r, loopsAPI := newTestContactController(t, contact)
+	if err := r.setupFinalizers(); err != nil {
+		t.Fatalf("setupFinalizers() failed: %v", err)
+	}
+
+	if _, err := r.Reconcile(ctx, ctrl.Request{NamespacedName: client.ObjectKeyFromObject(contact)}); err != nil {
+		t.Fatalf("Reconcile() failed: %v", err)
+	}
+
+	if len(loopsAPI.upserts) != 1 || loopsAPI.upserts[0].MailingLists != nil {
+		t.Errorf("Expected an upsert without mailing lists, got %+v", loopsAPI.upserts)
+	}
+}
+
+func TestReconcile_NewsletterAutoMembershipDisabled(t *testing.T) {
+	ctx := context.Background()
+	contact := newTestContact("newsletter-jane")
+	contact.Finalizers = []string{loopsContactFinalizerKey}
+
+	r, _ := newTestContactController(t, contact)
+	r.DisableNewsletterAutoMembership = true
+	// The newsletter contact group is not configured in deployments without the feature
+	r.NewsLetterContactGroupName = ""
+	r.NewsLetterContactGroupNamespace = ""
+	if err := r.setupFinalizers(); err != nil {
+		t.Fatalf("setupFinalizers() failed: %v", err)
+	}
+
+	if _, err := r.Reconcile(ctx, ctrl.Request{NamespacedName: client.ObjectKeyFromObject(contact)}); err != nil {
+		t.Fatalf("Reconcile() failed: %v", err)
+	}
+
+	var memberships notificationmiloapiscomv1alpha1.ContactGroupMembershipList
+	if err := r.Client.List(ctx, &memberships); err != nil {
+		t.Fatalf("List() failed: %v", err)
+	}
+	if len(memberships.Items) != 0 {
+		t.Errorf("Expected no newsletter membership, got %d", len(memberships.Items))
+	}
+
+	updated := &notificationmiloapiscomv1alpha1.Contact{}
+	if err := r.Client.Get(ctx, client.ObjectKeyFromObject(contact), updated); err != nil {
+		t.Fatalf("Get() failed: %v", err)
+	}
+	if cond := meta.FindStatusCondition(updated.Status.Conditions, NewsLetterAddedCondition); cond != nil {
+		t.Errorf("Expected no %s condition, got %+v", NewsLetterAddedCondition, cond)
+	}
+}
+
+func TestReconcile_ContactWithoutUID(t *testing.T) {
+	ctx := context.Background()
+	contact := newTestContact("jane")
+	contact.UID = ""
+	contact.Finalizers = []string{loopsContactFinalizerKey}
+
+	r, loopsAPI := newTestContactController(t, contact)
+	if err := r.setupFinalizers(); err != nil {
+		t.Fatalf("setupFinalizers() failed: %v", err)
+	}
+	req := ctrl.Request{NamespacedName: client.ObjectKeyFromObject(contact)}
+
+	// The empty userId is a terminal failure, it is not retried
+	if _, err := r.Reconcile(ctx, req); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(loopsAPI.upserts) != 0 {
+		t.Errorf("Expected no upsert, got %+v", loopsAPI.upserts)
+	}
+
+	updated := &notificationmiloapiscomv1alpha1.Contact{}
+	if err := r.Client.Get(ctx, req.NamespacedName, updated); err != nil {
+		t.Fatalf("Get() failed: %v", err)
+	}
+	cond := meta.FindStatusCondition(updated.Status.Conditions, LoopsContactReadyCondition)
+	if cond == nil || cond.Status != metav1.ConditionFalse || cond.Reason != LoopsContactIDMissingReason {
+		t.Errorf("Expected condition %s with reason %s, got %+v", LoopsContactReadyCondition, LoopsContactIDMissingReason, cond)
+	}
+
+	// Deleting the contact releases it without calling Loops
+	if err := r.Client.Delete(ctx, updated); err != nil {
+		t.Fatalf("Delete() failed: %v", err)
+	}
+	if _, err := r.Reconcile(ctx, req); err != nil {
+		t.Fatalf("Reconcile() failed: %v", err)
+	}
+	if len(loopsAPI.deletes) != 0 {
+		t.Errorf("Expected no delete, got %v", loopsAPI.deletes)
+	}
+	if err := r.Client.Get(ctx, req.NamespacedName, updated); !apierrors.IsNotFound(err) {
+		t.Errorf("Expected the Contact to be released, got %v", err)
+	}
+}
+
+func TestReconcile_RecordsEchoedMailingLists(t *testing.T) {
+	tests := []struct {
+		name         string
+		mailingLists map[string]bool
+		expected     string
+		annotated    bool
+	}{
+		{
+			name:         "response with mailing lists",
+			mailingLists: map[string]bool{"list-b": true, "list-a": true, "list-c": false},
+			expected:     "list-a,list-b",
+			annotated:    true,
+		},
+		{
+			name:         "response with no subscribed mailing list",
+			mailingLists: map[string]bool{"list-a": false},
+			expected:     "",
+			annotated:    true,
+		},
+		{
+			name: "response without mailing lists",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			contact := newTestContact("jane")
+			contact.Finalizers = []string{loopsContactFinalizerKey}
+
+			r, loopsAPI := newTestContactController(t, contact)
+			loopsAPI.upsertMailingLists = tt.mailingLists
+			if err := r.setupFinalizers(); err != nil {
+				t.Fatalf("setupFinalizers() failed: %v", err)
+			}
+
+			req := ctrl.Request{NamespacedName: client.ObjectKeyFromObject(contact)}
+			if _, err := r.Reconcile(ctx, req); err != nil {
+				t.Fatalf("Reconcile() failed: %v", err)
+			}
+
+			updated := &notificationmiloapiscomv1alpha1.Contact{}
+			if err := r.Client.Get(ctx, req.NamespacedName, updated); err != nil {
+				t.Fatalf("Get() failed: %v", err)
+			}
+			mailingLists, ok := updated.Annotations[mailingListsAnnotation]
+			if ok != tt.annotated || mailingLists != tt.expected {
+				t.Errorf("Expected mailing lists %q (annotated: %t), got %q (annotated: %t)", tt.expected, tt.annotated, mailingLists, ok)
+			}
+			cond := meta.FindStatusCondition(updated.Status.Conditions, LoopsContactReadyCondition)
+			if cond == nil || cond.Status != metav1.ConditionTrue {
+				t.Errorf("Expected condition %s to be true, got %+v", LoopsContactReadyCondition, cond)
+			}
+		})
+	}
+}
+
+func TestReconcile_SyncRecoveredEvent(t *testing.T) {
+	ctx := context.Background()
+	contact := newTestContact("jane")
+	contact.Finalizers = []string{loopsContactFinalizerKey}
+
+	r, loopsAPI := newTestContactController(t, contact)
+	recorder := record.NewFakeRecorder(10)
+	r.Recorder = recorder
+	if err := r.setupFinalizers(); err != nil {
+		t.Fatalf("setupFinalizers() failed: %v", err)
+	}
+	req := ctrl.Request{NamespacedName: client.ObjectKeyFromObject(contact)}
+
+	loopsAPI.err = errors.New("loops unavailable")
+	if _, err := r.Reconcile(ctx, req); err == nil {
+		t.Fatal("Expected Reconcile() to fail")
+	}
+	if len(recorder.Events) != 0 {
+		t.Errorf("Expected no event after a failure, got %q", <-recorder.Events)
+	}
+
+	loopsAPI.err = nil
+	if _, err := r.Reconcile(ctx, req); err != nil {
+		t.Fatalf("Reconcile() failed: %v", err)
+	}
+	select {
+	case event := <-recorder.Events:
+		if !strings.Contains(event, SyncRecoveredReason) {
+			t.Errorf("Expected a %s event, got %q", SyncRecoveredReason, event)
+		}
+	default:
+		t.Errorf("Expected a %s event, got none", SyncRecoveredReason)
+	}
+
+	// A reconciliation of a contact that is already synced does not record the event again
+	if _, err := r.Reconcile(ctx, req); err != nil {
+		t.Fatalf("Reconcile() failed: %v", err)
+	}
+	if len(recorder.Events) != 0 {
+		t.Errorf("Expected no event for a synced contact, got %q", <-recorder.Events)
+	}
+}
+
+func TestGenerateCgmName(t *testing.T) {
+	tests := []struct {
+		name          string
+		contactName   string
+		hashLength    int
+		expectedHash  int
+		expectedFront string
+	}{
+		{name: "full hash", contactName: "newsletter-jane", expectedHash: 64, expectedFront: "newsletter-jane"},
+		{name: "truncated hash", contactName: "newsletter-jane", hashLength: 16, expectedHash: 16,
+			expectedFront: "newsletter-jane"},
+		{name: "hash length above the hash size", contactName: "newsletter-jane", hashLength: 100, expectedHash: 64,
+			expectedFront: "newsletter-jane"},
+		{name: "long name with full hash", contactName: strings.Repeat("a", 253), expectedHash: 64,
+			expectedFront: strings.Repeat("a", 188)},
+		{name: "long name with truncated hash", contactName: strings.Repeat("a", 253), hashLength: 16, expectedHash: 16,
+			expectedFront: strings.Repeat("a", 236)},
+		{name: "long name truncated at a separator", contactName: strings.Repeat("a", 235) + "-.b", hashLength: 16,
+			expectedHash: 16, expectedFront: strings.Repeat("a", 235)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := &LoopsContactController{MembershipNameHashLength: tt.hashLength}
+			contact := newTestContact(tt.contactName)
+
+			name := r.generateCgmName(contact)
+			if errs := validation.IsDNS1123Subdomain(name); len(errs) != 0 {
+				t.Errorf("Expected a valid name, got %q: %v", name, errs)
+			}
+			prefix, hash, _ := strings.Cut(strings.TrimPrefix(name, tt.expectedFront), "-")
+			if !strings.HasPrefix(name, tt.expectedFront) || prefix != "" {
+				t.Errorf("Expected name to start with %q followed by the hash, got %q", tt.expectedFront, name)
+			}
+			if len(hash) != tt.expectedHash {
+				t.Errorf("Expected a hash of %d characters, got %q", tt.expectedHash, hash)
+			}
+			if again := r.generateCgmName(contact); again != name {
+				t.Errorf("Expected a deterministic name %q, got %q", name, again)
+			}
+
+			other := newTestContact(tt.contactName)
+			other.UID = "other-uid"
+			if otherName := r.generateCgmName(other); otherName == name {
+				t.Errorf("Expected Contacts with different UIDs to get different names, got %q", name)
+			}
+		})
+	}
+}
+
+func TestReconcile_ProviderSyncState(t *testing.T) {
+	ctx := context.Background()
+	contact := newTestContact("jane")
+	contact.Finalizers = []string{loopsContactFinalizerKey}
+
+	r, loopsAPI := newTestContactController(t, contact)
+	if err := r.setupFinalizers(); err != nil {
+		t.Fatalf("setupFinalizers() failed: %v", err)
+	}
+	req := ctrl.Request{NamespacedName: client.ObjectKeyFromObject(contact)}
+
+	loopsAPI.err = &loops.Error{StatusCode: http.StatusBadRequest, Body: `{"message":"Invalid email"}`}
+	if _, err := r.Reconcile(ctx, req); err == nil {
+		t.Fatal("Expected Reconcile() to fail")
+	}
+	updated := &notificationmiloapiscomv1alpha1.Contact{}
+	if err := r.Client.Get(ctx, req.NamespacedName, updated); err != nil {
+		t.Fatalf("Get() failed: %v", err)
+	}
+	if state := updated.Annotations[providerSyncStateAnnotation]; state != ProviderSyncStateFailed {
+		t.Errorf("Expected sync state %s, got %q", ProviderSyncStateFailed, state)
+	}
+	if lastError := updated.Annotations[providerLastSyncErrorAnnotation]; !strings.Contains(lastError, "Invalid email") {
+		t.Errorf("Expected the last sync error to record the bad request, got %q", lastError)
+	}
+
+	loopsAPI.err = nil
+	if _, err := r.Reconcile(ctx, req); err != nil {
+		t.Fatalf("Reconcile() failed: %v", err)
+	}
+	if err := r.Client.Get(ctx, req.NamespacedName, updated); err != nil {
+		t.Fatalf("Get() failed: %v", err)
+	}
+	if state := updated.Annotations[providerSyncStateAnnotation]; state != ProviderSyncStateSynced {
+		t.Errorf("Expected sync state %s, got %q", ProviderSyncStateSynced, state)
+	}
+	if lastError, ok := updated.Annotations[providerLastSyncErrorAnnotation]; ok {
+		t.Errorf("Expected the last sync error to be cleared, got %q", lastError)
+	}
+}
+
+func TestReconcile_SyncDegraded(t *testing.T) {
+	ctx := context.Background()
+	contact := newTestContact("jane")
+	contact.Finalizers = []string{loopsContactFinalizerKey}
+
+	r, loopsAPI := newTestContactController(t, contact)
+	r.SyncDegradedThreshold = 3
+	if err := r.setupFinalizers(); err != nil {
+		t.Fatalf("setupFinalizers() failed: %v", err)
+	}
+	req := ctrl.Request{NamespacedName: client.ObjectKeyFromObject(contact)}
+
+	syncDegraded := func() (*metav1.Condition, string) {
+		t.Helper()
+		updated := &notificationmiloapiscomv1alpha1.Contact{}
+		if err := r.Client.Get(ctx, req.NamespacedName, updated); err != nil {
+			t.Fatalf("Get() failed: %v", err)
+		}
+		return meta.FindStatusCondition(updated.Status.Conditions, SyncDegradedCondition),
+			updated.Annotations[providerConsecutiveFailuresAnnotation]
+	}
+
+	loopsAPI.err = &loops.Error{StatusCode: http.StatusInternalServerError}
+	for i := 1; i <= 3; i++ {
+		if _, err := r.Reconcile(ctx, req); err == nil {
+			t.Fatal("Expected Reconcile() to fail")
+		}
+
+		cond, failures := syncDegraded()
+		if failures != strconv.Itoa(i) {
+			t.Errorf("Expected %d consecutive failures, got %q", i, failures)
+		}
+		wantStatus := metav1.ConditionFalse
+		if i == 3 {
+			wantStatus = metav1.ConditionTrue
+		}
+		if cond == nil || cond.Status != wantStatus {
+			t.Errorf("Expected SyncDegraded %s after %d failures, got %+v", wantStatus, i, cond)
+		}
+	}
+
+	// A successful sync resets the count
+	loopsAPI.err = nil
+	if _, err := r.Reconcile(ctx, req); err != nil {
+		t.Fatalf("Reconcile() failed: %v", err)
+	}
+	cond, failures := syncDegraded()
+	if failures != "" {
+		t.Errorf("Expected the consecutive failures to be cleared, got %q", failures)
+	}
+	if cond == nil || cond.Status != metav1.ConditionFalse || cond.Reason != SyncHealthyReason {
+		t.Errorf("Expected SyncDegraded False with reason %s, got %+v", SyncHealthyReason, cond)
+	}
+}
+
+func TestContactChangedPredicate(t *testing.T) {
+	old := newTestContact("jane")
+	old.Generation = 1
+
+	tests := []struct {
+		name     string
+		mutate   func(contact *notificationmiloapiscomv1alpha1.Contact)
+		expected bool
+	}{
+		{
+			name: "sync state annotations",
+			mutate: func(contact *notificationmiloapiscomv1alpha1.Contact) {
+				contact.Annotations = map[string]string{
+					providerSyncStateAnnotation:           ProviderSyncStateFailed,
+					providerConsecutiveFailuresAnnotation: "2",
+				}
+			},
+			expected: false,
+		},
+		{
+			name: "status only",
+			mutate: func(contact *notificationmiloapiscomv1alpha1.Contact) {
+				meta.SetStatusCondition(&contact.Status.Conditions, metav1.Condition{
+					Type:   SyncDegradedCondition,
+					Status: metav1.ConditionTrue,
+					Reason: ConsecutiveSyncFailuresReason,
+				})
+			},
+			expected: false,
+		},
+		{
+			name: "spec change",
+			mutate: func(contact *notificationmiloapiscomv1alpha1.Contact) {
+				contact.Spec.GivenName = "Janet"
+				contact.Generation = 2
+			},
+			expected: true,
+		},
+		{
+			name: "labels",
+			mutate: func(contact *notificationmiloapiscomv1alpha1.Contact) {
+				contact.Labels = map[string]string{"provider": "loops"}
+			},
+			expected: true,
+		},
+		{
+			name: "skip loops sync annotation",
+			mutate: func(contact *notificationmiloapiscomv1alpha1.Contact) {
+				contact.Annotations = map[string]string{skipLoopsSyncAnnotation: "true"}
+			},
+			expected: true,
+		},
+		{
+			name: "deletion",
+			mutate: func(contact *notificationmiloapiscomv1alpha1.Contact) {
+				contact.DeletionTimestamp = &metav1.Time{Time: time.Now()}
+			},
+			expected: true,
+		},
+	}
+
+	p := contactChangedPredicate()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			updated := old.DeepCopy()
+			tt.mutate(updated)
+			if got := p.Update(event.UpdateEvent{ObjectOld: old, ObjectNew: updated}); got != tt.expected {
+				t.Errorf("Expected Update() %v, got %v", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestReconcile_SkipLoopsSync(t *testing.T) {
+	ctx := context.Background()
+	contact := newTestContact("jane")
+	contact.Finalizers = []string{loopsContactFinalizerKey}
+	contact.Annotations = map[string]string{skipLoopsSyncAnnotation: "true"}
+
+	r, loopsAPI := newTestContactController(t, contact)
+	if err := r.setupFinalizers(); err != nil {
+		t.Fatalf("setupFinalizers() failed: %v", err)
+	}
+	req := ctrl.Request{NamespacedName: client.ObjectKeyFromObject(contact)}
+
+	if _, err := r.Reconcile(ctx, req); err != nil {
+		t.Fatalf("Reconcile() failed: %v", err)
+	}
+	if len(loopsAPI.upserts) != 0 {
+		t.Errorf("Expected no upserts, got %d", len(loopsAPI.upserts))
+	}
+
+	got := &notificationmiloapiscomv1alpha1.Contact{}
+	if err := r.Client.Get(ctx, req.NamespacedName, got); err != nil {
+		t.Fatalf("Get() failed: %v", err)
+	}
+	cond := meta.FindStatusCondition(got.Status.Conditions, LoopsContactReadyCondition)
+	if cond == nil || cond.Reason != LoopsContactSyncSkippedReason {
+		t.Fatalf("Expected %s condition, got %v", LoopsContactSyncSkippedReason, cond)
+	}
+
+	// Removing the annotation syncs the contact again
+	got.Annotations = nil
+	if err := r.Client.Update(ctx, got); err != nil {
+		t.Fatalf("Update() failed: %v", err)
+	}
+	if _, err := r.Reconcile(ctx, req); err != nil {
+		t.Fatalf("Reconcile() failed: %v", err)
+	}
+	if len(loopsAPI.upserts) != 1 {
+		t.Errorf("Expected 1 upsert after removing the annotation, got %d", len(loopsAPI.upserts))
+	}
+}
+
+func TestFinalize_SkipLoopsSync(t *testing.T) {
+	ctx := context.Background()
+	contact := newTestContact("jane")
+	contact.Finalizers = []string{loopsContactFinalizerKey}
+	contact.Annotations = map[string]string{skipLoopsSyncAnnotation: "true"}
+
+	r, loopsAPI := newTestContactController(t, contact)
+	if err := r.setupFinalizers(); err != nil {
+		t.Fatalf("setupFinalizers() failed: %v", err)
+	}
+
+	if err := r.Client.Delete(ctx, contact); err != nil {
+		t.Fatalf("Delete() failed: %v", err)
+	}
+	if _, err := r.Reconcile(ctx, ctrl.Request{NamespacedName: client.ObjectKeyFromObject(contact)}); err != nil {
+		t.Fatalf("Reconcile() failed: %v", err)
+	}
+
+	if len(loopsAPI.deletes) != 0 || len(loopsAPI.unsubscribes) != 0 {
+		t.Errorf("Expected no Loops calls, got deletes %v and unsubscribes %v", loopsAPI.deletes, loopsAPI.unsubscribes)
+	}
+}
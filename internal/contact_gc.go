@@ -0,0 +1,177 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	loops "go.miloapis.com/email-provider-loops/pkg/loops"
+	notificationmiloapiscomv1alpha1 "go.miloapis.com/milo/pkg/apis/notification/v1alpha1"
+
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// DefaultGarbageCollectionInterval is the default interval between two runs of the ContactGarbageCollector
+const DefaultGarbageCollectionInterval = time.Hour
+
+// GarbageCollectorAPI is the part of the Loops API the ContactGarbageCollector uses. The public Loops API offers no
+// way to list contacts, so they are listed through the mailing lists, see loops.WithMailingListContacts.
+type GarbageCollectorAPI interface {
+	// ListContactsInMailingList returns the contacts subscribed to a mailing list.
+	ListContactsInMailingList(ctx context.Context, listID string) ([]loops.Contact, error)
+	// DeleteContact deletes a contact from Loops.
+	DeleteContact(ctx context.Context, userID string) (*loops.APIResponse, error)
+}
+
+// ContactGarbageCollector periodically deletes the Loops contacts orphaned by a Contact whose finalizer did not run,
+// e.g. when it was removed by hand. Loops contacts are listed through the mailing lists of the ContactGroups, so an
+// orphaned contact subscribed to none of them is not found. Only the contacts with the controller's source are
+// deleted, and only when their userId is not the one of a live Contact.
+type ContactGarbageCollector struct {
+	// Client lists the ContactGroups whose mailing lists are scanned.
+	Client client.Client
+	// APIReader lists the live Contacts. It must see every Contact, unlike a cache filtered by labels, as the Loops
+	// contacts of the Contacts it misses are deleted, e.g. the manager's API reader.
+	APIReader client.Reader
+	Loops     GarbageCollectorAPI
+
+	// ContactIDResolver resolves the Loops userId of the live Contacts, defaults to their UID.
+	ContactIDResolver ContactIDResolver
+	// Source is the Loops source of the contacts created by the controller, the only ones that are deleted.
+	Source string
+	// Interval is the time between two runs, defaults to DefaultGarbageCollectionInterval.
+	Interval time.Duration
+	// DryRun logs the orphaned Loops contacts without deleting them.
+	DryRun bool
+	// ProviderCallTimeout bounds each call to Loops, 0 disables the timeout.
+	ProviderCallTimeout time.Duration
+}
+
+// Start collects the orphaned Loops contacts every Interval until ctx is done.
+func (gc *ContactGarbageCollector) Start(ctx context.Context) error {
+	interval := gc.Interval
+	if interval <= 0 {
+		interval = DefaultGarbageCollectionInterval
+	}
+
+	log := logf.FromContext(ctx).WithValues("component", "ContactGarbageCollector")
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+			if _, err := gc.collect(ctx); err != nil {
+				log.Error(err, "Failed to collect the orphaned Loops contacts, retrying on the next run")
+			}
+		}
+	}
+}
+
+// NeedLeaderElection runs the garbage collector on the leader only, so that replicas do not delete the same contacts.
+func (gc *ContactGarbageCollector) NeedLeaderElection() bool {
+	return true
+}
+
+// collect deletes the orphaned Loops contacts, or only logs them in dry run, and returns their userIds.
+func (gc *ContactGarbageCollector) collect(ctx context.Context) ([]string, error) {
+	log := logf.FromContext(ctx).WithValues("component", "ContactGarbageCollector", "dryRun", gc.DryRun)
+
+	candidates, listCount, err := gc.candidateContactIDs(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// The Contacts are read after the Loops contacts, so that a Contact synced to Loops during the run is seen live
+	liveIDs, err := gc.liveContactIDs(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var orphaned []string
+	for _, contactID := range candidates {
+		if liveIDs[contactID] {
+			continue
+		}
+
+		if gc.DryRun {
+			log.Info("Found an orphaned Loops contact, not deleting it in dry run", "userId", contactID)
+			orphaned = append(orphaned, contactID)
+			continue
+		}
+
+		log.Info("Deleting orphaned Loops contact", "userId", contactID)
+		callCtx, cancel := withProviderCallTimeout(ctx, gc.ProviderCallTimeout)
+		_, err := gc.Loops.DeleteContact(callCtx, contactID)
+		cancel()
+		if err != nil && !loops.IsNotFound(err) {
+			return orphaned, fmt.Errorf("failed to delete orphaned Loops contact %s: %w", contactID, err)
+		}
+		orphaned = append(orphaned, contactID)
+	}
+
+	log.Info("Collected the orphaned Loops contacts", "count", len(orphaned), "mailingLists", listCount)
+	return orphaned, nil
+}
+
+// candidateContactIDs returns the userIds of the Loops contacts with the controller's source subscribed to the mailing
+// lists of the ContactGroups, and the number of mailing lists read.
+func (gc *ContactGarbageCollector) candidateContactIDs(ctx context.Context) ([]string, int, error) {
+	var groups notificationmiloapiscomv1alpha1.ContactGroupList
+	if err := gc.Client.List(ctx, &groups); err != nil {
+		return nil, 0, fmt.Errorf("failed to list ContactGroups: %w", err)
+	}
+
+	seenLists := map[string]bool{}
+	seenContacts := map[string]bool{}
+	var candidates []string
+	for i := range groups.Items {
+		mailingListId, err := getMailingListId(&groups.Items[i])
+		if err != nil || seenLists[mailingListId] {
+			continue
+		}
+		seenLists[mailingListId] = true
+
+		callCtx, cancel := withProviderCallTimeout(ctx, gc.ProviderCallTimeout)
+		contacts, err := gc.Loops.ListContactsInMailingList(callCtx, mailingListId)
+		cancel()
+		if err != nil {
+			return nil, 0, fmt.Errorf("failed to list the Loops contacts of mailing list %s: %w", mailingListId, err)
+		}
+
+		for _, contact := range contacts {
+			if contact.UserID == "" || contact.Source != gc.Source || seenContacts[contact.UserID] {
+				continue
+			}
+			seenContacts[contact.UserID] = true
+			candidates = append(candidates, contact.UserID)
+		}
+	}
+	return candidates, len(seenLists), nil
+}
+
+// liveContactIDs returns the Loops userIds of the Contacts, including the ones being deleted as their finalizer still
+// removes their Loops contact.
+func (gc *ContactGarbageCollector) liveContactIDs(ctx context.Context) (map[string]bool, error) {
+	var contacts notificationmiloapiscomv1alpha1.ContactList
+	if err := gc.APIReader.List(ctx, &contacts); err != nil {
+		return nil, fmt.Errorf("failed to list Contacts: %w", err)
+	}
+
+	ids := make(map[string]bool, len(contacts.Items))
+	for i := range contacts.Items {
+		contactID, err := resolveContactID(gc.ContactIDResolver, &contacts.Items[i])
+		if isEmptyContactID(err) {
+			continue
+		}
+		if err != nil {
+			// A Contact whose userId is unknown could own any Loops contact, so none can be deleted safely
+			return nil, fmt.Errorf("failed to resolve the Loops contact ID of Contact %s: %w",
+				client.ObjectKeyFromObject(&contacts.Items[i]), err)
+		}
+		ids[contactID] = true
+	}
+	return ids, nil
+}
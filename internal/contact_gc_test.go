@@ -0,0 +1,164 @@
+package controller
+
+import (
+	"context"
+	"slices"
+	"testing"
+
+	loops "go.miloapis.com/email-provider-loops/pkg/loops"
+
+	"k8s.io/apimachinery/pkg/labels"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestContactGarbageCollector_Collect(t *testing.T) {
+	tests := []struct {
+		name            string
+		dryRun          bool
+		expectedDeletes []string
+	}{
+		{name: "deletes the orphaned contacts", expectedDeletes: []string{"gone-uid", "gone-newsletter-uid"}},
+		{name: "dry run", dryRun: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			objs := []client.Object{
+				newTestContact("jane"),
+				newTestContactGroup("product", "list-product"),
+				newTestContactGroup("newsletter", "list-newsletter"),
+				// Groups sharing a mailing list, or without one, are listed at most once
+				newTestContactGroup("product-copy", "list-product"),
+				newTestContactGroup("draft", ""),
+			}
+			k8sClient := fake.NewClientBuilder().WithScheme(newTestScheme(t)).WithObjects(objs...).Build()
+
+			loopsAPI := newFakeLoops()
+			loopsAPI.listContacts["list-product"] = []loops.Contact{
+				{UserID: "jane-uid", Source: DefaultContactSource},
+				{UserID: "gone-uid", Source: DefaultContactSource},
+				// Contacts created outside of the controller are kept
+				{UserID: "imported-uid", Source: "csv-import"},
+				{Email: "no-user-id@example.com", Source: DefaultContactSource},
+			}
+			loopsAPI.listContacts["list-newsletter"] = []loops.Contact{
+				{UserID: "gone-uid", Source: DefaultContactSource},
+				{UserID: "gone-newsletter-uid", Source: DefaultContactSource},
+			}
+
+			gc := &ContactGarbageCollector{
+				Client:    k8sClient,
+				APIReader: k8sClient,
+				Loops:     loopsAPI,
+				Source:    DefaultContactSource,
+				DryRun:    tt.dryRun,
+			}
+			orphaned, err := gc.collect(ctx)
+			if err != nil {
+				t.Fatalf("collect() failed: %v", err)
+			}
+
+			slices.Sort(orphaned)
+			if expected := []string{"gone-newsletter-uid", "gone-uid"}; !slices.Equal(orphaned, expected) {
+				t.Errorf("Expected orphaned contacts %v, got %v", expected, orphaned)
+			}
+			deletes := slices.Clone(loopsAPI.deletes)
+			slices.Sort(deletes)
+			expectedDeletes := slices.Clone(tt.expectedDeletes)
+			slices.Sort(expectedDeletes)
+			if !slices.Equal(deletes, expectedDeletes) {
+				t.Errorf("Expected deletes %v, got %v", expectedDeletes, deletes)
+			}
+		})
+	}
+}
+
+func TestContactGarbageCollector_CollectKeepsContactsOnListError(t *testing.T) {
+	ctx := context.Background()
+	k8sClient := fake.NewClientBuilder().WithScheme(newTestScheme(t)).
+		WithObjects(newTestContactGroup("product", "list-product")).Build()
+
+	loopsAPI := newFakeLoops()
+	loopsAPI.err = loops.ErrMailingListContactsUnavailable
+	gc := &ContactGarbageCollector{
+		Client: k8sClient, APIReader: k8sClient, Loops: loopsAPI, Source: DefaultContactSource,
+	}
+
+	if _, err := gc.collect(ctx); err == nil {
+		t.Error("Expected an error, got none")
+	}
+	if len(loopsAPI.deletes) != 0 {
+		t.Errorf("Expected no delete, got %v", loopsAPI.deletes)
+	}
+}
+
+// labelFilteredClient lists the objects matching selector only, like the manager cache with --label-selector.
+type labelFilteredClient struct {
+	client.Client
+	selector labels.Selector
+}
+
+func (c labelFilteredClient) List(ctx context.Context, list client.ObjectList, opts ...client.ListOption) error {
+	return c.Client.List(ctx, list, append(opts, client.MatchingLabelsSelector{Selector: c.selector})...)
+}
+
+func TestContactGarbageCollector_CollectKeepsContactsOutsideTheCache(t *testing.T) {
+	ctx := context.Background()
+	jane := newTestContact("jane")
+	jane.Labels = map[string]string{"tenant": "a"}
+	group := newTestContactGroup("product", "list-product")
+	group.Labels = map[string]string{"tenant": "a"}
+	// bob is live, but outside the label selector of the cache
+	bob := newTestContact("bob")
+	k8sClient := fake.NewClientBuilder().WithScheme(newTestScheme(t)).WithObjects(jane, group, bob).Build()
+	cached := labelFilteredClient{Client: k8sClient, selector: labels.SelectorFromSet(labels.Set{"tenant": "a"})}
+
+	loopsAPI := newFakeLoops()
+	loopsAPI.listContacts["list-product"] = []loops.Contact{
+		{UserID: "jane-uid", Source: DefaultContactSource},
+		{UserID: "bob-uid", Source: DefaultContactSource},
+		{UserID: "gone-uid", Source: DefaultContactSource},
+	}
+
+	gc := &ContactGarbageCollector{Client: cached, APIReader: k8sClient, Loops: loopsAPI, Source: DefaultContactSource}
+	if _, err := gc.collect(ctx); err != nil {
+		t.Fatalf("collect() failed: %v", err)
+	}
+	if !slices.Equal(loopsAPI.deletes, []string{"gone-uid"}) {
+		t.Errorf("Expected only gone-uid to be deleted, got %v", loopsAPI.deletes)
+	}
+}
+
+// creatingLoops creates a Contact when the mailing list is listed, like a Contact synced to Loops during a run.
+type creatingLoops struct {
+	*fakeLoops
+	client  client.Client
+	contact client.Object
+}
+
+func (f *creatingLoops) ListContactsInMailingList(ctx context.Context, listID string) ([]loops.Contact, error) {
+	if err := f.client.Create(ctx, f.contact); err != nil {
+		return nil, err
+	}
+	return f.fakeLoops.ListContactsInMailingList(ctx, listID)
+}
+
+func TestContactGarbageCollector_CollectKeepsContactsCreatedDuringTheRun(t *testing.T) {
+	ctx := context.Background()
+	k8sClient := fake.NewClientBuilder().WithScheme(newTestScheme(t)).
+		WithObjects(newTestContactGroup("product", "list-product")).Build()
+
+	fakeAPI := newFakeLoops()
+	fakeAPI.listContacts["list-product"] = []loops.Contact{{UserID: "jane-uid", Source: DefaultContactSource}}
+	loopsAPI := &creatingLoops{fakeLoops: fakeAPI, client: k8sClient, contact: newTestContact("jane")}
+
+	gc := &ContactGarbageCollector{Client: k8sClient, APIReader: k8sClient, Loops: loopsAPI, Source: DefaultContactSource}
+	if _, err := gc.collect(ctx); err != nil {
+		t.Fatalf("collect() failed: %v", err)
+	}
+	if len(fakeAPI.deletes) != 0 {
+		t.Errorf("Expected the contact created during the run to be kept, got deletes %v", fakeAPI.deletes)
+	}
+}
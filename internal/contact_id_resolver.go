@@ -0,0 +1,51 @@
+package controller
+
+import (
+	"errors"
+
+	notificationmiloapiscomv1alpha1 "go.miloapis.com/milo/pkg/apis/notification/v1alpha1"
+)
+
+// ErrEmptyContactID is returned when a Contact resolves to an empty Loops userId, e.g. a Contact without a UID. Loops
+// would accept an upsert without a userId, leaving a contact that could not be deleted later.
+var ErrEmptyContactID = errors.New("contact resolves to an empty Loops userId")
+
+// ContactIDResolver resolves the userId that identifies a Contact in Loops.
+type ContactIDResolver interface {
+	ContactID(contact *notificationmiloapiscomv1alpha1.Contact) (string, error)
+}
+
+// ContactIDResolverFunc is a function that implements ContactIDResolver.
+type ContactIDResolverFunc func(contact *notificationmiloapiscomv1alpha1.Contact) (string, error)
+
+func (f ContactIDResolverFunc) ContactID(contact *notificationmiloapiscomv1alpha1.Contact) (string, error) {
+	return f(contact)
+}
+
+// UIDContactIDResolver is the default ContactIDResolver, it uses the Kubernetes UID of the Contact.
+type UIDContactIDResolver struct{}
+
+func (UIDContactIDResolver) ContactID(contact *notificationmiloapiscomv1alpha1.Contact) (string, error) {
+	return string(contact.UID), nil
+}
+
+// resolveContactID resolves the Loops userId of the contact, falling back to the UID-based resolver. It returns
+// ErrEmptyContactID rather than an empty userId.
+func resolveContactID(resolver ContactIDResolver, contact *notificationmiloapiscomv1alpha1.Contact) (string, error) {
+	if resolver == nil {
+		resolver = UIDContactIDResolver{}
+	}
+	contactID, err := resolver.ContactID(contact)
+	if err != nil {
+		return "", err
+	}
+	if contactID == "" {
+		return "", ErrEmptyContactID
+	}
+	return contactID, nil
+}
+
+// isEmptyContactID returns true if err is, or wraps, ErrEmptyContactID.
+func isEmptyContactID(err error) bool {
+	return errors.Is(err, ErrEmptyContactID)
+}
@@ -0,0 +1,60 @@
+package controller
+
+import (
+	"fmt"
+	"strings"
+	"text/template"
+
+	notificationmiloapiscomv1alpha1 "go.miloapis.com/milo/pkg/apis/notification/v1alpha1"
+)
+
+// DefaultContactSource is the Loops source of the contacts created by the controller
+const DefaultContactSource = "email-provider-loops-k8s-controller"
+
+// ContactSource renders the Loops source field of a Contact from a text/template, e.g. "milo-{{.Namespace}}".
+type ContactSource struct {
+	tmpl *template.Template
+}
+
+// contactSourceData is the data a ContactSource template is rendered with.
+type contactSourceData struct {
+	// Name is the name of the Contact
+	Name string
+	// Namespace is the namespace of the Contact
+	Namespace string
+}
+
+// ParseContactSource parses the contact source template. The template is rendered once against an empty Contact so
+// that references to unknown fields fail here rather than on every reconcile.
+func ParseContactSource(text string) (*ContactSource, error) {
+	tmpl, err := template.New("contact-source").Option("missingkey=error").Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse contact source template: %w", err)
+	}
+
+	source := &ContactSource{tmpl: tmpl}
+	if _, err := source.render(contactSourceData{}); err != nil {
+		return nil, err
+	}
+
+	return source, nil
+}
+
+// Render renders the source of the contact. A nil ContactSource renders DefaultContactSource.
+func (s *ContactSource) Render(contact *notificationmiloapiscomv1alpha1.Contact) (string, error) {
+	if s == nil {
+		return DefaultContactSource, nil
+	}
+	return s.render(contactSourceData{
+		Name:      contact.Name,
+		Namespace: contact.Namespace,
+	})
+}
+
+func (s *ContactSource) render(data contactSourceData) (string, error) {
+	var sb strings.Builder
+	if err := s.tmpl.Execute(&sb, data); err != nil {
+		return "", fmt.Errorf("failed to render contact source template: %w", err)
+	}
+	return sb.String(), nil
+}
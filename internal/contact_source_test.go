@@ -0,0 +1,93 @@
+package controller
+
+import (
+	"context"
+	"testing"
+
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+func TestContactSource_Render(t *testing.T) {
+	contact := newTestContact("jane")
+	contact.Namespace = "project-a"
+
+	tests := []struct {
+		name     string
+		template string
+		expected string
+	}{
+		{name: "static", template: "milo", expected: "milo"},
+		{name: "templated", template: "milo-{{.Namespace}}", expected: "milo-project-a"},
+		{name: "templated name", template: "{{.Namespace}}/{{.Name}}", expected: "project-a/jane"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			source, err := ParseContactSource(tt.template)
+			if err != nil {
+				t.Fatalf("ParseContactSource() failed: %v", err)
+			}
+			got, err := source.Render(contact)
+			if err != nil {
+				t.Fatalf("Render() failed: %v", err)
+			}
+			if got != tt.expected {
+				t.Errorf("Expected source %q, got %q", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestParseContactSource_Invalid(t *testing.T) {
+	tests := []struct {
+		name     string
+		template string
+	}{
+		{name: "missing field", template: "milo-{{.Project}}"},
+		{name: "malformed", template: "milo-{{.Namespace"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := ParseContactSource(tt.template); err == nil {
+				t.Errorf("Expected an error for template %q, got none", tt.template)
+			}
+		})
+	}
+}
+
+func TestReconcile_ContactSource(t *testing.T) {
+	ctx := context.Background()
+	contact := newTestContact("jane")
+	contact.Finalizers = []string{loopsContactFinalizerKey}
+
+	r, loopsAPI := newTestContactController(t, contact)
+	if err := r.setupFinalizers(); err != nil {
+		t.Fatalf("setupFinalizers() failed: %v", err)
+	}
+
+	// A nil ContactSource falls back to the default source
+	if _, err := r.Reconcile(ctx, ctrl.Request{NamespacedName: client.ObjectKeyFromObject(contact)}); err != nil {
+		t.Fatalf("Reconcile() failed: %v", err)
+	}
+	if len(loopsAPI.upserts) != 1 {
+		t.Fatalf("Expected 1 upsert, got %d", len(loopsAPI.upserts))
+	}
+	if loopsAPI.upserts[0].Source != DefaultContactSource {
+		t.Errorf("Expected source %q, got %q", DefaultContactSource, loopsAPI.upserts[0].Source)
+	}
+
+	source, err := ParseContactSource("milo-{{.Namespace}}")
+	if err != nil {
+		t.Fatalf("ParseContactSource() failed: %v", err)
+	}
+	r.ContactSource = source
+	if _, err := r.upsertContact(ctx, contact, false); err != nil {
+		t.Fatalf("upsertContact() failed: %v", err)
+	}
+	expected := "milo-" + contact.Namespace
+	if loopsAPI.upserts[1].Source != expected {
+		t.Errorf("Expected source %q, got %q", expected, loopsAPI.upserts[1].Source)
+	}
+}
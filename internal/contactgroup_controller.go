@@ -0,0 +1,222 @@
+package controller
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"go.miloapis.com/email-provider-loops/internal/util"
+	loops "go.miloapis.com/email-provider-loops/pkg/loops"
+	notificationmiloapiscomv1alpha1 "go.miloapis.com/milo/pkg/apis/notification/v1alpha1"
+
+	"go.opentelemetry.io/otel/trace"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/clock"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+)
+
+const (
+	// LoopsContactGroupReadyCondition is a condition that is set to true when the mailing list metadata is synced
+	LoopsContactGroupReadyCondition = "LoopsContactGroupReady"
+	// LoopsContactGroupUpdatedReason is a reason that is set when the mailing list metadata is updated
+	LoopsContactGroupUpdatedReason = "MailingListUpdated"
+	// LoopsContactGroupNotUpdatedReason is a reason that is set when the mailing list metadata failed to be updated
+	LoopsContactGroupNotUpdatedReason = "MailingListNotUpdated"
+	// LoopsContactGroupMailingListIDMissingReason is a reason that is set when the Loops provider has no mailing list ID
+	LoopsContactGroupMailingListIDMissingReason = "MailingListIDMissing"
+)
+
+// ErrMailingListIDNotFound is returned by getMailingListId for contact groups without a Loops mailing list ID
+var ErrMailingListIDNotFound = errors.New("mailing list ID not found for contact group")
+
+// contactGroupVisibilityPublic is the ContactGroup visibility of the mailing lists shown to the contacts in Loops
+const contactGroupVisibilityPublic = "public"
+
+// LoopsContactGroupController reconciles a ContactGroup object. It syncs the display name and visibility of the
+// ContactGroups with a Loops provider to the metadata of their mailing list. It requires a Loops client with the
+// mailing list update endpoint enabled, see loops.WithMailingListUpdate.
+type LoopsContactGroupController struct {
+	Client client.Client
+	Loops  loops.API
+	// ProviderCallTimeout bounds each call to Loops. Zero means no per-call timeout.
+	ProviderCallTimeout time.Duration
+	// TracerProvider records a span per reconciliation when set
+	TracerProvider trace.TracerProvider
+	// RateLimitGate delays the reconciliations while Loops rate limits the controllers sharing it. Reconciliations are
+	// not delayed when nil.
+	RateLimitGate *RateLimitGate
+	// Clock is the source of the current time of the time-dependent logic, the real clock when nil. Tests set a fake one
+	// to make it deterministic.
+	Clock clock.PassiveClock
+}
+
+// +kubebuilder:rbac:groups=notification.miloapis.com,resources=contactgroups,verbs=get;list;watch
+// +kubebuilder:rbac:groups=notification.miloapis.com,resources=contactgroups/status,verbs=get;update;patch
+
+// Reconcile is the main function that reconciles the ContactGroup object.
+func (r *LoopsContactGroupController) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	return traceReconcile(ctx, r.TracerProvider, "LoopsContactGroupController", req,
+		func(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+			return gateReconcile(ctx, r.RateLimitGate, req,
+				withReconcileSummary("LoopsContactGroupController", r.Clock, r.reconcile))
+		})
+}
+
+func (r *LoopsContactGroupController) reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	log := logf.FromContext(ctx).WithValues("controller", "ContactGroupController", "trigger", req.NamespacedName)
+	log.Info("Starting reconciliation", "namespacedName", req.String(), "name", req.Name, "namespace", req.Namespace)
+	summary := reconcileSummaryFrom(ctx)
+
+	// Get ContactGroup
+	group := &notificationmiloapiscomv1alpha1.ContactGroup{}
+	err := r.Client.Get(ctx, req.NamespacedName, group)
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			log.Info("ContactGroup not found. Probably deleted.")
+			return ctrl.Result{}, nil
+		}
+		return ctrl.Result{}, fmt.Errorf("failed to get contactgroup: %w", err)
+	}
+
+	if !group.DeletionTimestamp.IsZero() {
+		log.Info("ContactGroup is being deleted, skipping reconciliation")
+		return ctrl.Result{}, nil
+	}
+
+	if !hasLoopsProvider(group) {
+		log.Info("ContactGroup has no Loops provider, skipping reconciliation")
+		return ctrl.Result{}, nil
+	}
+
+	// Skip groups whose metadata is already synced for their generation
+	readyCond := meta.FindStatusCondition(group.Status.Conditions, LoopsContactGroupReadyCondition)
+	if readyCond != nil && readyCond.ObservedGeneration == group.GetGeneration() &&
+		readyCond.Reason != LoopsContactGroupNotUpdatedReason {
+		log.Info("ContactGroup already synced, skipping reconciliation")
+		return ctrl.Result{}, nil
+	}
+
+	oldStatus := group.Status.DeepCopy()
+	original := group.DeepCopy()
+
+	var reconcileError error
+	mailingListID, err := getMailingListId(group)
+	if errors.Is(err, ErrMailingListIDNotFound) {
+		// Retrying does not help until the spec is fixed, which triggers a new reconciliation
+		log.Info("ContactGroup has no Loops mailing list ID, cannot sync its metadata")
+		meta.SetStatusCondition(&group.Status.Conditions, metav1.Condition{
+			Type:               LoopsContactGroupReadyCondition,
+			Status:             metav1.ConditionFalse,
+			Reason:             LoopsContactGroupMailingListIDMissingReason,
+			Message:            "The Loops provider of the contact group has no mailing list ID",
+			LastTransitionTime: r.now(),
+			ObservedGeneration: group.GetGeneration(),
+		})
+	} else {
+		summary.setAction(reconcileActionUpdate)
+		if err := r.updateMailingList(ctx, group, mailingListID); err != nil {
+			log.Error(err, "Failed to update mailing list")
+			// Retrying does not help until the update endpoint is enabled
+			if !errors.Is(err, loops.ErrMailingListUpdateUnavailable) {
+				reconcileError = err
+			}
+			meta.SetStatusCondition(&group.Status.Conditions, metav1.Condition{
+				Type:               LoopsContactGroupReadyCondition,
+				Status:             metav1.ConditionFalse,
+				Reason:             LoopsContactGroupNotUpdatedReason,
+				Message:            fmt.Sprintf("Loops mailing list not updated on email provider: %s", err.Error()),
+				LastTransitionTime: r.now(),
+				ObservedGeneration: group.GetGeneration(),
+			})
+		} else {
+			log.Info("Loops mailing list updated")
+			meta.SetStatusCondition(&group.Status.Conditions, metav1.Condition{
+				Type:               LoopsContactGroupReadyCondition,
+				Status:             metav1.ConditionTrue,
+				Reason:             LoopsContactGroupUpdatedReason,
+				Message:            "Loops mailing list updated on email provider",
+				LastTransitionTime: r.now(),
+				ObservedGeneration: group.GetGeneration(),
+			})
+		}
+	}
+	summary.setCondition(meta.FindStatusCondition(group.Status.Conditions, LoopsContactGroupReadyCondition))
+
+	if err := util.PatchStatusWithRetry(ctx, util.StatusPatchParams{
+		Client:     r.Client,
+		Logger:     log,
+		Object:     group,
+		Original:   original,
+		OldStatus:  oldStatus,
+		NewStatus:  &group.Status,
+		FieldOwner: "loopscontactgroup-controller",
+	}); err != nil {
+		return ctrl.Result{}, err
+	}
+
+	if reconcileError != nil {
+		return ctrl.Result{}, reconcileError
+	}
+
+	log.Info("ContactGroup reconciled")
+	return ctrl.Result{}, nil
+}
+
+// now returns the current time of the Clock, for the timestamps of the conditions.
+func (r *LoopsContactGroupController) now() metav1.Time {
+	return metav1.NewTime(clockOrReal(r.Clock).Now())
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *LoopsContactGroupController) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&notificationmiloapiscomv1alpha1.ContactGroup{},
+			builder.WithPredicates(predicate.GenerationChangedPredicate{})).
+		Named("loopscontactgroup").
+		Complete(r)
+}
+
+// updateMailingList syncs the display name and visibility of the contact group to its Loops mailing list.
+func (r *LoopsContactGroupController) updateMailingList(ctx context.Context, group *notificationmiloapiscomv1alpha1.ContactGroup, mailingListID string) error {
+	update := loops.MailingListUpdate{Name: group.Spec.DisplayName}
+	if visibility := string(group.Spec.Visibility); visibility != "" {
+		isPublic := visibility == contactGroupVisibilityPublic
+		update.IsPublic = &isPublic
+	}
+
+	callCtx, cancel := withProviderCallTimeout(ctx, r.ProviderCallTimeout)
+	defer cancel()
+	if _, err := r.Loops.UpdateMailingList(callCtx, mailingListID, update); err != nil {
+		return fmt.Errorf("failed to update Loops mailing list %s: %w", mailingListID, err)
+	}
+	return nil
+}
+
+// hasLoopsProvider returns true if the contact group is synced with a Loops mailing list.
+func hasLoopsProvider(group *notificationmiloapiscomv1alpha1.ContactGroup) bool {
+	for _, provider := range group.Spec.Providers {
+		if provider.Name == "Loops" {
+			return true
+		}
+	}
+	return false
+}
+
+// getMailingListId returns the Loops mailing list ID of the contact group, or ErrMailingListIDNotFound if it has no
+// Loops provider or the provider has no ID.
+func getMailingListId(cg *notificationmiloapiscomv1alpha1.ContactGroup) (string, error) {
+	for _, provider := range cg.Spec.Providers {
+		if provider.Name == "Loops" && provider.ID != "" {
+			return provider.ID, nil
+		}
+	}
+
+	return "", ErrMailingListIDNotFound
+}
@@ -0,0 +1,156 @@
+package controller
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	loops "go.miloapis.com/email-provider-loops/pkg/loops"
+	notificationmiloapiscomv1alpha1 "go.miloapis.com/milo/pkg/apis/notification/v1alpha1"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func newTestContactGroupController(t *testing.T, objs ...client.Object) (*LoopsContactGroupController, *fakeLoops) {
+	t.Helper()
+	k8sClient := fake.NewClientBuilder().
+		WithScheme(newTestScheme(t)).
+		WithObjects(objs...).
+		WithStatusSubresource(&notificationmiloapiscomv1alpha1.ContactGroup{}).
+		Build()
+
+	loopsAPI := newFakeLoops()
+	r := &LoopsContactGroupController{
+		Client: k8sClient,
+		Loops:  loopsAPI,
+	}
+
+	return r, loopsAPI
+}
+
+func TestReconcile_ContactGroupUpdated(t *testing.T) {
+	ctx := context.Background()
+	group := newTestContactGroup("product", "list-product")
+	group.Generation = 1
+	group.Spec.DisplayName = "Product updates"
+	group.Spec.Visibility = "public"
+
+	r, loopsAPI := newTestContactGroupController(t, group)
+	req := ctrl.Request{NamespacedName: client.ObjectKeyFromObject(group)}
+
+	if _, err := r.Reconcile(ctx, req); err != nil {
+		t.Fatalf("Reconcile() failed: %v", err)
+	}
+	updates := loopsAPI.listUpdates["list-product"]
+	if len(updates) != 1 {
+		t.Fatalf("Expected 1 mailing list update, got %v", updates)
+	}
+	if updates[0].Name != "Product updates" || updates[0].IsPublic == nil || !*updates[0].IsPublic {
+		t.Errorf("Expected a public mailing list named Product updates, got %+v", updates[0])
+	}
+
+	if err := r.Client.Get(ctx, req.NamespacedName, group); err != nil {
+		t.Fatalf("Get() failed: %v", err)
+	}
+	readyCond := meta.FindStatusCondition(group.Status.Conditions, LoopsContactGroupReadyCondition)
+	if readyCond == nil || readyCond.Status != metav1.ConditionTrue || readyCond.Reason != LoopsContactGroupUpdatedReason ||
+		readyCond.ObservedGeneration != 1 {
+		t.Errorf("Expected a ready condition for generation 1, got %+v", readyCond)
+	}
+
+	// The synced generation is not sent again
+	if _, err := r.Reconcile(ctx, req); err != nil {
+		t.Fatalf("Reconcile() failed: %v", err)
+	}
+	if got := len(loopsAPI.listUpdates["list-product"]); got != 1 {
+		t.Errorf("Expected no update of a synced generation, got %d updates", got)
+	}
+
+	// A spec change is synced
+	group.Spec.DisplayName = "Product news"
+	group.Spec.Visibility = "private"
+	group.Generation = 2
+	if err := r.Client.Update(ctx, group); err != nil {
+		t.Fatalf("Update() failed: %v", err)
+	}
+	if _, err := r.Reconcile(ctx, req); err != nil {
+		t.Fatalf("Reconcile() failed: %v", err)
+	}
+	updates = loopsAPI.listUpdates["list-product"]
+	if len(updates) != 2 || updates[1].Name != "Product news" || updates[1].IsPublic == nil || *updates[1].IsPublic {
+		t.Errorf("Expected a second update to a private mailing list named Product news, got %+v", updates)
+	}
+}
+
+func TestReconcile_ContactGroupNotSynced(t *testing.T) {
+	tests := []struct {
+		name           string
+		group          func() *notificationmiloapiscomv1alpha1.ContactGroup
+		loopsErr       error
+		expectedErr    bool
+		expectedReason string
+	}{
+		{
+			name:           "missing mailing list ID",
+			group:          func() *notificationmiloapiscomv1alpha1.ContactGroup { return newTestContactGroup("product", "") },
+			expectedReason: LoopsContactGroupMailingListIDMissingReason,
+		},
+		{
+			name: "no Loops provider",
+			group: func() *notificationmiloapiscomv1alpha1.ContactGroup {
+				group := newTestContactGroup("product", "")
+				group.Spec.Providers = nil
+				return group
+			},
+		},
+		{
+			name: "update endpoint not enabled",
+			group: func() *notificationmiloapiscomv1alpha1.ContactGroup {
+				return newTestContactGroup("product", "list-product")
+			},
+			loopsErr:       loops.ErrMailingListUpdateUnavailable,
+			expectedReason: LoopsContactGroupNotUpdatedReason,
+		},
+		{
+			name: "provider failure",
+			group: func() *notificationmiloapiscomv1alpha1.ContactGroup {
+				return newTestContactGroup("product", "list-product")
+			},
+			loopsErr:       errors.New("connection refused"),
+			expectedErr:    true,
+			expectedReason: LoopsContactGroupNotUpdatedReason,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			group := tt.group()
+			r, loopsAPI := newTestContactGroupController(t, group)
+			loopsAPI.err = tt.loopsErr
+
+			_, err := r.Reconcile(ctx, ctrl.Request{NamespacedName: client.ObjectKeyFromObject(group)})
+			if (err != nil) != tt.expectedErr {
+				t.Errorf("Expected error %t, got %v", tt.expectedErr, err)
+			}
+
+			if err := r.Client.Get(ctx, client.ObjectKeyFromObject(group), group); err != nil {
+				t.Fatalf("Get() failed: %v", err)
+			}
+			readyCond := meta.FindStatusCondition(group.Status.Conditions, LoopsContactGroupReadyCondition)
+			if tt.expectedReason == "" {
+				if readyCond != nil {
+					t.Errorf("Expected no ready condition, got %+v", readyCond)
+				}
+				return
+			}
+			if readyCond == nil || readyCond.Status != metav1.ConditionFalse || readyCond.Reason != tt.expectedReason {
+				t.Errorf("Expected a false ready condition with reason %s, got %+v", tt.expectedReason, readyCond)
+			}
+		})
+	}
+}
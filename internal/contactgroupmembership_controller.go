@@ -2,19 +2,31 @@ package controller
 
 import (
 	"context"
+	goerrors "errors"
 	"fmt"
+	"slices"
+	"sort"
+	"strings"
+	"time"
 
 	"go.miloapis.com/email-provider-loops/internal/util"
 	loops "go.miloapis.com/email-provider-loops/pkg/loops"
 	notificationmiloapiscomv1alpha1 "go.miloapis.com/milo/pkg/apis/notification/v1alpha1"
 
+	"go.opentelemetry.io/otel/trace"
 	"k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/clock"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/event"
 	"sigs.k8s.io/controller-runtime/pkg/finalizer"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
 	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 )
 
 const (
@@ -26,23 +38,71 @@ const (
 	LoopsContactGroupMembershipCreatedReason = "ContactGroupMembershipCreated"
 	// LoopsContactGroupMembershipNotFinalizedReason is a reason that is set when the Loops contact group membership is not finalized
 	LoopsContactGroupMembershipNotFinalizedReason = "ContactGroupMembershipNotFinalized"
+	// LoopsContactGroupMembershipUpdatedReason is a reason that is set when the Loops contact is moved to the mailing list of a new contact group
+	LoopsContactGroupMembershipUpdatedReason = "ContactGroupMembershipUpdated"
+	// LoopsContactGroupMembershipNotUpdatedReason is a reason that is set when the Loops contact is not moved to the mailing list of a new contact group
+	LoopsContactGroupMembershipNotUpdatedReason = "ContactGroupMembershipNotUpdated"
+	// LoopsContactGroupMembershipMailingListIDMissingReason is a reason that is set when the Loops contact group membership is not created because the contact group has no Loops mailing list ID
+	LoopsContactGroupMembershipMailingListIDMissingReason = "MailingListIDMissing"
+	// LoopsContactGroupMembershipSyncSkippedReason is a reason that is set when the contact of the membership opted out of the Loops sync
+	LoopsContactGroupMembershipSyncSkippedReason = "LoopsSyncSkipped"
+)
+
+// The contact, contact group and mailing list a ContactGroupMembership was last synced to Loops with. The
+// ContactGroupMembership status is defined by Milo and has no field for them, so they are kept as annotations. The
+// Loops userId of the synced contact is the one recorded in the status providers.
+const (
+	syncedContactAnnotation      = "notification.miloapis.com/loops-synced-contact"
+	syncedContactGroupAnnotation = "notification.miloapis.com/loops-synced-contact-group"
+	syncedMailingListAnnotation  = "notification.miloapis.com/loops-synced-mailing-list"
 )
 
 const (
 	loopsContactGroupMembershipFinalizerKey = "notification.miloapis.com/loops-contact-group-membership"
 )
 
+const (
+	// contactGroupMembershipPairIndexKey indexes ContactGroupMemberships by their contact and contact group references
+	contactGroupMembershipPairIndexKey = "contact-group-membership-pair"
+	// contactGroupMembershipContactIndexKey indexes ContactGroupMemberships by their contact reference
+	contactGroupMembershipContactIndexKey = "contact-group-membership-contact"
+	// contactGroupMembershipContactGroupIndexKey indexes ContactGroupMemberships by their contact group reference
+	contactGroupMembershipContactGroupIndexKey = "contact-group-membership-contact-group"
+)
+
 // LoopsContactGroupMembershipReconciler reconciles a LoopsContact object
 type LoopsContactGroupMembershipController struct {
 	Client     client.Client
 	Finalizers finalizer.Finalizers
 	Loops      loops.API
+	// ContactIDResolver resolves the Loops userId of a Contact. Defaults to the Contact UID.
+	ContactIDResolver ContactIDResolver
+	// ProviderCallTimeout bounds each call to Loops. Zero means no per-call timeout.
+	ProviderCallTimeout time.Duration
+	// InstanceID distinguishes the finalizer key of this controller instance from other instances
+	InstanceID string
+	// PreviousInstanceIDs are the instance IDs the controller ran with before, whose finalizers it takes over
+	PreviousInstanceIDs []string
+	// TracerProvider records a span per reconciliation when set
+	TracerProvider trace.TracerProvider
+	// RateLimitGate delays the reconciliations while Loops rate limits the controllers sharing it. Reconciliations are
+	// not delayed when nil.
+	RateLimitGate *RateLimitGate
+	// Clock is the source of the current time of the time-dependent logic, the real clock when nil. Tests set a fake one
+	// to make it deterministic.
+	Clock clock.PassiveClock
+	// VerifyListRemoval makes the finalizer confirm with Loops that the contact left the mailing list before
+	// releasing the membership.
+	VerifyListRemoval bool
 }
 
 // loopsContactGroupMembershipController is a finalizer for the Contact object
 type loopsContactGroupMembershipFinalizer struct {
-	Client client.Client
-	Loops  loops.API
+	Client              client.Client
+	Loops               loops.API
+	ContactIDResolver   ContactIDResolver
+	ProviderCallTimeout time.Duration
+	VerifyListRemoval   bool
 }
 
 func (f *loopsContactGroupMembershipFinalizer) Finalize(ctx context.Context, obj client.Object) (finalizer.Result, error) {
@@ -65,8 +125,27 @@ func (f *loopsContactGroupMembershipFinalizer) Finalize(ctx context.Context, obj
 		finalizerError = fmt.Errorf("failed to get referenced resources: %w", err)
 	}
 
-	// Delete Loops contact
+	// Leave the Loops contact of a contact that opted out of the Loops sync as-is
+	if finalizerError == nil && skipsLoopsSync(contact) {
+		log.Info("Contact opted out of the Loops sync, leaving its Loops mailing lists as-is")
+		return finalizer.Result{}, nil
+	}
+
+	// Skip the Loops removal if another membership still holds the contact in the mailing list
+	keepInMailingList := false
 	if finalizerError == nil {
+		keepInMailingList, err = hasOtherContactGroupMemberships(ctx, f.Client, cgm)
+		if err != nil {
+			log.Error(err, "Failed to list contact group memberships for the same contact and group")
+			finalizerError = fmt.Errorf("failed to list contact group memberships: %w", err)
+		}
+		if keepInMailingList {
+			log.Info("Another ContactGroupMembership exists for the same contact and group, keeping Loops contact in mailing list")
+		}
+	}
+
+	// Delete Loops contact
+	if finalizerError == nil && !keepInMailingList {
 		err = f.removeContactFromMailingList(ctx, contact, contactGroup)
 		if err != nil {
 			log.Error(err, "Failed to delete Loops contact")
@@ -89,7 +168,7 @@ func (f *loopsContactGroupMembershipFinalizer) Finalize(ctx context.Context, obj
 			ObservedGeneration: cgm.GetGeneration(),
 		})
 
-		err = util.PatchStatusIfChanged(ctx, util.StatusPatchParams{
+		err = util.PatchStatusWithRetry(ctx, util.StatusPatchParams{
 			Client:     f.Client,
 			Logger:     log,
 			Object:     cgm,
@@ -109,13 +188,22 @@ func (f *loopsContactGroupMembershipFinalizer) Finalize(ctx context.Context, obj
 	return finalizer.Result{}, nil
 }
 
-// +kubebuilder:rbac:groups=notification.miloapis.com,resources=contactgroupmemberships,verbs=get;list;watch
+// +kubebuilder:rbac:groups=notification.miloapis.com,resources=contactgroupmemberships,verbs=get;list;watch;patch;delete
 // +kubebuilder:rbac:groups=notification.miloapis.com,resources=contactgroupmemberships/status,verbs=get;update;patch
 // +kubebuilder:rbac:groups=notification.miloapis.com,resources=contactgroupmemberships/finalizers,verbs=update
 
 // Reconcile is the main function that reconciles the ContactGroupMembership object.
 func (r *LoopsContactGroupMembershipController) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	return traceReconcile(ctx, r.TracerProvider, "LoopsContactGroupMembershipController", req,
+		func(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+			return gateReconcile(ctx, r.RateLimitGate, req,
+				withReconcileSummary("LoopsContactGroupMembershipController", r.Clock, r.reconcile))
+		})
+}
+
+func (r *LoopsContactGroupMembershipController) reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
 	log := logf.FromContext(ctx).WithValues("controller", "ContactGroupMembershipController", "trigger", req.NamespacedName)
+	summary := reconcileSummaryFrom(ctx)
 	log.Info("Starting reconciliation", "namespacedName", req.String(), "name", req.Name, "namespace", req.Namespace)
 
 	// Get ContactGroupMembership
@@ -130,17 +218,19 @@ func (r *LoopsContactGroupMembershipController) Reconcile(ctx context.Context, r
 	}
 
 	// Run finalizers
+	adopted := adoptPreviousFinalizers(cgm, loopsContactGroupMembershipFinalizerKey, r.InstanceID, r.PreviousInstanceIDs)
 	finalizeResult, err := r.Finalizers.Finalize(ctx, cgm)
 	if err != nil {
 		log.Error(err, "Failed to run finalizers for ContactGroupMembership")
 		return ctrl.Result{}, fmt.Errorf("failed to run finalizers for ContactGroupMembership: %w", err)
 	}
-	if finalizeResult.Updated {
+	if finalizeResult.Updated || adopted {
+		summary.setAction(reconcileActionFinalize)
 		log.Info("finalizer updated the contactgroupmembership object, updating API server")
 		if updateErr := r.Client.Update(ctx, cgm); updateErr != nil {
 			if errors.IsConflict(updateErr) {
 				log.Info("Conflict updating ContactGroupMembership after finalizer update; requeuing")
-				return ctrl.Result{Requeue: true}, nil
+				return conflictRequeueResult(), nil
 			}
 			log.Error(updateErr, "Failed to update ContactGroupMembership after finalizer update")
 			return ctrl.Result{}, updateErr
@@ -148,6 +238,18 @@ func (r *LoopsContactGroupMembershipController) Reconcile(ctx context.Context, r
 		return ctrl.Result{}, nil
 	}
 
+	// Collapse duplicated memberships for the same contact and group
+	deleted, err := r.collapseDuplicateMemberships(ctx, cgm)
+	if err != nil {
+		log.Error(err, "Failed to collapse duplicate ContactGroupMemberships")
+		return ctrl.Result{}, fmt.Errorf("failed to collapse duplicate ContactGroupMemberships: %w", err)
+	}
+	if deleted {
+		summary.setAction(reconcileActionCollapseDuplicate)
+		log.Info("ContactGroupMembership was a duplicate and has been deleted")
+		return ctrl.Result{}, nil
+	}
+
 	var reconcileError error
 
 	// Get Referenced resources
@@ -160,7 +262,7 @@ func (r *LoopsContactGroupMembershipController) Reconcile(ctx context.Context, r
 			Status:             metav1.ConditionFalse,
 			Reason:             LoopsContactGroupMembershipNotCreatedReason,
 			Message:            fmt.Sprintf("Failed to get referenced resources: %s", err.Error()),
-			LastTransitionTime: metav1.Now(),
+			LastTransitionTime: r.now(),
 			ObservedGeneration: cgm.GetGeneration(),
 		})
 
@@ -170,19 +272,53 @@ func (r *LoopsContactGroupMembershipController) Reconcile(ctx context.Context, r
 	original := cgm.DeepCopy()
 	readyCond := meta.FindStatusCondition(cgm.Status.Conditions, LoopsContactGroupMembershipReadyCondition)
 
-	if (readyCond == nil || readyCond.Reason == LoopsContactGroupMembershipNotCreatedReason) && reconcileError == nil {
+	// A contact that opted out of the Loops sync is neither added to nor moved between mailing lists
+	if reconcileError == nil && skipsLoopsSync(contact) {
+		log.Info("Contact opted out of the Loops sync, skipping reconciliation")
+		summary.setAction(reconcileActionSkip)
+		meta.SetStatusCondition(&cgm.Status.Conditions, metav1.Condition{
+			Type:               LoopsContactGroupMembershipReadyCondition,
+			Status:             metav1.ConditionFalse,
+			Reason:             LoopsContactGroupMembershipSyncSkippedReason,
+			Message:            fmt.Sprintf("Contact is not synced to Loops as it is annotated with %s", skipLoopsSyncAnnotation),
+			LastTransitionTime: r.now(),
+			ObservedGeneration: cgm.GetGeneration(),
+		})
+		summary.setCondition(meta.FindStatusCondition(cgm.Status.Conditions, LoopsContactGroupMembershipReadyCondition))
+		return ctrl.Result{}, util.PatchStatusWithRetry(ctx, util.StatusPatchParams{
+			Client:     r.Client,
+			Logger:     log,
+			Object:     cgm,
+			Original:   original,
+			OldStatus:  oldStatus,
+			NewStatus:  &cgm.Status,
+			FieldOwner: "loopscontactgroupmembership-controller",
+		})
+	}
+
+	// A membership whose contact opted back into the Loops sync is created again
+	notCreated := readyCond == nil || readyCond.Reason == LoopsContactGroupMembershipNotCreatedReason ||
+		readyCond.Reason == LoopsContactGroupMembershipMailingListIDMissingReason ||
+		readyCond.Reason == LoopsContactGroupMembershipSyncSkippedReason
+
+	if notCreated && reconcileError == nil {
 		log.Info("LoopsContact creation")
+		summary.setAction(reconcileActionCreate)
 
-		err = r.addContactToMailingList(ctx, contact, contactGroup)
+		contactID, err := r.addContactToMailingList(ctx, contact, contactGroup)
 		if err != nil {
 			reconcileError = err
 			log.Error(err, "Failed to add contact to mailing list")
+			reason := LoopsContactGroupMembershipNotCreatedReason
+			if goerrors.Is(err, ErrMailingListIDNotFound) {
+				reason = LoopsContactGroupMembershipMailingListIDMissingReason
+			}
 			meta.SetStatusCondition(&cgm.Status.Conditions, metav1.Condition{
 				Type:               LoopsContactGroupMembershipReadyCondition,
 				Status:             metav1.ConditionFalse,
-				Reason:             LoopsContactGroupMembershipNotCreatedReason,
+				Reason:             reason,
 				Message:            fmt.Sprintf("Loops contact group membership not created on email provider: %s", err.Error()),
-				LastTransitionTime: metav1.Now(),
+				LastTransitionTime: r.now(),
 				ObservedGeneration: cgm.GetGeneration(),
 			})
 		}
@@ -194,19 +330,72 @@ func (r *LoopsContactGroupMembershipController) Reconcile(ctx context.Context, r
 				Status:             metav1.ConditionTrue,
 				Reason:             LoopsContactGroupMembershipCreatedReason,
 				Message:            "Loops contact group membership created on email provider",
-				LastTransitionTime: metav1.Now(),
+				LastTransitionTime: r.now(),
 				ObservedGeneration: cgm.GetGeneration(),
 			})
 			cgm.Status.Providers = []notificationmiloapiscomv1alpha1.ContactProviderStatus{
 				{
 					Name: "Loops",
-					ID:   string(contact.UID),
+					ID:   contactID,
 				},
 			}
 		}
 	}
 
-	if err := util.PatchStatusIfChanged(ctx, util.StatusPatchParams{
+	// Update – the membership was synced with another contact or contact group, move the membership in Loops
+	syncedContact := cgm.Annotations[syncedContactAnnotation]
+	contactChanged := syncedContact != "" && syncedContact != contactKey(cgm)
+	syncedGroup := cgm.Annotations[syncedContactGroupAnnotation]
+	groupChanged := syncedGroup != "" && syncedGroup != contactGroupKey(cgm)
+	if !notCreated && reconcileError == nil &&
+		(contactChanged || groupChanged) {
+		summary.setAction(reconcileActionUpdate)
+		var err error
+		if contactChanged {
+			// Moving to the new contact also covers a simultaneous change of contact group
+			log.Info("ContactRef changed", "previousContact", syncedContact, "previousContactGroup", syncedGroup)
+			err = r.moveMembershipToContact(ctx, cgm, contact, contactGroup)
+		} else {
+			log.Info("ContactGroupRef changed", "previousContactGroup", syncedGroup)
+			err = r.moveContactToMailingList(ctx, cgm, contact, contactGroup)
+		}
+		if err != nil {
+			reconcileError = err
+			log.Error(err, "Failed to move contact to mailing list")
+			meta.SetStatusCondition(&cgm.Status.Conditions, metav1.Condition{
+				Type:               LoopsContactGroupMembershipReadyCondition,
+				Status:             metav1.ConditionFalse,
+				Reason:             LoopsContactGroupMembershipNotUpdatedReason,
+				Message:            fmt.Sprintf("Loops contact group membership not updated on email provider: %s", err.Error()),
+				LastTransitionTime: r.now(),
+				ObservedGeneration: cgm.GetGeneration(),
+			})
+		}
+
+		if err == nil {
+			log.Info("Loops contact group membership updated")
+			meta.SetStatusCondition(&cgm.Status.Conditions, metav1.Condition{
+				Type:               LoopsContactGroupMembershipReadyCondition,
+				Status:             metav1.ConditionTrue,
+				Reason:             LoopsContactGroupMembershipUpdatedReason,
+				Message:            "Loops contact group membership updated on email provider",
+				LastTransitionTime: r.now(),
+				ObservedGeneration: cgm.GetGeneration(),
+			})
+		}
+	}
+
+	// Record the contact and contact group the membership is synced with, so that a later change of them can be detected
+	if reconcileError == nil {
+		if err := r.recordSyncedContactGroup(ctx, cgm, contactGroup); err != nil {
+			log.Error(err, "Failed to record the synced contact group")
+			reconcileError = err
+		}
+	}
+
+	summary.setCondition(meta.FindStatusCondition(cgm.Status.Conditions, LoopsContactGroupMembershipReadyCondition))
+
+	if err := util.PatchStatusWithRetry(ctx, util.StatusPatchParams{
 		Client:     r.Client,
 		Logger:     log,
 		Object:     cgm,
@@ -218,6 +407,13 @@ func (r *LoopsContactGroupMembershipController) Reconcile(ctx context.Context, r
 		return ctrl.Result{}, err
 	}
 
+	// Retrying cannot resolve a contact group without a mailing list ID nor a contact without a userId: the membership
+	// is reconciled again when it changes or its ContactGroup gains a mailing list ID
+	if goerrors.Is(reconcileError, ErrMailingListIDNotFound) || isEmptyContactID(reconcileError) {
+		log.Info("Membership cannot be synced to Loops until its references change", "reason", reconcileError.Error())
+		return ctrl.Result{}, nil
+	}
+
 	if reconcileError != nil {
 		return ctrl.Result{}, reconcileError
 	}
@@ -226,45 +422,184 @@ func (r *LoopsContactGroupMembershipController) Reconcile(ctx context.Context, r
 	return ctrl.Result{}, nil
 }
 
+// contactMemberships maps a Contact to its ContactGroupMemberships, using the indexed field.
+func (r *LoopsContactGroupMembershipController) contactMemberships(ctx context.Context, obj client.Object) []reconcile.Request {
+	var membershipList notificationmiloapiscomv1alpha1.ContactGroupMembershipList
+	if err := r.Client.List(ctx, &membershipList,
+		client.MatchingFields{contactGroupMembershipContactIndexKey: obj.GetNamespace() + "/" + obj.GetName()},
+	); err != nil {
+		logf.FromContext(ctx).Error(err, "Failed to list the ContactGroupMemberships of Contact", "contact", client.ObjectKeyFromObject(obj))
+		return nil
+	}
+
+	requests := make([]reconcile.Request, 0, len(membershipList.Items))
+	for _, membership := range membershipList.Items {
+		requests = append(requests, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(&membership)})
+	}
+	return requests
+}
+
+// contactGroupMemberships maps a ContactGroup to its ContactGroupMemberships, using the indexed field.
+func (r *LoopsContactGroupMembershipController) contactGroupMemberships(ctx context.Context, obj client.Object) []reconcile.Request {
+	var membershipList notificationmiloapiscomv1alpha1.ContactGroupMembershipList
+	if err := r.Client.List(ctx, &membershipList,
+		client.MatchingFields{contactGroupMembershipContactGroupIndexKey: obj.GetNamespace() + "/" + obj.GetName()},
+	); err != nil {
+		logf.FromContext(ctx).Error(err, "Failed to list the ContactGroupMemberships of ContactGroup", "contactGroup", client.ObjectKeyFromObject(obj))
+		return nil
+	}
+
+	requests := make([]reconcile.Request, 0, len(membershipList.Items))
+	for _, membership := range membershipList.Items {
+		requests = append(requests, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(&membership)})
+	}
+	return requests
+}
+
+// mailingListIDChanged returns true if the Loops mailing list ID of the ContactGroup changed.
+func mailingListIDChanged(e event.UpdateEvent) bool {
+	oldGroup, ok := e.ObjectOld.(*notificationmiloapiscomv1alpha1.ContactGroup)
+	if !ok {
+		return false
+	}
+	newGroup, ok := e.ObjectNew.(*notificationmiloapiscomv1alpha1.ContactGroup)
+	if !ok {
+		return false
+	}
+	oldID, _ := getMailingListId(oldGroup)
+	newID, _ := getMailingListId(newGroup)
+	return oldID != newID
+}
+
+// now returns the current time of the Clock, for the timestamps of the conditions.
+func (r *LoopsContactGroupMembershipController) now() metav1.Time {
+	return metav1.NewTime(clockOrReal(r.Clock).Now())
+}
+
 // SetupWithManager sets up the controller with the Manager.
 func (r *LoopsContactGroupMembershipController) SetupWithManager(mgr ctrl.Manager) error {
-	// Register finalizer
-	r.Finalizers = finalizer.NewFinalizers()
-	if err := r.Finalizers.Register(loopsContactGroupMembershipFinalizerKey, &loopsContactGroupMembershipFinalizer{
-		Client: r.Client,
-		Loops:  r.Loops,
-	}); err != nil {
-		return fmt.Errorf("failed to register loops contact group membership finalizer: %w", err)
+	if err := r.setupFinalizers(); err != nil {
+		return err
+	}
+
+	// Index ContactGroupMembership objects by their contact and group references so that duplicates
+	// for the same pair can be found quickly.
+	if err := mgr.GetFieldIndexer().IndexField(
+		context.Background(),
+		&notificationmiloapiscomv1alpha1.ContactGroupMembership{},
+		contactGroupMembershipPairIndexKey,
+		func(rawObj client.Object) []string {
+			cgm := rawObj.(*notificationmiloapiscomv1alpha1.ContactGroupMembership)
+			return []string{buildContactGroupMembershipPairIndexKey(cgm)}
+		},
+	); err != nil {
+		return fmt.Errorf("failed to create contact group membership index for contact and group pair: %w", err)
+	}
+
+	// Index ContactGroupMembership objects by their contact reference so that the memberships of a contact opting in
+	// or out of the Loops sync are reconciled again.
+	if err := mgr.GetFieldIndexer().IndexField(
+		context.Background(),
+		&notificationmiloapiscomv1alpha1.ContactGroupMembership{},
+		contactGroupMembershipContactIndexKey,
+		func(rawObj client.Object) []string {
+			cgm := rawObj.(*notificationmiloapiscomv1alpha1.ContactGroupMembership)
+			return []string{contactKey(cgm)}
+		},
+	); err != nil {
+		return fmt.Errorf("failed to create contact group membership index for contact: %w", err)
+	}
+
+	// Index ContactGroupMembership objects by their contact group reference so that the memberships of a contact group
+	// gaining a Loops mailing list ID are reconciled again.
+	if err := mgr.GetFieldIndexer().IndexField(
+		context.Background(),
+		&notificationmiloapiscomv1alpha1.ContactGroupMembership{},
+		contactGroupMembershipContactGroupIndexKey,
+		func(rawObj client.Object) []string {
+			cgm := rawObj.(*notificationmiloapiscomv1alpha1.ContactGroupMembership)
+			return []string{contactGroupKey(cgm)}
+		},
+	); err != nil {
+		return fmt.Errorf("failed to create contact group membership index for contact group: %w", err)
 	}
 
 	return ctrl.NewControllerManagedBy(mgr).
-		For(&notificationmiloapiscomv1alpha1.ContactGroupMembership{}).
+		For(&notificationmiloapiscomv1alpha1.ContactGroupMembership{},
+			builder.WithPredicates(contactGroupMembershipChangedPredicate())).
+		Watches(
+			&notificationmiloapiscomv1alpha1.Contact{},
+			handler.EnqueueRequestsFromMapFunc(r.contactMemberships),
+			builder.WithPredicates(predicate.Funcs{
+				CreateFunc: func(event.CreateEvent) bool { return false },
+				UpdateFunc: func(e event.UpdateEvent) bool {
+					return e.ObjectOld.GetAnnotations()[skipLoopsSyncAnnotation] != e.ObjectNew.GetAnnotations()[skipLoopsSyncAnnotation]
+				},
+				DeleteFunc:  func(event.DeleteEvent) bool { return false },
+				GenericFunc: func(event.GenericEvent) bool { return false },
+			}),
+		).
+		Watches(
+			&notificationmiloapiscomv1alpha1.ContactGroup{},
+			handler.EnqueueRequestsFromMapFunc(r.contactGroupMemberships),
+			builder.WithPredicates(predicate.Funcs{
+				CreateFunc:  func(event.CreateEvent) bool { return false },
+				UpdateFunc:  mailingListIDChanged,
+				DeleteFunc:  func(event.DeleteEvent) bool { return false },
+				GenericFunc: func(event.GenericEvent) bool { return false },
+			}),
+		).
 		Named("loopscontactgroupmembership").
 		Complete(r)
 }
 
-func (r *LoopsContactGroupMembershipController) addContactToMailingList(ctx context.Context, c *notificationmiloapiscomv1alpha1.Contact, cg *notificationmiloapiscomv1alpha1.ContactGroup) error {
+// setupFinalizers registers the contact group membership finalizer under the instance finalizer key.
+func (r *LoopsContactGroupMembershipController) setupFinalizers() error {
+	r.Finalizers = finalizer.NewFinalizers()
+	if err := r.Finalizers.Register(finalizerKey(loopsContactGroupMembershipFinalizerKey, r.InstanceID), &loopsContactGroupMembershipFinalizer{
+		Client:              r.Client,
+		Loops:               r.Loops,
+		ContactIDResolver:   r.ContactIDResolver,
+		ProviderCallTimeout: r.ProviderCallTimeout,
+		VerifyListRemoval:   r.VerifyListRemoval,
+	}); err != nil {
+		return fmt.Errorf("failed to register loops contact group membership finalizer: %w", err)
+	}
+
+	return nil
+}
+
+// addContactToMailingList adds the Loops contact to the mailing list and returns the userId it is identified by.
+func (r *LoopsContactGroupMembershipController) addContactToMailingList(ctx context.Context, c *notificationmiloapiscomv1alpha1.Contact, cg *notificationmiloapiscomv1alpha1.ContactGroup) (string, error) {
 	log := logf.FromContext(ctx).WithValues("controller", "LoopsContactGroupMembershipController", "trigger", c.Name)
 	log.Info("Adding Loops contact to mailing list")
 
 	mailingListId, err := getMailingListId(cg)
 	if err != nil {
 		log.Error(err, "Failed to get Loops mailing list ID")
-		return fmt.Errorf("failed to get Loops mailing list ID: %w", err)
+		return "", fmt.Errorf("failed to get Loops mailing list ID: %w", err)
 	}
 
-	_, err = r.Loops.AddToMailingList(ctx, string(c.UID), mailingListId)
+	contactID, err := resolveContactID(r.ContactIDResolver, c)
+	if err != nil {
+		log.Error(err, "Failed to resolve Loops contact ID")
+		return "", fmt.Errorf("failed to resolve Loops contact ID: %w", err)
+	}
+
+	callCtx, cancel := withProviderCallTimeout(ctx, r.ProviderCallTimeout)
+	defer cancel()
+	_, err = r.Loops.AddToMailingList(callCtx, contactID, mailingListId)
 	if err != nil {
 		log.Error(err, "Failed to add Loops contact to mailing list")
-		return fmt.Errorf("failed to add Loops contact to mailing list: %w", err)
+		return "", fmt.Errorf("failed to add Loops contact to mailing list: %w", err)
 	}
+	membershipChangesTotal.WithLabelValues(mailingListId, membershipChangeActionAdd).Inc()
 
-	return nil
+	return contactID, nil
 }
 
 func (f *loopsContactGroupMembershipFinalizer) removeContactFromMailingList(ctx context.Context, c *notificationmiloapiscomv1alpha1.Contact, cg *notificationmiloapiscomv1alpha1.ContactGroup) error {
 	log := logf.FromContext(ctx).WithValues("controller", "LoopsContactGroupMembershipController", "trigger", c.Name)
-	log.Info("Removing Loops contact from mailing list")
 
 	mailingListId, err := getMailingListId(cg)
 	if err != nil {
@@ -272,23 +607,314 @@ func (f *loopsContactGroupMembershipFinalizer) removeContactFromMailingList(ctx
 		return fmt.Errorf("failed to get Loops mailing list ID: %w", err)
 	}
 
-	_, err = f.Loops.RemoveFromMailingList(ctx, string(c.UID), mailingListId)
+	if err := removeFromMailingList(ctx, f.Loops, f.ContactIDResolver, f.ProviderCallTimeout, c, mailingListId); err != nil {
+		return err
+	}
+
+	if f.VerifyListRemoval {
+		return f.verifyListRemoval(ctx, c, mailingListId)
+	}
+	return nil
+}
+
+// verifyListRemoval confirms with Loops that the contact is no longer subscribed to the mailing list. A contact
+// that no longer exists in Loops is not subscribed to any list.
+func (f *loopsContactGroupMembershipFinalizer) verifyListRemoval(ctx context.Context, c *notificationmiloapiscomv1alpha1.Contact, mailingListId string) error {
+	log := logf.FromContext(ctx).WithValues("controller", "LoopsContactGroupMembershipController", "trigger", c.Name)
+
+	contactID, err := resolveContactID(f.ContactIDResolver, c)
+	if err != nil {
+		log.Error(err, "Failed to resolve Loops contact ID")
+		return fmt.Errorf("failed to resolve Loops contact ID: %w", err)
+	}
+
+	callCtx, cancel := withProviderCallTimeout(ctx, f.ProviderCallTimeout)
+	defer cancel()
+	mailingLists, err := f.Loops.GetContactMailingLists(callCtx, contactID)
+	if err != nil {
+		if loops.IsNotFound(err) {
+			return nil
+		}
+		log.Error(err, "Failed to get Loops contact mailing lists")
+		return fmt.Errorf("failed to verify the removal from the Loops mailing list: %w", err)
+	}
+	if mailingLists[mailingListId] {
+		log.Info("Loops contact still subscribed to mailing list after removal", "mailingListId", mailingListId)
+		return fmt.Errorf("removal of Loops contact from mailing list %s not confirmed yet", mailingListId)
+	}
+
+	return nil
+}
+
+// moveContactToMailingList adds the Loops contact to the mailing list of the contact group the membership now
+// references, then removes it from the mailing list the membership was previously synced with, unless another
+// membership still holds the contact in it.
+func (r *LoopsContactGroupMembershipController) moveContactToMailingList(ctx context.Context, cgm *notificationmiloapiscomv1alpha1.ContactGroupMembership, c *notificationmiloapiscomv1alpha1.Contact, cg *notificationmiloapiscomv1alpha1.ContactGroup) error {
+	log := logf.FromContext(ctx).WithValues("controller", "LoopsContactGroupMembershipController", "trigger", cgm.Name)
+
+	mailingListId, err := getMailingListId(cg)
+	if err != nil {
+		log.Error(err, "Failed to get Loops mailing list ID")
+		return fmt.Errorf("failed to get Loops mailing list ID: %w", err)
+	}
+
+	previousMailingListId := cgm.Annotations[syncedMailingListAnnotation]
+	if previousMailingListId == mailingListId {
+		log.Info("Contact groups share the same mailing list, nothing to move")
+		return nil
+	}
+
+	if _, err := r.addContactToMailingList(ctx, c, cg); err != nil {
+		return err
+	}
+
+	if previousMailingListId == "" {
+		return nil
+	}
+
+	// Look for other memberships of the contact in the previous contact group
+	previous := cgm.DeepCopy()
+	previousGroup := strings.SplitN(cgm.Annotations[syncedContactGroupAnnotation], "/", 2)
+	if len(previousGroup) == 2 {
+		previous.Spec.ContactGroupRef = notificationmiloapiscomv1alpha1.ContactGroupReference{
+			Namespace: previousGroup[0],
+			Name:      previousGroup[1],
+		}
+	}
+	keepInMailingList, err := hasOtherContactGroupMemberships(ctx, r.Client, previous)
+	if err != nil {
+		log.Error(err, "Failed to list contact group memberships for the previous contact group")
+		return fmt.Errorf("failed to list contact group memberships: %w", err)
+	}
+	if keepInMailingList {
+		log.Info("Another ContactGroupMembership exists for the previous contact group, keeping Loops contact in its mailing list")
+		return nil
+	}
+
+	return removeFromMailingList(ctx, r.Loops, r.ContactIDResolver, r.ProviderCallTimeout, c, previousMailingListId)
+}
+
+// moveMembershipToContact adds the Loops contact the membership now references to the mailing list of its contact
+// group, then removes the previously synced Loops contact from the mailing list the membership was synced with, unless
+// another membership still holds the previous contact in it.
+func (r *LoopsContactGroupMembershipController) moveMembershipToContact(ctx context.Context, cgm *notificationmiloapiscomv1alpha1.ContactGroupMembership, c *notificationmiloapiscomv1alpha1.Contact, cg *notificationmiloapiscomv1alpha1.ContactGroup) error {
+	log := logf.FromContext(ctx).WithValues("controller", "LoopsContactGroupMembershipController", "trigger", cgm.Name)
+
+	previousContactID := loopsProviderID(cgm.Status.Providers)
+	contactID, err := r.addContactToMailingList(ctx, c, cg)
+	if err != nil {
+		return err
+	}
+	cgm.Status.Providers = []notificationmiloapiscomv1alpha1.ContactProviderStatus{
+		{
+			Name: "Loops",
+			ID:   contactID,
+		},
+	}
+
+	previousMailingListId := cgm.Annotations[syncedMailingListAnnotation]
+	if previousContactID == "" || previousMailingListId == "" {
+		log.Info("Previous Loops contact or mailing list unknown, skipping its removal")
+		return nil
+	}
+	if previousContactID == contactID {
+		if mailingListId, err := getMailingListId(cg); err == nil && mailingListId == previousMailingListId {
+			log.Info("Contacts share the same Loops contact, nothing to move")
+			return nil
+		}
+	}
+
+	// Look for other memberships of the previous contact in the previous contact group
+	previous := cgm.DeepCopy()
+	if previousContact := strings.SplitN(cgm.Annotations[syncedContactAnnotation], "/", 2); len(previousContact) == 2 {
+		previous.Spec.ContactRef = notificationmiloapiscomv1alpha1.ContactReference{
+			Namespace: previousContact[0],
+			Name:      previousContact[1],
+		}
+	}
+	if previousGroup := strings.SplitN(cgm.Annotations[syncedContactGroupAnnotation], "/", 2); len(previousGroup) == 2 {
+		previous.Spec.ContactGroupRef = notificationmiloapiscomv1alpha1.ContactGroupReference{
+			Namespace: previousGroup[0],
+			Name:      previousGroup[1],
+		}
+	}
+	keepInMailingList, err := hasOtherContactGroupMemberships(ctx, r.Client, previous)
+	if err != nil {
+		log.Error(err, "Failed to list contact group memberships for the previous contact")
+		return fmt.Errorf("failed to list contact group memberships: %w", err)
+	}
+	if keepInMailingList {
+		log.Info("Another ContactGroupMembership exists for the previous contact, keeping it in its mailing list")
+		return nil
+	}
+
+	return removeContactIDFromMailingList(ctx, r.Loops, r.ProviderCallTimeout, previousContactID, previousMailingListId)
+}
+
+// loopsProviderID returns the ID recorded for Loops in the status providers, if any.
+func loopsProviderID(providers []notificationmiloapiscomv1alpha1.ContactProviderStatus) string {
+	for _, provider := range providers {
+		if provider.Name == "Loops" {
+			return provider.ID
+		}
+	}
+	return ""
+}
+
+// recordSyncedContactGroup annotates the membership with the contact, contact group and mailing list it is synced with.
+func (r *LoopsContactGroupMembershipController) recordSyncedContactGroup(ctx context.Context, cgm *notificationmiloapiscomv1alpha1.ContactGroupMembership, cg *notificationmiloapiscomv1alpha1.ContactGroup) error {
+	readyCond := meta.FindStatusCondition(cgm.Status.Conditions, LoopsContactGroupMembershipReadyCondition)
+	if readyCond == nil || readyCond.Status != metav1.ConditionTrue {
+		return nil
+	}
+
+	mailingListId, err := getMailingListId(cg)
+	if err != nil {
+		return fmt.Errorf("failed to get Loops mailing list ID: %w", err)
+	}
+	if cgm.Annotations[syncedContactAnnotation] == contactKey(cgm) &&
+		cgm.Annotations[syncedContactGroupAnnotation] == contactGroupKey(cgm) &&
+		cgm.Annotations[syncedMailingListAnnotation] == mailingListId {
+		return nil
+	}
+
+	// Patch a copy, as the patch response would otherwise overwrite the pending status changes of cgm
+	annotated := cgm.DeepCopy()
+	if annotated.Annotations == nil {
+		annotated.Annotations = map[string]string{}
+	}
+	annotated.Annotations[syncedContactAnnotation] = contactKey(cgm)
+	annotated.Annotations[syncedContactGroupAnnotation] = contactGroupKey(cgm)
+	annotated.Annotations[syncedMailingListAnnotation] = mailingListId
+	if err := r.Client.Patch(ctx, annotated, client.MergeFrom(cgm)); err != nil {
+		return fmt.Errorf("failed to annotate ContactGroupMembership with the synced contact group: %w", err)
+	}
+
+	return nil
+}
+
+// removeFromMailingList removes the Loops contact from the mailing list.
+func removeFromMailingList(ctx context.Context, loopsAPI loops.API, resolver ContactIDResolver, timeout time.Duration, c *notificationmiloapiscomv1alpha1.Contact, mailingListId string) error {
+	log := logf.FromContext(ctx).WithValues("controller", "LoopsContactGroupMembershipController", "trigger", c.Name)
+	log.Info("Removing Loops contact from mailing list")
+
+	contactID, err := resolveContactID(resolver, c)
+	if isEmptyContactID(err) {
+		// Contacts without a userId are never added to Loops mailing lists
+		log.Info("Contact has no Loops userId, skipping mailing list removal")
+		return nil
+	}
+	if err != nil {
+		log.Error(err, "Failed to resolve Loops contact ID")
+		return fmt.Errorf("failed to resolve Loops contact ID: %w", err)
+	}
+
+	return removeContactIDFromMailingList(ctx, loopsAPI, timeout, contactID, mailingListId)
+}
+
+// removeContactIDFromMailingList removes the Loops contact with the given userId from the mailing list.
+func removeContactIDFromMailingList(ctx context.Context, loopsAPI loops.API, timeout time.Duration, contactID string, mailingListId string) error {
+	log := logf.FromContext(ctx).WithValues("controller", "LoopsContactGroupMembershipController", "contactID", contactID)
+
+	callCtx, cancel := withProviderCallTimeout(ctx, timeout)
+	defer cancel()
+	_, err := loopsAPI.RemoveFromMailingList(callCtx, contactID, mailingListId)
+	if loops.IsNotFound(err) {
+		// A contact that no longer exists in Loops is not subscribed to any list
+		log.Info("Loops contact not found, already removed from mailing list", "mailingListId", mailingListId)
+		return nil
+	}
 	if err != nil {
 		log.Error(err, "Failed to remove Loops contact from mailing list")
 		return fmt.Errorf("failed to remove Loops contact from mailing list: %w", err)
 	}
+	membershipChangesTotal.WithLabelValues(mailingListId, membershipChangeActionRemove).Inc()
 
 	return nil
 }
 
-func getMailingListId(cg *notificationmiloapiscomv1alpha1.ContactGroup) (string, error) {
-	for _, provider := range cg.Spec.Providers {
-		if provider.Name == "Loops" {
-			return provider.ID, nil
+// collapseDuplicateMemberships keeps the oldest ContactGroupMembership for the contact and group pair referenced by cgm
+// and deletes the rest. It returns true if cgm itself was deleted.
+func (r *LoopsContactGroupMembershipController) collapseDuplicateMemberships(ctx context.Context, cgm *notificationmiloapiscomv1alpha1.ContactGroupMembership) (bool, error) {
+	log := logf.FromContext(ctx).WithValues("controller", "LoopsContactGroupMembershipController", "trigger", cgm.Name)
+
+	memberships, err := listContactGroupMemberships(ctx, r.Client, cgm)
+	if err != nil {
+		return false, err
+	}
+	if len(memberships) <= 1 {
+		return false, nil
+	}
+
+	// Oldest first; the name breaks ties between memberships created in the same second
+	sort.Slice(memberships, func(i, j int) bool {
+		if !memberships[i].CreationTimestamp.Equal(&memberships[j].CreationTimestamp) {
+			return memberships[i].CreationTimestamp.Before(&memberships[j].CreationTimestamp)
+		}
+		return memberships[i].Name < memberships[j].Name
+	})
+
+	survivor := memberships[0]
+	for i := range memberships[1:] {
+		duplicate := &memberships[i+1]
+		log.Info("Deleting duplicate ContactGroupMembership", "duplicate", duplicate.Name, "survivor", survivor.Name)
+		if err := r.Client.Delete(ctx, duplicate); err != nil && !errors.IsNotFound(err) {
+			return false, fmt.Errorf("failed to delete duplicate ContactGroupMembership %s: %w", duplicate.Name, err)
+		}
+	}
+
+	return survivor.Name != cgm.Name, nil
+}
+
+// hasOtherContactGroupMemberships returns true if a ContactGroupMembership other than cgm, and not being deleted,
+// exists for the same contact and group pair.
+func hasOtherContactGroupMemberships(ctx context.Context, k8sClient client.Client, cgm *notificationmiloapiscomv1alpha1.ContactGroupMembership) (bool, error) {
+	memberships, err := listContactGroupMemberships(ctx, k8sClient, cgm)
+	if err != nil {
+		return false, err
+	}
+
+	for _, membership := range memberships {
+		if membership.Name != cgm.Name {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+// listContactGroupMemberships lists the ContactGroupMemberships, not being deleted, that share the contact and group
+// references of cgm using the indexed field.
+func listContactGroupMemberships(ctx context.Context, k8sClient client.Client, cgm *notificationmiloapiscomv1alpha1.ContactGroupMembership) ([]notificationmiloapiscomv1alpha1.ContactGroupMembership, error) {
+	var membershipList notificationmiloapiscomv1alpha1.ContactGroupMembershipList
+	if err := k8sClient.List(ctx, &membershipList,
+		client.MatchingFields{contactGroupMembershipPairIndexKey: buildContactGroupMembershipPairIndexKey(cgm)},
+	); err != nil {
+		return nil, err
+	}
+
+	memberships := make([]notificationmiloapiscomv1alpha1.ContactGroupMembership, 0, len(membershipList.Items))
+	for _, membership := range membershipList.Items {
+		if membership.DeletionTimestamp.IsZero() {
+			memberships = append(memberships, membership)
 		}
 	}
 
-	return "", fmt.Errorf("mailing list ID not found for contact group")
+	return memberships, nil
+}
+
+func buildContactGroupMembershipPairIndexKey(cgm *notificationmiloapiscomv1alpha1.ContactGroupMembership) string {
+	return fmt.Sprintf("%s-%s-%s-%s", cgm.Spec.ContactRef.Name, cgm.Spec.ContactRef.Namespace, cgm.Spec.ContactGroupRef.Name, cgm.Spec.ContactGroupRef.Namespace)
+}
+
+// contactKey returns the "namespace/name" key of the contact referenced by the membership.
+func contactKey(cgm *notificationmiloapiscomv1alpha1.ContactGroupMembership) string {
+	return cgm.Spec.ContactRef.Namespace + "/" + cgm.Spec.ContactRef.Name
+}
+
+// contactGroupKey returns the "namespace/name" key of the contact group referenced by the membership.
+func contactGroupKey(cgm *notificationmiloapiscomv1alpha1.ContactGroupMembership) string {
+	return cgm.Spec.ContactGroupRef.Namespace + "/" + cgm.Spec.ContactGroupRef.Name
 }
 
 func getReferencedResources(ctx context.Context, k8sClient client.Client, cgm *notificationmiloapiscomv1alpha1.ContactGroupMembership) (*notificationmiloapiscomv1alpha1.Contact, *notificationmiloapiscomv1alpha1.ContactGroup, error) {
@@ -308,3 +934,19 @@ func getReferencedResources(ctx context.Context, k8sClient client.Client, cgm *n
 
 	return contact, contactGroup, nil
 }
+
+// contactGroupMembershipChangedPredicate filters out the updates that only change the status or metadata of a
+// ContactGroupMembership, such as the status patches of this controller. Spec changes bump the generation, while the
+// deletion timestamp and finalizers are compared too, so that the finalizer still runs on deletion.
+func contactGroupMembershipChangedPredicate() predicate.Predicate {
+	return predicate.Funcs{
+		UpdateFunc: func(e event.UpdateEvent) bool {
+			if e.ObjectOld == nil || e.ObjectNew == nil {
+				return true
+			}
+			return e.ObjectOld.GetGeneration() != e.ObjectNew.GetGeneration() ||
+				!e.ObjectOld.GetDeletionTimestamp().Equal(e.ObjectNew.GetDeletionTimestamp()) ||
+				!slices.Equal(e.ObjectOld.GetFinalizers(), e.ObjectNew.GetFinalizers())
+		},
+	}
+}
@@ -0,0 +1,558 @@
+package controller
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	notificationmiloapiscomv1alpha1 "go.miloapis.com/milo/pkg/apis/notification/v1alpha1"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/utils/ptr"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+)
+
+func newTestScheme(t *testing.T) *runtime.Scheme {
+	t.Helper()
+	scheme := runtime.NewScheme()
+	if err := notificationmiloapiscomv1alpha1.AddToScheme(scheme); err != nil {
+		t.Fatalf("failed to add notification scheme: %v", err)
+	}
+	return scheme
+}
+
+func newTestContactGroupMembershipController(t *testing.T, objs ...client.Object) (*LoopsContactGroupMembershipController, *fakeLoops) {
+	t.Helper()
+	k8sClient := fake.NewClientBuilder().
+		WithScheme(newTestScheme(t)).
+		WithObjects(objs...).
+		WithStatusSubresource(&notificationmiloapiscomv1alpha1.ContactGroupMembership{}).
+		WithIndex(&notificationmiloapiscomv1alpha1.ContactGroupMembership{}, contactGroupMembershipPairIndexKey, func(rawObj client.Object) []string {
+			return []string{buildContactGroupMembershipPairIndexKey(rawObj.(*notificationmiloapiscomv1alpha1.ContactGroupMembership))}
+		}).
+		WithIndex(&notificationmiloapiscomv1alpha1.ContactGroupMembership{}, contactGroupMembershipContactIndexKey, func(rawObj client.Object) []string {
+			return []string{contactKey(rawObj.(*notificationmiloapiscomv1alpha1.ContactGroupMembership))}
+		}).
+		WithIndex(&notificationmiloapiscomv1alpha1.ContactGroupMembership{}, contactGroupMembershipContactGroupIndexKey, func(rawObj client.Object) []string {
+			return []string{contactGroupKey(rawObj.(*notificationmiloapiscomv1alpha1.ContactGroupMembership))}
+		}).
+		Build()
+
+	loopsAPI := newFakeLoops()
+	r := &LoopsContactGroupMembershipController{
+		Client: k8sClient,
+		Loops:  loopsAPI,
+	}
+	if err := r.setupFinalizers(); err != nil {
+		t.Fatalf("setupFinalizers() failed: %v", err)
+	}
+
+	return r, loopsAPI
+}
+
+func newTestContact(name string) *notificationmiloapiscomv1alpha1.Contact {
+	return &notificationmiloapiscomv1alpha1.Contact{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: "default",
+			UID:       types.UID(name + "-uid"),
+		},
+		Spec: notificationmiloapiscomv1alpha1.ContactSpec{
+			Email: name + "@example.com",
+		},
+	}
+}
+
+func newTestContactGroup(name, listID string) *notificationmiloapiscomv1alpha1.ContactGroup {
+	return &notificationmiloapiscomv1alpha1.ContactGroup{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: "default",
+		},
+		Spec: notificationmiloapiscomv1alpha1.ContactGroupSpec{
+			Providers: []notificationmiloapiscomv1alpha1.ContactGroupProvider{
+				{Name: "Loops", ID: listID},
+			},
+		},
+	}
+}
+
+func newTestContactGroupMembership(name string, contact *notificationmiloapiscomv1alpha1.Contact, group *notificationmiloapiscomv1alpha1.ContactGroup, created time.Time) *notificationmiloapiscomv1alpha1.ContactGroupMembership {
+	return &notificationmiloapiscomv1alpha1.ContactGroupMembership{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:              name,
+			Namespace:         "default",
+			CreationTimestamp: metav1.NewTime(created),
+			Finalizers:        []string{loopsContactGroupMembershipFinalizerKey},
+		},
+		Spec: notificationmiloapiscomv1alpha1.ContactGroupMembershipSpec{
+			ContactRef: notificationmiloapiscomv1alpha1.ContactReference{
+				Name:      contact.Name,
+				Namespace: contact.Namespace,
+			},
+			ContactGroupRef: notificationmiloapiscomv1alpha1.ContactGroupReference{
+				Name:      group.Name,
+				Namespace: group.Namespace,
+			},
+		},
+	}
+}
+
+func TestReconcile_CollapsesDuplicateMemberships(t *testing.T) {
+	ctx := context.Background()
+	contact := newTestContact("jane")
+	group := newTestContactGroup("newsletter", "list-abc")
+	now := time.Now()
+	oldest := newTestContactGroupMembership("newsletter-jane-a", contact, group, now.Add(-time.Minute))
+	newest := newTestContactGroupMembership("newsletter-jane-b", contact, group, now)
+
+	r, loopsAPI := newTestContactGroupMembershipController(t, contact, group, oldest, newest)
+
+	// Reconciling the newest membership deletes it in favour of the oldest one
+	if _, err := r.Reconcile(ctx, ctrl.Request{NamespacedName: client.ObjectKeyFromObject(newest)}); err != nil {
+		t.Fatalf("Reconcile() failed: %v", err)
+	}
+
+	// Reconciling again runs the finalizer of the deleted duplicate
+	if _, err := r.Reconcile(ctx, ctrl.Request{NamespacedName: client.ObjectKeyFromObject(newest)}); err != nil {
+		t.Fatalf("Reconcile() failed: %v", err)
+	}
+
+	var memberships notificationmiloapiscomv1alpha1.ContactGroupMembershipList
+	if err := r.Client.List(ctx, &memberships); err != nil {
+		t.Fatalf("List() failed: %v", err)
+	}
+	if len(memberships.Items) != 1 {
+		t.Fatalf("Expected 1 surviving membership, got %d", len(memberships.Items))
+	}
+	if memberships.Items[0].Name != oldest.Name {
+		t.Errorf("Expected surviving membership %s, got %s", oldest.Name, memberships.Items[0].Name)
+	}
+
+	// The survivor still holds the contact in the mailing list
+	if len(loopsAPI.removals["list-abc"]) != 0 {
+		t.Errorf("Expected no mailing list removal, got %v", loopsAPI.removals["list-abc"])
+	}
+}
+
+// membershipChanges scrapes loops_membership_changes_total for the given list and action from the metrics registry.
+func membershipChanges(t *testing.T, listID, action string) float64 {
+	t.Helper()
+	families, err := metrics.Registry.Gather()
+	if err != nil {
+		t.Fatalf("Failed to gather metrics: %v", err)
+	}
+	for _, family := range families {
+		if family.GetName() != "loops_membership_changes_total" {
+			continue
+		}
+		for _, metric := range family.GetMetric() {
+			labels := map[string]string{}
+			for _, label := range metric.GetLabel() {
+				labels[label.GetName()] = label.GetValue()
+			}
+			if labels["list_id"] == listID && labels["action"] == action {
+				return metric.GetCounter().GetValue()
+			}
+		}
+	}
+	return 0
+}
+
+func TestReconcile_MembershipChangesMetric(t *testing.T) {
+	ctx := context.Background()
+	contact := newTestContact("jane")
+	group := newTestContactGroup("product", "list-product")
+	cgm := newTestContactGroupMembership("product-jane", contact, group, time.Now())
+
+	r, _ := newTestContactGroupMembershipController(t, contact, group, cgm)
+	req := ctrl.Request{NamespacedName: client.ObjectKeyFromObject(cgm)}
+
+	addsBefore := membershipChanges(t, "list-product", "add")
+	removesBefore := membershipChanges(t, "list-product", "remove")
+
+	if _, err := r.Reconcile(ctx, req); err != nil {
+		t.Fatalf("Reconcile() failed: %v", err)
+	}
+	if got := membershipChanges(t, "list-product", "add"); got != addsBefore+1 {
+		t.Errorf("Expected %v adds, got %v", addsBefore+1, got)
+	}
+
+	if err := r.Client.Delete(ctx, cgm); err != nil {
+		t.Fatalf("Delete() failed: %v", err)
+	}
+	if _, err := r.Reconcile(ctx, req); err != nil {
+		t.Fatalf("Reconcile() failed: %v", err)
+	}
+	if got := membershipChanges(t, "list-product", "remove"); got != removesBefore+1 {
+		t.Errorf("Expected %v removes, got %v", removesBefore+1, got)
+	}
+}
+
+func TestGetMailingListId(t *testing.T) {
+	tests := []struct {
+		name        string
+		group       *notificationmiloapiscomv1alpha1.ContactGroup
+		expectedID  string
+		expectedErr error
+	}{
+		{name: "Loops provider", group: newTestContactGroup("product", "list-product"), expectedID: "list-product"},
+		{name: "empty mailing list ID", group: newTestContactGroup("product", ""), expectedErr: ErrMailingListIDNotFound},
+		{
+			name: "no Loops provider",
+			group: func() *notificationmiloapiscomv1alpha1.ContactGroup {
+				group := newTestContactGroup("product", "list-product")
+				group.Spec.Providers = nil
+				return group
+			}(),
+			expectedErr: ErrMailingListIDNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			id, err := getMailingListId(tt.group)
+			if !errors.Is(err, tt.expectedErr) {
+				t.Errorf("Expected error %v, got %v", tt.expectedErr, err)
+			}
+			if id != tt.expectedID {
+				t.Errorf("Expected mailing list ID %q, got %q", tt.expectedID, id)
+			}
+		})
+	}
+}
+
+func TestReconcile_MailingListIDMissing(t *testing.T) {
+	ctx := context.Background()
+	contact := newTestContact("jane")
+	group := newTestContactGroup("product", "")
+	cgm := newTestContactGroupMembership("product-jane", contact, group, time.Now())
+
+	r, loopsAPI := newTestContactGroupMembershipController(t, contact, group, cgm)
+	req := ctrl.Request{NamespacedName: client.ObjectKeyFromObject(cgm)}
+
+	// The membership is not retried until the contact group gains a mailing list ID
+	if _, err := r.Reconcile(ctx, req); err != nil {
+		t.Errorf("Expected no error, got %v", err)
+	}
+	if err := r.Client.Get(ctx, req.NamespacedName, cgm); err != nil {
+		t.Fatalf("Get() failed: %v", err)
+	}
+	readyCond := meta.FindStatusCondition(cgm.Status.Conditions, LoopsContactGroupMembershipReadyCondition)
+	if readyCond == nil || readyCond.Status != metav1.ConditionFalse ||
+		readyCond.Reason != LoopsContactGroupMembershipMailingListIDMissingReason {
+		t.Errorf("Expected a false ready condition with reason %s, got %+v",
+			LoopsContactGroupMembershipMailingListIDMissingReason, readyCond)
+	}
+
+	// The membership is created once the contact group has a mailing list ID
+	oldGroup := group.DeepCopy()
+	group.Spec.Providers[0].ID = "list-product"
+	if err := r.Client.Update(ctx, group); err != nil {
+		t.Fatalf("Update() failed: %v", err)
+	}
+	if !mailingListIDChanged(event.UpdateEvent{ObjectOld: oldGroup, ObjectNew: group}) {
+		t.Errorf("Expected the mailing list ID change to be detected")
+	}
+	if requests := r.contactGroupMemberships(ctx, group); len(requests) != 1 || requests[0].NamespacedName != req.NamespacedName {
+		t.Errorf("Expected the membership to be enqueued, got %v", requests)
+	}
+	if _, err := r.Reconcile(ctx, req); err != nil {
+		t.Fatalf("Reconcile() failed: %v", err)
+	}
+	if got := len(loopsAPI.adds["list-product"]); got != 1 {
+		t.Errorf("Expected 1 add to the mailing list, got %d", got)
+	}
+}
+
+func TestReconcile_ContactGroupRefChanged(t *testing.T) {
+	ctx := context.Background()
+	contact := newTestContact("jane")
+	product := newTestContactGroup("product", "list-product")
+	events := newTestContactGroup("events", "list-events")
+	cgm := newTestContactGroupMembership("membership-jane", contact, product, time.Now())
+
+	r, loopsAPI := newTestContactGroupMembershipController(t, contact, product, events, cgm)
+	req := ctrl.Request{NamespacedName: client.ObjectKeyFromObject(cgm)}
+
+	if _, err := r.Reconcile(ctx, req); err != nil {
+		t.Fatalf("Reconcile() failed: %v", err)
+	}
+
+	// Point the membership to another contact group
+	if err := r.Client.Get(ctx, req.NamespacedName, cgm); err != nil {
+		t.Fatalf("Get() failed: %v", err)
+	}
+	if got := cgm.Annotations[syncedContactGroupAnnotation]; got != "default/product" {
+		t.Fatalf("Expected the synced contact group to be default/product, got %q", got)
+	}
+	cgm.Spec.ContactGroupRef.Name = events.Name
+	cgm.Generation++
+	if err := r.Client.Update(ctx, cgm); err != nil {
+		t.Fatalf("Update() failed: %v", err)
+	}
+
+	if _, err := r.Reconcile(ctx, req); err != nil {
+		t.Fatalf("Reconcile() failed: %v", err)
+	}
+
+	if got := loopsAPI.adds["list-events"]; len(got) != 1 || got[0] != "jane-uid" {
+		t.Errorf("Expected jane-uid to be added to list-events, got %v", got)
+	}
+	if got := loopsAPI.removals["list-product"]; len(got) != 1 || got[0] != "jane-uid" {
+		t.Errorf("Expected jane-uid to be removed from list-product, got %v", got)
+	}
+
+	if err := r.Client.Get(ctx, req.NamespacedName, cgm); err != nil {
+		t.Fatalf("Get() failed: %v", err)
+	}
+	if got := cgm.Annotations[syncedContactGroupAnnotation]; got != "default/events" {
+		t.Errorf("Expected the synced contact group to be default/events, got %q", got)
+	}
+	if got := cgm.Annotations[syncedMailingListAnnotation]; got != "list-events" {
+		t.Errorf("Expected the synced mailing list to be list-events, got %q", got)
+	}
+	readyCond := meta.FindStatusCondition(cgm.Status.Conditions, LoopsContactGroupMembershipReadyCondition)
+	if readyCond == nil || readyCond.Reason != LoopsContactGroupMembershipUpdatedReason {
+		t.Errorf("Expected the ready condition reason to be %s, got %+v", LoopsContactGroupMembershipUpdatedReason, readyCond)
+	}
+
+	// A later reconcile does not move the contact again
+	if _, err := r.Reconcile(ctx, req); err != nil {
+		t.Fatalf("Reconcile() failed: %v", err)
+	}
+	if got := len(loopsAPI.adds["list-events"]); got != 1 {
+		t.Errorf("Expected 1 add to list-events, got %d", got)
+	}
+}
+
+func TestReconcile_ContactRefChanged(t *testing.T) {
+	ctx := context.Background()
+	jane := newTestContact("jane")
+	john := newTestContact("john")
+	product := newTestContactGroup("product", "list-product")
+	cgm := newTestContactGroupMembership("membership-jane", jane, product, time.Now())
+
+	r, loopsAPI := newTestContactGroupMembershipController(t, jane, john, product, cgm)
+	req := ctrl.Request{NamespacedName: client.ObjectKeyFromObject(cgm)}
+
+	if _, err := r.Reconcile(ctx, req); err != nil {
+		t.Fatalf("Reconcile() failed: %v", err)
+	}
+
+	// Point the membership to another contact
+	if err := r.Client.Get(ctx, req.NamespacedName, cgm); err != nil {
+		t.Fatalf("Get() failed: %v", err)
+	}
+	if got := cgm.Annotations[syncedContactAnnotation]; got != "default/jane" {
+		t.Fatalf("Expected the synced contact to be default/jane, got %q", got)
+	}
+	cgm.Spec.ContactRef.Name = john.Name
+	cgm.Generation++
+	if err := r.Client.Update(ctx, cgm); err != nil {
+		t.Fatalf("Update() failed: %v", err)
+	}
+
+	if _, err := r.Reconcile(ctx, req); err != nil {
+		t.Fatalf("Reconcile() failed: %v", err)
+	}
+
+	if got := loopsAPI.adds["list-product"]; len(got) != 2 || got[1] != "john-uid" {
+		t.Errorf("Expected john-uid to be added to list-product, got %v", got)
+	}
+	if got := loopsAPI.removals["list-product"]; len(got) != 1 || got[0] != "jane-uid" {
+		t.Errorf("Expected jane-uid to be removed from list-product, got %v", got)
+	}
+
+	if err := r.Client.Get(ctx, req.NamespacedName, cgm); err != nil {
+		t.Fatalf("Get() failed: %v", err)
+	}
+	if got := cgm.Annotations[syncedContactAnnotation]; got != "default/john" {
+		t.Errorf("Expected the synced contact to be default/john, got %q", got)
+	}
+	if got := loopsProviderID(cgm.Status.Providers); got != "john-uid" {
+		t.Errorf("Expected the Loops provider ID to be john-uid, got %q", got)
+	}
+	readyCond := meta.FindStatusCondition(cgm.Status.Conditions, LoopsContactGroupMembershipReadyCondition)
+	if readyCond == nil || readyCond.Reason != LoopsContactGroupMembershipUpdatedReason {
+		t.Errorf("Expected the ready condition reason to be %s, got %+v", LoopsContactGroupMembershipUpdatedReason, readyCond)
+	}
+
+	// A later reconcile does not move the membership again
+	if _, err := r.Reconcile(ctx, req); err != nil {
+		t.Fatalf("Reconcile() failed: %v", err)
+	}
+	if got := len(loopsAPI.removals["list-product"]); got != 1 {
+		t.Errorf("Expected 1 removal from list-product, got %d", got)
+	}
+}
+
+func TestFinalize_VerifyListRemoval(t *testing.T) {
+	ctx := context.Background()
+	contact := newTestContact("jane")
+	group := newTestContactGroup("product", "list-product")
+	cgm := newTestContactGroupMembership("product-jane", contact, group, time.Now())
+
+	r, loopsAPI := newTestContactGroupMembershipController(t, contact, group, cgm)
+	r.VerifyListRemoval = true
+	if err := r.setupFinalizers(); err != nil {
+		t.Fatalf("setupFinalizers() failed: %v", err)
+	}
+	req := ctrl.Request{NamespacedName: client.ObjectKeyFromObject(cgm)}
+
+	// Loops still reports the contact in the mailing list after the removal
+	loopsAPI.mailingLists["jane-uid"] = map[string]bool{"list-product": true}
+	if err := r.Client.Delete(ctx, cgm); err != nil {
+		t.Fatalf("Delete() failed: %v", err)
+	}
+	if _, err := r.Reconcile(ctx, req); err == nil {
+		t.Fatal("Expected an error while the removal is not confirmed, got none")
+	}
+	if err := r.Client.Get(ctx, req.NamespacedName, cgm); err != nil {
+		t.Fatalf("Expected the membership to be kept until the removal is confirmed: %v", err)
+	}
+
+	// Once Loops processed the removal, the membership is released
+	loopsAPI.mailingLists["jane-uid"] = map[string]bool{"list-product": false}
+	if _, err := r.Reconcile(ctx, req); err != nil {
+		t.Fatalf("Reconcile() failed: %v", err)
+	}
+	if err := r.Client.Get(ctx, req.NamespacedName, cgm); !apierrors.IsNotFound(err) {
+		t.Errorf("Expected the membership to be deleted, got %v", err)
+	}
+}
+
+func TestReconcile_MembershipSkipLoopsSync(t *testing.T) {
+	ctx := context.Background()
+	contact := newTestContact("jane")
+	contact.Annotations = map[string]string{skipLoopsSyncAnnotation: "true"}
+	group := newTestContactGroup("product", "list-product")
+	cgm := newTestContactGroupMembership("product-jane", contact, group, time.Now())
+
+	r, loopsAPI := newTestContactGroupMembershipController(t, contact, group, cgm)
+	req := ctrl.Request{NamespacedName: client.ObjectKeyFromObject(cgm)}
+
+	if _, err := r.Reconcile(ctx, req); err != nil {
+		t.Fatalf("Reconcile() failed: %v", err)
+	}
+	if got := len(loopsAPI.adds["list-product"]); got != 0 {
+		t.Errorf("Expected no mailing list add, got %d", got)
+	}
+	if err := r.Client.Get(ctx, req.NamespacedName, cgm); err != nil {
+		t.Fatalf("Get() failed: %v", err)
+	}
+	cond := meta.FindStatusCondition(cgm.Status.Conditions, LoopsContactGroupMembershipReadyCondition)
+	if cond == nil || cond.Status != metav1.ConditionFalse || cond.Reason != LoopsContactGroupMembershipSyncSkippedReason {
+		t.Errorf("Expected Ready False with reason %s, got %+v", LoopsContactGroupMembershipSyncSkippedReason, cond)
+	}
+
+	// Removing the annotation reconciles the memberships of the contact, which are then created
+	if requests := r.contactMemberships(ctx, contact); len(requests) != 1 || requests[0] != req {
+		t.Errorf("Expected the contact to map to %v, got %v", req, requests)
+	}
+	contact.Annotations = nil
+	if err := r.Client.Update(ctx, contact); err != nil {
+		t.Fatalf("Update() failed: %v", err)
+	}
+	if _, err := r.Reconcile(ctx, req); err != nil {
+		t.Fatalf("Reconcile() failed: %v", err)
+	}
+	if got := len(loopsAPI.adds["list-product"]); got != 1 {
+		t.Errorf("Expected 1 mailing list add once the contact opted back in, got %d", got)
+	}
+}
+
+func TestFinalize_MembershipSkipLoopsSync(t *testing.T) {
+	ctx := context.Background()
+	contact := newTestContact("jane")
+	contact.Annotations = map[string]string{skipLoopsSyncAnnotation: "true"}
+	group := newTestContactGroup("product", "list-product")
+	cgm := newTestContactGroupMembership("product-jane", contact, group, time.Now())
+
+	r, loopsAPI := newTestContactGroupMembershipController(t, contact, group, cgm)
+	req := ctrl.Request{NamespacedName: client.ObjectKeyFromObject(cgm)}
+
+	if err := r.Client.Delete(ctx, cgm); err != nil {
+		t.Fatalf("Delete() failed: %v", err)
+	}
+	if _, err := r.Reconcile(ctx, req); err != nil {
+		t.Fatalf("Reconcile() failed: %v", err)
+	}
+	if got := len(loopsAPI.removals["list-product"]); got != 0 {
+		t.Errorf("Expected no mailing list removal, got %d", got)
+	}
+	if err := r.Client.Get(ctx, req.NamespacedName, cgm); !apierrors.IsNotFound(err) {
+		t.Errorf("Expected the membership to be deleted, got %v", err)
+	}
+}
+
+func TestContactGroupMembershipChangedPredicate(t *testing.T) {
+	old := newTestContactGroupMembership("jane-newsletter", newTestContact("jane"),
+		newTestContactGroup("newsletter", "list-abc"), time.Now())
+	old.Generation = 1
+
+	tests := []struct {
+		name     string
+		mutate   func(cgm *notificationmiloapiscomv1alpha1.ContactGroupMembership)
+		expected bool
+	}{
+		{
+			name: "status only",
+			mutate: func(cgm *notificationmiloapiscomv1alpha1.ContactGroupMembership) {
+				meta.SetStatusCondition(&cgm.Status.Conditions, metav1.Condition{
+					Type:   LoopsContactGroupMembershipReadyCondition,
+					Status: metav1.ConditionTrue,
+					Reason: LoopsContactGroupMembershipCreatedReason,
+				})
+				cgm.ResourceVersion = "2"
+			},
+			expected: false,
+		},
+		{
+			name: "spec change",
+			mutate: func(cgm *notificationmiloapiscomv1alpha1.ContactGroupMembership) {
+				cgm.Spec.ContactGroupRef.Name = "product"
+				cgm.Generation = 2
+			},
+			expected: true,
+		},
+		{
+			name: "deletion",
+			mutate: func(cgm *notificationmiloapiscomv1alpha1.ContactGroupMembership) {
+				cgm.DeletionTimestamp = ptr.To(metav1.Now())
+			},
+			expected: true,
+		},
+		{
+			name: "finalizer removed",
+			mutate: func(cgm *notificationmiloapiscomv1alpha1.ContactGroupMembership) {
+				cgm.Finalizers = nil
+			},
+			expected: true,
+		},
+	}
+
+	p := contactGroupMembershipChangedPredicate()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			updated := old.DeepCopy()
+			tt.mutate(updated)
+			if got := p.Update(event.UpdateEvent{ObjectOld: old, ObjectNew: updated}); got != tt.expected {
+				t.Errorf("Expected Update() %v, got %v", tt.expected, got)
+			}
+		})
+	}
+
+	if !p.Delete(event.DeleteEvent{Object: old}) {
+		t.Error("Expected deletions to be reconciled")
+	}
+}
@@ -0,0 +1,298 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"go.miloapis.com/email-provider-loops/internal/util"
+	loops "go.miloapis.com/email-provider-loops/pkg/loops"
+	notificationmiloapiscomv1alpha1 "go.miloapis.com/milo/pkg/apis/notification/v1alpha1"
+
+	"go.opentelemetry.io/otel/trace"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/finalizer"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+const (
+	// LoopsContactGroupMembershipRemovalReadyCondition is a condition that is set to true when the removal is applied
+	LoopsContactGroupMembershipRemovalReadyCondition = "LoopsContactGroupMembershipRemovalReady"
+	// LoopsContactGroupMembershipRemovedReason is a reason that is set when the membership is removed
+	LoopsContactGroupMembershipRemovedReason = "ContactGroupMembershipRemoved"
+	// LoopsContactGroupMembershipNotRemovedReason is a reason that is set when the membership is not removed
+	LoopsContactGroupMembershipNotRemovedReason = "ContactGroupMembershipNotRemoved"
+
+	// contactGroupMembershipRemovalPairIndexKey indexes ContactGroupMembershipRemovals by their contact and contact
+	// group references
+	contactGroupMembershipRemovalPairIndexKey = "contact-group-membership-removal-pair"
+)
+
+// loopsContactGroupMembershipRemovalFinalizerKey holds a ContactGroupMembershipRemoval until it is applied, so that a
+// removal deleted before being reconciled still removes the contact from the mailing list.
+const loopsContactGroupMembershipRemovalFinalizerKey = util.ContactGroupMembershipRemovalFinalizerKey
+
+// loopsContactGroupMembershipRemovalFinalizer applies a ContactGroupMembershipRemoval that is deleted before being
+// applied. A removal already applied has nothing left to do.
+type loopsContactGroupMembershipRemovalFinalizer struct {
+	removeMembership func(context.Context, *notificationmiloapiscomv1alpha1.ContactGroupMembershipRemoval) error
+}
+
+func (f *loopsContactGroupMembershipRemovalFinalizer) Finalize(ctx context.Context, obj client.Object) (finalizer.Result, error) {
+	log := logf.FromContext(ctx).WithValues("finalizer", "ContactGroupMembershipRemovalFinalizer", "trigger", obj.GetName())
+	log.Info("Finalizing ContactGroupMembershipRemoval")
+
+	// Type assertion
+	removal, ok := obj.(*notificationmiloapiscomv1alpha1.ContactGroupMembershipRemoval)
+	if !ok {
+		log.Error(fmt.Errorf("object is not a ContactGroupMembershipRemoval"), "Failed to finalize ContactGroupMembershipRemoval")
+		return finalizer.Result{}, fmt.Errorf("object is not a ContactGroupMembershipRemoval")
+	}
+
+	if isRemovalApplied(removal) {
+		log.Info("ContactGroupMembershipRemoval already applied")
+		return finalizer.Result{}, nil
+	}
+
+	// Deleting the memberships and removing the contact from the mailing list again is harmless
+	if err := f.removeMembership(ctx, removal); err != nil {
+		log.Error(err, "Failed to apply ContactGroupMembershipRemoval")
+		return finalizer.Result{}, fmt.Errorf("failed to apply ContactGroupMembershipRemoval: %w", err)
+	}
+	return finalizer.Result{}, nil
+}
+
+// LoopsContactGroupMembershipRemovalController reconciles a ContactGroupMembershipRemoval object. It deletes the
+// ContactGroupMemberships of the referenced contact and group, removes the Loops contact from the mailing list, and
+// records the removal as applied in its status. The removal is kept as the persistent opt-out of the contact, until a
+// resubscribe deletes it. Its finalizer applies the removals deleted before being reconciled. It looks the memberships
+// up with the contact and group pair index registered by LoopsContactGroupMembershipController, so it must be set up
+// with it.
+type LoopsContactGroupMembershipRemovalController struct {
+	Client     client.Client
+	Finalizers finalizer.Finalizers
+	Loops      loops.API
+	// ContactIDResolver resolves the Loops userId of a Contact. Defaults to the Contact UID.
+	ContactIDResolver ContactIDResolver
+	// ProviderCallTimeout bounds each call to Loops. Zero means no per-call timeout.
+	ProviderCallTimeout time.Duration
+	// TracerProvider records a span per reconciliation when set
+	TracerProvider trace.TracerProvider
+	// RateLimitGate delays the reconciliations while Loops rate limits the controllers sharing it. Reconciliations are
+	// not delayed when nil.
+	RateLimitGate *RateLimitGate
+	// InstanceID distinguishes the finalizer key of this controller instance from other instances
+	InstanceID string
+	// PreviousInstanceIDs are the instance IDs the controller ran with before, whose finalizers it takes over
+	PreviousInstanceIDs []string
+}
+
+// +kubebuilder:rbac:groups=notification.miloapis.com,resources=contactgroupmembershipremovals,verbs=get;list;watch;update
+// +kubebuilder:rbac:groups=notification.miloapis.com,resources=contactgroupmembershipremovals/status,verbs=get;update;patch
+// +kubebuilder:rbac:groups=notification.miloapis.com,resources=contactgroupmembershipremovals/finalizers,verbs=update
+// +kubebuilder:rbac:groups=notification.miloapis.com,resources=contactgroups,verbs=get;list;watch
+
+// Reconcile is the main function that reconciles the ContactGroupMembershipRemoval object.
+func (r *LoopsContactGroupMembershipRemovalController) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	return traceReconcile(ctx, r.TracerProvider, "LoopsContactGroupMembershipRemovalController", req,
+		func(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+			return gateReconcile(ctx, r.RateLimitGate, req, r.reconcile)
+		})
+}
+
+func (r *LoopsContactGroupMembershipRemovalController) reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	log := logf.FromContext(ctx).WithValues("controller", "ContactGroupMembershipRemovalController", "trigger", req.NamespacedName)
+	log.Info("Starting reconciliation", "namespacedName", req.String(), "name", req.Name, "namespace", req.Namespace)
+
+	// Get ContactGroupMembershipRemoval
+	removal := &notificationmiloapiscomv1alpha1.ContactGroupMembershipRemoval{}
+	err := r.Client.Get(ctx, req.NamespacedName, removal)
+	if err != nil {
+		if errors.IsNotFound(err) {
+			log.Info("ContactGroupMembershipRemoval not found. Probably deleted.")
+			return ctrl.Result{}, nil
+		}
+		return ctrl.Result{}, fmt.Errorf("failed to get contactgroupmembershipremoval: %w", err)
+	}
+
+	// Run finalizers, which add the finalizer to new removals and apply the removals deleted before being applied
+	adopted := adoptPreviousFinalizers(removal, loopsContactGroupMembershipRemovalFinalizerKey, r.InstanceID, r.PreviousInstanceIDs)
+	finalizeResult, err := r.Finalizers.Finalize(ctx, removal)
+	if err != nil {
+		log.Error(err, "Failed to run finalizers for ContactGroupMembershipRemoval")
+		return ctrl.Result{}, fmt.Errorf("failed to run finalizers for ContactGroupMembershipRemoval: %w", err)
+	}
+	if finalizeResult.Updated || adopted {
+		log.Info("finalizer updated the contactgroupmembershipremoval object, updating API server")
+		if updateErr := r.Client.Update(ctx, removal); updateErr != nil {
+			if errors.IsConflict(updateErr) {
+				log.Info("Conflict updating ContactGroupMembershipRemoval after finalizer update; requeuing")
+				return conflictRequeueResult(), nil
+			}
+			log.Error(updateErr, "Failed to update ContactGroupMembershipRemoval after finalizer update")
+			return ctrl.Result{}, updateErr
+		}
+	}
+
+	if !removal.DeletionTimestamp.IsZero() {
+		log.Info("ContactGroupMembershipRemoval is being deleted, skipping reconciliation")
+		return ctrl.Result{}, nil
+	}
+
+	// An applied removal is the persistent opt-out of the contact, it keeps the memberships of the pair deleted. Their
+	// finalizer removes the contact from the mailing list again.
+	if isRemovalApplied(removal) {
+		if err := r.deleteMemberships(ctx, removal); err != nil {
+			log.Error(err, "Failed to delete contact group memberships")
+			return ctrl.Result{}, err
+		}
+		log.Info("ContactGroupMembershipRemoval already applied")
+		return ctrl.Result{}, nil
+	}
+
+	oldStatus := removal.Status.DeepCopy()
+	original := removal.DeepCopy()
+
+	removeErr := r.removeMembership(ctx, removal)
+	if removeErr != nil {
+		log.Error(removeErr, "Failed to remove contact group membership")
+		meta.SetStatusCondition(&removal.Status.Conditions, metav1.Condition{
+			Type:               LoopsContactGroupMembershipRemovalReadyCondition,
+			Status:             metav1.ConditionFalse,
+			Reason:             LoopsContactGroupMembershipNotRemovedReason,
+			Message:            fmt.Sprintf("Loops contact group membership not removed on email provider: %s", removeErr.Error()),
+			LastTransitionTime: metav1.Now(),
+			ObservedGeneration: removal.GetGeneration(),
+		})
+	} else {
+		meta.SetStatusCondition(&removal.Status.Conditions, metav1.Condition{
+			Type:               LoopsContactGroupMembershipRemovalReadyCondition,
+			Status:             metav1.ConditionTrue,
+			Reason:             LoopsContactGroupMembershipRemovedReason,
+			Message:            "Loops contact group membership removed on email provider",
+			LastTransitionTime: metav1.Now(),
+			ObservedGeneration: removal.GetGeneration(),
+		})
+	}
+
+	if err := util.PatchStatusWithRetry(ctx, util.StatusPatchParams{
+		Client:     r.Client,
+		Logger:     log,
+		Object:     removal,
+		Original:   original,
+		OldStatus:  oldStatus,
+		NewStatus:  &removal.Status,
+		FieldOwner: "loopscontactgroupmembershipremoval-controller",
+	}); err != nil {
+		return ctrl.Result{}, err
+	}
+
+	if removeErr != nil {
+		return ctrl.Result{}, removeErr
+	}
+
+	log.Info("ContactGroupMembershipRemoval reconciled")
+	return ctrl.Result{}, nil
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *LoopsContactGroupMembershipRemovalController) SetupWithManager(mgr ctrl.Manager) error {
+	if err := r.setupFinalizers(); err != nil {
+		return err
+	}
+
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&notificationmiloapiscomv1alpha1.ContactGroupMembershipRemoval{}).
+		Named("loopscontactgroupmembershipremoval").
+		Complete(r)
+}
+
+// setupFinalizers registers the removal finalizer under the instance finalizer key.
+func (r *LoopsContactGroupMembershipRemovalController) setupFinalizers() error {
+	r.Finalizers = finalizer.NewFinalizers()
+	if err := r.Finalizers.Register(finalizerKey(loopsContactGroupMembershipRemovalFinalizerKey, r.InstanceID), &loopsContactGroupMembershipRemovalFinalizer{
+		removeMembership: r.removeMembership,
+	}); err != nil {
+		return fmt.Errorf("failed to register loops contact group membership removal finalizer: %w", err)
+	}
+
+	return nil
+}
+
+// removeMembership deletes the ContactGroupMemberships of the contact and group referenced by the removal and removes
+// the Loops contact from the mailing list of the group. A missing contact or group has no Loops membership to remove.
+func (r *LoopsContactGroupMembershipRemovalController) removeMembership(ctx context.Context, removal *notificationmiloapiscomv1alpha1.ContactGroupMembershipRemoval) error {
+	log := logf.FromContext(ctx).WithValues("controller", "LoopsContactGroupMembershipRemovalController", "trigger", removal.Name)
+
+	if err := r.deleteMemberships(ctx, removal); err != nil {
+		return err
+	}
+
+	contact := &notificationmiloapiscomv1alpha1.Contact{}
+	contactKey := client.ObjectKey{Namespace: removal.Spec.ContactRef.Namespace, Name: removal.Spec.ContactRef.Name}
+	if err := r.Client.Get(ctx, contactKey, contact); err != nil {
+		if errors.IsNotFound(err) {
+			log.Info("Contact not found, no Loops membership to remove")
+			return nil
+		}
+		return fmt.Errorf("failed to get Contact: %w", err)
+	}
+
+	contactGroup := &notificationmiloapiscomv1alpha1.ContactGroup{}
+	groupKey := client.ObjectKey{Namespace: removal.Spec.ContactGroupRef.Namespace, Name: removal.Spec.ContactGroupRef.Name}
+	if err := r.Client.Get(ctx, groupKey, contactGroup); err != nil {
+		if errors.IsNotFound(err) {
+			log.Info("ContactGroup not found, no Loops membership to remove")
+			return nil
+		}
+		return fmt.Errorf("failed to get ContactGroup: %w", err)
+	}
+
+	mailingListId, err := getMailingListId(contactGroup)
+	if err != nil {
+		return fmt.Errorf("failed to get Loops mailing list ID: %w", err)
+	}
+
+	// Remove the contact from the mailing list even if no membership existed, as Loops is the source of the removal
+	return removeFromMailingList(ctx, r.Loops, r.ContactIDResolver, r.ProviderCallTimeout, contact, mailingListId)
+}
+
+// deleteMemberships deletes the ContactGroupMemberships of the contact and group referenced by the removal. Their
+// finalizers remove them from Loops too.
+func (r *LoopsContactGroupMembershipRemovalController) deleteMemberships(ctx context.Context, removal *notificationmiloapiscomv1alpha1.ContactGroupMembershipRemoval) error {
+	log := logf.FromContext(ctx).WithValues("controller", "LoopsContactGroupMembershipRemovalController", "trigger", removal.Name)
+
+	memberships, err := listContactGroupMemberships(ctx, r.Client, &notificationmiloapiscomv1alpha1.ContactGroupMembership{
+		Spec: notificationmiloapiscomv1alpha1.ContactGroupMembershipSpec{
+			ContactRef:      removal.Spec.ContactRef,
+			ContactGroupRef: removal.Spec.ContactGroupRef,
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to list ContactGroupMemberships: %w", err)
+	}
+	for i := range memberships {
+		cgm := &memberships[i]
+		log.Info("Deleting ContactGroupMembership", "contactGroupMembership", cgm.Name)
+		if err := r.Client.Delete(ctx, cgm); err != nil && !errors.IsNotFound(err) {
+			return fmt.Errorf("failed to delete ContactGroupMembership %s: %w", cgm.Name, err)
+		}
+	}
+	return nil
+}
+
+// isRemovalApplied returns true if the current generation of the removal was applied.
+func isRemovalApplied(removal *notificationmiloapiscomv1alpha1.ContactGroupMembershipRemoval) bool {
+	cond := meta.FindStatusCondition(removal.Status.Conditions, LoopsContactGroupMembershipRemovalReadyCondition)
+	return cond != nil && cond.Status == metav1.ConditionTrue && cond.ObservedGeneration == removal.GetGeneration()
+}
+
+// buildContactGroupMembershipRemovalPairIndexKey builds the contact and group pair key of a removal, in the format of
+// buildContactGroupMembershipPairIndexKey.
+func buildContactGroupMembershipRemovalPairIndexKey(removal *notificationmiloapiscomv1alpha1.ContactGroupMembershipRemoval) string {
+	return fmt.Sprintf("%s-%s-%s-%s", removal.Spec.ContactRef.Name, removal.Spec.ContactRef.Namespace, removal.Spec.ContactGroupRef.Name, removal.Spec.ContactGroupRef.Namespace)
+}
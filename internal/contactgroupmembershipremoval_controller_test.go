@@ -0,0 +1,277 @@
+package controller
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	notificationmiloapiscomv1alpha1 "go.miloapis.com/milo/pkg/apis/notification/v1alpha1"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	loops "go.miloapis.com/email-provider-loops/pkg/loops"
+)
+
+func newTestContactGroupMembershipRemovalController(t *testing.T, objs ...client.Object) (*LoopsContactGroupMembershipRemovalController, *fakeLoops) {
+	t.Helper()
+	k8sClient := fake.NewClientBuilder().
+		WithScheme(newTestScheme(t)).
+		WithObjects(objs...).
+		WithStatusSubresource(&notificationmiloapiscomv1alpha1.ContactGroupMembershipRemoval{}).
+		WithIndex(&notificationmiloapiscomv1alpha1.ContactGroupMembership{}, contactGroupMembershipPairIndexKey, func(rawObj client.Object) []string {
+			return []string{buildContactGroupMembershipPairIndexKey(rawObj.(*notificationmiloapiscomv1alpha1.ContactGroupMembership))}
+		}).
+		Build()
+
+	loopsAPI := newFakeLoops()
+	r := &LoopsContactGroupMembershipRemovalController{
+		Client: k8sClient,
+		Loops:  loopsAPI,
+	}
+	if err := r.setupFinalizers(); err != nil {
+		t.Fatalf("setupFinalizers() failed: %v", err)
+	}
+
+	return r, loopsAPI
+}
+
+func newTestContactGroupMembershipRemoval(name string, contact *notificationmiloapiscomv1alpha1.Contact, group *notificationmiloapiscomv1alpha1.ContactGroup) *notificationmiloapiscomv1alpha1.ContactGroupMembershipRemoval {
+	return &notificationmiloapiscomv1alpha1.ContactGroupMembershipRemoval{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: "default",
+		},
+		Spec: notificationmiloapiscomv1alpha1.ContactGroupMembershipRemovalSpec{
+			ContactRef: notificationmiloapiscomv1alpha1.ContactReference{
+				Name:      contact.Name,
+				Namespace: contact.Namespace,
+			},
+			ContactGroupRef: notificationmiloapiscomv1alpha1.ContactGroupReference{
+				Name:      group.Name,
+				Namespace: group.Namespace,
+			},
+		},
+	}
+}
+
+func TestReconcile_ContactGroupMembershipRemoval(t *testing.T) {
+	ctx := context.Background()
+	contact := newTestContact("jane")
+	other := newTestContact("john")
+	group := newTestContactGroup("newsletter", "list-abc")
+	cgm := newTestContactGroupMembership("newsletter-jane", contact, group, time.Now())
+	otherCgm := newTestContactGroupMembership("newsletter-john", other, group, time.Now())
+	removal := newTestContactGroupMembershipRemoval("newsletter-jane-removal", contact, group)
+
+	r, loopsAPI := newTestContactGroupMembershipRemovalController(t, contact, other, group, cgm, otherCgm, removal)
+
+	if _, err := r.Reconcile(ctx, ctrl.Request{NamespacedName: client.ObjectKeyFromObject(removal)}); err != nil {
+		t.Fatalf("Reconcile() failed: %v", err)
+	}
+
+	// The membership is deleted, its finalizer holds it until the CGM controller removes it from Loops
+	deleted := &notificationmiloapiscomv1alpha1.ContactGroupMembership{}
+	if err := r.Client.Get(ctx, client.ObjectKeyFromObject(cgm), deleted); err != nil {
+		t.Fatalf("Get() failed: %v", err)
+	}
+	if deleted.DeletionTimestamp.IsZero() {
+		t.Error("Expected the ContactGroupMembership to be deleted")
+	}
+
+	kept := &notificationmiloapiscomv1alpha1.ContactGroupMembership{}
+	if err := r.Client.Get(ctx, client.ObjectKeyFromObject(otherCgm), kept); err != nil {
+		t.Fatalf("Get() failed: %v", err)
+	}
+	if !kept.DeletionTimestamp.IsZero() {
+		t.Error("Expected the ContactGroupMembership of another contact to be kept")
+	}
+
+	if removals := loopsAPI.removals["list-abc"]; len(removals) != 1 || removals[0] != string(contact.UID) {
+		t.Errorf("Expected removal of %s from list-abc, got %v", contact.UID, removals)
+	}
+
+	assertRemovalApplied(t, r, removal)
+
+	// The applied removal keeps the contact opted out: a membership created again is deleted, without calling Loops,
+	// as the finalizer of the membership removes the contact from the mailing list
+	recreated := newTestContactGroupMembership("newsletter-jane-2", contact, group, time.Now())
+	recreated.Finalizers = []string{"example.com/hold"}
+	if err := r.Client.Create(ctx, recreated); err != nil {
+		t.Fatalf("Create() failed: %v", err)
+	}
+	if _, err := r.Reconcile(ctx, ctrl.Request{NamespacedName: client.ObjectKeyFromObject(removal)}); err != nil {
+		t.Fatalf("Reconcile() failed: %v", err)
+	}
+	if err := r.Client.Get(ctx, client.ObjectKeyFromObject(recreated), recreated); err != nil {
+		t.Fatalf("Get() failed: %v", err)
+	}
+	if recreated.DeletionTimestamp.IsZero() {
+		t.Error("Expected the recreated ContactGroupMembership to be deleted")
+	}
+	if removals := loopsAPI.removals["list-abc"]; len(removals) != 1 {
+		t.Errorf("Expected no more Loops removals, got %v", removals)
+	}
+}
+
+// assertRemovalApplied checks that the removal is kept, recorded as applied.
+func assertRemovalApplied(t *testing.T, r *LoopsContactGroupMembershipRemovalController, removal *notificationmiloapiscomv1alpha1.ContactGroupMembershipRemoval) {
+	t.Helper()
+	kept := &notificationmiloapiscomv1alpha1.ContactGroupMembershipRemoval{}
+	if err := r.Client.Get(context.Background(), client.ObjectKeyFromObject(removal), kept); err != nil {
+		t.Fatalf("Expected the ContactGroupMembershipRemoval to be kept, got %v", err)
+	}
+	if !isRemovalApplied(kept) {
+		t.Errorf("Expected the ContactGroupMembershipRemoval to be applied, got %+v", kept.Status.Conditions)
+	}
+}
+
+func TestReconcile_ContactGroupMembershipRemovalFailure(t *testing.T) {
+	ctx := context.Background()
+	contact := newTestContact("jane")
+	group := newTestContactGroup("newsletter", "list-abc")
+	removal := newTestContactGroupMembershipRemoval("newsletter-jane-removal", contact, group)
+
+	r, loopsAPI := newTestContactGroupMembershipRemovalController(t, contact, group, removal)
+	loopsAPI.err = errors.New("loops unavailable")
+
+	if _, err := r.Reconcile(ctx, ctrl.Request{NamespacedName: client.ObjectKeyFromObject(removal)}); err == nil {
+		t.Fatal("Expected an error, got none")
+	}
+
+	updated := &notificationmiloapiscomv1alpha1.ContactGroupMembershipRemoval{}
+	if err := r.Client.Get(ctx, client.ObjectKeyFromObject(removal), updated); err != nil {
+		t.Fatalf("Expected the ContactGroupMembershipRemoval to be kept for a retry, got %v", err)
+	}
+	cond := meta.FindStatusCondition(updated.Status.Conditions, LoopsContactGroupMembershipRemovalReadyCondition)
+	if cond == nil || cond.Status != metav1.ConditionFalse || cond.Reason != LoopsContactGroupMembershipNotRemovedReason {
+		t.Errorf("Expected condition %s with reason %s, got %+v",
+			LoopsContactGroupMembershipRemovalReadyCondition, LoopsContactGroupMembershipNotRemovedReason, cond)
+	}
+}
+
+func TestReconcile_ContactGroupMembershipRemovalMissingContact(t *testing.T) {
+	ctx := context.Background()
+	contact := newTestContact("jane")
+	group := newTestContactGroup("newsletter", "list-abc")
+	removal := newTestContactGroupMembershipRemoval("newsletter-jane-removal", contact, group)
+
+	r, loopsAPI := newTestContactGroupMembershipRemovalController(t, group, removal)
+
+	if _, err := r.Reconcile(ctx, ctrl.Request{NamespacedName: client.ObjectKeyFromObject(removal)}); err != nil {
+		t.Fatalf("Reconcile() failed: %v", err)
+	}
+
+	if len(loopsAPI.removals) != 0 {
+		t.Errorf("Expected no Loops removal, got %v", loopsAPI.removals)
+	}
+	assertRemovalApplied(t, r, removal)
+}
+
+func TestFinalize_ContactGroupMembershipRemoval(t *testing.T) {
+	tests := []struct {
+		name             string
+		applied          bool
+		loopsErr         error
+		expectedErr      bool
+		expectedRemovals int
+		expectedReleased bool
+	}{
+		{name: "not applied", expectedRemovals: 1, expectedReleased: true},
+		{name: "already applied", applied: true, expectedReleased: true},
+		{name: "contact already removed from Loops", loopsErr: &loops.Error{StatusCode: 404}, expectedReleased: true},
+		{name: "provider failure", loopsErr: errors.New("loops unavailable"), expectedErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			contact := newTestContact("jane")
+			group := newTestContactGroup("newsletter", "list-abc")
+			cgm := newTestContactGroupMembership("newsletter-jane", contact, group, time.Now())
+			removal := newTestContactGroupMembershipRemoval("newsletter-jane-removal", contact, group)
+			removal.Finalizers = []string{loopsContactGroupMembershipRemovalFinalizerKey}
+			removal.DeletionTimestamp = &metav1.Time{Time: time.Now()}
+			if tt.applied {
+				meta.SetStatusCondition(&removal.Status.Conditions, metav1.Condition{
+					Type:   LoopsContactGroupMembershipRemovalReadyCondition,
+					Status: metav1.ConditionTrue,
+					Reason: LoopsContactGroupMembershipRemovedReason,
+				})
+			}
+
+			r, loopsAPI := newTestContactGroupMembershipRemovalController(t, contact, group, cgm, removal)
+			loopsAPI.err = tt.loopsErr
+
+			_, err := r.Reconcile(ctx, ctrl.Request{NamespacedName: client.ObjectKeyFromObject(removal)})
+			if (err != nil) != tt.expectedErr {
+				t.Errorf("Expected error %t, got %v", tt.expectedErr, err)
+			}
+
+			if got := len(loopsAPI.removals["list-abc"]); got != tt.expectedRemovals {
+				t.Errorf("Expected %d Loops removals, got %d", tt.expectedRemovals, got)
+			}
+
+			deleted := &notificationmiloapiscomv1alpha1.ContactGroupMembership{}
+			if err := r.Client.Get(ctx, client.ObjectKeyFromObject(cgm), deleted); err != nil {
+				t.Fatalf("Get() failed: %v", err)
+			}
+			if got := !deleted.DeletionTimestamp.IsZero(); got != (!tt.applied) {
+				t.Errorf("Expected the ContactGroupMembership deleted to be %t, got %t", !tt.applied, got)
+			}
+
+			err = r.Client.Get(ctx, client.ObjectKeyFromObject(removal), &notificationmiloapiscomv1alpha1.ContactGroupMembershipRemoval{})
+			if got := apierrors.IsNotFound(err); got != tt.expectedReleased {
+				t.Errorf("Expected the ContactGroupMembershipRemoval released to be %t, got %v", tt.expectedReleased, err)
+			}
+		})
+	}
+}
+
+func TestReconcile_ContactGroupMembershipRemovalFinalizer(t *testing.T) {
+	ctx := context.Background()
+	contact := newTestContact("jane")
+	group := newTestContactGroup("newsletter", "list-abc")
+	removal := newTestContactGroupMembershipRemoval("newsletter-jane-removal", contact, group)
+
+	r, loopsAPI := newTestContactGroupMembershipRemovalController(t, contact, group, removal)
+	loopsAPI.err = errors.New("loops unavailable")
+
+	// A removal not applied yet is held by the finalizer
+	if _, err := r.Reconcile(ctx, ctrl.Request{NamespacedName: client.ObjectKeyFromObject(removal)}); err == nil {
+		t.Fatal("Expected an error, got none")
+	}
+	held := &notificationmiloapiscomv1alpha1.ContactGroupMembershipRemoval{}
+	if err := r.Client.Get(ctx, client.ObjectKeyFromObject(removal), held); err != nil {
+		t.Fatalf("Get() failed: %v", err)
+	}
+	if len(held.Finalizers) != 1 || held.Finalizers[0] != loopsContactGroupMembershipRemovalFinalizerKey {
+		t.Errorf("Expected the removal finalizer, got %v", held.Finalizers)
+	}
+
+	// and kept once applied, deleting it then releases it without applying it again
+	loopsAPI.err = nil
+	if _, err := r.Reconcile(ctx, ctrl.Request{NamespacedName: client.ObjectKeyFromObject(removal)}); err != nil {
+		t.Fatalf("Reconcile() failed: %v", err)
+	}
+	assertRemovalApplied(t, r, removal)
+
+	if err := r.Client.Delete(ctx, removal); err != nil {
+		t.Fatalf("Delete() failed: %v", err)
+	}
+	if _, err := r.Reconcile(ctx, ctrl.Request{NamespacedName: client.ObjectKeyFromObject(removal)}); err != nil {
+		t.Fatalf("Reconcile() failed: %v", err)
+	}
+	err := r.Client.Get(ctx, client.ObjectKeyFromObject(removal), &notificationmiloapiscomv1alpha1.ContactGroupMembershipRemoval{})
+	if !apierrors.IsNotFound(err) {
+		t.Errorf("Expected the ContactGroupMembershipRemoval to be released, got %v", err)
+	}
+	if removals := loopsAPI.removals["list-abc"]; len(removals) != 1 {
+		t.Errorf("Expected a single Loops removal, got %v", removals)
+	}
+}
@@ -0,0 +1,26 @@
+package controller
+
+import (
+	loops "go.miloapis.com/email-provider-loops/pkg/loops"
+	notificationmiloapiscomv1alpha1 "go.miloapis.com/milo/pkg/apis/notification/v1alpha1"
+)
+
+// LoopsContactID returns the Loops userId the Contact is synced with: the one recorded in its status, or the one
+// resolver resolves for a Contact that was not synced yet. It returns ErrEmptyContactID rather than an empty userId.
+func LoopsContactID(resolver ContactIDResolver, contact *notificationmiloapiscomv1alpha1.Contact) (string, error) {
+	if contactID := loopsProviderID(contact.Status.Providers); contactID != "" {
+		return contactID, nil
+	}
+	return resolveContactID(resolver, contact)
+}
+
+// SkipsLoopsSync returns true if the Contact opted out of the Loops sync, see skipLoopsSyncAnnotation.
+func SkipsLoopsSync(contact *notificationmiloapiscomv1alpha1.Contact) bool {
+	return skipsLoopsSync(contact)
+}
+
+// MailingListsFromLabels returns the mailing lists the Contact is desired to be subscribed to, or unsubscribed from,
+// when mailing lists are synced with MailingListSourceLabels.
+func MailingListsFromLabels(contact *notificationmiloapiscomv1alpha1.Contact) loops.MailingListOps {
+	return mailingListsFromLabels(contact)
+}
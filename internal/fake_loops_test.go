@@ -0,0 +1,173 @@
+package controller
+
+import (
+	"context"
+	"sync"
+
+	loops "go.miloapis.com/email-provider-loops/pkg/loops"
+)
+
+// fakeLoops is an in-memory loops.API that records the calls it receives.
+type fakeLoops struct {
+	mu sync.Mutex
+
+	upserts []loops.ContactRequest
+	deletes []string
+	// unsubscribes holds the userIds of the unsubscribed contacts
+	unsubscribes []string
+	adds         map[string][]string
+	removals     map[string][]string
+	// mailingLists holds the mailing list subscriptions by userId
+	mailingLists map[string]map[string]bool
+	// contacts holds the upserted contacts by userId
+	contacts map[string]*loops.Contact
+	// upsertMailingLists is echoed in the UpsertContact responses when set
+	upsertMailingLists map[string]bool
+	// listUpdates holds the mailing list updates by mailing list ID
+	listUpdates map[string][]loops.MailingListUpdate
+	// listContacts holds the contacts returned by ListContactsInMailingList by mailing list ID
+	listContacts map[string][]loops.Contact
+
+	err error
+	// block makes every call wait until its context is done
+	block bool
+}
+
+var (
+	_ loops.API           = &fakeLoops{}
+	_ GarbageCollectorAPI = &fakeLoops{}
+)
+
+func newFakeLoops() *fakeLoops {
+	return &fakeLoops{
+		adds:         map[string][]string{},
+		removals:     map[string][]string{},
+		mailingLists: map[string]map[string]bool{},
+		contacts:     map[string]*loops.Contact{},
+		listUpdates:  map[string][]loops.MailingListUpdate{},
+		listContacts: map[string][]loops.Contact{},
+	}
+}
+
+func (f *fakeLoops) UpsertContact(ctx context.Context, req loops.ContactRequest) (*loops.APIResponse, error) {
+	if err := f.wait(ctx); err != nil {
+		return nil, err
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.err != nil {
+		return nil, f.err
+	}
+	f.upserts = append(f.upserts, req)
+	f.contacts[req.UserID] = &loops.Contact{Email: req.Email, UserID: req.UserID}
+	return &loops.APIResponse{Success: true, MailingLists: f.upsertMailingLists}, nil
+}
+
+func (f *fakeLoops) DeleteContact(ctx context.Context, userID string) (*loops.APIResponse, error) {
+	if err := f.wait(ctx); err != nil {
+		return nil, err
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.err != nil {
+		return nil, f.err
+	}
+	f.deletes = append(f.deletes, userID)
+	return &loops.APIResponse{Success: true}, nil
+}
+
+func (f *fakeLoops) Unsubscribe(ctx context.Context, userID string) (*loops.APIResponse, error) {
+	if err := f.wait(ctx); err != nil {
+		return nil, err
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.err != nil {
+		return nil, f.err
+	}
+	f.unsubscribes = append(f.unsubscribes, userID)
+	return &loops.APIResponse{Success: true}, nil
+}
+
+func (f *fakeLoops) AddToMailingList(ctx context.Context, userID string, listID string) (*loops.APIResponse, error) {
+	if err := f.wait(ctx); err != nil {
+		return nil, err
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.err != nil {
+		return nil, f.err
+	}
+	f.adds[listID] = append(f.adds[listID], userID)
+	return &loops.APIResponse{Success: true}, nil
+}
+
+func (f *fakeLoops) RemoveFromMailingList(ctx context.Context, userID string, listID string) (*loops.APIResponse, error) {
+	if err := f.wait(ctx); err != nil {
+		return nil, err
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.err != nil {
+		return nil, f.err
+	}
+	f.removals[listID] = append(f.removals[listID], userID)
+	return &loops.APIResponse{Success: true}, nil
+}
+
+func (f *fakeLoops) GetContactMailingLists(ctx context.Context, userID string) (map[string]bool, error) {
+	if err := f.wait(ctx); err != nil {
+		return nil, err
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.err != nil {
+		return nil, f.err
+	}
+	return f.mailingLists[userID], nil
+}
+
+func (f *fakeLoops) FindContact(ctx context.Context, req loops.FindContactRequest) (*loops.Contact, error) {
+	if err := f.wait(ctx); err != nil {
+		return nil, err
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.err != nil {
+		return nil, f.err
+	}
+	return f.contacts[req.UserID], nil
+}
+
+func (f *fakeLoops) UpdateMailingList(ctx context.Context, id string, req loops.MailingListUpdate) (*loops.APIResponse, error) {
+	if err := f.wait(ctx); err != nil {
+		return nil, err
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.err != nil {
+		return nil, f.err
+	}
+	f.listUpdates[id] = append(f.listUpdates[id], req)
+	return &loops.APIResponse{Success: true}, nil
+}
+
+func (f *fakeLoops) ListContactsInMailingList(ctx context.Context, listID string) ([]loops.Contact, error) {
+	if err := f.wait(ctx); err != nil {
+		return nil, err
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.err != nil {
+		return nil, f.err
+	}
+	return f.listContacts[listID], nil
+}
+
+func (f *fakeLoops) wait(ctx context.Context) error {
+	if !f.block {
+		return nil
+	}
+	<-ctx.Done()
+	return ctx.Err()
+}
@@ -0,0 +1,34 @@
+package controller
+
+import (
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+)
+
+// finalizerKey returns the finalizer key for the given base key. The key is suffixed with the instance ID when one is
+// set, so that several controller instances backed by different Loops accounts do not fight over the same finalizer.
+func finalizerKey(base, instanceID string) string {
+	if instanceID == "" {
+		return base
+	}
+	return base + "-" + instanceID
+}
+
+// adoptPreviousFinalizers replaces the finalizer keys obj carries for base under the previous instance IDs with the
+// key of instanceID, so that changing the instance ID does not orphan the objects finalized under the previous one.
+// An empty previous instance ID stands for the unsuffixed key. It returns true if obj changed, which the caller must
+// persist. On an object being deleted, the adopted key is in turn removed by the finalizer it runs.
+func adoptPreviousFinalizers(obj client.Object, base, instanceID string, previousInstanceIDs []string) bool {
+	key := finalizerKey(base, instanceID)
+	adopted := false
+	for _, previous := range previousInstanceIDs {
+		previousKey := finalizerKey(base, previous)
+		if previousKey != key && controllerutil.RemoveFinalizer(obj, previousKey) {
+			adopted = true
+		}
+	}
+	if adopted {
+		controllerutil.AddFinalizer(obj, key)
+	}
+	return adopted
+}
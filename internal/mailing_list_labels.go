@@ -0,0 +1,90 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	loops "go.miloapis.com/email-provider-loops/pkg/loops"
+	notificationmiloapiscomv1alpha1 "go.miloapis.com/milo/pkg/apis/notification/v1alpha1"
+
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// MailingListSource defines what Loops mailing list memberships are reconciled from.
+type MailingListSource string
+
+const (
+	// MailingListSourceMemberships reconciles mailing lists from ContactGroupMemberships
+	MailingListSourceMemberships MailingListSource = "memberships"
+	// MailingListSourceLabels reconciles mailing lists from MailingListLabelPrefix labels on the Contact
+	MailingListSourceLabels MailingListSource = "labels"
+)
+
+// MailingListLabelPrefix prefixes the Contact labels holding mailing list memberships, e.g.
+// loops.list/<mailing list ID>=true.
+const MailingListLabelPrefix = "loops.list/"
+
+// syncedMailingListsAnnotation holds the comma-separated, sorted IDs of the mailing lists last synced from the
+// Contact labels, so that removed labels are unsubscribed from in Loops.
+const syncedMailingListsAnnotation = "notification.miloapis.com/loops-synced-mailing-lists"
+
+// labeledMailingLists returns the sorted IDs of the mailing lists the contact is labeled with.
+func labeledMailingLists(contact *notificationmiloapiscomv1alpha1.Contact) []string {
+	var mailingLists []string
+	for key, value := range contact.Labels {
+		mailingListID, ok := strings.CutPrefix(key, MailingListLabelPrefix)
+		if ok && mailingListID != "" && value == "true" {
+			mailingLists = append(mailingLists, mailingListID)
+		}
+	}
+	slices.Sort(mailingLists)
+	return mailingLists
+}
+
+// syncedMailingLists returns the IDs of the mailing lists last synced from the contact labels.
+func syncedMailingLists(contact *notificationmiloapiscomv1alpha1.Contact) []string {
+	synced := contact.Annotations[syncedMailingListsAnnotation]
+	if synced == "" {
+		return nil
+	}
+	return strings.Split(synced, ",")
+}
+
+// mailingListLabelsChanged returns true if the contact labels changed since they were last synced.
+func mailingListLabelsChanged(contact *notificationmiloapiscomv1alpha1.Contact) bool {
+	return !slices.Equal(labeledMailingLists(contact), syncedMailingLists(contact))
+}
+
+// mailingListsFromLabels returns the full mailing list map of the contact: the labeled mailing lists are subscribed,
+// while the synced ones whose label was removed are unsubscribed.
+func mailingListsFromLabels(contact *notificationmiloapiscomv1alpha1.Contact) loops.MailingListOps {
+	mailingLists := loops.MailingListOps{}
+	for _, mailingListID := range syncedMailingLists(contact) {
+		mailingLists[mailingListID] = loops.MailingListRemove
+	}
+	for _, mailingListID := range labeledMailingLists(contact) {
+		mailingLists[mailingListID] = loops.MailingListAdd
+	}
+	return mailingLists
+}
+
+// recordSyncedMailingLists annotates the contact with the mailing lists synced from its labels.
+func (r *LoopsContactController) recordSyncedMailingLists(ctx context.Context, contact *notificationmiloapiscomv1alpha1.Contact) error {
+	if !mailingListLabelsChanged(contact) {
+		return nil
+	}
+
+	// Patch a copy, as the patch response would otherwise overwrite the pending status changes of contact
+	annotated := contact.DeepCopy()
+	if annotated.Annotations == nil {
+		annotated.Annotations = map[string]string{}
+	}
+	annotated.Annotations[syncedMailingListsAnnotation] = strings.Join(labeledMailingLists(contact), ",")
+	if err := r.Client.Patch(ctx, annotated, client.MergeFrom(contact)); err != nil {
+		return fmt.Errorf("failed to annotate Contact with the synced mailing lists: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,28 @@
+package controller
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+)
+
+// Membership change actions
+const (
+	membershipChangeActionAdd    = "add"
+	membershipChangeActionRemove = "remove"
+)
+
+var (
+	// membershipChangesTotal counts the contacts added to and removed from Loops mailing lists. It is only labeled
+	// with the Loops list ID, a finite set, to keep cardinality in check.
+	membershipChangesTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "loops_membership_changes_total",
+			Help: "Total number of contacts added to or removed from Loops mailing lists, by list ID and action.",
+		},
+		[]string{"list_id", "action"},
+	)
+)
+
+func init() {
+	metrics.Registry.MustRegister(membershipChangesTotal)
+}
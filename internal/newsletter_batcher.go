@@ -0,0 +1,83 @@
+package controller
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	notificationmiloapiscomv1alpha1 "go.miloapis.com/milo/pkg/apis/notification/v1alpha1"
+
+	"k8s.io/apimachinery/pkg/api/errors"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// newsletterBatcher collects the newsletter memberships to create and creates them together once per window, so that
+// many newsletter contacts created at once do not each create their membership right away. The creation of a
+// membership enqueues its contact, which then records the NewsLetterAdded condition.
+type newsletterBatcher struct {
+	client client.Client
+	window time.Duration
+
+	mu      sync.Mutex
+	pending map[client.ObjectKey]*notificationmiloapiscomv1alpha1.ContactGroupMembership
+}
+
+// newNewsletterBatcher returns a newsletterBatcher creating the memberships with c every window.
+func newNewsletterBatcher(c client.Client, window time.Duration) *newsletterBatcher {
+	return &newsletterBatcher{
+		client:  c,
+		window:  window,
+		pending: map[client.ObjectKey]*notificationmiloapiscomv1alpha1.ContactGroupMembership{},
+	}
+}
+
+// add queues the membership for the next flush. A membership already queued under the same name is replaced.
+func (b *newsletterBatcher) add(cgm *notificationmiloapiscomv1alpha1.ContactGroupMembership) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.pending[client.ObjectKeyFromObject(cgm)] = cgm
+}
+
+// flush creates the queued memberships. Memberships that failed to be created stay queued for the next flush, except
+// the ones that already exist.
+func (b *newsletterBatcher) flush(ctx context.Context) {
+	b.mu.Lock()
+	batch := b.pending
+	b.pending = map[client.ObjectKey]*notificationmiloapiscomv1alpha1.ContactGroupMembership{}
+	b.mu.Unlock()
+	if len(batch) == 0 {
+		return
+	}
+
+	log := logf.FromContext(ctx).WithValues("component", "NewsletterBatcher")
+	log.Info("Creating newsletter ContactGroupMemberships", "count", len(batch))
+	for key, cgm := range batch {
+		err := b.client.Create(ctx, cgm)
+		if err == nil || errors.IsAlreadyExists(err) {
+			continue
+		}
+		log.Error(err, "Failed to create newsletter ContactGroupMembership, retrying on the next flush",
+			"contactGroupMembership", key)
+
+		b.mu.Lock()
+		if _, queued := b.pending[key]; !queued {
+			b.pending[key] = cgm
+		}
+		b.mu.Unlock()
+	}
+}
+
+// Start flushes the queued memberships every window until ctx is done.
+func (b *newsletterBatcher) Start(ctx context.Context) error {
+	ticker := time.NewTicker(b.window)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+			b.flush(ctx)
+		}
+	}
+}
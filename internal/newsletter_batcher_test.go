@@ -0,0 +1,97 @@
+package controller
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	notificationmiloapiscomv1alpha1 "go.miloapis.com/milo/pkg/apis/notification/v1alpha1"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+)
+
+func TestReconcile_NewsletterBatch(t *testing.T) {
+	ctx := context.Background()
+	var contacts []client.Object
+	for _, name := range []string{"newsletter-jane", "newsletter-john", "newsletter-joe"} {
+		contact := newTestContact(name)
+		contact.Finalizers = []string{loopsContactFinalizerKey}
+		contacts = append(contacts, contact)
+	}
+
+	var created []string
+	failJohn := true
+	r, _ := newTestContactController(t)
+	r.Client = fake.NewClientBuilder().
+		WithScheme(newTestScheme(t)).
+		WithObjects(contacts...).
+		WithStatusSubresource(&notificationmiloapiscomv1alpha1.Contact{}).
+		WithIndex(&notificationmiloapiscomv1alpha1.ContactGroupMembershipRemoval{}, contactGroupMembershipRemovalPairIndexKey, contactGroupMembershipRemovalPairIndex).
+		WithIndex(&notificationmiloapiscomv1alpha1.ContactGroupMembership{}, contactGroupMembershipPairIndexKey, contactGroupMembershipPairIndex).
+		WithInterceptorFuncs(interceptor.Funcs{
+			Create: func(ctx context.Context, c client.WithWatch, obj client.Object, opts ...client.CreateOption) error {
+				cgm, ok := obj.(*notificationmiloapiscomv1alpha1.ContactGroupMembership)
+				if !ok {
+					return c.Create(ctx, obj, opts...)
+				}
+				if cgm.Spec.ContactRef.Name == "newsletter-john" && failJohn {
+					failJohn = false
+					return errors.New("connection refused")
+				}
+				created = append(created, cgm.Spec.ContactRef.Name)
+				return c.Create(ctx, obj, opts...)
+			},
+		}).
+		Build()
+	r.newsletterBatcher = newNewsletterBatcher(r.Client, time.Minute)
+	if err := r.setupFinalizers(); err != nil {
+		t.Fatalf("setupFinalizers() failed: %v", err)
+	}
+
+	// The additions within the window are queued
+	for _, contact := range contacts {
+		if _, err := r.Reconcile(ctx, ctrl.Request{NamespacedName: client.ObjectKeyFromObject(contact)}); err != nil {
+			t.Fatalf("Reconcile() failed: %v", err)
+		}
+	}
+	if len(created) != 0 {
+		t.Fatalf("Expected no membership created before the flush, got %v", created)
+	}
+
+	// and flushed together, a failed creation being retried on the next flush
+	r.newsletterBatcher.flush(ctx)
+	if len(created) != 2 {
+		t.Errorf("Expected 2 memberships created by the first flush, got %v", created)
+	}
+	r.newsletterBatcher.flush(ctx)
+	if len(created) != 3 || created[2] != "newsletter-john" {
+		t.Errorf("Expected the failed membership to be created by the second flush, got %v", created)
+	}
+	r.newsletterBatcher.flush(ctx)
+	if len(created) != 3 {
+		t.Errorf("Expected an empty third flush, got %v", created)
+	}
+
+	// The contacts enqueued by the creation record the added condition
+	for _, obj := range contacts {
+		contact := obj.(*notificationmiloapiscomv1alpha1.Contact)
+		if _, err := r.Reconcile(ctx, ctrl.Request{NamespacedName: client.ObjectKeyFromObject(contact)}); err != nil {
+			t.Fatalf("Reconcile() failed: %v", err)
+		}
+		if err := r.Client.Get(ctx, client.ObjectKeyFromObject(contact), contact); err != nil {
+			t.Fatalf("Get() failed: %v", err)
+		}
+		if !meta.IsStatusConditionTrue(contact.Status.Conditions, NewsLetterAddedCondition) {
+			t.Errorf("Expected the %s condition of %s to be true, got %+v", NewsLetterAddedCondition, contact.Name,
+				contact.Status.Conditions)
+		}
+	}
+	if len(created) != 3 {
+		t.Errorf("Expected no more membership created, got %v", created)
+	}
+}
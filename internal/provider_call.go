@@ -0,0 +1,16 @@
+package controller
+
+import (
+	"context"
+	"time"
+)
+
+// withProviderCallTimeout derives a context bounding a single call to the email provider. A non-positive timeout
+// leaves the call bounded only by the parent context. It also records the call in the reconciliation summary.
+func withProviderCallTimeout(ctx context.Context, timeout time.Duration) (context.Context, context.CancelFunc) {
+	reconcileSummaryFrom(ctx).markAPICall()
+	if timeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, timeout)
+}
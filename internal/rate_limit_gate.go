@@ -0,0 +1,94 @@
+package controller
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	loops "go.miloapis.com/email-provider-loops/pkg/loops"
+
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/utils/clock"
+	ctrl "sigs.k8s.io/controller-runtime"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// defaultRateLimitCooldown is the cooldown after a 429 that carries no usable Retry-After header
+const defaultRateLimitCooldown = 10 * time.Second
+
+// RateLimitGate coordinates the controllers calling Loops after a rate limit. Once a reconciliation of any of the
+// controllers sharing the gate fails with a 429, all of them requeue their reconciliations until the cooldown is over
+// instead of calling Loops, so that they do not retry independently and prolong the rate limit. A nil gate never
+// delays reconciliations.
+type RateLimitGate struct {
+	mu    sync.Mutex
+	until time.Time
+	clock clock.PassiveClock
+}
+
+// NewRateLimitGate returns a RateLimitGate with no cooldown in progress.
+func NewRateLimitGate() *RateLimitGate {
+	return NewRateLimitGateWithClock(clock.RealClock{})
+}
+
+// NewRateLimitGateWithClock returns a RateLimitGate timing its cooldowns with the given clock, e.g. a fake clock in
+// tests.
+func NewRateLimitGateWithClock(clock clock.PassiveClock) *RateLimitGate {
+	return &RateLimitGate{clock: clock}
+}
+
+// Observe starts a cooldown if err is a Loops rate limit error, lasting for its Retry-After or
+// defaultRateLimitCooldown. It returns true if err is a rate limit error.
+func (g *RateLimitGate) Observe(err error) bool {
+	if g == nil || !loops.IsRateLimited(err) {
+		return false
+	}
+
+	cooldown := defaultRateLimitCooldown
+	var apiErr *loops.Error
+	if errors.As(err, &apiErr) && apiErr.RetryAfter > 0 {
+		cooldown = apiErr.RetryAfter
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if until := g.clock.Now().Add(cooldown); until.After(g.until) {
+		g.until = until
+	}
+	return true
+}
+
+// Remaining returns how long the current cooldown lasts, zero when there is none.
+func (g *RateLimitGate) Remaining() time.Duration {
+	if g == nil {
+		return 0
+	}
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return max(g.until.Sub(g.clock.Now()), 0)
+}
+
+// gateReconcile skips reconcile while a cooldown is in progress and starts one when reconcile fails with a rate
+// limit error, requeueing the request after the cooldown in both cases. The requeue delay is jittered so that the
+// delayed reconciliations do not all call Loops at once when the cooldown ends.
+func gateReconcile(
+	ctx context.Context,
+	gate *RateLimitGate,
+	req ctrl.Request,
+	reconcile func(context.Context, ctrl.Request) (ctrl.Result, error),
+) (ctrl.Result, error) {
+	if remaining := gate.Remaining(); remaining > 0 {
+		logf.FromContext(ctx).Info("Loops rate limit cooldown in progress, requeuing", "requeueAfter", remaining)
+		return ctrl.Result{RequeueAfter: wait.Jitter(remaining, 0.5)}, nil
+	}
+
+	result, err := reconcile(ctx, req)
+	if gate.Observe(err) {
+		remaining := gate.Remaining()
+		logf.FromContext(ctx).Info("Loops rate limit exceeded, requeuing after the cooldown", "requeueAfter", remaining,
+			"error", err.Error())
+		return ctrl.Result{RequeueAfter: wait.Jitter(remaining, 0.5)}, nil
+	}
+	return result, err
+}
@@ -0,0 +1,83 @@
+package controller
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	loops "go.miloapis.com/email-provider-loops/pkg/loops"
+
+	testingclock "k8s.io/utils/clock/testing"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+func TestRateLimitGate_SharedBetweenControllers(t *testing.T) {
+	ctx := context.Background()
+	fakeClock := testingclock.NewFakePassiveClock(time.Now())
+	gate := NewRateLimitGateWithClock(fakeClock)
+
+	contact := newTestContact("jane")
+	contact.Finalizers = []string{loopsContactFinalizerKey}
+	contactController, contactLoops := newTestContactController(t, contact)
+	contactController.RateLimitGate = gate
+	if err := contactController.setupFinalizers(); err != nil {
+		t.Fatalf("setupFinalizers() failed: %v", err)
+	}
+	contactLoops.err = &loops.Error{StatusCode: http.StatusTooManyRequests, RetryAfter: 30 * time.Second}
+
+	result, err := contactController.Reconcile(ctx, ctrl.Request{NamespacedName: client.ObjectKeyFromObject(contact)})
+	if err != nil {
+		t.Fatalf("Expected the rate limit to requeue without an error, got %v", err)
+	}
+	if result.RequeueAfter < 30*time.Second {
+		t.Errorf("Expected a requeue after at least 30s, got %v", result.RequeueAfter)
+	}
+
+	// The membership controller backs off too, without calling Loops
+	group := newTestContactGroup("product", "list-product")
+	cgm := newTestContactGroupMembership("product-jane", contact, group, fakeClock.Now())
+	cgmController, cgmLoops := newTestContactGroupMembershipController(t, contact, group, cgm)
+	cgmController.RateLimitGate = gate
+
+	result, err = cgmController.Reconcile(ctx, ctrl.Request{NamespacedName: client.ObjectKeyFromObject(cgm)})
+	if err != nil {
+		t.Fatalf("Reconcile() failed: %v", err)
+	}
+	if result.RequeueAfter < 30*time.Second {
+		t.Errorf("Expected a requeue after at least 30s, got %v", result.RequeueAfter)
+	}
+	if len(cgmLoops.adds["list-product"]) != 0 {
+		t.Errorf("Expected no Loops call during the cooldown, got adds %v", cgmLoops.adds)
+	}
+
+	// Once the cooldown is over, the membership is reconciled
+	fakeClock.SetTime(fakeClock.Now().Add(31 * time.Second))
+	result, err = cgmController.Reconcile(ctx, ctrl.Request{NamespacedName: client.ObjectKeyFromObject(cgm)})
+	if err != nil {
+		t.Fatalf("Reconcile() failed: %v", err)
+	}
+	if len(cgmLoops.adds["list-product"]) != 1 {
+		t.Errorf("Expected the membership to be added after the cooldown, got adds %v", cgmLoops.adds)
+	}
+}
+
+func TestRateLimitGate_DefaultCooldown(t *testing.T) {
+	gate := NewRateLimitGateWithClock(testingclock.NewFakePassiveClock(time.Now()))
+
+	if gate.Observe(&loops.Error{StatusCode: http.StatusBadRequest}) {
+		t.Error("Expected a 400 not to start a cooldown")
+	}
+	if !gate.Observe(&loops.Error{StatusCode: http.StatusTooManyRequests}) {
+		t.Error("Expected a 429 to start a cooldown")
+	}
+	if got := gate.Remaining(); got != defaultRateLimitCooldown {
+		t.Errorf("Expected a cooldown of %v, got %v", defaultRateLimitCooldown, got)
+	}
+
+	var nilGate *RateLimitGate
+	if nilGate.Observe(&loops.Error{StatusCode: http.StatusTooManyRequests}) || nilGate.Remaining() != 0 {
+		t.Error("Expected a nil gate to never delay reconciliations")
+	}
+}
@@ -0,0 +1,109 @@
+package controller
+
+import (
+	"context"
+	"errors"
+
+	loops "go.miloapis.com/email-provider-loops/pkg/loops"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/clock"
+	ctrl "sigs.k8s.io/controller-runtime"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// reconcileSummaryMessage is the message of the log line summarizing a reconciliation
+const reconcileSummaryMessage = "Reconciliation summary"
+
+// Actions of a reconciliation reported in its summary
+const (
+	reconcileActionNone              = "none"
+	reconcileActionFinalize          = "finalize"
+	reconcileActionSkip              = "skip"
+	reconcileActionCreate            = "create"
+	reconcileActionUpdate            = "update"
+	reconcileActionRecreate          = "recreate"
+	reconcileActionCollapseDuplicate = "collapseDuplicate"
+)
+
+// reconcileSummary collects the outcome of a reconciliation, logged as a single line once it is over so that the
+// outcome does not have to be pieced together from the logs along the way. A nil summary ignores updates.
+type reconcileSummary struct {
+	action    string
+	condition string
+	apiCalled bool
+}
+
+type reconcileSummaryKey struct{}
+
+// reconcileSummaryFrom returns the summary of the reconciliation running with ctx, nil outside of one.
+func reconcileSummaryFrom(ctx context.Context) *reconcileSummary {
+	summary, _ := ctx.Value(reconcileSummaryKey{}).(*reconcileSummary)
+	return summary
+}
+
+// setAction records the action taken by the reconciliation.
+func (s *reconcileSummary) setAction(action string) {
+	if s != nil {
+		s.action = action
+	}
+}
+
+// setCondition records the Ready condition resulting from the reconciliation, as its status and reason.
+func (s *reconcileSummary) setCondition(cond *metav1.Condition) {
+	if s != nil && cond != nil {
+		s.condition = string(cond.Status) + "/" + cond.Reason
+	}
+}
+
+// markAPICall records that the reconciliation called the email provider.
+func (s *reconcileSummary) markAPICall() {
+	if s != nil {
+		s.apiCalled = true
+	}
+}
+
+// withReconcileSummary wraps reconcile to log a summary of each reconciliation once it is over: the action taken, the
+// resulting Ready condition, whether the email provider was called, the duration and the error if any. The duration
+// is measured with clk, or the real clock when nil. Provider calls canceled by the shutdown are not reported as errors.
+func withReconcileSummary(
+	controllerName string,
+	clk clock.PassiveClock,
+	reconcile func(context.Context, ctrl.Request) (ctrl.Result, error),
+) func(context.Context, ctrl.Request) (ctrl.Result, error) {
+	return func(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+		summary := &reconcileSummary{action: reconcileActionNone}
+		clk := clockOrReal(clk)
+		start := clk.Now()
+		result, err := reconcile(context.WithValue(ctx, reconcileSummaryKey{}, summary), req)
+
+		keysAndValues := []any{
+			"controller", controllerName,
+			"trigger", req.NamespacedName,
+			"action", summary.action,
+			"condition", summary.condition,
+			"apiCalled", summary.apiCalled,
+			"duration", clk.Since(start).String(),
+			"requeueAfter", result.RequeueAfter.String(),
+		}
+		// A reconciliation whose provider call was canceled by the shutdown of the manager did not fail, it runs again
+		// on the next start
+		if errors.Is(err, loops.ErrCanceled) && ctx.Err() != nil {
+			keysAndValues = append(keysAndValues, "canceled", true)
+			err = nil
+		}
+		if err != nil {
+			keysAndValues = append(keysAndValues, "error", err.Error())
+		}
+		logf.FromContext(ctx).Info(reconcileSummaryMessage, keysAndValues...)
+
+		return result, err
+	}
+}
+
+// clockOrReal returns clk, or the real clock when it is nil.
+func clockOrReal(clk clock.PassiveClock) clock.PassiveClock {
+	if clk == nil {
+		return clock.RealClock{}
+	}
+	return clk
+}
@@ -0,0 +1,211 @@
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-logr/logr/funcr"
+	loops "go.miloapis.com/email-provider-loops/pkg/loops"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	testingclock "k8s.io/utils/clock/testing"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// captureReconcileSummaries returns a context logging to a JSON sink, and a function returning the reconciliation
+// summaries logged so far.
+func captureReconcileSummaries(t *testing.T) (context.Context, func() []map[string]any) {
+	t.Helper()
+	var lines []string
+	logger := funcr.NewJSON(func(obj string) {
+		lines = append(lines, obj)
+	}, funcr.Options{})
+
+	return logf.IntoContext(context.Background(), logger), func() []map[string]any {
+		var summaries []map[string]any
+		for _, line := range lines {
+			var entry map[string]any
+			if err := json.Unmarshal([]byte(line), &entry); err != nil {
+				t.Fatalf("failed to parse log line %q: %v", line, err)
+			}
+			if entry["msg"] == reconcileSummaryMessage {
+				summaries = append(summaries, entry)
+			}
+		}
+		return summaries
+	}
+}
+
+func TestReconcile_SummaryLog(t *testing.T) {
+	contact := newTestContact("jane")
+	contact.Finalizers = []string{loopsContactFinalizerKey}
+	group := newTestContactGroup("newsletter", "list-1")
+	cgm := newTestContactGroupMembership("jane-newsletter", contact, group, time.Now())
+
+	tests := []struct {
+		name              string
+		reconcile         func(t *testing.T, ctx context.Context) error
+		expectedAction    string
+		expectedCondition string
+		expectedAPICalled bool
+	}{
+		{
+			name: "contact created",
+			reconcile: func(t *testing.T, ctx context.Context) error {
+				r, _ := newTestContactController(t, contact.DeepCopy())
+				if err := r.setupFinalizers(); err != nil {
+					t.Fatalf("setupFinalizers() failed: %v", err)
+				}
+				_, err := r.Reconcile(ctx, ctrl.Request{NamespacedName: client.ObjectKeyFromObject(contact)})
+				return err
+			},
+			expectedAction:    reconcileActionCreate,
+			expectedCondition: "True/" + LoopsContactCreatedReason,
+			expectedAPICalled: true,
+		},
+		{
+			name: "contact skipped",
+			reconcile: func(t *testing.T, ctx context.Context) error {
+				skipped := contact.DeepCopy()
+				skipped.Annotations = map[string]string{skipLoopsSyncAnnotation: "true"}
+				r, _ := newTestContactController(t, skipped)
+				if err := r.setupFinalizers(); err != nil {
+					t.Fatalf("setupFinalizers() failed: %v", err)
+				}
+				_, err := r.Reconcile(ctx, ctrl.Request{NamespacedName: client.ObjectKeyFromObject(contact)})
+				return err
+			},
+			expectedAction: reconcileActionSkip,
+		},
+		{
+			name: "membership created",
+			reconcile: func(t *testing.T, ctx context.Context) error {
+				r, _ := newTestContactGroupMembershipController(t, contact.DeepCopy(), group.DeepCopy(), cgm.DeepCopy())
+				_, err := r.Reconcile(ctx, ctrl.Request{NamespacedName: client.ObjectKeyFromObject(cgm)})
+				return err
+			},
+			expectedAction:    reconcileActionCreate,
+			expectedCondition: "True/" + LoopsContactGroupMembershipCreatedReason,
+			expectedAPICalled: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, summaries := captureReconcileSummaries(t)
+			if err := tt.reconcile(t, ctx); err != nil {
+				t.Fatalf("Reconcile() failed: %v", err)
+			}
+
+			logged := summaries()
+			if len(logged) != 1 {
+				t.Fatalf("Expected 1 summary log, got %d", len(logged))
+			}
+			summary := logged[0]
+			if summary["action"] != tt.expectedAction {
+				t.Errorf("Expected action %q, got %v", tt.expectedAction, summary["action"])
+			}
+			if summary["condition"] != tt.expectedCondition {
+				t.Errorf("Expected condition %q, got %v", tt.expectedCondition, summary["condition"])
+			}
+			if summary["apiCalled"] != tt.expectedAPICalled {
+				t.Errorf("Expected apiCalled %t, got %v", tt.expectedAPICalled, summary["apiCalled"])
+			}
+			if duration, ok := summary["duration"].(string); !ok || !strings.HasSuffix(duration, "s") {
+				t.Errorf("Expected a duration, got %v", summary["duration"])
+			}
+			if _, ok := summary["error"]; ok {
+				t.Errorf("Expected no error, got %v", summary["error"])
+			}
+		})
+	}
+}
+
+func TestReconcile_FakeClock(t *testing.T) {
+	start := time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC)
+	fakeClock := testingclock.NewFakeClock(start)
+
+	// The summary measures the duration with the clock
+	ctx, summaries := captureReconcileSummaries(t)
+	reconcile := withReconcileSummary("TestController", fakeClock, func(context.Context, ctrl.Request) (ctrl.Result, error) {
+		fakeClock.Step(2 * time.Second)
+		return ctrl.Result{}, nil
+	})
+	if _, err := reconcile(ctx, ctrl.Request{}); err != nil {
+		t.Fatalf("reconcile() failed: %v", err)
+	}
+	if logged := summaries(); len(logged) != 1 || logged[0]["duration"] != "2s" {
+		t.Errorf("Expected a summary with duration 2s, got %v", logged)
+	}
+
+	// The conditions are timestamped with the clock
+	contact := newTestContact("jane")
+	contact.Finalizers = []string{loopsContactFinalizerKey}
+	r, _ := newTestContactController(t, contact)
+	r.Clock = fakeClock
+	if err := r.setupFinalizers(); err != nil {
+		t.Fatalf("setupFinalizers() failed: %v", err)
+	}
+	if _, err := r.Reconcile(context.Background(), ctrl.Request{NamespacedName: client.ObjectKeyFromObject(contact)}); err != nil {
+		t.Fatalf("Reconcile() failed: %v", err)
+	}
+	if err := r.Client.Get(context.Background(), client.ObjectKeyFromObject(contact), contact); err != nil {
+		t.Fatalf("Get() failed: %v", err)
+	}
+	readyCond := meta.FindStatusCondition(contact.Status.Conditions, LoopsContactReadyCondition)
+	expected := metav1.NewTime(fakeClock.Now())
+	if readyCond == nil || !readyCond.LastTransitionTime.Equal(&expected) {
+		t.Errorf("Expected the Ready condition to transition at %v, got %+v", expected, readyCond)
+	}
+}
+
+func TestReconcile_SummaryCanceledByShutdown(t *testing.T) {
+	canceledErr := fmt.Errorf("failed to upsert Loops contact: %w", loops.ErrCanceled)
+
+	tests := []struct {
+		name             string
+		shutdown         bool
+		err              error
+		expectedErr      bool
+		expectedCanceled bool
+	}{
+		{name: "shutdown", shutdown: true, err: canceledErr, expectedCanceled: true},
+		{name: "canceled without shutdown", err: canceledErr, expectedErr: true},
+		{name: "timeout on shutdown", shutdown: true, err: loops.ErrTimeout, expectedErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, summaries := captureReconcileSummaries(t)
+			ctx, cancel := context.WithCancel(ctx)
+			defer cancel()
+			if tt.shutdown {
+				cancel()
+			}
+
+			reconcile := withReconcileSummary("TestController", nil, func(context.Context, ctrl.Request) (ctrl.Result, error) {
+				return ctrl.Result{}, tt.err
+			})
+			if _, err := reconcile(ctx, ctrl.Request{}); (err != nil) != tt.expectedErr {
+				t.Errorf("Expected error %t, got %v", tt.expectedErr, err)
+			}
+
+			logged := summaries()
+			if len(logged) != 1 {
+				t.Fatalf("Expected 1 summary log, got %d", len(logged))
+			}
+			if got := logged[0]["canceled"] == true; got != tt.expectedCanceled {
+				t.Errorf("Expected canceled %t, got %v", tt.expectedCanceled, logged[0]["canceled"])
+			}
+			if _, got := logged[0]["error"]; got != tt.expectedErr {
+				t.Errorf("Expected an error logged to be %t, got %v", tt.expectedErr, logged[0]["error"])
+			}
+		})
+	}
+}
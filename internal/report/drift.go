@@ -0,0 +1,217 @@
+package report
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+
+	controller "go.miloapis.com/email-provider-loops/internal"
+	loops "go.miloapis.com/email-provider-loops/pkg/loops"
+	notificationmiloapiscomv1alpha1 "go.miloapis.com/milo/pkg/apis/notification/v1alpha1"
+
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// FieldDrift is a field of a Loops contact whose actual value differs from the one desired by Milo.
+type FieldDrift struct {
+	// Field is the drifted field, e.g. "email" or "mailingLists.<mailing list ID>"
+	Field   string `json:"field"`
+	Desired string `json:"desired"`
+	Actual  string `json:"actual"`
+}
+
+// ContactDrift is the drift of a single Milo Contact.
+type ContactDrift struct {
+	Name      string `json:"name"`
+	Namespace string `json:"namespace"`
+	UserID    string `json:"userId"`
+	// Missing is true when Loops has no contact with the userId, in which case Fields is empty
+	Missing bool         `json:"missing,omitempty"`
+	Fields  []FieldDrift `json:"fields,omitempty"`
+}
+
+// DriftReport is the result of comparing the Milo Contacts with their Loops state.
+type DriftReport struct {
+	// ContactsScanned is the number of contacts whose Loops state was read
+	ContactsScanned int `json:"contactsScanned"`
+	// Drifted are the contacts whose Loops state differs from the desired one, sorted by namespace and name
+	Drifted []ContactDrift `json:"drifted"`
+}
+
+// DriftOptions configures Drift the way the manager is configured.
+type DriftOptions struct {
+	// ContactIDResolver resolves the Loops userId of the Contacts not synced yet, defaults to their UID.
+	ContactIDResolver controller.ContactIDResolver
+	// MailingListSource is what the mailing lists are synced from, defaults to controller.MailingListSourceMemberships.
+	MailingListSource controller.MailingListSource
+	// SyncedFields are the fields the manager syncs, the others are not compared. Defaults to every field.
+	SyncedFields controller.SyncedFields
+}
+
+// Drift compares the desired Loops state of each Milo Contact, i.e. its email, its subscription and its mailing list
+// memberships, with the state stored in Loops. It only reads, nothing is changed in Milo or Loops. The Contacts that
+// opted out of the Loops sync are not compared.
+//
+// With controller.MailingListSourceMemberships, only the mailing lists of a Milo ContactGroup are compared: a contact
+// is desired to be subscribed to the ones it has a ContactGroupMembership for, not being deleted, and unsubscribed
+// from the others. With controller.MailingListSourceLabels, the mailing lists of the Contact labels are compared, see
+// controller.MailingListsFromLabels.
+//
+// The fields left out of opts.SyncedFields are not compared, as Loops keeps whatever value they have there.
+func Drift(ctx context.Context, k8sClient client.Client, loopsAPI loops.API, opts DriftOptions) (*DriftReport, error) {
+	log := logf.FromContext(ctx).WithValues("report", "Drift")
+	fromLabels := opts.MailingListSource == controller.MailingListSourceLabels
+	// The mailing lists of the labels are sent on upsert, unlike the ones of the memberships
+	comparesLists := !fromLabels || opts.SyncedFields.Syncs(controller.SyncedFieldMailingLists)
+
+	var listIDs []string
+	var desiredLists map[client.ObjectKey]map[string]bool
+	if !fromLabels {
+		var err error
+		if listIDs, desiredLists, err = membershipMailingLists(ctx, k8sClient); err != nil {
+			return nil, err
+		}
+	}
+
+	var contactList notificationmiloapiscomv1alpha1.ContactList
+	if err := k8sClient.List(ctx, &contactList); err != nil {
+		return nil, fmt.Errorf("failed to list contacts: %w", err)
+	}
+
+	report := &DriftReport{Drifted: []ContactDrift{}}
+	var errs []error
+	for i := range contactList.Items {
+		contact := &contactList.Items[i]
+		if controller.SkipsLoopsSync(contact) {
+			continue
+		}
+
+		contactID, err := controller.LoopsContactID(opts.ContactIDResolver, contact)
+		if errors.Is(err, controller.ErrEmptyContactID) {
+			// Contacts without a userId are never synced to Loops
+			continue
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("failed to resolve the Loops contact ID of contact %s/%s: %w", contact.Namespace, contact.Name, err))
+			continue
+		}
+		drift := ContactDrift{Name: contact.Name, Namespace: contact.Namespace, UserID: contactID}
+
+		found, err := loopsAPI.FindContact(ctx, loops.FindContactRequest{UserID: drift.UserID})
+		if err != nil {
+			log.Error(err, "Failed to find Loops contact", "contactName", contact.Name, "contactNamespace", contact.Namespace)
+			errs = append(errs, fmt.Errorf("failed to find Loops contact for contact %s/%s: %w", contact.Namespace, contact.Name, err))
+			continue
+		}
+		report.ContactsScanned++
+
+		if found == nil {
+			drift.Missing = true
+			report.Drifted = append(report.Drifted, drift)
+			continue
+		}
+
+		desiredEmail := loops.NormalizeEmail(contact.Spec.Email)
+		if opts.SyncedFields.Syncs(controller.SyncedFieldEmail) && desiredEmail != loops.NormalizeEmail(found.Email) {
+			drift.Fields = append(drift.Fields, FieldDrift{Field: "email", Desired: desiredEmail, Actual: found.Email})
+		}
+		// The contact controller always upserts contacts as subscribed
+		if opts.SyncedFields.Syncs(controller.SyncedFieldSubscribed) && !found.Subscribed {
+			drift.Fields = append(drift.Fields, FieldDrift{Field: "subscribed", Desired: "true", Actual: "false"})
+		}
+
+		var compared []string
+		var desired map[string]bool
+		switch {
+		case !comparesLists:
+		case fromLabels:
+			compared, desired = labelMailingLists(contact)
+		default:
+			compared, desired = listIDs, desiredLists[client.ObjectKeyFromObject(contact)]
+		}
+		for _, listID := range compared {
+			if desired[listID] != found.MailingLists[listID] {
+				drift.Fields = append(drift.Fields, FieldDrift{
+					Field:   "mailingLists." + listID,
+					Desired: strconv.FormatBool(desired[listID]),
+					Actual:  strconv.FormatBool(found.MailingLists[listID]),
+				})
+			}
+		}
+
+		if len(drift.Fields) > 0 {
+			report.Drifted = append(report.Drifted, drift)
+		}
+	}
+
+	sort.Slice(report.Drifted, func(i, j int) bool {
+		if report.Drifted[i].Namespace != report.Drifted[j].Namespace {
+			return report.Drifted[i].Namespace < report.Drifted[j].Namespace
+		}
+		return report.Drifted[i].Name < report.Drifted[j].Name
+	})
+
+	return report, errors.Join(errs...)
+}
+
+// membershipMailingLists returns the sorted mailing list IDs of the contact groups, and the desired mailing lists of
+// each contact from its ContactGroupMemberships not being deleted.
+func membershipMailingLists(ctx context.Context, k8sClient client.Client) ([]string, map[client.ObjectKey]map[string]bool, error) {
+	// Index contact groups by their Loops mailing list ID
+	var groupList notificationmiloapiscomv1alpha1.ContactGroupList
+	if err := k8sClient.List(ctx, &groupList); err != nil {
+		return nil, nil, fmt.Errorf("failed to list contact groups: %w", err)
+	}
+	listIDsByGroup := map[client.ObjectKey]string{}
+	var listIDs []string
+	for _, group := range groupList.Items {
+		for _, provider := range group.Spec.Providers {
+			if provider.Name == "Loops" && provider.ID != "" {
+				listIDsByGroup[client.ObjectKeyFromObject(&group)] = provider.ID
+				listIDs = append(listIDs, provider.ID)
+			}
+		}
+	}
+	sort.Strings(listIDs)
+
+	// Index the desired mailing lists by contact
+	var membershipList notificationmiloapiscomv1alpha1.ContactGroupMembershipList
+	if err := k8sClient.List(ctx, &membershipList); err != nil {
+		return nil, nil, fmt.Errorf("failed to list contact group memberships: %w", err)
+	}
+	desiredLists := map[client.ObjectKey]map[string]bool{}
+	for _, membership := range membershipList.Items {
+		if !membership.DeletionTimestamp.IsZero() {
+			continue
+		}
+		groupKey := client.ObjectKey{Namespace: membership.Spec.ContactGroupRef.Namespace, Name: membership.Spec.ContactGroupRef.Name}
+		listID, ok := listIDsByGroup[groupKey]
+		if !ok {
+			continue
+		}
+		contactKey := client.ObjectKey{Namespace: membership.Spec.ContactRef.Namespace, Name: membership.Spec.ContactRef.Name}
+		if desiredLists[contactKey] == nil {
+			desiredLists[contactKey] = map[string]bool{}
+		}
+		desiredLists[contactKey][listID] = true
+	}
+
+	return listIDs, desiredLists, nil
+}
+
+// labelMailingLists returns the sorted IDs of the mailing lists synced from the contact labels, and whether the
+// contact is desired to be subscribed to each of them.
+func labelMailingLists(contact *notificationmiloapiscomv1alpha1.Contact) ([]string, map[string]bool) {
+	ops := controller.MailingListsFromLabels(contact)
+	listIDs := make([]string, 0, len(ops))
+	desired := make(map[string]bool, len(ops))
+	for listID, op := range ops {
+		listIDs = append(listIDs, listID)
+		desired[listID] = op == loops.MailingListAdd
+	}
+	sort.Strings(listIDs)
+	return listIDs, desired
+}
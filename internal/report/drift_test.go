@@ -0,0 +1,234 @@
+package report
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	controller "go.miloapis.com/email-provider-loops/internal"
+	loops "go.miloapis.com/email-provider-loops/pkg/loops"
+	notificationmiloapiscomv1alpha1 "go.miloapis.com/milo/pkg/apis/notification/v1alpha1"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+// fakeLoops serves contacts by userId, failing for the userIds in errs.
+type fakeLoops struct {
+	loops.API
+	contacts map[string]*loops.Contact
+	errs     map[string]error
+}
+
+func (f *fakeLoops) FindContact(_ context.Context, req loops.FindContactRequest) (*loops.Contact, error) {
+	if err := f.errs[req.UserID]; err != nil {
+		return nil, err
+	}
+	return f.contacts[req.UserID], nil
+}
+
+func newContact(name, email string) *notificationmiloapiscomv1alpha1.Contact {
+	return &notificationmiloapiscomv1alpha1.Contact{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default", UID: types.UID(name + "-uid")},
+		Spec:       notificationmiloapiscomv1alpha1.ContactSpec{Email: email},
+	}
+}
+
+func newContactGroup(name, listID string) *notificationmiloapiscomv1alpha1.ContactGroup {
+	return &notificationmiloapiscomv1alpha1.ContactGroup{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default"},
+		Spec: notificationmiloapiscomv1alpha1.ContactGroupSpec{
+			Providers: []notificationmiloapiscomv1alpha1.ContactGroupProvider{{Name: "Loops", ID: listID}},
+		},
+	}
+}
+
+func newContactGroupMembership(name string, contact *notificationmiloapiscomv1alpha1.Contact, group *notificationmiloapiscomv1alpha1.ContactGroup) *notificationmiloapiscomv1alpha1.ContactGroupMembership {
+	return &notificationmiloapiscomv1alpha1.ContactGroupMembership{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default"},
+		Spec: notificationmiloapiscomv1alpha1.ContactGroupMembershipSpec{
+			ContactRef:      notificationmiloapiscomv1alpha1.ContactReference{Name: contact.Name, Namespace: contact.Namespace},
+			ContactGroupRef: notificationmiloapiscomv1alpha1.ContactGroupReference{Name: group.Name, Namespace: group.Namespace},
+		},
+	}
+}
+
+func TestDrift(t *testing.T) {
+	ctx := context.Background()
+	scheme := runtime.NewScheme()
+	if err := notificationmiloapiscomv1alpha1.AddToScheme(scheme); err != nil {
+		t.Fatalf("failed to add notification scheme: %v", err)
+	}
+
+	jane := newContact("jane", "jane@example.com")
+	bob := newContact("bob", "bob@example.com")
+	ghost := newContact("ghost", "ghost@example.com")
+	broken := newContact("broken", "broken@example.com")
+	skipped := newContact("skipped", "skipped@example.com")
+	skipped.Annotations = map[string]string{"notification.miloapis.com/skip-loops-sync": "true"}
+	newsletter := newContactGroup("newsletter", "list-newsletter")
+	product := newContactGroup("product", "list-product")
+	janeNewsletter := newContactGroupMembership("newsletter-jane", jane, newsletter)
+	bobNewsletter := newContactGroupMembership("newsletter-bob", bob, newsletter)
+	bobProduct := newContactGroupMembership("product-bob", bob, product)
+	bobProduct.Finalizers = []string{"notification.miloapis.com/loops"}
+	bobProduct.DeletionTimestamp = &metav1.Time{Time: time.Now()}
+
+	k8sClient := fake.NewClientBuilder().
+		WithScheme(scheme).
+		WithObjects(jane, bob, ghost, broken, skipped, newsletter, product, janeNewsletter, bobNewsletter, bobProduct).
+		Build()
+
+	loopsAPI := &fakeLoops{
+		contacts: map[string]*loops.Contact{
+			// Matches, the email only differing in case
+			"jane-uid": {UserID: "jane-uid", Email: "Jane@Example.com", Subscribed: true,
+				MailingLists: map[string]bool{"list-newsletter": true, "list-unknown": true}},
+			"bob-uid": {UserID: "bob-uid", Email: "robert@example.com", Subscribed: false,
+				MailingLists: map[string]bool{"list-product": true}},
+		},
+		errs: map[string]error{"broken-uid": errors.New("loops unavailable")},
+	}
+
+	report, err := Drift(ctx, k8sClient, loopsAPI, DriftOptions{})
+	if err == nil {
+		t.Error("Expected an error for the contact that failed to be read")
+	}
+	if report.ContactsScanned != 3 {
+		t.Errorf("Expected 3 contacts scanned, got %d", report.ContactsScanned)
+	}
+
+	expected := []ContactDrift{
+		{
+			Name: "bob", Namespace: "default", UserID: "bob-uid",
+			Fields: []FieldDrift{
+				{Field: "email", Desired: "bob@example.com", Actual: "robert@example.com"},
+				{Field: "subscribed", Desired: "true", Actual: "false"},
+				{Field: "mailingLists.list-newsletter", Desired: "true", Actual: "false"},
+				{Field: "mailingLists.list-product", Desired: "false", Actual: "true"},
+			},
+		},
+		{Name: "ghost", Namespace: "default", UserID: "ghost-uid", Missing: true},
+	}
+	if len(report.Drifted) != len(expected) {
+		t.Fatalf("Expected %d drifted contacts, got %+v", len(expected), report.Drifted)
+	}
+	for i, drift := range report.Drifted {
+		want := expected[i]
+		if drift.Name != want.Name || drift.UserID != want.UserID || drift.Missing != want.Missing {
+			t.Errorf("Expected drifted contact %+v, got %+v", want, drift)
+			continue
+		}
+		if len(drift.Fields) != len(want.Fields) {
+			t.Errorf("Expected fields %+v for %s, got %+v", want.Fields, drift.Name, drift.Fields)
+			continue
+		}
+		for j := range drift.Fields {
+			if drift.Fields[j] != want.Fields[j] {
+				t.Errorf("Expected field %+v for %s, got %+v", want.Fields[j], drift.Name, drift.Fields[j])
+			}
+		}
+	}
+}
+
+func TestDrift_MailingListsFromLabels(t *testing.T) {
+	ctx := context.Background()
+	scheme := runtime.NewScheme()
+	if err := notificationmiloapiscomv1alpha1.AddToScheme(scheme); err != nil {
+		t.Fatalf("failed to add notification scheme: %v", err)
+	}
+
+	jane := newContact("jane", "jane@example.com")
+	jane.Labels = map[string]string{controller.MailingListLabelPrefix + "list-product": "true"}
+	// The newsletter label was removed since the last sync
+	jane.Annotations = map[string]string{"notification.miloapis.com/loops-synced-mailing-lists": "list-newsletter,list-product"}
+	// Memberships are ignored when the mailing lists are synced from the labels
+	newsletter := newContactGroup("newsletter", "list-newsletter")
+	janeNewsletter := newContactGroupMembership("newsletter-jane", jane, newsletter)
+
+	k8sClient := fake.NewClientBuilder().WithScheme(scheme).WithObjects(jane, newsletter, janeNewsletter).Build()
+	loopsAPI := &fakeLoops{
+		contacts: map[string]*loops.Contact{
+			"jane-uid": {UserID: "jane-uid", Email: "jane@example.com", Subscribed: true,
+				MailingLists: map[string]bool{"list-newsletter": true, "list-unmanaged": true}},
+		},
+	}
+
+	report, err := Drift(ctx, k8sClient, loopsAPI, DriftOptions{MailingListSource: controller.MailingListSourceLabels})
+	if err != nil {
+		t.Fatalf("Drift() failed: %v", err)
+	}
+
+	expected := []FieldDrift{
+		{Field: "mailingLists.list-newsletter", Desired: "false", Actual: "true"},
+		{Field: "mailingLists.list-product", Desired: "true", Actual: "false"},
+	}
+	if len(report.Drifted) != 1 || len(report.Drifted[0].Fields) != len(expected) {
+		t.Fatalf("Expected jane to drift on %+v, got %+v", expected, report.Drifted)
+	}
+	for i, field := range report.Drifted[0].Fields {
+		if field != expected[i] {
+			t.Errorf("Expected field %+v, got %+v", expected[i], field)
+		}
+	}
+}
+
+func TestDrift_SyncedFields(t *testing.T) {
+	ctx := context.Background()
+	scheme := runtime.NewScheme()
+	if err := notificationmiloapiscomv1alpha1.AddToScheme(scheme); err != nil {
+		t.Fatalf("failed to add notification scheme: %v", err)
+	}
+
+	jane := newContact("jane", "jane@example.com")
+	jane.Labels = map[string]string{controller.MailingListLabelPrefix + "list-product": "true"}
+	loopsAPI := &fakeLoops{
+		contacts: map[string]*loops.Contact{
+			"jane-uid": {UserID: "jane-uid", Email: "janet@example.com", Subscribed: false},
+		},
+	}
+
+	tests := []struct {
+		name         string
+		syncedFields controller.SyncedFields
+		expected     []string
+	}{
+		{name: "every field", expected: []string{"email", "subscribed", "mailingLists.list-product"}},
+		{
+			name:         "names only",
+			syncedFields: controller.SyncedFields{controller.SyncedFieldFirstName: true},
+		},
+		{
+			name:         "subscription only",
+			syncedFields: controller.SyncedFields{controller.SyncedFieldSubscribed: true},
+			expected:     []string{"subscribed"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			k8sClient := fake.NewClientBuilder().WithScheme(scheme).WithObjects(jane.DeepCopy()).Build()
+			report, err := Drift(ctx, k8sClient, loopsAPI, DriftOptions{
+				MailingListSource: controller.MailingListSourceLabels,
+				SyncedFields:      tt.syncedFields,
+			})
+			if err != nil {
+				t.Fatalf("Drift() failed: %v", err)
+			}
+
+			var fields []string
+			for _, drift := range report.Drifted {
+				for _, field := range drift.Fields {
+					fields = append(fields, field.Field)
+				}
+			}
+			if !slices.Equal(fields, tt.expected) {
+				t.Errorf("Expected drifted fields %v, got %v", tt.expected, fields)
+			}
+		})
+	}
+}
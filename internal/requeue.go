@@ -0,0 +1,17 @@
+package controller
+
+import (
+	"time"
+
+	"k8s.io/apimachinery/pkg/util/wait"
+	ctrl "sigs.k8s.io/controller-runtime"
+)
+
+// conflictRequeueBaseDelay is the minimum delay before retrying after a conflict
+const conflictRequeueBaseDelay = 200 * time.Millisecond
+
+// conflictRequeueResult requeues after a small randomized delay, so that writers contending on the same object do not
+// retry in lockstep and end up in a tight conflict loop.
+func conflictRequeueResult() ctrl.Result {
+	return ctrl.Result{RequeueAfter: wait.Jitter(conflictRequeueBaseDelay, 1.0)}
+}
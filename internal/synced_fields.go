@@ -0,0 +1,81 @@
+package controller
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	loops "go.miloapis.com/email-provider-loops/pkg/loops"
+)
+
+// Contact fields the contact controller can sync to Loops, named after the Loops contact properties
+const (
+	SyncedFieldEmail        = "email"
+	SyncedFieldFirstName    = loops.ContactFieldFirstName
+	SyncedFieldLastName     = loops.ContactFieldLastName
+	SyncedFieldSource       = "source"
+	SyncedFieldSubscribed   = "subscribed"
+	SyncedFieldMailingLists = "mailingLists"
+)
+
+// syncableFields are the valid SyncedFields names
+var syncableFields = []string{
+	SyncedFieldEmail,
+	SyncedFieldFirstName,
+	SyncedFieldLastName,
+	SyncedFieldSource,
+	SyncedFieldSubscribed,
+	SyncedFieldMailingLists,
+}
+
+// SyncedFields is the set of fields the contact controller populates when upserting a Loops contact, e.g. to keep
+// names out of Loops for privacy. The userId is always sent, as it identifies the contact. A nil SyncedFields syncs
+// every field.
+type SyncedFields map[string]bool
+
+// ParseSyncedFields parses a comma-separated list of field names, e.g. "email,subscribed". An empty list syncs every
+// field. Loops creates contacts from their email, so contacts missing from Loops are only created when the email is
+// synced.
+func ParseSyncedFields(text string) (SyncedFields, error) {
+	if strings.TrimSpace(text) == "" {
+		return nil, nil
+	}
+
+	fields := SyncedFields{}
+	for _, field := range strings.Split(text, ",") {
+		field = strings.TrimSpace(field)
+		if !slices.Contains(syncableFields, field) {
+			return nil, fmt.Errorf("unknown synced field %q, must be one of: %s", field, strings.Join(syncableFields, ", "))
+		}
+		fields[field] = true
+	}
+	return fields, nil
+}
+
+// Syncs returns true if the field is populated on upsert.
+func (f SyncedFields) Syncs(field string) bool {
+	return f == nil || f[field]
+}
+
+// apply empties the fields of req that are not synced, so that they are omitted from the payload.
+func (f SyncedFields) apply(req *loops.ContactRequest) {
+	if !f.Syncs(SyncedFieldEmail) {
+		req.Email = ""
+	}
+	if !f.Syncs(SyncedFieldFirstName) {
+		req.FirstName = ""
+	}
+	if !f.Syncs(SyncedFieldLastName) {
+		req.LastName = ""
+	}
+	if !f.Syncs(SyncedFieldSource) {
+		req.Source = ""
+	}
+	if !f.Syncs(SyncedFieldSubscribed) {
+		req.Subscribed = nil
+	}
+	if !f.Syncs(SyncedFieldMailingLists) {
+		req.MailingLists = nil
+	}
+	req.ClearFields = slices.DeleteFunc(req.ClearFields, func(field string) bool { return !f.Syncs(field) })
+}
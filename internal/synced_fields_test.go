@@ -0,0 +1,96 @@
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+func TestParseSyncedFields(t *testing.T) {
+	tests := []struct {
+		name        string
+		text        string
+		expected    []string
+		expectedErr bool
+	}{
+		{name: "empty syncs every field", text: ""},
+		{name: "selected fields", text: "email, subscribed", expected: []string{SyncedFieldEmail, SyncedFieldSubscribed}},
+		{name: "unknown field", text: "email,phone", expectedErr: true},
+		{name: "empty field", text: "email,", expectedErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fields, err := ParseSyncedFields(tt.text)
+			if (err != nil) != tt.expectedErr {
+				t.Fatalf("Expected error %t, got %v", tt.expectedErr, err)
+			}
+			if tt.expectedErr {
+				return
+			}
+			if tt.expected == nil {
+				if fields != nil {
+					t.Errorf("Expected every field to be synced, got %v", fields)
+				}
+				return
+			}
+			if len(fields) != len(tt.expected) {
+				t.Errorf("Expected fields %v, got %v", tt.expected, fields)
+			}
+			for _, field := range tt.expected {
+				if !fields.Syncs(field) {
+					t.Errorf("Expected %s to be synced, got %v", field, fields)
+				}
+			}
+		})
+	}
+}
+
+func TestReconcile_SyncedFields(t *testing.T) {
+	ctx := context.Background()
+	contact := newTestContact("jane")
+	contact.Finalizers = []string{loopsContactFinalizerKey}
+	contact.Spec.GivenName = "Jane"
+	contact.Spec.FamilyName = "Doe"
+
+	r, loopsAPI := newTestContactController(t, contact)
+	fields, err := ParseSyncedFields("email,subscribed")
+	if err != nil {
+		t.Fatalf("ParseSyncedFields() failed: %v", err)
+	}
+	r.SyncedFields = fields
+	if err := r.setupFinalizers(); err != nil {
+		t.Fatalf("setupFinalizers() failed: %v", err)
+	}
+
+	if _, err := r.Reconcile(ctx, ctrl.Request{NamespacedName: client.ObjectKeyFromObject(contact)}); err != nil {
+		t.Fatalf("Reconcile() failed: %v", err)
+	}
+	// Names emptied in Milo are not cleared in Loops either
+	contact.Spec.GivenName = ""
+	if _, err := r.upsertContact(ctx, contact, true); err != nil {
+		t.Fatalf("upsertContact() failed: %v", err)
+	}
+
+	if len(loopsAPI.upserts) != 2 {
+		t.Fatalf("Expected 2 upserts, got %d", len(loopsAPI.upserts))
+	}
+	for _, req := range loopsAPI.upserts {
+		if req.Email != contact.Spec.Email || req.Subscribed == nil || !*req.Subscribed {
+			t.Errorf("Expected the email and subscribed fields to be synced, got %+v", req)
+		}
+		data, err := json.Marshal(req)
+		if err != nil {
+			t.Fatalf("Marshal() failed: %v", err)
+		}
+		for _, field := range []string{SyncedFieldFirstName, SyncedFieldLastName, SyncedFieldSource} {
+			if strings.Contains(string(data), `"`+field+`"`) {
+				t.Errorf("Expected %s to be omitted, got %s", field, data)
+			}
+		}
+	}
+}
@@ -0,0 +1,39 @@
+package controller
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+	ctrl "sigs.k8s.io/controller-runtime"
+)
+
+const tracerName = "go.miloapis.com/email-provider-loops/internal"
+
+// traceReconcile runs reconcile within a span named after the controller when a TracerProvider is set, so that the
+// calls made to Loops are recorded as its children. Without a TracerProvider, reconcile is called as is.
+func traceReconcile(
+	ctx context.Context,
+	provider trace.TracerProvider,
+	controllerName string,
+	req ctrl.Request,
+	reconcile func(context.Context, ctrl.Request) (ctrl.Result, error),
+) (ctrl.Result, error) {
+	if provider == nil {
+		return reconcile(ctx, req)
+	}
+
+	ctx, span := provider.Tracer(tracerName).Start(ctx, controllerName+".Reconcile", trace.WithAttributes(
+		attribute.String("k8s.namespace.name", req.Namespace),
+		attribute.String("k8s.object.name", req.Name),
+	))
+	defer span.End()
+
+	result, err := reconcile(ctx, req)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	return result, err
+}
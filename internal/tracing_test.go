@@ -0,0 +1,38 @@
+package controller
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+func TestReconcile_TracerProvider(t *testing.T) {
+	ctx := context.Background()
+	contact := newTestContact("jane")
+	group := newTestContactGroup("product", "list-product")
+	cgm := newTestContactGroupMembership("product-jane", contact, group, time.Now())
+
+	r, _ := newTestContactGroupMembershipController(t, contact, group, cgm)
+	recorder := tracetest.NewSpanRecorder()
+	r.TracerProvider = sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+
+	req := ctrl.Request{NamespacedName: client.ObjectKeyFromObject(cgm)}
+	for i := 0; i < 2; i++ {
+		if _, err := r.Reconcile(ctx, req); err != nil {
+			t.Fatalf("Reconcile() failed: %v", err)
+		}
+	}
+
+	spans := recorder.Ended()
+	if len(spans) != 2 {
+		t.Fatalf("Expected 2 spans, got %d", len(spans))
+	}
+	if spans[0].Name() != "LoopsContactGroupMembershipController.Reconcile" {
+		t.Errorf("Expected span LoopsContactGroupMembershipController.Reconcile, got %s", spans[0].Name())
+	}
+}
@@ -0,0 +1,6 @@
+package util
+
+// ContactGroupMembershipRemovalFinalizerKey is the finalizer the ContactGroupMembershipRemoval controller holds the
+// removals with until they are applied, suffixed with its instance ID when one is set. The webhook drops it from the
+// removals a resubscribe supersedes, so that deleting them does not apply them.
+const ContactGroupMembershipRemovalFinalizerKey = "notification.miloapis.com/loops-contact-group-membership-removal"
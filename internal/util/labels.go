@@ -0,0 +1,12 @@
+package util
+
+import "maps"
+
+// InheritedLabels returns the labels of an object created on behalf of a contact: the labels of the contact, so that
+// the --label-selector matching the contact matches the object too, overridden by the given labels.
+func InheritedLabels(contactLabels, labels map[string]string) map[string]string {
+	inherited := make(map[string]string, len(contactLabels)+len(labels))
+	maps.Copy(inherited, contactLabels)
+	maps.Copy(inherited, labels)
+	return inherited
+}
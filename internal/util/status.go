@@ -6,6 +6,8 @@ import (
 
 	"github.com/go-logr/logr"
 	"k8s.io/apimachinery/pkg/api/equality"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/client-go/util/retry"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 )
 
@@ -33,3 +35,33 @@ func PatchStatusIfChanged(ctx context.Context, params StatusPatchParams) error {
 
 	return nil
 }
+
+// PatchStatusWithRetry is PatchStatusIfChanged retrying conflicts with the retry.DefaultRetry backoff rather than
+// failing the reconciliation. On a conflict, the latest version of the object is re-fetched into Original, so that
+// the next patch re-applies the status of Object on top of it.
+func PatchStatusWithRetry(ctx context.Context, params StatusPatchParams) error {
+	if equality.Semantic.DeepEqual(params.OldStatus, params.NewStatus) {
+		params.Logger.Info("Resource status unchanged, skipping update")
+		return nil
+	}
+
+	err := retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		err := params.Client.Status().Patch(ctx, params.Object, client.MergeFrom(params.Original), client.FieldOwner(params.FieldOwner))
+		if !apierrors.IsConflict(err) {
+			return err
+		}
+
+		params.Logger.Info("Conflict patching resource status, retrying on the latest version")
+		if getErr := params.Client.Get(ctx, client.ObjectKeyFromObject(params.Object), params.Original); getErr != nil {
+			return getErr
+		}
+		params.Object.SetResourceVersion(params.Original.GetResourceVersion())
+		return err
+	})
+	if err != nil {
+		params.Logger.Error(err, "Failed to patch resource status")
+		return fmt.Errorf("failed to patch resource status: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,94 @@
+package util
+
+import (
+	"context"
+	"testing"
+
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+)
+
+func TestPatchStatusWithRetry(t *testing.T) {
+	tests := []struct {
+		name      string
+		conflicts int
+		wantErr   bool
+	}{
+		{name: "no conflict"},
+		{name: "conflict on the first patch", conflicts: 1},
+		{name: "persistent conflict", conflicts: 100, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			pod := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "pod", Namespace: "default"}}
+
+			patches, gets := 0, 0
+			k8sClient := fake.NewClientBuilder().
+				WithScheme(scheme.Scheme).
+				WithObjects(pod).
+				WithStatusSubresource(&corev1.Pod{}).
+				WithInterceptorFuncs(interceptor.Funcs{
+					Get: func(ctx context.Context, c client.WithWatch, key client.ObjectKey, obj client.Object, opts ...client.GetOption) error {
+						gets++
+						return c.Get(ctx, key, obj, opts...)
+					},
+					SubResourcePatch: func(ctx context.Context, c client.Client, subResourceName string, obj client.Object, patch client.Patch, opts ...client.SubResourcePatchOption) error {
+						patches++
+						if patches <= tt.conflicts {
+							return apierrors.NewConflict(schema.GroupResource{Resource: "pods"}, obj.GetName(), nil)
+						}
+						return c.SubResource(subResourceName).Patch(ctx, obj, patch, opts...)
+					},
+				}).
+				Build()
+
+			current := &corev1.Pod{}
+			if err := k8sClient.Get(ctx, client.ObjectKeyFromObject(pod), current); err != nil {
+				t.Fatalf("Get() failed: %v", err)
+			}
+			gets = 0
+			original := current.DeepCopy()
+			oldStatus := current.Status.DeepCopy()
+			current.Status.Message = "patched"
+
+			err := PatchStatusWithRetry(ctx, StatusPatchParams{
+				Client:     k8sClient,
+				Logger:     logr.Discard(),
+				Object:     current,
+				Original:   original,
+				OldStatus:  oldStatus,
+				NewStatus:  &current.Status,
+				FieldOwner: "test",
+			})
+			if tt.wantErr {
+				if !apierrors.IsConflict(err) {
+					t.Errorf("Expected a conflict error, got %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("PatchStatusWithRetry() failed: %v", err)
+			}
+
+			if patches != tt.conflicts+1 || gets != tt.conflicts {
+				t.Errorf("Expected %d patches and %d re-fetches, got %d and %d", tt.conflicts+1, tt.conflicts, patches, gets)
+			}
+			updated := &corev1.Pod{}
+			if err := k8sClient.Get(ctx, client.ObjectKeyFromObject(pod), updated); err != nil {
+				t.Fatalf("Get() failed: %v", err)
+			}
+			if updated.Status.Message != "patched" {
+				t.Errorf("Expected status message patched, got %q", updated.Status.Message)
+			}
+		})
+	}
+}
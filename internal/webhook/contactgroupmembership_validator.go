@@ -0,0 +1,101 @@
+package webhook
+
+import (
+	"context"
+	"fmt"
+
+	notificationmiloapiscomv1alpha1 "go.miloapis.com/milo/pkg/apis/notification/v1alpha1"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+)
+
+// +kubebuilder:webhook:path=/validate-notification-miloapis-com-v1alpha1-contactgroupmembership,mutating=false,failurePolicy=fail,sideEffects=None,groups=notification.miloapis.com,resources=contactgroupmemberships,verbs=create;update,versions=v1alpha1,name=vcontactgroupmembership-v1alpha1.loops.notification.miloapis.com,admissionReviewVersions=v1
+
+var contactGroupMembershipGroupKind = schema.GroupKind{Group: "notification.miloapis.com", Kind: "ContactGroupMembership"}
+
+// ContactGroupMembershipValidator rejects ContactGroupMemberships whose contact or contact group does not exist, so
+// that typos in the references are caught at admission rather than as failed reconciliations.
+type ContactGroupMembershipValidator struct {
+	Client client.Client
+}
+
+var _ admission.CustomValidator = &ContactGroupMembershipValidator{}
+
+// SetupWithManager registers the validating webhook with the Manager.
+func (v *ContactGroupMembershipValidator) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewWebhookManagedBy(mgr).
+		For(&notificationmiloapiscomv1alpha1.ContactGroupMembership{}).
+		WithValidator(v).
+		Complete()
+}
+
+// ValidateCreate checks that the references of a new ContactGroupMembership resolve.
+func (v *ContactGroupMembershipValidator) ValidateCreate(ctx context.Context, obj runtime.Object) (admission.Warnings, error) {
+	return nil, v.validateReferences(ctx, obj)
+}
+
+// ValidateUpdate checks that the references of an updated ContactGroupMembership resolve, as the contact group may
+// be changed. Updates that keep the references, such as the finalizer removal of a membership whose contact is
+// already gone, and updates of memberships being deleted are allowed.
+func (v *ContactGroupMembershipValidator) ValidateUpdate(ctx context.Context, oldObj, newObj runtime.Object) (admission.Warnings, error) {
+	oldCgm, ok := oldObj.(*notificationmiloapiscomv1alpha1.ContactGroupMembership)
+	if !ok {
+		return nil, fmt.Errorf("expected a ContactGroupMembership, got %T", oldObj)
+	}
+	newCgm, ok := newObj.(*notificationmiloapiscomv1alpha1.ContactGroupMembership)
+	if !ok {
+		return nil, fmt.Errorf("expected a ContactGroupMembership, got %T", newObj)
+	}
+	if !newCgm.DeletionTimestamp.IsZero() ||
+		(oldCgm.Spec.ContactRef == newCgm.Spec.ContactRef && oldCgm.Spec.ContactGroupRef == newCgm.Spec.ContactGroupRef) {
+		return nil, nil
+	}
+	return nil, v.validateReferences(ctx, newObj)
+}
+
+// ValidateDelete allows every deletion, including of memberships whose references are already gone.
+func (v *ContactGroupMembershipValidator) ValidateDelete(_ context.Context, _ runtime.Object) (admission.Warnings, error) {
+	return nil, nil
+}
+
+func (v *ContactGroupMembershipValidator) validateReferences(ctx context.Context, obj runtime.Object) error {
+	cgm, ok := obj.(*notificationmiloapiscomv1alpha1.ContactGroupMembership)
+	if !ok {
+		return fmt.Errorf("expected a ContactGroupMembership, got %T", obj)
+	}
+	log := logf.FromContext(ctx).WithValues("contactGroupMembership", client.ObjectKeyFromObject(cgm))
+
+	var errs field.ErrorList
+
+	contactPath := field.NewPath("spec", "contactRef")
+	contactKey := client.ObjectKey{Namespace: cgm.Spec.ContactRef.Namespace, Name: cgm.Spec.ContactRef.Name}
+	if err := v.Client.Get(ctx, contactKey, &notificationmiloapiscomv1alpha1.Contact{}); err != nil {
+		if !apierrors.IsNotFound(err) {
+			log.Error(err, "Failed to get Contact")
+			return apierrors.NewInternalError(fmt.Errorf("failed to get Contact: %w", err))
+		}
+		errs = append(errs, field.NotFound(contactPath, contactKey.String()))
+	}
+
+	groupPath := field.NewPath("spec", "contactGroupRef")
+	groupKey := client.ObjectKey{Namespace: cgm.Spec.ContactGroupRef.Namespace, Name: cgm.Spec.ContactGroupRef.Name}
+	if err := v.Client.Get(ctx, groupKey, &notificationmiloapiscomv1alpha1.ContactGroup{}); err != nil {
+		if !apierrors.IsNotFound(err) {
+			log.Error(err, "Failed to get ContactGroup")
+			return apierrors.NewInternalError(fmt.Errorf("failed to get ContactGroup: %w", err))
+		}
+		errs = append(errs, field.NotFound(groupPath, groupKey.String()))
+	}
+
+	if len(errs) > 0 {
+		return apierrors.NewInvalid(contactGroupMembershipGroupKind, cgm.Name, errs)
+	}
+	return nil
+}
@@ -0,0 +1,122 @@
+package webhook
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	notificationmiloapiscomv1alpha1 "go.miloapis.com/milo/pkg/apis/notification/v1alpha1"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+func newTestMembership(contactName, groupName string) *notificationmiloapiscomv1alpha1.ContactGroupMembership {
+	return &notificationmiloapiscomv1alpha1.ContactGroupMembership{
+		ObjectMeta: metav1.ObjectMeta{Name: "newsletter-jane", Namespace: "default"},
+		Spec: notificationmiloapiscomv1alpha1.ContactGroupMembershipSpec{
+			ContactRef:      notificationmiloapiscomv1alpha1.ContactReference{Name: contactName, Namespace: "default"},
+			ContactGroupRef: notificationmiloapiscomv1alpha1.ContactGroupReference{Name: groupName, Namespace: "default"},
+		},
+	}
+}
+
+func TestContactGroupMembershipValidator(t *testing.T) {
+	tests := []struct {
+		name        string
+		objs        []client.Object
+		membership  *notificationmiloapiscomv1alpha1.ContactGroupMembership
+		wantInvalid []string
+	}{
+		{
+			name:       "references resolve",
+			objs:       []client.Object{newTestContact(), newTestContactGroup()},
+			membership: newTestMembership("jane", "newsletter"),
+		},
+		{
+			name:        "missing contact",
+			objs:        []client.Object{newTestContactGroup()},
+			membership:  newTestMembership("jane", "newsletter"),
+			wantInvalid: []string{"spec.contactRef"},
+		},
+		{
+			name:        "missing group",
+			objs:        []client.Object{newTestContact()},
+			membership:  newTestMembership("jane", "newsletter"),
+			wantInvalid: []string{"spec.contactGroupRef"},
+		},
+		{
+			name:        "missing contact and group",
+			membership:  newTestMembership("jnae", "newsleter"),
+			wantInvalid: []string{"spec.contactRef", "spec.contactGroupRef"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			v := &ContactGroupMembershipValidator{Client: newTestClient(t, tt.objs...)}
+
+			_, createErr := v.ValidateCreate(ctx, tt.membership)
+			// An update changing the references validates them
+			_, updateErr := v.ValidateUpdate(ctx, newTestMembership("john", "announcements"), tt.membership)
+			for _, err := range []error{createErr, updateErr} {
+				if len(tt.wantInvalid) == 0 {
+					if err != nil {
+						t.Errorf("Expected no error, got %v", err)
+					}
+					continue
+				}
+				if !apierrors.IsInvalid(err) {
+					t.Fatalf("Expected an Invalid error, got %v", err)
+				}
+				for _, field := range tt.wantInvalid {
+					if !strings.Contains(err.Error(), field) {
+						t.Errorf("Expected the error to report %s, got %v", field, err)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestContactGroupMembershipValidator_AllowsUpdatesKeepingReferences(t *testing.T) {
+	deleting := newTestMembership("jane", "newsletter")
+	deleting.DeletionTimestamp = &metav1.Time{Time: time.Now()}
+
+	tests := []struct {
+		name       string
+		oldObj     *notificationmiloapiscomv1alpha1.ContactGroupMembership
+		membership *notificationmiloapiscomv1alpha1.ContactGroupMembership
+	}{
+		{
+			name:       "references unchanged",
+			oldObj:     newTestMembership("jane", "newsletter"),
+			membership: newTestMembership("jane", "newsletter"),
+		},
+		{
+			name:       "membership being deleted",
+			oldObj:     newTestMembership("john", "announcements"),
+			membership: deleting,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Neither the contact nor the group exists
+			v := &ContactGroupMembershipValidator{Client: newTestClient(t)}
+			if _, err := v.ValidateUpdate(context.Background(), tt.oldObj, tt.membership); err != nil {
+				t.Errorf("Expected the update to be allowed, got %v", err)
+			}
+		})
+	}
+}
+
+func TestContactGroupMembershipValidator_AllowsDeletion(t *testing.T) {
+	v := &ContactGroupMembershipValidator{Client: newTestClient(t)}
+	if _, err := v.ValidateDelete(context.Background(), newTestMembership("jane", "newsletter")); err != nil {
+		t.Errorf("Expected deletion to be allowed, got %v", err)
+	}
+}
@@ -3,7 +3,11 @@ package webhook
 import (
 	"context"
 	"fmt"
+	"strings"
+	"time"
 
+	"go.miloapis.com/email-provider-loops/internal/util"
+	"go.miloapis.com/email-provider-loops/pkg/loops"
 	notificationmiloapiscomv1alpha1 "go.miloapis.com/milo/pkg/apis/notification/v1alpha1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -11,29 +15,42 @@ import (
 	logf "sigs.k8s.io/controller-runtime/pkg/log"
 )
 
+// CreatedByLabel marks the provenance of the objects created on behalf of Loops webhook events, so that the garbage
+// collection and reconcilers can tell them apart from the ones created by users.
+const CreatedByLabel = "notification.miloapis.com/created-by"
+
+// CreatedByLoopsWebhook is the CreatedByLabel value of the objects created by this webhook.
+const CreatedByLoopsWebhook = "loops-webhook"
+
+// EventTimeAnnotation records, in RFC 3339, the time of the latest Loops event this webhook processed for the
+// object's contact and group. Older events delivered out of order are ignored.
+const EventTimeAnnotation = "notification.miloapis.com/loops-event-time"
+
 // +kubebuilder:rbac:groups=events.k8s.io,resources=events,verbs=create
 // +kubebuilder:rbac:groups=notification.miloapis.com,resources=contacts,verbs=get;list
+// +kubebuilder:rbac:groups=notification.miloapis.com,resources=contactgroupmemberships,verbs=list;create;patch
+// +kubebuilder:rbac:groups=notification.miloapis.com,resources=contactgroupmembershipremovals,verbs=list;create;patch;delete
 
 func NewLoopsContactGroupMembershipWebhookV1(k8sClient client.Client, signingSecret string) *Webhook {
 	return &Webhook{
 		Handler: HandlerFunc(func(ctx context.Context, req Request) Response {
 			log := logf.FromContext(ctx).WithName("loops-webhook-handler")
 
-			userUID := req.BaseEvent.ContactIdentity.UserID
-			if userUID == "" {
-				log.Info("ContactIdentity.UserID is empty, cannot find contact")
+			identity := req.BaseEvent.ContactIdentity
+			if identity.UserID == "" && identity.Email == "" {
+				log.Info("ContactIdentity.UserID and ContactIdentity.Email are empty, cannot find contact")
 				return BadRequestResponse()
 			}
 
-			contact, err := getContactByProviderID(ctx, k8sClient, userUID)
+			contact, err := getContactByIdentity(ctx, k8sClient, identity)
 			if err != nil {
-				log.Error(err, "Failed to get contact by user UID",
-					"userUID", userUID)
+				log.Error(err, "Failed to get contact by identity",
+					"userID", identity.UserID)
 				return InternalServerErrorResponse()
 			}
 			if contact == nil {
-				log.Info("Contact not found for user UID",
-					"userID", userUID)
+				log.Info("Contact not found for identity",
+					"userID", identity.UserID)
 				return BadRequestResponse()
 			}
 			log.Info("Found contact for webhook event", "contactName", contact.Name, "contactNamespace", contact.Namespace, "contactUID", contact.UID)
@@ -65,7 +82,7 @@ func NewLoopsContactGroupMembershipWebhookV1(k8sClient client.Client, signingSec
 
 			// Handle mailing list subscribed event
 			if req.MailingListSubscribedEvent != nil {
-				log.Info("Processing SUBSCRIBED event")
+				log.Info("Processing SUBSCRIBED event", "eventName", req.BaseEvent.EventName)
 
 				// Get assoaciate contact group memebership removal
 				removal, err := getContactGroupMembershipRemoval(ctx, k8sClient, contact, group)
@@ -74,6 +91,12 @@ func NewLoopsContactGroupMembershipWebhookV1(k8sClient client.Client, signingSec
 					return InternalServerErrorResponse()
 				}
 
+				// Ignore a subscribe delivered after a newer unsubscribe
+				if removal != nil && isStaleEvent(removal, req.BaseEvent.EventTime) {
+					log.Info("Ignoring subscribe older than the last processed unsubscribe", "eventTime", req.BaseEvent.EventTime, "lastEventTime", removal.Annotations[EventTimeAnnotation])
+					return OkResponse()
+				}
+
 				// If there is a removal, we need to delete it
 				if removal != nil {
 					log.Info("Contact group membership removal found, deleting", "contactName", removal.Spec.ContactRef.Name, "contactNamespace", removal.Spec.ContactRef.Namespace)
@@ -86,8 +109,23 @@ func NewLoopsContactGroupMembershipWebhookV1(k8sClient client.Client, signingSec
 					log.Info("Contact group membership removal not found, continuing")
 				}
 
+				membership, err := getContactGroupMembership(ctx, k8sClient, contact, group)
+				if err != nil {
+					log.Error(err, "Failed to get contact group membership", "contactName", contact.Name, "contactNamespace", contact.Namespace, "groupID", groupID)
+					return InternalServerErrorResponse()
+				}
+
+				// Record the subscribe on an existing membership, so that older unsubscribes are ignored
+				if membership != nil {
+					if err := recordEventTime(ctx, k8sClient, membership, req.BaseEvent.EventTime); err != nil {
+						log.Error(err, "Failed to record event time on contact group membership", "membershipName", membership.Name, "membershipNamespace", membership.Namespace)
+						return InternalServerErrorResponse()
+					}
+					return OkResponse()
+				}
+
 				// Create the corresponding contact group membership
-				err = createContactGroupMembership(ctx, k8sClient, contact, group)
+				err = createContactGroupMembership(ctx, k8sClient, contact, group, req.BaseEvent.EventTime)
 				if err != nil && !apierrors.IsAlreadyExists(err) {
 					log.Error(err, "Failed to create contact group membership")
 					return InternalServerErrorResponse()
@@ -100,6 +138,17 @@ func NewLoopsContactGroupMembershipWebhookV1(k8sClient client.Client, signingSec
 			if req.MailingListUnsubscribedEvent != nil {
 				log.Info("Processing UNSUBSCRIBED event")
 
+				// Ignore an unsubscribe delivered after a newer subscribe
+				membership, err := getContactGroupMembership(ctx, k8sClient, contact, group)
+				if err != nil {
+					log.Error(err, "Failed to get contact group membership", "contactName", contact.Name, "contactNamespace", contact.Namespace, "groupID", groupID)
+					return InternalServerErrorResponse()
+				}
+				if membership != nil && isStaleEvent(membership, req.BaseEvent.EventTime) {
+					log.Info("Ignoring unsubscribe older than the last processed subscribe", "eventTime", req.BaseEvent.EventTime, "lastEventTime", membership.Annotations[EventTimeAnnotation])
+					return OkResponse()
+				}
+
 				// Get assoaciate contact group memebership removal
 				removal, err := getContactGroupMembershipRemoval(ctx, k8sClient, contact, group)
 				if err != nil && !apierrors.IsNotFound(err) {
@@ -109,9 +158,13 @@ func NewLoopsContactGroupMembershipWebhookV1(k8sClient client.Client, signingSec
 
 				if removal != nil {
 					log.Info("Contact group membership removal found, skiping creation", "contactName", removal.Spec.ContactRef.Name, "contactNamespace", removal.Spec.ContactRef.Namespace)
+					if err := recordEventTime(ctx, k8sClient, removal, req.BaseEvent.EventTime); err != nil {
+						log.Error(err, "Failed to record event time on contact group membership removal", "removalName", removal.Name, "removalNamespace", removal.Namespace)
+						return InternalServerErrorResponse()
+					}
 					return OkResponse()
 				} else {
-					err := createContactGroupMembershipRemoval(ctx, k8sClient, contact, group)
+					err := createContactGroupMembershipRemoval(ctx, k8sClient, contact, group, req.BaseEvent.EventTime)
 					if err != nil {
 						log.Error(err, "Failed to create contact group membership removal", "contactName", contact.Name, "contactNamespace", contact.Namespace, "groupID", groupID)
 						return InternalServerErrorResponse()
@@ -153,6 +206,46 @@ func getContactByProviderID(ctx context.Context, k8sClient client.Client, provid
 	return &contactList.Items[0], nil
 }
 
+// getContactByIdentity retrieves the Contact of a webhook event by its Loops userId, falling back to its normalized
+// email for contacts Loops knows without a userId only. A userId matching no Contact is not resolved by email, as the
+// Contact with that email may be another one.
+func getContactByIdentity(ctx context.Context, k8sClient client.Client, identity loops.ContactIdentity) (*notificationmiloapiscomv1alpha1.Contact, error) {
+	if identity.UserID != "" {
+		return getContactByProviderID(ctx, k8sClient, identity.UserID)
+	}
+
+	email := loops.NormalizeEmail(identity.Email)
+	if email == "" {
+		return nil, nil
+	}
+	return getContactByEmail(ctx, k8sClient, email)
+}
+
+// getContactByEmail retrieves a Contact by its normalized spec.email field using the indexed field. An email shared by
+// several Contacts does not resolve to any of them.
+func getContactByEmail(ctx context.Context, k8sClient client.Client, email string) (*notificationmiloapiscomv1alpha1.Contact, error) {
+	log := logf.FromContext(ctx)
+
+	var contactList notificationmiloapiscomv1alpha1.ContactList
+	if err := k8sClient.List(ctx, &contactList,
+		client.MatchingFields{contactEmailIndexKey: email},
+	); err != nil {
+		return nil, err
+	}
+
+	if len(contactList.Items) == 0 {
+		return nil, nil
+	}
+
+	if len(contactList.Items) > 1 {
+		log.Info("Multiple contacts found with same email, cannot tell which one the event is for",
+			"count", len(contactList.Items))
+		return nil, nil
+	}
+
+	return &contactList.Items[0], nil
+}
+
 // getContactGroupByProviderID retrieves a ContactGroup by its spec.providers.loops.providerID field using the indexed field
 func getContactGroupByProviderID(ctx context.Context, k8sClient client.Client, providerID string) (*notificationmiloapiscomv1alpha1.ContactGroup, error) {
 	log := logf.FromContext(ctx)
@@ -178,13 +271,15 @@ func getContactGroupByProviderID(ctx context.Context, k8sClient client.Client, p
 }
 
 // CreateContactGroupMembership creates a ContactGroupMembership in Kubernetes
-func createContactGroupMembership(ctx context.Context, k8sClient client.Client, contact *notificationmiloapiscomv1alpha1.Contact, group *notificationmiloapiscomv1alpha1.ContactGroup) error {
+func createContactGroupMembership(ctx context.Context, k8sClient client.Client, contact *notificationmiloapiscomv1alpha1.Contact, group *notificationmiloapiscomv1alpha1.ContactGroup, eventTime int64) error {
 	log := logf.FromContext(ctx)
 
 	contactGroupMembership := &notificationmiloapiscomv1alpha1.ContactGroupMembership{
 		ObjectMeta: metav1.ObjectMeta{
 			GenerateName: fmt.Sprintf("%s-%s", group.Name, contact.Name),
 			Namespace:    group.Namespace,
+			Labels:       util.InheritedLabels(contact.Labels, map[string]string{CreatedByLabel: CreatedByLoopsWebhook}),
+			Annotations:  eventTimeAnnotations(eventTime),
 		},
 		Spec: notificationmiloapiscomv1alpha1.ContactGroupMembershipSpec{
 			ContactRef: notificationmiloapiscomv1alpha1.ContactReference{
@@ -206,6 +301,34 @@ func createContactGroupMembership(ctx context.Context, k8sClient client.Client,
 	return nil
 }
 
+// getContactGroupMembership retrieves a ContactGroupMembership by its spec.contactRef and spec.contactGroupRef using the indexed field
+func getContactGroupMembership(ctx context.Context, k8sClient client.Client, contact *notificationmiloapiscomv1alpha1.Contact, group *notificationmiloapiscomv1alpha1.ContactGroup) (*notificationmiloapiscomv1alpha1.ContactGroupMembership, error) {
+	log := logf.FromContext(ctx)
+
+	var membershipList notificationmiloapiscomv1alpha1.ContactGroupMembershipList
+	if err := k8sClient.List(ctx, &membershipList,
+		client.MatchingFields{groupMembershipIndexKey: buildGroupMembershipRemovalIndexKey(&notificationmiloapiscomv1alpha1.ContactReference{
+			Name:      contact.Name,
+			Namespace: contact.Namespace,
+		}, &notificationmiloapiscomv1alpha1.ContactGroupReference{
+			Name:      group.Name,
+			Namespace: group.Namespace,
+		})},
+	); err != nil {
+		return nil, err
+	}
+
+	if len(membershipList.Items) == 0 {
+		return nil, nil
+	}
+
+	if len(membershipList.Items) > 1 {
+		log.Info("Multiple contact group memberships found with same contact and group, using first one", "contactName", contact.Name, "contactNamespace", contact.Namespace, "groupName", group.Name, "groupNamespace", group.Namespace, "count", len(membershipList.Items))
+	}
+
+	return &membershipList.Items[0], nil
+}
+
 // GetContactGroupMembershipRemoval retrieves a ContactGroupMembershipRemoval by its spec.contactRef and spec.contactGroupRef using the indexed field
 func getContactGroupMembershipRemoval(ctx context.Context, k8sClient client.Client, contact *notificationmiloapiscomv1alpha1.Contact, group *notificationmiloapiscomv1alpha1.ContactGroup) (*notificationmiloapiscomv1alpha1.ContactGroupMembershipRemoval, error) {
 	log := logf.FromContext(ctx)
@@ -234,10 +357,25 @@ func getContactGroupMembershipRemoval(ctx context.Context, k8sClient client.Clie
 	return &removalList.Items[0], nil
 }
 
-// DeleteContactGroupMembershipRemoval deletes a ContactGroupMembershipRemoval by its name and namespace
+// DeleteContactGroupMembershipRemoval deletes a ContactGroupMembershipRemoval by its name and namespace. The removal is
+// superseded by a resubscribe, so its finalizer is dropped first, which would otherwise still apply it.
 func deleteContactGroupMembershipRemoval(ctx context.Context, k8sClient client.Client, removal *notificationmiloapiscomv1alpha1.ContactGroupMembershipRemoval) error {
 	log := logf.FromContext(ctx)
 
+	var finalizers []string
+	for _, f := range removal.Finalizers {
+		if !strings.HasPrefix(f, util.ContactGroupMembershipRemovalFinalizerKey) {
+			finalizers = append(finalizers, f)
+		}
+	}
+	if len(finalizers) != len(removal.Finalizers) {
+		released := removal.DeepCopy()
+		released.Finalizers = finalizers
+		if err := k8sClient.Patch(ctx, released, client.MergeFrom(removal)); err != nil {
+			return fmt.Errorf("failed to drop the finalizer of the contact group membership removal: %w", err)
+		}
+	}
+
 	if err := k8sClient.Delete(ctx, removal); err != nil {
 		return err
 	}
@@ -247,13 +385,15 @@ func deleteContactGroupMembershipRemoval(ctx context.Context, k8sClient client.C
 }
 
 // CreateContactGroupMembershipRemoval creates a ContactGroupMembershipRemoval in Kubernetes
-func createContactGroupMembershipRemoval(ctx context.Context, k8sClient client.Client, contact *notificationmiloapiscomv1alpha1.Contact, group *notificationmiloapiscomv1alpha1.ContactGroup) error {
+func createContactGroupMembershipRemoval(ctx context.Context, k8sClient client.Client, contact *notificationmiloapiscomv1alpha1.Contact, group *notificationmiloapiscomv1alpha1.ContactGroup, eventTime int64) error {
 	log := logf.FromContext(ctx)
 
 	removal := &notificationmiloapiscomv1alpha1.ContactGroupMembershipRemoval{
 		ObjectMeta: metav1.ObjectMeta{
 			GenerateName: fmt.Sprintf("%s-%s", group.Name, contact.Name),
 			Namespace:    group.Namespace,
+			Labels:       util.InheritedLabels(contact.Labels, map[string]string{CreatedByLabel: CreatedByLoopsWebhook}),
+			Annotations:  eventTimeAnnotations(eventTime),
 		},
 		Spec: notificationmiloapiscomv1alpha1.ContactGroupMembershipRemovalSpec{
 			ContactRef: notificationmiloapiscomv1alpha1.ContactReference{
@@ -274,3 +414,45 @@ func createContactGroupMembershipRemoval(ctx context.Context, k8sClient client.C
 	log.Info("Created contact group membership removal", "removalName", removal.Name, "removalNamespace", removal.Namespace)
 	return nil
 }
+
+// eventTimeAnnotations returns the annotations recording the Unix time, in seconds, of a Loops event. Events without a
+// time get none.
+func eventTimeAnnotations(eventTime int64) map[string]string {
+	if eventTime <= 0 {
+		return nil
+	}
+	return map[string]string{EventTimeAnnotation: time.Unix(eventTime, 0).UTC().Format(time.RFC3339)}
+}
+
+// recordedEventTime returns the Unix time of the EventTimeAnnotation of an object, or 0 when it has none.
+func recordedEventTime(obj client.Object) int64 {
+	recorded, err := time.Parse(time.RFC3339, obj.GetAnnotations()[EventTimeAnnotation])
+	if err != nil {
+		return 0
+	}
+	return recorded.Unix()
+}
+
+// isStaleEvent reports whether an event happened before the last one recorded on an object. Events without a time,
+// and objects without a recorded time, are never stale.
+func isStaleEvent(obj client.Object, eventTime int64) bool {
+	return eventTime > 0 && eventTime < recordedEventTime(obj)
+}
+
+// recordEventTime patches the EventTimeAnnotation of an object when the event is newer than the recorded one.
+func recordEventTime(ctx context.Context, k8sClient client.Client, obj client.Object, eventTime int64) error {
+	if eventTime <= recordedEventTime(obj) {
+		return nil
+	}
+
+	orig := obj.DeepCopyObject().(client.Object)
+	annotations := obj.GetAnnotations()
+	if annotations == nil {
+		annotations = map[string]string{}
+	}
+	for key, value := range eventTimeAnnotations(eventTime) {
+		annotations[key] = value
+	}
+	obj.SetAnnotations(annotations)
+	return k8sClient.Patch(ctx, obj, client.MergeFrom(orig))
+}
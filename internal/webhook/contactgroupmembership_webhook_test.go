@@ -0,0 +1,401 @@
+package webhook
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"go.miloapis.com/email-provider-loops/internal/util"
+	"go.miloapis.com/email-provider-loops/pkg/loops"
+	notificationmiloapiscomv1alpha1 "go.miloapis.com/milo/pkg/apis/notification/v1alpha1"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+func newTestContact() *notificationmiloapiscomv1alpha1.Contact {
+	return &notificationmiloapiscomv1alpha1.Contact{
+		ObjectMeta: metav1.ObjectMeta{Name: "jane", Namespace: "default", UID: types.UID("jane-uid")},
+	}
+}
+
+func newTestContactGroup() *notificationmiloapiscomv1alpha1.ContactGroup {
+	return &notificationmiloapiscomv1alpha1.ContactGroup{
+		ObjectMeta: metav1.ObjectMeta{Name: "newsletter", Namespace: "default"},
+		Spec: notificationmiloapiscomv1alpha1.ContactGroupSpec{
+			Providers: []notificationmiloapiscomv1alpha1.ContactGroupProvider{{Name: "Loops", ID: "list-abc"}},
+		},
+	}
+}
+
+func newTestEvent(eventName string) loops.MailingListSubscribedEvent {
+	return loops.MailingListSubscribedEvent{
+		WebhookEvent: loops.WebhookEvent{
+			EventName:            eventName,
+			EventTime:            1700000000,
+			WebhookSchemaVersion: "1.0.0",
+			ContactIdentity:      loops.ContactIdentity{ID: "loops-id", Email: "jane@example.com", UserID: "jane-uid"},
+		},
+		MailingList: loops.MailingList{ID: "list-abc", Name: "Newsletter"},
+	}
+}
+
+func TestContactGroupMembershipWebhook_SubscribeUnsubscribe(t *testing.T) {
+	ctx := context.Background()
+	k8sClient := newTestClient(t, newTestContact(), newTestContactGroup())
+	wh := NewLoopsContactGroupMembershipWebhookV1(k8sClient, testSigningSecret)
+
+	// Unsubscribing records a removal
+	if resp := serveEvent(t, wh, testSigningSecret, newTestEvent(loops.EventNameMailingListUnsubscribed)); resp.HttpStatus != http.StatusOK {
+		t.Fatalf("Expected status 200 for unsubscribe, got %d", resp.HttpStatus)
+	}
+	assertCount(t, k8sClient, &notificationmiloapiscomv1alpha1.ContactGroupMembershipRemovalList{}, 1)
+
+	// Subscribing deletes the removal and creates a membership
+	if resp := serveEvent(t, wh, testSigningSecret, newTestEvent(loops.EventNameMailingListSubscribed)); resp.HttpStatus != http.StatusOK {
+		t.Fatalf("Expected status 200 for subscribe, got %d", resp.HttpStatus)
+	}
+	assertCount(t, k8sClient, &notificationmiloapiscomv1alpha1.ContactGroupMembershipRemovalList{}, 0)
+	assertCount(t, k8sClient, &notificationmiloapiscomv1alpha1.ContactGroupMembershipList{}, 1)
+
+	var memberships notificationmiloapiscomv1alpha1.ContactGroupMembershipList
+	if err := k8sClient.List(ctx, &memberships); err != nil {
+		t.Fatalf("List() failed: %v", err)
+	}
+	if memberships.Items[0].Spec.ContactRef.Name != "jane" || memberships.Items[0].Spec.ContactGroupRef.Name != "newsletter" {
+		t.Errorf("Unexpected membership spec: %+v", memberships.Items[0].Spec)
+	}
+}
+
+func TestContactGroupMembershipWebhook_SubscribeReleasesRemoval(t *testing.T) {
+	ctx := context.Background()
+	k8sClient := newTestClient(t, newTestContact(), newTestContactGroup())
+	wh := NewLoopsContactGroupMembershipWebhookV1(k8sClient, testSigningSecret)
+
+	if resp := serveEvent(t, wh, testSigningSecret, newTestEvent(loops.EventNameMailingListUnsubscribed)); resp.HttpStatus != http.StatusOK {
+		t.Fatalf("Expected status 200 for unsubscribe, got %d", resp.HttpStatus)
+	}
+
+	// The controller holds the removal until it is applied
+	var removals notificationmiloapiscomv1alpha1.ContactGroupMembershipRemovalList
+	if err := k8sClient.List(ctx, &removals); err != nil {
+		t.Fatalf("List() failed: %v", err)
+	}
+	removal := &removals.Items[0]
+	removal.Finalizers = []string{util.ContactGroupMembershipRemovalFinalizerKey + "-instance", "example.com/other"}
+	if err := k8sClient.Update(ctx, removal); err != nil {
+		t.Fatalf("Update() failed: %v", err)
+	}
+
+	// A resubscribe supersedes the removal, which is released without being applied
+	if resp := serveEvent(t, wh, testSigningSecret, newTestEvent(loops.EventNameMailingListSubscribed)); resp.HttpStatus != http.StatusOK {
+		t.Fatalf("Expected status 200 for subscribe, got %d", resp.HttpStatus)
+	}
+	if err := k8sClient.Get(ctx, client.ObjectKeyFromObject(removal), removal); err != nil {
+		t.Fatalf("Get() failed: %v", err)
+	}
+	if removal.DeletionTimestamp.IsZero() {
+		t.Error("Expected the removal to be deleted")
+	}
+	if len(removal.Finalizers) != 1 || removal.Finalizers[0] != "example.com/other" {
+		t.Errorf("Expected only the removal finalizer to be dropped, got %v", removal.Finalizers)
+	}
+}
+
+func TestContactGroupMembershipWebhook_CreatedByLabel(t *testing.T) {
+	ctx := context.Background()
+	k8sClient := newTestClient(t, newTestContact(), newTestContactGroup())
+	wh := NewLoopsContactGroupMembershipWebhookV1(k8sClient, testSigningSecret)
+
+	if resp := serveEvent(t, wh, testSigningSecret, newTestEvent(loops.EventNameMailingListUnsubscribed)); resp.HttpStatus != http.StatusOK {
+		t.Fatalf("Expected status 200 for unsubscribe, got %d", resp.HttpStatus)
+	}
+	var removals notificationmiloapiscomv1alpha1.ContactGroupMembershipRemovalList
+	if err := k8sClient.List(ctx, &removals, client.MatchingLabels{CreatedByLabel: CreatedByLoopsWebhook}); err != nil {
+		t.Fatalf("List() failed: %v", err)
+	}
+	if len(removals.Items) != 1 {
+		t.Errorf("Expected 1 removal labeled as created by the webhook, got %d", len(removals.Items))
+	}
+
+	if resp := serveEvent(t, wh, testSigningSecret, newTestEvent(loops.EventNameMailingListSubscribed)); resp.HttpStatus != http.StatusOK {
+		t.Fatalf("Expected status 200 for subscribe, got %d", resp.HttpStatus)
+	}
+	var memberships notificationmiloapiscomv1alpha1.ContactGroupMembershipList
+	if err := k8sClient.List(ctx, &memberships, client.MatchingLabels{CreatedByLabel: CreatedByLoopsWebhook}); err != nil {
+		t.Fatalf("List() failed: %v", err)
+	}
+	if len(memberships.Items) != 1 {
+		t.Errorf("Expected 1 membership labeled as created by the webhook, got %d", len(memberships.Items))
+	}
+}
+
+func TestContactGroupMembershipWebhook_EventTimeAnnotation(t *testing.T) {
+	ctx := context.Background()
+	k8sClient := newTestClient(t, newTestContact(), newTestContactGroup())
+	wh := NewLoopsContactGroupMembershipWebhookV1(k8sClient, testSigningSecret)
+	// newTestEvent happens at 1700000000
+	const expected = "2023-11-14T22:13:20Z"
+
+	if resp := serveEvent(t, wh, testSigningSecret, newTestEvent(loops.EventNameMailingListUnsubscribed)); resp.HttpStatus != http.StatusOK {
+		t.Fatalf("Expected status 200 for unsubscribe, got %d", resp.HttpStatus)
+	}
+	var removals notificationmiloapiscomv1alpha1.ContactGroupMembershipRemovalList
+	if err := k8sClient.List(ctx, &removals); err != nil {
+		t.Fatalf("List() failed: %v", err)
+	}
+	if len(removals.Items) != 1 || removals.Items[0].Annotations[EventTimeAnnotation] != expected {
+		t.Errorf("Expected 1 removal with event time %s, got %+v", expected, removals.Items)
+	}
+
+	subscribed := newTestEvent(loops.EventNameMailingListSubscribed)
+	subscribed.EventTime = 1700000060
+	if resp := serveEvent(t, wh, testSigningSecret, subscribed); resp.HttpStatus != http.StatusOK {
+		t.Fatalf("Expected status 200 for subscribe, got %d", resp.HttpStatus)
+	}
+	var memberships notificationmiloapiscomv1alpha1.ContactGroupMembershipList
+	if err := k8sClient.List(ctx, &memberships); err != nil {
+		t.Fatalf("List() failed: %v", err)
+	}
+	if len(memberships.Items) != 1 || memberships.Items[0].Annotations[EventTimeAnnotation] != "2023-11-14T22:14:20Z" {
+		t.Errorf("Expected 1 membership with event time 2023-11-14T22:14:20Z, got %+v", memberships.Items)
+	}
+}
+
+func TestContactGroupMembershipWebhook_OutOfOrderEvents(t *testing.T) {
+	at := func(name string, eventTime int64) loops.MailingListSubscribedEvent {
+		event := newTestEvent(name)
+		event.EventTime = eventTime
+		return event
+	}
+
+	tests := []struct {
+		name               string
+		events             []any
+		expectedMembers    int
+		expectedRemovals   int
+		expectedRecordedAt string
+	}{
+		{
+			name: "unsubscribe older than the processed subscribe is ignored",
+			events: []any{
+				at(loops.EventNameMailingListSubscribed, 1700000060),
+				at(loops.EventNameMailingListUnsubscribed, 1700000000),
+			},
+			expectedMembers:  1,
+			expectedRemovals: 0,
+		},
+		{
+			name: "subscribe older than the processed unsubscribe is ignored",
+			events: []any{
+				at(loops.EventNameMailingListUnsubscribed, 1700000060),
+				at(loops.EventNameMailingListSubscribed, 1700000000),
+			},
+			expectedMembers:  0,
+			expectedRemovals: 1,
+		},
+		{
+			name: "newer unsubscribe is applied",
+			events: []any{
+				at(loops.EventNameMailingListSubscribed, 1700000000),
+				at(loops.EventNameMailingListUnsubscribed, 1700000060),
+			},
+			expectedMembers:  1,
+			expectedRemovals: 1,
+		},
+		{
+			name: "repeated subscribe records the newest time",
+			events: []any{
+				at(loops.EventNameMailingListSubscribed, 1700000000),
+				at(loops.EventNameMailingListSubscribed, 1700000120),
+				at(loops.EventNameMailingListUnsubscribed, 1700000060),
+			},
+			expectedMembers:    1,
+			expectedRemovals:   0,
+			expectedRecordedAt: "2023-11-14T22:15:20Z",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			k8sClient := newTestClient(t, newTestContact(), newTestContactGroup())
+			wh := NewLoopsContactGroupMembershipWebhookV1(k8sClient, testSigningSecret)
+
+			for _, event := range tt.events {
+				if resp := serveEvent(t, wh, testSigningSecret, event); resp.HttpStatus != http.StatusOK {
+					t.Fatalf("Expected status 200, got %d", resp.HttpStatus)
+				}
+			}
+			assertCount(t, k8sClient, &notificationmiloapiscomv1alpha1.ContactGroupMembershipList{}, tt.expectedMembers)
+			assertCount(t, k8sClient, &notificationmiloapiscomv1alpha1.ContactGroupMembershipRemovalList{}, tt.expectedRemovals)
+
+			if tt.expectedRecordedAt != "" {
+				var memberships notificationmiloapiscomv1alpha1.ContactGroupMembershipList
+				if err := k8sClient.List(ctx, &memberships); err != nil {
+					t.Fatalf("List() failed: %v", err)
+				}
+				if got := memberships.Items[0].Annotations[EventTimeAnnotation]; got != tt.expectedRecordedAt {
+					t.Errorf("Expected recorded event time %s, got %s", tt.expectedRecordedAt, got)
+				}
+			}
+		})
+	}
+}
+
+func TestContactGroupMembershipWebhook_MailingListAdded(t *testing.T) {
+	k8sClient := newTestClient(t, newTestContact(), newTestContactGroup())
+	wh := NewLoopsContactGroupMembershipWebhookV1(k8sClient, testSigningSecret)
+
+	if resp := serveEvent(t, wh, testSigningSecret, newTestEvent(loops.EventNameMailingListAdded)); resp.HttpStatus != http.StatusOK {
+		t.Fatalf("Expected status 200 for added, got %d", resp.HttpStatus)
+	}
+	assertCount(t, k8sClient, &notificationmiloapiscomv1alpha1.ContactGroupMembershipList{}, 1)
+}
+
+func TestContactGroupMembershipWebhook_InvalidSignature(t *testing.T) {
+	wh := NewLoopsContactGroupMembershipWebhookV1(newTestClient(t), testSigningSecret)
+
+	resp := serveEvent(t, wh, "whsec_b3RoZXItc2VjcmV0", newTestEvent(loops.EventNameMailingListSubscribed))
+	if resp.HttpStatus != http.StatusUnauthorized {
+		t.Errorf("Expected status 401, got %d", resp.HttpStatus)
+	}
+}
+
+func assertCount(t *testing.T, k8sClient client.Client, list client.ObjectList, want int) {
+	t.Helper()
+	if err := k8sClient.List(context.Background(), list); err != nil {
+		t.Fatalf("List() failed: %v", err)
+	}
+	if items := meta.LenList(list); items != want {
+		t.Errorf("Expected %d %T items, got %d", want, list, items)
+	}
+}
+
+func TestContactGroupMembershipWebhook_MatchesContactByNormalizedEmail(t *testing.T) {
+	tests := []struct {
+		name         string
+		contactEmail string
+		eventEmail   string
+	}{
+		{name: "mixed case", contactEmail: "Jane@Example.com", eventEmail: "jane@example.com"},
+		{name: "whitespace", contactEmail: " jane@example.com ", eventEmail: "jane@example.com"},
+		{name: "mixed case event", contactEmail: "jane@example.com", eventEmail: "JANE@example.COM"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			contact := newTestContact()
+			contact.Spec.Email = tt.contactEmail
+			k8sClient := newTestClient(t, contact, newTestContactGroup())
+			wh := NewLoopsContactGroupMembershipWebhookV1(k8sClient, testSigningSecret)
+
+			// Contacts Loops knows without a userId are matched by email
+			event := newTestEvent(loops.EventNameMailingListUnsubscribed)
+			event.ContactIdentity.UserID = ""
+			event.ContactIdentity.Email = tt.eventEmail
+
+			if resp := serveEvent(t, wh, testSigningSecret, event); resp.HttpStatus != http.StatusOK {
+				t.Fatalf("Expected status 200, got %d", resp.HttpStatus)
+			}
+			assertCount(t, k8sClient, &notificationmiloapiscomv1alpha1.ContactGroupMembershipRemovalList{}, 1)
+		})
+	}
+}
+
+func TestContactGroupMembershipWebhook_UnresolvedContact(t *testing.T) {
+	tests := []struct {
+		name   string
+		userID string
+		others int
+	}{
+		// The contact with the event's email may not be the one Loops knows by the userId
+		{name: "unknown userId", userID: "john-uid"},
+		{name: "email shared by several contacts", others: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			objs := []client.Object{newTestContactGroup()}
+			for i := 0; i <= tt.others; i++ {
+				contact := newTestContact()
+				contact.Name = fmt.Sprintf("jane-%d", i)
+				contact.UID = types.UID(fmt.Sprintf("jane-uid-%d", i))
+				contact.Spec.Email = "jane@example.com"
+				objs = append(objs, contact)
+			}
+			k8sClient := newTestClient(t, objs...)
+			wh := NewLoopsContactGroupMembershipWebhookV1(k8sClient, testSigningSecret)
+
+			event := newTestEvent(loops.EventNameMailingListUnsubscribed)
+			event.ContactIdentity.UserID = tt.userID
+
+			if resp := serveEvent(t, wh, testSigningSecret, event); resp.HttpStatus != http.StatusBadRequest {
+				t.Fatalf("Expected status 400, got %d", resp.HttpStatus)
+			}
+			assertCount(t, k8sClient, &notificationmiloapiscomv1alpha1.ContactGroupMembershipRemovalList{}, 0)
+		})
+	}
+}
+
+func TestContactGroupMembershipWebhook_BatchPayload(t *testing.T) {
+	unknownGroup := newTestEvent(loops.EventNameMailingListSubscribed)
+	unknownGroup.MailingList.ID = "list-unknown"
+
+	tests := []struct {
+		name     string
+		events   []any
+		expected int
+	}{
+		{
+			name: "all succeed",
+			events: []any{
+				newTestEvent(loops.EventNameMailingListUnsubscribed),
+				newTestEvent(loops.EventNameMailingListSubscribed),
+			},
+			expected: http.StatusOK,
+		},
+		{
+			name:     "partial failure",
+			events:   []any{newTestEvent(loops.EventNameMailingListUnsubscribed), unknownGroup},
+			expected: http.StatusMultiStatus,
+		},
+		{
+			name:     "all fail",
+			events:   []any{unknownGroup},
+			expected: http.StatusBadRequest,
+		},
+		{
+			name:     "empty",
+			events:   []any{},
+			expected: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			k8sClient := newTestClient(t, newTestContact(), newTestContactGroup())
+			wh := NewLoopsContactGroupMembershipWebhookV1(k8sClient, testSigningSecret)
+
+			if resp := serveEvent(t, wh, testSigningSecret, tt.events); resp.HttpStatus != tt.expected {
+				t.Errorf("Expected status %d, got %d", tt.expected, resp.HttpStatus)
+			}
+		})
+	}
+
+	// Events of a batch are handled in order
+	k8sClient := newTestClient(t, newTestContact(), newTestContactGroup())
+	wh := NewLoopsContactGroupMembershipWebhookV1(k8sClient, testSigningSecret)
+	batch := []any{
+		newTestEvent(loops.EventNameMailingListUnsubscribed),
+		newTestEvent(loops.EventNameMailingListSubscribed),
+	}
+	if resp := serveEvent(t, wh, testSigningSecret, batch); resp.HttpStatus != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", resp.HttpStatus)
+	}
+	assertCount(t, k8sClient, &notificationmiloapiscomv1alpha1.ContactGroupMembershipRemovalList{}, 0)
+	assertCount(t, k8sClient, &notificationmiloapiscomv1alpha1.ContactGroupMembershipList{}, 1)
+}
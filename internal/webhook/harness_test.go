@@ -0,0 +1,92 @@
+package webhook
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	notificationmiloapiscomv1alpha1 "go.miloapis.com/milo/pkg/apis/notification/v1alpha1"
+
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+const testSigningSecret = "whsec_dGVzdC1zaWduaW5nLXNlY3JldA=="
+
+// sign returns the v1 signature of the body for the given event ID and timestamp.
+func sign(t *testing.T, secret, eventID, timestamp string, body []byte) string {
+	t.Helper()
+	return "v1," + signWith(t, secret, fmt.Sprintf("%s.%s.%s", eventID, timestamp, string(body)))
+}
+
+// signWith returns the base64-encoded HMAC-SHA256 of the content with the secret.
+func signWith(t *testing.T, secret, content string) string {
+	t.Helper()
+	secretBytes, err := base64.StdEncoding.DecodeString(secret[strings.Index(secret, "_")+1:])
+	if err != nil {
+		t.Fatalf("failed to decode secret: %v", err)
+	}
+	h := hmac.New(sha256.New, secretBytes)
+	h.Write([]byte(content))
+	return base64.StdEncoding.EncodeToString(h.Sum(nil))
+}
+
+// signedRequest builds a POST request carrying the body and the webhook headers signed with the secret.
+func signedRequest(t *testing.T, secret string, body []byte) *http.Request {
+	t.Helper()
+	eventID := fmt.Sprintf("msg_%d", time.Now().UnixNano())
+	timestamp := fmt.Sprintf("%d", time.Now().Unix())
+
+	r := httptest.NewRequest(http.MethodPost, "/", bytes.NewReader(body))
+	r.Header.Set("Content-Type", "application/json")
+	r.Header.Set("webhook-id", eventID)
+	r.Header.Set("webhook-timestamp", timestamp)
+	r.Header.Set("webhook-signature", sign(t, secret, eventID, timestamp, body))
+	return r
+}
+
+// serveEvent marshals the event, signs it with the secret, invokes ServeHTTP and returns the webhook response.
+func serveEvent(t *testing.T, wh *Webhook, secret string, event any) Response {
+	t.Helper()
+	body, err := json.Marshal(event)
+	if err != nil {
+		t.Fatalf("failed to marshal event: %v", err)
+	}
+
+	return serveRequest(wh, signedRequest(t, secret, body))
+}
+
+// serveRequest invokes ServeHTTP with the request and returns the webhook response.
+func serveRequest(wh *Webhook, r *http.Request) Response {
+	rec := httptest.NewRecorder()
+	wh.ServeHTTP(rec, r)
+	return Response{HttpStatus: rec.Code}
+}
+
+// newTestClient returns a fake client with the webhook field indexes registered.
+func newTestClient(t *testing.T, objs ...client.Object) client.Client {
+	t.Helper()
+	scheme := runtime.NewScheme()
+	if err := notificationmiloapiscomv1alpha1.AddToScheme(scheme); err != nil {
+		t.Fatalf("failed to add notification scheme: %v", err)
+	}
+
+	return fake.NewClientBuilder().
+		WithScheme(scheme).
+		WithObjects(objs...).
+		WithIndex(&notificationmiloapiscomv1alpha1.Contact{}, contactStatusProviderIDIndexKey, indexContactByProviderID).
+		WithIndex(&notificationmiloapiscomv1alpha1.Contact{}, contactEmailIndexKey, indexContactByEmail).
+		WithIndex(&notificationmiloapiscomv1alpha1.ContactGroup{}, groupProviderIDIndexKey, indexContactGroupByProviderID).
+		WithIndex(&notificationmiloapiscomv1alpha1.ContactGroupMembershipRemoval{}, groupMembershipRemovalIndexKey, indexGroupMembershipRemoval).
+		WithIndex(&notificationmiloapiscomv1alpha1.ContactGroupMembership{}, groupMembershipIndexKey, indexGroupMembership).
+		Build()
+}
@@ -1,6 +1,7 @@
 package webhook
 
 import (
+	"bytes"
 	"context"
 	"crypto/hmac"
 	"crypto/sha256"
@@ -9,9 +10,14 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"mime"
 	"net/http"
+	"strconv"
 	"strings"
+	"time"
 
+	"github.com/go-logr/logr"
+	"k8s.io/utils/clock"
 	ctrl "sigs.k8s.io/controller-runtime"
 	logf "sigs.k8s.io/controller-runtime/pkg/log"
 
@@ -24,8 +30,94 @@ type Webhook struct {
 	Handler       Handler
 	Endpoint      string
 	signingSecret string // Loops signing secret for webhook verification
+	// SigningSecretProvider provides the signing secret on each request, instead of the secret passed on creation, so
+	// that it can be rotated without a restart.
+	SigningSecretProvider SigningSecretProvider
+	// UnknownEventResponse defines the response to events with an unknown name. Defaults to UnknownEventResponseOK.
+	UnknownEventResponse UnknownEventResponseMode
+	// RoutePrefix is prepended to the Endpoint, e.g. "/providers/loops", to host several provider webhooks behind
+	// one server.
+	RoutePrefix string
+	// SignatureSchemes are the signature versions accepted in the webhook-signature header, keyed by their version
+	// prefix. Defaults to DefaultSignatureSchemes.
+	SignatureSchemes map[string]SignatureScheme
+	// RequireJSONContentType rejects requests whose Content-Type is not application/json with a 415, before reading
+	// their body. Loops always sends JSON, so it is off by default.
+	RequireJSONContentType bool
+	// InsecureSkipSignatureVerification accepts requests without verifying their signature, for local testing behind
+	// proxies that strip the signature headers. Anyone able to reach the webhook can then forge events, so it must
+	// never be set in production.
+	InsecureSkipSignatureVerification bool
+	// UnsignedTestEvent acknowledges the unsigned test events matching it, so that operators can validate the endpoint
+	// with the "send test" of the Loops dashboard, which may omit the signature. Unsigned requests not matching it
+	// still fail verification. Nil means every request must be signed.
+	UnsignedTestEvent *UnsignedTestEventAllowance
+	// CacheSynced reports whether the cache the handler looks objects up in is synced, waiting for it until the context
+	// is done. Requests received before are answered with a 503 so that Loops retries them, instead of a 400 for
+	// objects missing from the cache. SetupWithManager sets it to wait for the manager cache; nil means always synced.
+	CacheSynced func(ctx context.Context) bool
+	// TimestampTolerance rejects the requests whose webhook-timestamp is further than it from the current time, so
+	// that a captured request cannot be replayed later. Zero accepts any timestamp.
+	TimestampTolerance time.Duration
+	// Clock provides the current time the webhook-timestamp is checked against. Defaults to the real clock.
+	Clock clock.PassiveClock
 }
 
+// cacheSyncWait bounds how long a request waits for the cache to sync before being answered with a 503
+const cacheSyncWait = 5 * time.Second
+
+// UnsignedTestEventAllowance identifies the unsigned test events to acknowledge. A request matches it when it carries
+// no signature header, has the Header set to the HeaderValue, and its body is a single event named EventName. Matching
+// events are acknowledged without being handled, so that they cannot change any object. An allowance missing any of
+// its fields matches no request.
+type UnsignedTestEventAllowance struct {
+	// EventName is the name of the test event
+	EventName string
+	// Header is the name of the header marking the test requests
+	Header string
+	// HeaderValue is the value the Header must have
+	HeaderValue string
+}
+
+// matches reports whether the request is an unsigned test event allowed by the allowance.
+func (a *UnsignedTestEventAllowance) matches(r *http.Request, body []byte) bool {
+	if a == nil || a.EventName == "" || a.Header == "" || a.HeaderValue == "" {
+		return false
+	}
+	if headerValue(r.Header, webhookSignatureHeaders...) != "" {
+		return false
+	}
+	if headerValue(r.Header, a.Header) != a.HeaderValue {
+		return false
+	}
+
+	var event loops.WebhookEvent
+	if err := json.Unmarshal(body, &event); err != nil {
+		return false
+	}
+	return event.EventName == a.EventName
+}
+
+// Path returns the path the webhook is served at, i.e. the Endpoint prefixed with the RoutePrefix. This is the path
+// of the webhook URL configured in Loops.
+func (w *Webhook) Path() string {
+	prefix := strings.TrimSuffix(w.RoutePrefix, "/")
+	if prefix != "" && !strings.HasPrefix(prefix, "/") {
+		prefix = "/" + prefix
+	}
+	return prefix + w.Endpoint
+}
+
+// UnknownEventResponseMode defines how the webhook responds to events with an unknown name
+type UnknownEventResponseMode string
+
+const (
+	// UnknownEventResponseOK acknowledges unknown events so that Loops stops retrying their delivery
+	UnknownEventResponseOK UnknownEventResponseMode = "ok"
+	// UnknownEventResponseBadRequest rejects unknown events, surfacing misconfigured event subscriptions
+	UnknownEventResponseBadRequest UnknownEventResponseMode = "badrequest"
+)
+
 type Request struct {
 	MailingListSubscribedEvent   *loops.MailingListSubscribedEvent
 	MailingListUnsubscribedEvent *loops.MailingListUnsubscribedEvent
@@ -46,9 +138,21 @@ type Handler interface {
 	Handle(context.Context, Request) Response
 }
 
+// VerificationErrorCode identifies the reason a webhook verification failed
+type VerificationErrorCode string
+
+// Webhook verification error codes
+const (
+	VerificationErrorCodeMissingHeaders          VerificationErrorCode = "MISSING_HEADERS"
+	VerificationErrorCodeInvalidSecretFormat     VerificationErrorCode = "INVALID_SECRET_FORMAT"
+	VerificationErrorCodeInvalidSecretEncoding   VerificationErrorCode = "INVALID_SECRET_ENCODING"
+	VerificationErrorCodeInvalidSignature        VerificationErrorCode = "INVALID_SIGNATURE"
+	VerificationErrorCodeTimestampOutOfTolerance VerificationErrorCode = "TIMESTAMP_OUT_OF_TOLERANCE"
+)
+
 // WebhookVerificationError represents errors that can occur during webhook verification
 type WebhookVerificationError struct {
-	Code    string
+	Code    VerificationErrorCode
 	Message string
 	Err     error
 }
@@ -60,25 +164,86 @@ func (e *WebhookVerificationError) Error() string {
 	return e.Message
 }
 
+func (e *WebhookVerificationError) Unwrap() error {
+	return e.Err
+}
+
+// IsVerificationErrorCode checks if the error is a WebhookVerificationError with the given code
+func IsVerificationErrorCode(err error, code VerificationErrorCode) bool {
+	var verifyErr *WebhookVerificationError
+	if errors.As(err, &verifyErr) {
+		return verifyErr.Code == code
+	}
+	return false
+}
+
 // Webhook verification error codes
 var (
 	ErrMissingHeaders     = errors.New("missing required webhook header")
 	ErrMissingSecret      = errors.New("missing LOOPS_SIGNING_SECRET environment variable")
 	ErrInvalidSignature   = errors.New("invalid signature")
+	ErrInvalidTimestamp   = errors.New("webhook timestamp out of tolerance")
 	ErrVerificationFailed = errors.New("webhook verification failed")
 )
 
-// verifyWebhook verifies the webhook signature from Loops
-func verifyWebhook(r *http.Request, body []byte, secret string) error {
+// Webhook header names. Loops signs its webhooks following the Standard Webhooks specification, whose headers
+// may also be delivered under the svix-* aliases.
+var (
+	webhookIDHeaders        = []string{"webhook-id", "svix-id"}
+	webhookTimestampHeaders = []string{"webhook-timestamp", "svix-timestamp"}
+	webhookSignatureHeaders = []string{"webhook-signature", "svix-signature"}
+)
+
+// headerValue returns the value of the first of the given headers present in h. Header names are matched
+// case-insensitively, even when a proxy stored them in the map under a non-canonical key.
+func headerValue(h http.Header, names ...string) string {
+	for _, name := range names {
+		if value := h.Get(name); value != "" {
+			return value
+		}
+		for key, values := range h {
+			if strings.EqualFold(key, name) && len(values) > 0 && values[0] != "" {
+				return values[0]
+			}
+		}
+	}
+	return ""
+}
+
+// SignatureScheme verifies a signature of one version of the webhook-signature header against the signed content,
+// given the decoded signing secret.
+type SignatureScheme func(secret []byte, signedContent string, signature string) bool
+
+// DefaultSignatureSchemes are the signature versions Loops signs its webhooks with.
+var DefaultSignatureSchemes = map[string]SignatureScheme{
+	"v1": verifyHMACSHA256Signature,
+}
+
+// verifyHMACSHA256Signature verifies a v1 signature, the base64-encoded HMAC-SHA256 of the signed content.
+func verifyHMACSHA256Signature(secret []byte, signedContent string, signature string) bool {
+	h := hmac.New(sha256.New, secret)
+	h.Write([]byte(signedContent))
+	expected := base64.StdEncoding.EncodeToString(h.Sum(nil))
+	return hmac.Equal([]byte(signature), []byte(expected))
+}
+
+// verifyWebhook verifies the webhook signature from Loops with the given signature schemes, or with the
+// DefaultSignatureSchemes when nil, then that its webhook-timestamp is within tolerance of now. A zero tolerance
+// accepts any timestamp.
+func verifyWebhook(r *http.Request, body []byte, secret string, schemes map[string]SignatureScheme, tolerance time.Duration, now time.Time) error {
+	if schemes == nil {
+		schemes = DefaultSignatureSchemes
+	}
+
 	// Get the webhook-related headers
-	eventID := r.Header.Get("webhook-id")
-	timestamp := r.Header.Get("webhook-timestamp")
-	webhookSignature := r.Header.Get("webhook-signature")
+	eventID := headerValue(r.Header, webhookIDHeaders...)
+	timestamp := headerValue(r.Header, webhookTimestampHeaders...)
+	webhookSignature := headerValue(r.Header, webhookSignatureHeaders...)
 
 	// Verify required headers are present
 	if eventID == "" || timestamp == "" || webhookSignature == "" {
 		return &WebhookVerificationError{
-			Code:    "MISSING_HEADERS",
+			Code:    VerificationErrorCodeMissingHeaders,
 			Message: "Missing required webhook header",
 			Err:     ErrMissingHeaders,
 		}
@@ -91,7 +256,7 @@ func verifyWebhook(r *http.Request, body []byte, secret string) error {
 	parts := strings.Split(secret, "_")
 	if len(parts) < 2 {
 		return &WebhookVerificationError{
-			Code:    "INVALID_SECRET_FORMAT",
+			Code:    VerificationErrorCodeInvalidSecretFormat,
 			Message: "Invalid LOOPS_SIGNING_SECRET format",
 			Err:     ErrMissingSecret,
 		}
@@ -100,23 +265,22 @@ func verifyWebhook(r *http.Request, body []byte, secret string) error {
 	secretBytes, err := base64.StdEncoding.DecodeString(parts[1])
 	if err != nil {
 		return &WebhookVerificationError{
-			Code:    "INVALID_SECRET_ENCODING",
+			Code:    VerificationErrorCodeInvalidSecretEncoding,
 			Message: "Failed to decode LOOPS_SIGNING_SECRET",
 			Err:     err,
 		}
 	}
 
-	// Create HMAC-SHA256 signature
-	h := hmac.New(sha256.New, secretBytes)
-	h.Write([]byte(signedContent))
-	signature := base64.StdEncoding.EncodeToString(h.Sum(nil))
-
-	// Check if the signature matches
-	// The webhook-signature header contains space-separated signatures
+	// The webhook-signature header contains space-separated signatures, each in the format "<version>,<signature>".
+	// Signatures of versions without a known scheme are skipped, so that Loops can add new versions next to v1.
 	signatureFound := false
-	for _, sig := range strings.Split(webhookSignature, " ") {
-		// Each signature is in the format "v1,<signature>"
-		if strings.Contains(sig, ","+signature) {
+	for _, sig := range strings.Fields(webhookSignature) {
+		version, signature, ok := strings.Cut(sig, ",")
+		if !ok {
+			continue
+		}
+		scheme, known := schemes[version]
+		if known && scheme(secretBytes, signedContent, signature) {
 			signatureFound = true
 			break
 		}
@@ -124,25 +288,97 @@ func verifyWebhook(r *http.Request, body []byte, secret string) error {
 
 	if !signatureFound {
 		return &WebhookVerificationError{
-			Code:    "INVALID_SIGNATURE",
+			Code:    VerificationErrorCodeInvalidSignature,
 			Message: "Invalid signature",
 			Err:     ErrInvalidSignature,
 		}
 	}
 
+	// The timestamp is signed, so it is only checked once the signature is
+	if tolerance > 0 {
+		seconds, err := strconv.ParseInt(timestamp, 10, 64)
+		if err != nil {
+			return &WebhookVerificationError{
+				Code:    VerificationErrorCodeTimestampOutOfTolerance,
+				Message: "Invalid webhook timestamp",
+				Err:     fmt.Errorf("%w: %w", ErrInvalidTimestamp, err),
+			}
+		}
+		if skew := now.Sub(time.Unix(seconds, 0)); skew > tolerance || skew < -tolerance {
+			return &WebhookVerificationError{
+				Code:    VerificationErrorCodeTimestampOutOfTolerance,
+				Message: fmt.Sprintf("Webhook timestamp is %s away from the current time, more than %s", skew.Abs(), tolerance),
+				Err:     ErrInvalidTimestamp,
+			}
+		}
+	}
+
 	return nil
 }
 
 const (
 	contactStatusProviderIDIndexKey = "contact-status-providerID"
+	contactEmailIndexKey            = "contact-email"
 	groupProviderIDIndexKey         = "group-providerID"
 	groupMembershipRemovalIndexKey  = "group-membership-removal"
+	groupMembershipIndexKey         = "group-membership"
 )
 
 func buildGroupMembershipRemovalIndexKey(contactRef *notificationmiloapiscomv1alpha1.ContactReference, groupRef *notificationmiloapiscomv1alpha1.ContactGroupReference) string {
 	return fmt.Sprintf("%s-%s-%s-%s", contactRef.Name, contactRef.Namespace, groupRef.Name, groupRef.Namespace)
 }
 
+// indexContactByProviderID returns the Loops userId of a Contact for the contactStatusProviderIDIndexKey index
+func indexContactByProviderID(rawObj client.Object) []string {
+	contact := rawObj.(*notificationmiloapiscomv1alpha1.Contact)
+	// The controller records the Loops userId in the status, which may differ from the UID
+	// when a custom contact ID resolver is configured.
+	for _, provider := range contact.Status.Providers {
+		if provider.Name == "Loops" && provider.ID != "" {
+			return []string{provider.ID}
+		}
+	}
+	if contact.UID == "" {
+		return nil
+	}
+	return []string{string(contact.UID)}
+}
+
+// indexContactByEmail returns the normalized email of a Contact for the contactEmailIndexKey index
+func indexContactByEmail(rawObj client.Object) []string {
+	contact := rawObj.(*notificationmiloapiscomv1alpha1.Contact)
+	email := loops.NormalizeEmail(contact.Spec.Email)
+	if email == "" {
+		return nil
+	}
+	return []string{email}
+}
+
+// indexContactGroupByProviderID returns the Loops mailing list ID of a ContactGroup for the groupProviderIDIndexKey index
+func indexContactGroupByProviderID(rawObj client.Object) []string {
+	group := rawObj.(*notificationmiloapiscomv1alpha1.ContactGroup)
+	for _, provider := range group.Spec.Providers {
+		if provider.Name == "Loops" {
+			return []string{provider.ID}
+		}
+	}
+	return nil
+}
+
+// indexGroupMembershipRemoval returns the contact and group pair of a ContactGroupMembershipRemoval for the
+// groupMembershipRemovalIndexKey index
+func indexGroupMembershipRemoval(rawObj client.Object) []string {
+	removal := rawObj.(*notificationmiloapiscomv1alpha1.ContactGroupMembershipRemoval)
+	return []string{buildGroupMembershipRemovalIndexKey(&removal.Spec.ContactRef, &removal.Spec.ContactGroupRef)}
+}
+
+// indexGroupMembership returns the contact and group pair of a ContactGroupMembership for the groupMembershipIndexKey
+// index
+func indexGroupMembership(rawObj client.Object) []string {
+	membership := rawObj.(*notificationmiloapiscomv1alpha1.ContactGroupMembership)
+	return []string{buildGroupMembershipRemovalIndexKey(&membership.Spec.ContactRef, &membership.Spec.ContactGroupRef)}
+}
+
 // setupIndexes sets up the required field indexes for webhook operations
 func setupIndexes(mgr ctrl.Manager) error {
 	// Index Contact objects by .status.providerID so that the webhook handler can
@@ -151,32 +387,29 @@ func setupIndexes(mgr ctrl.Manager) error {
 		context.Background(),
 		&notificationmiloapiscomv1alpha1.Contact{},
 		contactStatusProviderIDIndexKey,
-		func(rawObj client.Object) []string {
-			contact := rawObj.(*notificationmiloapiscomv1alpha1.Contact)
-			if contact.UID == "" {
-				return nil
-			}
-			return []string{string(contact.UID)}
-		},
+		indexContactByProviderID,
 	); err != nil {
 		return fmt.Errorf("failed to create contact index for providerID: %w", err)
 	}
 
+	// Index Contact objects by their normalized .spec.email so that events of contacts Loops knows
+	// without a userId can be matched too.
+	if err := mgr.GetFieldIndexer().IndexField(
+		context.Background(),
+		&notificationmiloapiscomv1alpha1.Contact{},
+		contactEmailIndexKey,
+		indexContactByEmail,
+	); err != nil {
+		return fmt.Errorf("failed to create contact index for email: %w", err)
+	}
+
 	// Index ContactGroup objects by .spec.providers.loops.providerID so that the webhook handler can
 	// quickly look them up when processing incoming Loops webhook events.
 	if err := mgr.GetFieldIndexer().IndexField(
 		context.Background(),
 		&notificationmiloapiscomv1alpha1.ContactGroup{},
 		groupProviderIDIndexKey,
-		func(rawObj client.Object) []string {
-			group := rawObj.(*notificationmiloapiscomv1alpha1.ContactGroup)
-			for _, provider := range group.Spec.Providers {
-				if provider.Name == "Loops" {
-					return []string{provider.ID}
-				}
-			}
-			return nil
-		},
+		indexContactGroupByProviderID,
 	); err != nil {
 		return fmt.Errorf("failed to create contact index for providerID: %w", err)
 	}
@@ -187,14 +420,22 @@ func setupIndexes(mgr ctrl.Manager) error {
 		context.Background(),
 		&notificationmiloapiscomv1alpha1.ContactGroupMembershipRemoval{},
 		groupMembershipRemovalIndexKey,
-		func(rawObj client.Object) []string {
-			removal := rawObj.(*notificationmiloapiscomv1alpha1.ContactGroupMembershipRemoval)
-			return []string{buildGroupMembershipRemovalIndexKey(&removal.Spec.ContactRef, &removal.Spec.ContactGroupRef)}
-		},
+		indexGroupMembershipRemoval,
 	); err != nil {
 		return fmt.Errorf("failed to create contact index for providerID: %w", err)
 	}
 
+	// Index ContactGroupMembership objects by their contact and group so that the webhook handler can
+	// compare an event with the last one processed for the pair.
+	if err := mgr.GetFieldIndexer().IndexField(
+		context.Background(),
+		&notificationmiloapiscomv1alpha1.ContactGroupMembership{},
+		groupMembershipIndexKey,
+		indexGroupMembership,
+	); err != nil {
+		return fmt.Errorf("failed to create contact group membership index: %w", err)
+	}
+
 	return nil
 }
 
@@ -205,8 +446,12 @@ func (w *Webhook) SetupWithManager(mgr ctrl.Manager) error {
 		return err
 	}
 
+	if w.CacheSynced == nil {
+		w.CacheSynced = mgr.GetCache().WaitForCacheSync
+	}
+
 	hookServer := mgr.GetWebhookServer()
-	hookServer.Register(w.Endpoint, w)
+	hookServer.Register(w.Path(), w)
 
 	return nil
 }
@@ -230,6 +475,12 @@ func (wh *Webhook) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if wh.RequireJSONContentType && !isJSONContentType(r.Header.Get("Content-Type")) {
+		log.Error(nil, "Unsupported content type", "contentType", r.Header.Get("Content-Type"))
+		wh.writeResponse(w, UnsupportedMediaTypeResponse())
+		return
+	}
+
 	body, err := io.ReadAll(r.Body)
 	if err != nil {
 		log.Error(err, "Failed to read request body")
@@ -246,7 +497,15 @@ func (wh *Webhook) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	log.Info("Received webhook body", "body", string(body))
 
 	// Verify webhook signature
-	if err := verifyWebhook(r, body, wh.signingSecret); err != nil {
+	if wh.InsecureSkipSignatureVerification {
+		log.Info("WARNING: skipping webhook signature verification, this must never be enabled in production")
+	} else if wh.UnsignedTestEvent.matches(r, body) {
+		log.Info("WARNING: acknowledging an unsigned test event without handling it",
+			"eventName", wh.UnsignedTestEvent.EventName, "header", wh.UnsignedTestEvent.Header)
+		wh.writeResponse(w, OkResponse())
+		return
+	} else if err := verifyWebhook(r, body, wh.currentSigningSecret(), wh.SignatureSchemes,
+		wh.TimestampTolerance, wh.now()); err != nil {
 		var verifyErr *WebhookVerificationError
 		if errors.As(err, &verifyErr) {
 			log.Error(err, "Webhook verification failed", "code", verifyErr.Code)
@@ -257,52 +516,173 @@ func (wh *Webhook) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// The handler looks the contacts and groups up in the cache, which misses them all until it is synced
+	if !wh.cacheSynced(r.Context()) {
+		log.Info("Cache not synced yet, asking Loops to retry")
+		wh.writeResponse(w, ServiceUnavailableResponse())
+		return
+	}
+
+	// Loops may deliver several events in one request as a JSON array
+	if trimmed := bytes.TrimSpace(body); len(trimmed) > 0 && trimmed[0] == '[' {
+		var events []json.RawMessage
+		if err := json.Unmarshal(trimmed, &events); err != nil {
+			log.Error(err, "Failed to parse batch webhook payload")
+			wh.writeResponse(w, BadRequestResponse())
+			return
+		}
+		log.Info("Handling batch of events", "count", len(events))
+
+		responses := make([]Response, 0, len(events))
+		for _, event := range events {
+			responses = append(responses, wh.handleEvent(r.Context(), event))
+		}
+		wh.writeResponse(w, aggregateResponses(responses))
+		return
+	}
+
+	wh.writeResponse(w, wh.handleEvent(r.Context(), body))
+}
+
+// now returns the current time of the Clock.
+func (wh *Webhook) now() time.Time {
+	if wh.Clock == nil {
+		return time.Now()
+	}
+	return wh.Clock.Now()
+}
+
+// currentSigningSecret returns the secret of the SigningSecretProvider, or the secret passed on creation when unset.
+func (wh *Webhook) currentSigningSecret() string {
+	if wh.SigningSecretProvider != nil {
+		return wh.SigningSecretProvider.SigningSecret()
+	}
+	return wh.signingSecret
+}
+
+// cacheSynced waits up to cacheSyncWait for the cache to sync, returning false if it is still not synced then.
+func (wh *Webhook) cacheSynced(ctx context.Context) bool {
+	if wh.CacheSynced == nil {
+		return true
+	}
+	ctx, cancel := context.WithTimeout(ctx, cacheSyncWait)
+	defer cancel()
+	return wh.CacheSynced(ctx)
+}
+
+// isJSONContentType reports whether the Content-Type header value is application/json, with any parameters.
+func isJSONContentType(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	return err == nil && mediaType == "application/json"
+}
+
+// handleEvent parses a single event and dispatches it to the handler according to its type.
+func (wh *Webhook) handleEvent(ctx context.Context, body []byte) Response {
+	log := logf.FromContext(ctx).WithName("loops-http-webhook")
+
 	// First, parse to determine the event type
 	var baseEvent loops.WebhookEvent
 	if err := json.Unmarshal(body, &baseEvent); err != nil {
 		log.Error(err, "Failed to parse base webhook event")
-		wh.writeResponse(w, BadRequestResponse())
-		return
+		return BadRequestResponse()
+	}
+
+	if err := baseEvent.Validate(); err != nil {
+		log.Error(err, "Invalid webhook event")
+		return BadRequestResponse()
 	}
 
 	log.Info("Parsed base event", "eventName", baseEvent.EventName, "eventTime", baseEvent.EventTime)
+	recordEvent(baseEvent.EventName)
 
 	// Handle based on event type
 	switch baseEvent.EventName {
 	case loops.EventNameMailingListSubscribed:
 		var subscribedEvent loops.MailingListSubscribedEvent
 		if err := json.Unmarshal(body, &subscribedEvent); err != nil {
-			log.Error(err, "Failed to parse mailing list subscribed event")
-			wh.writeResponse(w, BadRequestResponse())
-			return
+			return malformedEventResponse(log, baseEvent.EventName, err)
+		}
+		if err := subscribedEvent.MailingList.Validate(); err != nil {
+			return malformedEventResponse(log, baseEvent.EventName, err)
 		}
 
-		response := wh.Handler.Handle(r.Context(), Request{
+		return wh.Handler.Handle(ctx, Request{
+			MailingListSubscribedEvent: &subscribedEvent,
+			BaseEvent:                  &baseEvent,
+		})
+
+	case loops.EventNameMailingListAdded:
+		var addedEvent loops.MailingListAddedEvent
+		if err := json.Unmarshal(body, &addedEvent); err != nil {
+			return malformedEventResponse(log, baseEvent.EventName, err)
+		}
+		if err := addedEvent.MailingList.Validate(); err != nil {
+			return malformedEventResponse(log, baseEvent.EventName, err)
+		}
+
+		// A contact added to a list is a member just like one who subscribed. The event keeps its name, so that
+		// handlers can still tell both apart.
+		subscribedEvent := loops.MailingListSubscribedEvent(addedEvent)
+		return wh.Handler.Handle(ctx, Request{
 			MailingListSubscribedEvent: &subscribedEvent,
 			BaseEvent:                  &baseEvent,
 		})
-		wh.writeResponse(w, response)
-		return
 
 	case loops.EventNameMailingListUnsubscribed:
 		var unsubscribedEvent loops.MailingListUnsubscribedEvent
 		if err := json.Unmarshal(body, &unsubscribedEvent); err != nil {
-			log.Error(err, "Failed to parse mailing list unsubscribed event")
-			wh.writeResponse(w, BadRequestResponse())
-			return
+			return malformedEventResponse(log, baseEvent.EventName, err)
+		}
+		if err := unsubscribedEvent.MailingList.Validate(); err != nil {
+			return malformedEventResponse(log, baseEvent.EventName, err)
 		}
 
-		response := wh.Handler.Handle(r.Context(), Request{
+		return wh.Handler.Handle(ctx, Request{
 			MailingListUnsubscribedEvent: &unsubscribedEvent,
 			BaseEvent:                    &baseEvent,
 		})
-		wh.writeResponse(w, response)
-		return
 
 	default:
-		log.Info("Unknown event type", "eventName", baseEvent.EventName)
-		wh.writeResponse(w, BadRequestResponse())
-		return
+		log.Info("Unknown event type", "eventName", baseEvent.EventName, "unknownEventResponse", wh.UnknownEventResponse)
+		if wh.UnknownEventResponse == UnknownEventResponseBadRequest {
+			return BadRequestResponse()
+		}
+		return OkResponse()
+	}
+}
+
+// malformedEventResponse acknowledges a known event whose type-specific payload is malformed. Loops would redeliver
+// the same malformed event on every retry, so it is logged and dropped rather than rejected.
+func malformedEventResponse(log logr.Logger, eventName string, err error) Response {
+	log.Info("Dropping malformed webhook event", "eventName", eventName, "error", err.Error())
+	return OkResponse()
+}
+
+// aggregateResponses combines the responses to the events of a batch. A batch succeeds only if all of its events do.
+// Any server error fails the whole batch so that Loops retries it, which is safe as the event handling is
+// idempotent. Otherwise, a batch where only some events were rejected is a partial failure.
+func aggregateResponses(responses []Response) Response {
+	if len(responses) == 0 {
+		return BadRequestResponse()
+	}
+
+	succeeded := 0
+	for _, response := range responses {
+		switch {
+		case response.HttpStatus >= 500:
+			return InternalServerErrorResponse()
+		case response.HttpStatus < 300:
+			succeeded++
+		}
+	}
+
+	switch succeeded {
+	case len(responses):
+		return OkResponse()
+	case 0:
+		return BadRequestResponse()
+	default:
+		return MultiStatusResponse()
 	}
 }
 
@@ -0,0 +1,605 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-logr/logr/funcr"
+	"go.miloapis.com/email-provider-loops/pkg/loops"
+	notificationmiloapiscomv1alpha1 "go.miloapis.com/milo/pkg/apis/notification/v1alpha1"
+
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	ctrlwebhook "sigs.k8s.io/controller-runtime/pkg/webhook"
+)
+
+func TestVerifyWebhook_HeaderAliasesAndCasings(t *testing.T) {
+	body := []byte(`{"eventName":"contact.mailingList.subscribed"}`)
+	signature := sign(t, testSigningSecret, "msg_123", "1700000000", body)
+
+	tests := []struct {
+		name    string
+		headers map[string]string
+		raw     bool
+	}{
+		{
+			name: "webhook headers",
+			headers: map[string]string{
+				"webhook-id":        "msg_123",
+				"webhook-timestamp": "1700000000",
+				"webhook-signature": signature,
+			},
+		},
+		{
+			name: "svix headers",
+			headers: map[string]string{
+				"svix-id":        "msg_123",
+				"svix-timestamp": "1700000000",
+				"svix-signature": signature,
+			},
+		},
+		{
+			name: "upper case webhook headers",
+			headers: map[string]string{
+				"WEBHOOK-ID":        "msg_123",
+				"WEBHOOK-TIMESTAMP": "1700000000",
+				"WEBHOOK-SIGNATURE": signature,
+			},
+		},
+		{
+			name: "non canonical lower case keys",
+			raw:  true,
+			headers: map[string]string{
+				"webhook-id":        "msg_123",
+				"webhook-timestamp": "1700000000",
+				"webhook-signature": signature,
+			},
+		},
+		{
+			name: "non canonical svix keys",
+			raw:  true,
+			headers: map[string]string{
+				"SVIX-ID":        "msg_123",
+				"Svix-timestamp": "1700000000",
+				"svix-Signature": signature,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodPost, "/", bytes.NewReader(body))
+			for key, value := range tt.headers {
+				if tt.raw {
+					// Bypass canonicalization as some proxies end up doing
+					r.Header[key] = []string{value}
+				} else {
+					r.Header.Set(key, value)
+				}
+			}
+
+			if err := verifyWebhook(r, body, testSigningSecret, nil, 0, time.Now()); err != nil {
+				t.Errorf("verifyWebhook() failed: %v", err)
+			}
+		})
+	}
+}
+
+func TestVerifyWebhook_ErrorCodes(t *testing.T) {
+	body := []byte(`{}`)
+	validSignature := sign(t, testSigningSecret, "msg_123", "1700000000", body)
+
+	tests := []struct {
+		name      string
+		secret    string
+		signature string
+		wantCode  VerificationErrorCode
+	}{
+		{
+			name:     "Missing headers",
+			secret:   testSigningSecret,
+			wantCode: VerificationErrorCodeMissingHeaders,
+		},
+		{
+			name:      "Invalid secret format",
+			secret:    "whsec",
+			signature: validSignature,
+			wantCode:  VerificationErrorCodeInvalidSecretFormat,
+		},
+		{
+			name:      "Invalid secret encoding",
+			secret:    "whsec_not-base64!",
+			signature: validSignature,
+			wantCode:  VerificationErrorCodeInvalidSecretEncoding,
+		},
+		{
+			name:      "Invalid signature",
+			secret:    testSigningSecret,
+			signature: "v1,aW52YWxpZA==",
+			wantCode:  VerificationErrorCodeInvalidSignature,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodPost, "/", bytes.NewReader(body))
+			r.Header.Set("webhook-id", "msg_123")
+			r.Header.Set("webhook-timestamp", "1700000000")
+			if tt.signature != "" {
+				r.Header.Set("webhook-signature", tt.signature)
+			}
+
+			err := verifyWebhook(r, body, tt.secret, nil, 0, time.Now())
+			if !IsVerificationErrorCode(err, tt.wantCode) {
+				t.Errorf("Expected error code %s, got %v", tt.wantCode, err)
+			}
+		})
+	}
+}
+
+func TestVerifyWebhook_TimestampTolerance(t *testing.T) {
+	body := []byte(`{}`)
+	signedAt := time.Unix(1700000000, 0)
+	tolerance := 5 * time.Minute
+
+	tests := []struct {
+		name      string
+		timestamp string
+		now       time.Time
+		wantErr   bool
+	}{
+		{name: "current", timestamp: "1700000000", now: signedAt},
+		{name: "oldest accepted", timestamp: "1700000000", now: signedAt.Add(tolerance)},
+		{name: "too old", timestamp: "1700000000", now: signedAt.Add(tolerance + time.Second), wantErr: true},
+		{name: "newest accepted", timestamp: "1700000000", now: signedAt.Add(-tolerance)},
+		{name: "too new", timestamp: "1700000000", now: signedAt.Add(-tolerance - time.Second), wantErr: true},
+		{name: "not a number", timestamp: "yesterday", now: signedAt, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodPost, "/", bytes.NewReader(body))
+			r.Header.Set("webhook-id", "msg_123")
+			r.Header.Set("webhook-timestamp", tt.timestamp)
+			r.Header.Set("webhook-signature", sign(t, testSigningSecret, "msg_123", tt.timestamp, body))
+
+			err := verifyWebhook(r, body, testSigningSecret, nil, tolerance, tt.now)
+			if !tt.wantErr {
+				if err != nil {
+					t.Errorf("Expected no error, got %v", err)
+				}
+				return
+			}
+			if !IsVerificationErrorCode(err, VerificationErrorCodeTimestampOutOfTolerance) {
+				t.Errorf("Expected error code %s, got %v", VerificationErrorCodeTimestampOutOfTolerance, err)
+			}
+		})
+	}
+}
+
+func TestServeHTTP_UnknownEventResponse(t *testing.T) {
+	tests := []struct {
+		name       string
+		mode       UnknownEventResponseMode
+		wantStatus int
+	}{
+		{
+			name:       "Default acknowledges",
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "OK mode acknowledges",
+			mode:       UnknownEventResponseOK,
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "Bad request mode rejects",
+			mode:       UnknownEventResponseBadRequest,
+			wantStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			wh := NewLoopsContactGroupMembershipWebhookV1(newTestClient(t), testSigningSecret)
+			wh.UnknownEventResponse = tt.mode
+
+			resp := serveEvent(t, wh, testSigningSecret, map[string]any{"eventName": "contact.created", "webhookSchemaVersion": "1.0.0"})
+			if resp.HttpStatus != tt.wantStatus {
+				t.Errorf("Expected status %d, got %d", tt.wantStatus, resp.HttpStatus)
+			}
+		})
+	}
+}
+
+func TestWebhook_Path(t *testing.T) {
+	tests := []struct {
+		name     string
+		prefix   string
+		expected string
+	}{
+		{name: "no prefix", prefix: "", expected: "/hooks/loops"},
+		{name: "prefix", prefix: "/providers/loops", expected: "/providers/loops/hooks/loops"},
+		{name: "trailing slash", prefix: "/providers/loops/", expected: "/providers/loops/hooks/loops"},
+		{name: "missing leading slash", prefix: "providers/loops", expected: "/providers/loops/hooks/loops"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			wh := &Webhook{Endpoint: "/hooks/loops", RoutePrefix: tt.prefix}
+			if got := wh.Path(); got != tt.expected {
+				t.Errorf("Expected path %q, got %q", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestWebhook_RoutePrefixRegistered(t *testing.T) {
+	k8sClient := newTestClient(t, newTestContact(), newTestContactGroup())
+	wh := NewLoopsContactGroupMembershipWebhookV1(k8sClient, testSigningSecret)
+	wh.RoutePrefix = "/providers/loops"
+
+	hookServer := ctrlwebhook.NewServer(ctrlwebhook.Options{})
+	hookServer.Register(wh.Path(), wh)
+	ts := httptest.NewServer(hookServer.WebhookMux())
+	defer ts.Close()
+
+	body, err := json.Marshal(newTestEvent(loops.EventNameMailingListSubscribed))
+	if err != nil {
+		t.Fatalf("Failed to marshal event: %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		path     string
+		expected int
+	}{
+		{name: "combined route", path: "/providers/loops" + wh.Endpoint, expected: http.StatusOK},
+		{name: "unprefixed route", path: wh.Endpoint, expected: http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			u, err := url.Parse(ts.URL + tt.path)
+			if err != nil {
+				t.Fatalf("Failed to parse URL: %v", err)
+			}
+			req := signedRequest(t, testSigningSecret, body)
+			req.RequestURI = ""
+			req.URL = u
+
+			resp, err := ts.Client().Do(req)
+			if err != nil {
+				t.Fatalf("Request failed: %v", err)
+			}
+			_ = resp.Body.Close()
+			if resp.StatusCode != tt.expected {
+				t.Errorf("Expected status %d, got %d", tt.expected, resp.StatusCode)
+			}
+		})
+	}
+}
+
+func TestServeHTTP_InvalidEvent(t *testing.T) {
+	tests := []struct {
+		name  string
+		event map[string]any
+	}{
+		{name: "missing eventName", event: map[string]any{"webhookSchemaVersion": "1.0.0"}},
+		{name: "missing webhookSchemaVersion", event: map[string]any{"eventName": loops.EventNameMailingListSubscribed}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			wh := NewLoopsContactGroupMembershipWebhookV1(newTestClient(t), testSigningSecret)
+			if resp := serveEvent(t, wh, testSigningSecret, tt.event); resp.HttpStatus != http.StatusBadRequest {
+				t.Errorf("Expected status %d, got %d", http.StatusBadRequest, resp.HttpStatus)
+			}
+		})
+	}
+}
+
+func TestServeHTTP_MalformedEventDetail(t *testing.T) {
+	tests := []struct {
+		name  string
+		event map[string]any
+	}{
+		{
+			name: "subscribed event missing mailingList",
+			event: map[string]any{
+				"eventName":            loops.EventNameMailingListSubscribed,
+				"webhookSchemaVersion": "1.0.0",
+				"contactIdentity":      map[string]any{"userId": "contact-uid"},
+			},
+		},
+		{
+			name: "subscribed event with a mailingList of the wrong type",
+			event: map[string]any{
+				"eventName":            loops.EventNameMailingListSubscribed,
+				"webhookSchemaVersion": "1.0.0",
+				"mailingList":          "list-abc",
+			},
+		},
+		{
+			name: "unsubscribed event missing mailingList.id",
+			event: map[string]any{
+				"eventName":            loops.EventNameMailingListUnsubscribed,
+				"webhookSchemaVersion": "1.0.0",
+				"mailingList":          map[string]any{"name": "Newsletter"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handled := false
+			wh := &Webhook{
+				Handler: HandlerFunc(func(context.Context, Request) Response {
+					handled = true
+					return BadRequestResponse()
+				}),
+				signingSecret: testSigningSecret,
+			}
+
+			if resp := serveEvent(t, wh, testSigningSecret, tt.event); resp.HttpStatus != http.StatusOK {
+				t.Errorf("Expected a malformed event to be acknowledged with %d, got %d", http.StatusOK, resp.HttpStatus)
+			}
+			if handled {
+				t.Error("Expected a malformed event not to be handled")
+			}
+		})
+	}
+}
+
+func TestServeHTTP_RequireJSONContentType(t *testing.T) {
+	tests := []struct {
+		name        string
+		require     bool
+		contentType string
+		wantStatus  int
+	}{
+		{name: "JSON", require: true, contentType: "application/json", wantStatus: http.StatusOK},
+		{name: "JSON with charset", require: true, contentType: "application/json; charset=utf-8",
+			wantStatus: http.StatusOK},
+		{name: "wrong content type", require: true, contentType: "text/plain",
+			wantStatus: http.StatusUnsupportedMediaType},
+		{name: "missing content type", require: true, wantStatus: http.StatusUnsupportedMediaType},
+		{name: "lenient by default", contentType: "text/plain", wantStatus: http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			wh := NewLoopsContactGroupMembershipWebhookV1(newTestClient(t), testSigningSecret)
+			wh.RequireJSONContentType = tt.require
+
+			body := []byte(`{"eventName":"contact.created","webhookSchemaVersion":"1.0.0"}`)
+			r := signedRequest(t, testSigningSecret, body)
+			r.Header.Del("Content-Type")
+			if tt.contentType != "" {
+				r.Header.Set("Content-Type", tt.contentType)
+			}
+			if resp := serveRequest(wh, r); resp.HttpStatus != tt.wantStatus {
+				t.Errorf("Expected status %d, got %d", tt.wantStatus, resp.HttpStatus)
+			}
+		})
+	}
+}
+
+func TestServeHTTP_InsecureSkipSignatureVerification(t *testing.T) {
+	tests := []struct {
+		name        string
+		skip        bool
+		wantStatus  int
+		wantWarning bool
+	}{
+		{name: "verified by default", wantStatus: http.StatusUnauthorized},
+		{name: "skipped", skip: true, wantStatus: http.StatusOK, wantWarning: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			wh := NewLoopsContactGroupMembershipWebhookV1(newTestClient(t), testSigningSecret)
+			wh.InsecureSkipSignatureVerification = tt.skip
+
+			var logs strings.Builder
+			logger := funcr.New(func(prefix, args string) {
+				logs.WriteString(args + "\n")
+			}, funcr.Options{})
+
+			// An unsigned request, as forwarded by a proxy stripping the signature headers
+			body := `{"eventName":"contact.created","webhookSchemaVersion":"1.0.0"}`
+			r := httptest.NewRequest(http.MethodPost, wh.Path(), strings.NewReader(body))
+			r = r.WithContext(logf.IntoContext(r.Context(), logger))
+			if resp := serveRequest(wh, r); resp.HttpStatus != tt.wantStatus {
+				t.Errorf("Expected status %d, got %d", tt.wantStatus, resp.HttpStatus)
+			}
+			if got := strings.Contains(logs.String(), "skipping webhook signature verification"); got != tt.wantWarning {
+				t.Errorf("Expected warning logged to be %t, got logs %q", tt.wantWarning, logs.String())
+			}
+		})
+	}
+}
+
+func TestServeHTTP_UnsignedTestEvent(t *testing.T) {
+	allowance := &UnsignedTestEventAllowance{EventName: "test.event", Header: "X-Loops-Test", HeaderValue: "true"}
+	testEvent := `{"eventName":"test.event","webhookSchemaVersion":"1.0.0"}`
+
+	tests := []struct {
+		name        string
+		allowance   *UnsignedTestEventAllowance
+		body        string
+		headers     map[string]string
+		signed      bool
+		wantStatus  int
+		wantWarning bool
+	}{
+		{
+			name:        "allowed test event",
+			allowance:   allowance,
+			body:        testEvent,
+			headers:     map[string]string{"X-Loops-Test": "true"},
+			wantStatus:  http.StatusOK,
+			wantWarning: true,
+		},
+		{
+			name:       "no allowance",
+			body:       testEvent,
+			headers:    map[string]string{"X-Loops-Test": "true"},
+			wantStatus: http.StatusUnauthorized,
+		},
+		{
+			name:       "missing header",
+			allowance:  allowance,
+			body:       testEvent,
+			wantStatus: http.StatusUnauthorized,
+		},
+		{
+			name:       "wrong header value",
+			allowance:  allowance,
+			body:       testEvent,
+			headers:    map[string]string{"X-Loops-Test": "false"},
+			wantStatus: http.StatusUnauthorized,
+		},
+		{
+			name:       "real event",
+			allowance:  allowance,
+			body:       `{"eventName":"contact.mailingList.subscribed","webhookSchemaVersion":"1.0.0"}`,
+			headers:    map[string]string{"X-Loops-Test": "true"},
+			wantStatus: http.StatusUnauthorized,
+		},
+		{
+			name:       "batch of test events",
+			allowance:  allowance,
+			body:       "[" + testEvent + "]",
+			headers:    map[string]string{"X-Loops-Test": "true"},
+			wantStatus: http.StatusUnauthorized,
+		},
+		{
+			name:       "test event with an invalid signature",
+			allowance:  allowance,
+			body:       testEvent,
+			headers:    map[string]string{"X-Loops-Test": "true", "webhook-signature": "v1,aW52YWxpZA=="},
+			wantStatus: http.StatusUnauthorized,
+		},
+		{
+			name:       "signed real event",
+			allowance:  allowance,
+			body:       `{"eventName":"contact.created","webhookSchemaVersion":"1.0.0"}`,
+			signed:     true,
+			wantStatus: http.StatusOK,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			wh := NewLoopsContactGroupMembershipWebhookV1(newTestClient(t), testSigningSecret)
+			wh.UnsignedTestEvent = tt.allowance
+
+			var logs strings.Builder
+			logger := funcr.New(func(prefix, args string) {
+				logs.WriteString(args + "\n")
+			}, funcr.Options{})
+
+			var r *http.Request
+			if tt.signed {
+				r = signedRequest(t, testSigningSecret, []byte(tt.body))
+			} else {
+				r = httptest.NewRequest(http.MethodPost, wh.Path(), strings.NewReader(tt.body))
+			}
+			for key, value := range tt.headers {
+				r.Header.Set(key, value)
+			}
+			r = r.WithContext(logf.IntoContext(r.Context(), logger))
+
+			if resp := serveRequest(wh, r); resp.HttpStatus != tt.wantStatus {
+				t.Errorf("Expected status %d, got %d", tt.wantStatus, resp.HttpStatus)
+			}
+			if got := strings.Contains(logs.String(), "unsigned test event"); got != tt.wantWarning {
+				t.Errorf("Expected warning logged to be %t, got logs %q", tt.wantWarning, logs.String())
+			}
+		})
+	}
+}
+
+func TestServeHTTP_CacheNotSynced(t *testing.T) {
+	tests := []struct {
+		name        string
+		synced      bool
+		wantStatus  int
+		wantMembers int
+	}{
+		{name: "synced", synced: true, wantStatus: http.StatusOK, wantMembers: 1},
+		{name: "not synced", synced: false, wantStatus: http.StatusServiceUnavailable, wantMembers: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			k8sClient := newTestClient(t, newTestContact(), newTestContactGroup())
+			wh := NewLoopsContactGroupMembershipWebhookV1(k8sClient, testSigningSecret)
+			wh.CacheSynced = func(context.Context) bool { return tt.synced }
+
+			event := newTestEvent(loops.EventNameMailingListSubscribed)
+			if resp := serveEvent(t, wh, testSigningSecret, event); resp.HttpStatus != tt.wantStatus {
+				t.Errorf("Expected status %d, got %d", tt.wantStatus, resp.HttpStatus)
+			}
+			assertCount(t, k8sClient, &notificationmiloapiscomv1alpha1.ContactGroupMembershipList{}, tt.wantMembers)
+		})
+	}
+}
+
+func TestVerifyWebhook_SignatureVersions(t *testing.T) {
+	body := []byte(`{}`)
+	v1Signature := sign(t, testSigningSecret, "msg_123", "1700000000", body)
+	_, v1Value, _ := strings.Cut(v1Signature, ",")
+
+	// v2 signs the content reversed, standing in for a future scheme
+	v2Scheme := func(secret []byte, signedContent string, signature string) bool {
+		reversed := []rune(signedContent)
+		slices.Reverse(reversed)
+		return verifyHMACSHA256Signature(secret, string(reversed), signature)
+	}
+	reversedContent := []rune(fmt.Sprintf("%s.%s.%s", "msg_123", "1700000000", body))
+	slices.Reverse(reversedContent)
+	v2Signature := "v2," + signWith(t, testSigningSecret, string(reversedContent))
+
+	tests := []struct {
+		name      string
+		signature string
+		schemes   map[string]SignatureScheme
+		wantValid bool
+	}{
+		{name: "v1", signature: v1Signature, wantValid: true},
+		{name: "v1 among several signatures", signature: "v1,aW52YWxpZA== " + v1Signature, wantValid: true},
+		{name: "unknown version", signature: "v9," + v1Value},
+		{name: "unknown version next to v1", signature: "v9,aW52YWxpZA== " + v1Signature, wantValid: true},
+		{name: "malformed signature", signature: v1Value},
+		{name: "registered v2", signature: v2Signature, wantValid: true,
+			schemes: map[string]SignatureScheme{"v1": verifyHMACSHA256Signature, "v2": v2Scheme}},
+		{name: "unregistered v2", signature: v2Signature},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodPost, "/", bytes.NewReader(body))
+			r.Header.Set("webhook-id", "msg_123")
+			r.Header.Set("webhook-timestamp", "1700000000")
+			r.Header.Set("webhook-signature", tt.signature)
+
+			err := verifyWebhook(r, body, testSigningSecret, tt.schemes, 0, time.Now())
+			if tt.wantValid && err != nil {
+				t.Errorf("verifyWebhook() failed: %v", err)
+			}
+			if !tt.wantValid && !IsVerificationErrorCode(err, VerificationErrorCodeInvalidSignature) {
+				t.Errorf("Expected error code %s, got %v", VerificationErrorCodeInvalidSignature, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,36 @@
+package webhook
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"go.miloapis.com/email-provider-loops/pkg/loops"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+)
+
+// unknownEventName labels the events whose name is not a known Loops event, to keep cardinality in check.
+const unknownEventName = "unknown"
+
+var (
+	// eventsTotal counts the received webhook events by name, e.g. to tell contacts added to a mailing list from the
+	// ones subscribing to it.
+	eventsTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "loops_webhook_events_total",
+			Help: "Total number of Loops webhook events received, by event name.",
+		},
+		[]string{"event_name"},
+	)
+)
+
+func init() {
+	metrics.Registry.MustRegister(eventsTotal)
+}
+
+// recordEvent counts a received event.
+func recordEvent(eventName string) {
+	switch eventName {
+	case loops.EventNameMailingListSubscribed, loops.EventNameMailingListAdded, loops.EventNameMailingListUnsubscribed:
+	default:
+		eventName = unknownEventName
+	}
+	eventsTotal.WithLabelValues(eventName).Inc()
+}
@@ -28,6 +28,18 @@ func UnauthorizedResponse() Response {
 	return webhookResponse(http.StatusUnauthorized)
 }
 
+func MultiStatusResponse() Response {
+	return webhookResponse(http.StatusMultiStatus)
+}
+
+func UnsupportedMediaTypeResponse() Response {
+	return webhookResponse(http.StatusUnsupportedMediaType)
+}
+
+func ServiceUnavailableResponse() Response {
+	return webhookResponse(http.StatusServiceUnavailable)
+}
+
 func webhookResponse(httpStatus int) Response {
 	return Response{
 		HttpStatus: httpStatus,
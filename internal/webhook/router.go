@@ -0,0 +1,65 @@
+package webhook
+
+import (
+	"context"
+
+	"go.miloapis.com/email-provider-loops/pkg/loops"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// MailingListSubscribedHandlerFunc handles contact.mailingList.subscribed events, as well as contact.mailingList.added
+// ones, which only differ by their EventName.
+type MailingListSubscribedHandlerFunc func(context.Context, *loops.MailingListSubscribedEvent) Response
+
+// MailingListUnsubscribedHandlerFunc handles contact.mailingList.unsubscribed events.
+type MailingListUnsubscribedHandlerFunc func(context.Context, *loops.MailingListUnsubscribedEvent) Response
+
+// Router is a Handler dispatching each event to the handler registered for its type, so that handlers receive the
+// typed event instead of a Request to nil-check. Set it as the Handler of a Webhook.
+type Router struct {
+	// Default handles the events without a registered handler. They are acknowledged when it is nil, so that Loops
+	// stops retrying their delivery.
+	Default Handler
+
+	mailingListSubscribed   MailingListSubscribedHandlerFunc
+	mailingListUnsubscribed MailingListUnsubscribedHandlerFunc
+}
+
+var _ Handler = &Router{}
+
+// NewRouter returns a Router without registered handlers.
+func NewRouter() *Router {
+	return &Router{}
+}
+
+// OnMailingListSubscribed registers the handler of contact.mailingList.subscribed events.
+func (r *Router) OnMailingListSubscribed(h MailingListSubscribedHandlerFunc) *Router {
+	r.mailingListSubscribed = h
+	return r
+}
+
+// OnMailingListUnsubscribed registers the handler of contact.mailingList.unsubscribed events.
+func (r *Router) OnMailingListUnsubscribed(h MailingListUnsubscribedHandlerFunc) *Router {
+	r.mailingListUnsubscribed = h
+	return r
+}
+
+// Handle dispatches the event of the request to its registered handler, or to Default.
+func (r *Router) Handle(ctx context.Context, req Request) Response {
+	switch {
+	case req.MailingListSubscribedEvent != nil && r.mailingListSubscribed != nil:
+		return r.mailingListSubscribed(ctx, req.MailingListSubscribedEvent)
+	case req.MailingListUnsubscribedEvent != nil && r.mailingListUnsubscribed != nil:
+		return r.mailingListUnsubscribed(ctx, req.MailingListUnsubscribedEvent)
+	case r.Default != nil:
+		return r.Default.Handle(ctx, req)
+	}
+
+	eventName := ""
+	if req.BaseEvent != nil {
+		eventName = req.BaseEvent.EventName
+	}
+	logf.FromContext(ctx).WithName("loops-webhook-router").Info("No handler registered for event, acknowledging it",
+		"eventName", eventName)
+	return OkResponse()
+}
@@ -0,0 +1,113 @@
+package webhook
+
+import (
+	"context"
+	"net/http"
+	"slices"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"go.miloapis.com/email-provider-loops/pkg/loops"
+)
+
+func TestRouter_Dispatch(t *testing.T) {
+	var subscribedList, unsubscribedList string
+	router := NewRouter().
+		OnMailingListSubscribed(func(_ context.Context, event *loops.MailingListSubscribedEvent) Response {
+			subscribedList = event.MailingList.ID
+			return OkResponse()
+		}).
+		OnMailingListUnsubscribed(func(_ context.Context, event *loops.MailingListUnsubscribedEvent) Response {
+			unsubscribedList = event.MailingList.ID
+			return NotFoundResponse()
+		})
+	wh := &Webhook{Handler: router, signingSecret: testSigningSecret}
+
+	resp := serveEvent(t, wh, testSigningSecret, map[string]any{
+		"eventName":            loops.EventNameMailingListSubscribed,
+		"webhookSchemaVersion": "1.0.0",
+		"mailingList":          map[string]any{"id": "list-abc"},
+	})
+	if resp.HttpStatus != http.StatusOK {
+		t.Errorf("Expected status %d, got %d", http.StatusOK, resp.HttpStatus)
+	}
+	if subscribedList != "list-abc" {
+		t.Errorf("Expected the subscribed handler to get list-abc, got %q", subscribedList)
+	}
+
+	resp = serveEvent(t, wh, testSigningSecret, map[string]any{
+		"eventName":            loops.EventNameMailingListUnsubscribed,
+		"webhookSchemaVersion": "1.0.0",
+		"mailingList":          map[string]any{"id": "list-def"},
+	})
+	if resp.HttpStatus != http.StatusNotFound {
+		t.Errorf("Expected the response of the unsubscribed handler %d, got %d", http.StatusNotFound, resp.HttpStatus)
+	}
+	if unsubscribedList != "list-def" {
+		t.Errorf("Expected the unsubscribed handler to get list-def, got %q", unsubscribedList)
+	}
+}
+
+func TestRouter_MailingListAddedEvent(t *testing.T) {
+	var eventNames []string
+	router := NewRouter().OnMailingListSubscribed(func(_ context.Context, event *loops.MailingListSubscribedEvent) Response {
+		eventNames = append(eventNames, event.EventName)
+		return OkResponse()
+	})
+	wh := &Webhook{Handler: router, signingSecret: testSigningSecret}
+
+	addedBefore := testutil.ToFloat64(eventsTotal.WithLabelValues(loops.EventNameMailingListAdded))
+	subscribedBefore := testutil.ToFloat64(eventsTotal.WithLabelValues(loops.EventNameMailingListSubscribed))
+
+	for _, eventName := range []string{loops.EventNameMailingListAdded, loops.EventNameMailingListSubscribed} {
+		resp := serveEvent(t, wh, testSigningSecret, map[string]any{
+			"eventName":            eventName,
+			"webhookSchemaVersion": "1.0.0",
+			"mailingList":          map[string]any{"id": "list-abc"},
+		})
+		if resp.HttpStatus != http.StatusOK {
+			t.Errorf("Expected status %d for %s, got %d", http.StatusOK, eventName, resp.HttpStatus)
+		}
+	}
+
+	// Added events are routed to the subscribed handler, keeping their name
+	expected := []string{loops.EventNameMailingListAdded, loops.EventNameMailingListSubscribed}
+	if !slices.Equal(eventNames, expected) {
+		t.Errorf("Expected the subscribed handler to get %v, got %v", expected, eventNames)
+	}
+	if got := testutil.ToFloat64(eventsTotal.WithLabelValues(loops.EventNameMailingListAdded)) - addedBefore; got != 1 {
+		t.Errorf("Expected 1 added event to be counted, got %v", got)
+	}
+	if got := testutil.ToFloat64(eventsTotal.WithLabelValues(loops.EventNameMailingListSubscribed)) - subscribedBefore; got != 1 {
+		t.Errorf("Expected 1 subscribed event to be counted, got %v", got)
+	}
+}
+
+func TestRouter_UnregisteredEvent(t *testing.T) {
+	event := map[string]any{
+		"eventName":            loops.EventNameMailingListUnsubscribed,
+		"webhookSchemaVersion": "1.0.0",
+		"mailingList":          map[string]any{"id": "list-abc"},
+	}
+
+	router := NewRouter().OnMailingListSubscribed(func(context.Context, *loops.MailingListSubscribedEvent) Response {
+		t.Error("Expected the subscribed handler not to be called")
+		return OkResponse()
+	})
+	wh := &Webhook{Handler: router, signingSecret: testSigningSecret}
+	if resp := serveEvent(t, wh, testSigningSecret, event); resp.HttpStatus != http.StatusOK {
+		t.Errorf("Expected an unregistered event to be acknowledged with %d, got %d", http.StatusOK, resp.HttpStatus)
+	}
+
+	defaultCalled := false
+	router.Default = HandlerFunc(func(context.Context, Request) Response {
+		defaultCalled = true
+		return BadRequestResponse()
+	})
+	if resp := serveEvent(t, wh, testSigningSecret, event); resp.HttpStatus != http.StatusBadRequest {
+		t.Errorf("Expected the response of the default handler %d, got %d", http.StatusBadRequest, resp.HttpStatus)
+	}
+	if !defaultCalled {
+		t.Error("Expected the default handler to be called")
+	}
+}
@@ -0,0 +1,102 @@
+package webhook
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// SigningSecretProvider provides the secret the webhook signatures are verified with, so that the secret can be
+// rotated without recreating the webhook.
+type SigningSecretProvider interface {
+	SigningSecret() string
+}
+
+// DefaultSigningSecretPollInterval is how often a FileSigningSecret checks its file for a rotated secret by default.
+const DefaultSigningSecretPollInterval = 10 * time.Second
+
+// FileSigningSecret is a SigningSecretProvider reading the signing secret from a file, e.g. a mounted Secret. Once
+// started, it reads the file again every PollInterval, so that a rotated secret is picked up without a restart.
+type FileSigningSecret struct {
+	// PollInterval is how often the file is read again. Defaults to DefaultSigningSecretPollInterval.
+	PollInterval time.Duration
+
+	path string
+
+	mu     sync.RWMutex
+	secret string
+}
+
+var _ SigningSecretProvider = &FileSigningSecret{}
+
+// NewFileSigningSecret reads the signing secret from the file at path.
+func NewFileSigningSecret(path string) (*FileSigningSecret, error) {
+	f := &FileSigningSecret{path: path}
+	if _, err := f.Reload(); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+// SigningSecret returns the signing secret last read from the file.
+func (f *FileSigningSecret) SigningSecret() string {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	return f.secret
+}
+
+// Reload reads the signing secret from the file again and reports whether it changed. The previous secret is kept
+// when the file cannot be read or is empty.
+func (f *FileSigningSecret) Reload() (bool, error) {
+	data, err := os.ReadFile(f.path)
+	if err != nil {
+		return false, fmt.Errorf("failed to read signing secret file: %w", err)
+	}
+	secret := strings.TrimSpace(string(data))
+	if secret == "" {
+		return false, fmt.Errorf("signing secret file %q is empty", f.path)
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	changed := secret != f.secret
+	f.secret = secret
+	return changed, nil
+}
+
+// Start reads the file again every PollInterval until ctx is done. A failed read keeps the previous secret.
+func (f *FileSigningSecret) Start(ctx context.Context) error {
+	log := logf.FromContext(ctx).WithName("signing-secret-reloader")
+	interval := f.PollInterval
+	if interval <= 0 {
+		interval = DefaultSigningSecretPollInterval
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+			changed, err := f.Reload()
+			if err != nil {
+				log.Error(err, "failed to reload the signing secret, keeping the previous one")
+				continue
+			}
+			if changed {
+				log.Info("reloaded the rotated signing secret")
+			}
+		}
+	}
+}
+
+// NeedLeaderElection makes every replica reload its signing secret, not only the leader.
+func (f *FileSigningSecret) NeedLeaderElection() bool {
+	return false
+}
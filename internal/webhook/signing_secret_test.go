@@ -0,0 +1,73 @@
+package webhook
+
+import (
+	"context"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestFileSigningSecret(t *testing.T) {
+	const rotatedSigningSecret = "whsec_cm90YXRlZC1zaWduaW5nLXNlY3JldA=="
+	body := []byte(`{"eventName":"contact.created","webhookSchemaVersion":"1.0.0"}`)
+
+	path := filepath.Join(t.TempDir(), "signing-secret")
+	if err := os.WriteFile(path, []byte(testSigningSecret+"\n"), 0o600); err != nil {
+		t.Fatalf("WriteFile() failed: %v", err)
+	}
+
+	secret, err := NewFileSigningSecret(path)
+	if err != nil {
+		t.Fatalf("NewFileSigningSecret() failed: %v", err)
+	}
+	secret.PollInterval = 10 * time.Millisecond
+	if got := secret.SigningSecret(); got != testSigningSecret {
+		t.Errorf("Expected the secret %q, got %q", testSigningSecret, got)
+	}
+
+	// The secret passed on creation is ignored in favor of the file
+	wh := NewLoopsContactGroupMembershipWebhookV1(newTestClient(t), "whsec_aWdub3JlZA==")
+	wh.SigningSecretProvider = secret
+	if resp := serveRequest(wh, signedRequest(t, testSigningSecret, body)); resp.HttpStatus != http.StatusOK {
+		t.Errorf("Expected status %d with the file secret, got %d", http.StatusOK, resp.HttpStatus)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		if err := secret.Start(ctx); err != nil {
+			t.Errorf("Start() failed: %v", err)
+		}
+	}()
+
+	// A rotated secret is picked up
+	if err := os.WriteFile(path, []byte(rotatedSigningSecret), 0o600); err != nil {
+		t.Fatalf("WriteFile() failed: %v", err)
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for secret.SigningSecret() != rotatedSigningSecret && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if got := secret.SigningSecret(); got != rotatedSigningSecret {
+		t.Fatalf("Expected the rotated secret %q, got %q", rotatedSigningSecret, got)
+	}
+	if resp := serveRequest(wh, signedRequest(t, rotatedSigningSecret, body)); resp.HttpStatus != http.StatusOK {
+		t.Errorf("Expected status %d with the rotated secret, got %d", http.StatusOK, resp.HttpStatus)
+	}
+	if resp := serveRequest(wh, signedRequest(t, testSigningSecret, body)); resp.HttpStatus != http.StatusUnauthorized {
+		t.Errorf("Expected status %d with the previous secret, got %d", http.StatusUnauthorized, resp.HttpStatus)
+	}
+
+	// An emptied file keeps the previous secret
+	if err := os.WriteFile(path, nil, 0o600); err != nil {
+		t.Fatalf("WriteFile() failed: %v", err)
+	}
+	if _, err := secret.Reload(); err == nil {
+		t.Error("Expected an error reloading an empty file")
+	}
+	if got := secret.SigningSecret(); got != rotatedSigningSecret {
+		t.Errorf("Expected the previous secret to be kept, got %q", got)
+	}
+}
@@ -10,9 +10,21 @@ type API interface {
 	// DeleteContact deletes a contact from Loops.
 	DeleteContact(ctx context.Context, userID string) (*APIResponse, error)
 
+	// Unsubscribe globally unsubscribes a contact, retaining it and its mailing list subscriptions.
+	Unsubscribe(ctx context.Context, userID string) (*APIResponse, error)
+
 	// AddToMailingList adds a contact to a specific mailing list.
 	AddToMailingList(ctx context.Context, userID string, listID string) (*APIResponse, error)
 
 	// RemoveFromMailingList removes a contact from a specific mailing list.
 	RemoveFromMailingList(ctx context.Context, userID string, listID string) (*APIResponse, error)
+
+	// GetContactMailingLists returns the mailing list subscriptions of a contact, keyed by mailing list ID.
+	GetContactMailingLists(ctx context.Context, userID string) (map[string]bool, error)
+
+	// FindContact returns the contact matching the query, or nil if there is none.
+	FindContact(ctx context.Context, req FindContactRequest) (*Contact, error)
+
+	// UpdateMailingList updates the name, description or visibility of a mailing list.
+	UpdateMailingList(ctx context.Context, id string, req MailingListUpdate) (*APIResponse, error)
 }
@@ -0,0 +1,65 @@
+package loops
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+)
+
+// APIKeyProvider provides the API key sent with each request, so that the key can be rotated without recreating the
+// client.
+type APIKeyProvider interface {
+	APIKey() string
+}
+
+// WithAPIKeyProvider reads the API key from provider on each request, instead of using the key passed to NewSDK.
+func WithAPIKeyProvider(provider APIKeyProvider) ClientOption {
+	return func(c *Client) {
+		c.apiKeyProvider = provider
+	}
+}
+
+// FileAPIKey is an APIKeyProvider reading the API key from a file, e.g. a mounted Secret. The file is read again on
+// Reload, so that a rotated key is picked up without a restart.
+type FileAPIKey struct {
+	path string
+
+	mu  sync.RWMutex
+	key string
+}
+
+var _ APIKeyProvider = &FileAPIKey{}
+
+// NewFileAPIKey reads the API key from the file at path.
+func NewFileAPIKey(path string) (*FileAPIKey, error) {
+	f := &FileAPIKey{path: path}
+	if err := f.Reload(); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+// APIKey returns the API key last read from the file.
+func (f *FileAPIKey) APIKey() string {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	return f.key
+}
+
+// Reload reads the API key from the file again. The previous key is kept when the file cannot be read or is empty.
+func (f *FileAPIKey) Reload() error {
+	data, err := os.ReadFile(f.path)
+	if err != nil {
+		return fmt.Errorf("failed to read api key file: %w", err)
+	}
+	key := strings.TrimSpace(string(data))
+	if key == "" {
+		return fmt.Errorf("api key file %q is empty", f.path)
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.key = key
+	return nil
+}
@@ -0,0 +1,66 @@
+package loops
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestWithAPIKeyProvider_Reload(t *testing.T) {
+	var gotAuth string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotAuth = r.Header.Get("Authorization")
+		if err := json.NewEncoder(w).Encode(APIResponse{Success: true}); err != nil {
+			t.Errorf("Failed to write response: %v", err)
+		}
+	}))
+	defer ts.Close()
+
+	keyFile := filepath.Join(t.TempDir(), "api-key")
+	if err := os.WriteFile(keyFile, []byte("old-key\n"), 0o600); err != nil {
+		t.Fatalf("Failed to write key file: %v", err)
+	}
+	key, err := NewFileAPIKey(keyFile)
+	if err != nil {
+		t.Fatalf("NewFileAPIKey() failed: %v", err)
+	}
+	c, err := NewSDK("", WithBaseURL(ts.URL), WithAPIKeyProvider(key))
+	if err != nil {
+		t.Fatalf("NewSDK() failed: %v", err)
+	}
+
+	if _, err := c.UpsertContact(context.Background(), ContactRequest{Email: "test@example.com"}); err != nil {
+		t.Fatalf("UpsertContact() failed: %v", err)
+	}
+	if gotAuth != "Bearer old-key" {
+		t.Errorf("Expected Authorization %q, got %q", "Bearer old-key", gotAuth)
+	}
+
+	if err := os.WriteFile(keyFile, []byte("new-key\n"), 0o600); err != nil {
+		t.Fatalf("Failed to write key file: %v", err)
+	}
+	if err := key.Reload(); err != nil {
+		t.Fatalf("Reload() failed: %v", err)
+	}
+	if _, err := c.UpsertContact(context.Background(), ContactRequest{Email: "test@example.com"}); err != nil {
+		t.Fatalf("UpsertContact() failed: %v", err)
+	}
+	if gotAuth != "Bearer new-key" {
+		t.Errorf("Expected Authorization %q, got %q", "Bearer new-key", gotAuth)
+	}
+
+	// An emptied file keeps the previous key
+	if err := os.WriteFile(keyFile, nil, 0o600); err != nil {
+		t.Fatalf("Failed to write key file: %v", err)
+	}
+	if err := key.Reload(); err == nil {
+		t.Error("Expected an error for an empty key file, got none")
+	}
+	if key.APIKey() != "new-key" {
+		t.Errorf("Expected the key to stay %q, got %q", "new-key", key.APIKey())
+	}
+}
@@ -0,0 +1,21 @@
+package loops
+
+import (
+	"crypto/x509"
+	"fmt"
+	"os"
+)
+
+// LoadCACertPool reads a PEM bundle of CA certificates, e.g. for WithCACertPool.
+func LoadCACertPool(path string) (*x509.CertPool, error) {
+	pem, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read CA bundle: %w", err)
+	}
+
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(pem) {
+		return nil, fmt.Errorf("no CA certificate found in %s", path)
+	}
+	return pool, nil
+}
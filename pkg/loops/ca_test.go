@@ -0,0 +1,116 @@
+package loops
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestWithCACertPool(t *testing.T) {
+	// The test server certificate is signed by a CA that is not in the system pool
+	ts := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := json.NewEncoder(w).Encode(APIResponse{Success: true}); err != nil {
+			t.Errorf("Failed to write response: %v", err)
+		}
+	}))
+	defer ts.Close()
+
+	caFile := filepath.Join(t.TempDir(), "ca.pem")
+	caPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: ts.Certificate().Raw})
+	if err := os.WriteFile(caFile, caPEM, 0o600); err != nil {
+		t.Fatalf("Failed to write CA bundle: %v", err)
+	}
+	pool, err := LoadCACertPool(caFile)
+	if err != nil {
+		t.Fatalf("LoadCACertPool() failed: %v", err)
+	}
+
+	untrusting, _ := NewSDK("test-key", WithBaseURL(ts.URL))
+	if _, err := untrusting.UpsertContact(context.Background(), ContactRequest{Email: "test@example.com"}); err == nil {
+		t.Error("Expected an error without the custom CA, got none")
+	}
+
+	trusting, err := NewSDK("test-key", WithBaseURL(ts.URL), WithCACertPool(pool))
+	if err != nil {
+		t.Fatalf("NewSDK() failed: %v", err)
+	}
+	if _, err := trusting.UpsertContact(context.Background(), ContactRequest{Email: "test@example.com"}); err != nil {
+		t.Errorf("UpsertContact() failed: %v", err)
+	}
+}
+
+func TestLoadCACertPool_Invalid(t *testing.T) {
+	notPEM := filepath.Join(t.TempDir(), "ca.pem")
+	if err := os.WriteFile(notPEM, []byte("not a certificate"), 0o600); err != nil {
+		t.Fatalf("Failed to write CA bundle: %v", err)
+	}
+
+	for _, path := range []string{notPEM, filepath.Join(t.TempDir(), "missing.pem")} {
+		if _, err := LoadCACertPool(path); err == nil {
+			t.Errorf("Expected an error for %s, got none", path)
+		}
+	}
+}
+
+func TestWithMinTLSVersion(t *testing.T) {
+	tests := []struct {
+		name          string
+		serverMax     uint16
+		opts          []ClientOption
+		expectedError bool
+	}{
+		{name: "default accepts TLS 1.2", serverMax: tls.VersionTLS12},
+		{name: "default rejects TLS 1.1", serverMax: tls.VersionTLS11, expectedError: true},
+		{name: "TLS 1.3 minimum rejects TLS 1.2", serverMax: tls.VersionTLS12,
+			opts: []ClientOption{WithMinTLSVersion(tls.VersionTLS13)}, expectedError: true},
+		{name: "TLS 1.3 minimum accepts TLS 1.3", serverMax: tls.VersionTLS13,
+			opts: []ClientOption{WithMinTLSVersion(tls.VersionTLS13)}},
+		{name: "default is enforced on a custom transport", serverMax: tls.VersionTLS11,
+			opts: []ClientOption{WithHTTPClient(&http.Client{Transport: &http.Transport{
+				TLSClientConfig: &tls.Config{MinVersion: tls.VersionTLS10},
+			}})}, expectedError: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ts := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if err := json.NewEncoder(w).Encode(APIResponse{Success: true}); err != nil {
+					t.Errorf("Failed to write response: %v", err)
+				}
+			}))
+			ts.TLS = &tls.Config{MaxVersion: tt.serverMax}
+			ts.StartTLS()
+			defer ts.Close()
+
+			pool := x509.NewCertPool()
+			pool.AddCert(ts.Certificate())
+			opts := append([]ClientOption{WithBaseURL(ts.URL)}, tt.opts...)
+			opts = append(opts, WithCACertPool(pool))
+			client, err := NewSDK("test-key", opts...)
+			if err != nil {
+				t.Fatalf("NewSDK() failed: %v", err)
+			}
+
+			_, err = client.UpsertContact(context.Background(), ContactRequest{Email: "test@example.com"})
+			if tt.expectedError && err == nil {
+				t.Error("Expected a TLS error, got none")
+			}
+			if !tt.expectedError && err != nil {
+				t.Errorf("UpsertContact() failed: %v", err)
+			}
+		})
+	}
+}
+
+func TestWithMinTLSVersion_Invalid(t *testing.T) {
+	if _, err := NewSDK("test-key", WithMinTLSVersion(0x0200)); err == nil {
+		t.Error("Expected an error for an invalid TLS version, got none")
+	}
+}
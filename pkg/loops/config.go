@@ -0,0 +1,130 @@
+package loops
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// Duration is a time.Duration that is encoded in JSON as a Go duration string, e.g. "5s" or "1m30s".
+type Duration struct {
+	time.Duration
+}
+
+// MarshalJSON encodes the duration as a string.
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(d.String())
+}
+
+// UnmarshalJSON decodes a duration string.
+func (d *Duration) UnmarshalJSON(data []byte) error {
+	var value string
+	if err := json.Unmarshal(data, &value); err != nil {
+		return fmt.Errorf("duration must be a string such as \"5s\": %w", err)
+	}
+	duration, err := time.ParseDuration(value)
+	if err != nil {
+		return fmt.Errorf("invalid duration %q: %w", value, err)
+	}
+	d.Duration = duration
+	return nil
+}
+
+// Config configures a Client as a plain struct, for callers that load their configuration from a file. Zero values
+// keep the defaults of NewSDK. It maps to the same settings as the ClientOption functions.
+type Config struct {
+	// APIKey is the Loops API key. Required.
+	APIKey string `json:"apiKey"`
+	// BaseURL overrides the Loops API base URL.
+	BaseURL string `json:"baseURL,omitempty"`
+	// Timeout bounds each HTTP request. Defaults to 10 seconds.
+	Timeout Duration `json:"timeout,omitempty"`
+	// RetryAttempts is the number of retries of requests failing with a network error, a 429 or a 5xx.
+	RetryAttempts int `json:"retryAttempts,omitempty"`
+	// RetryBackoff is the wait before the first retry, doubled on each subsequent one.
+	RetryBackoff Duration `json:"retryBackoff,omitempty"`
+	// CircuitBreakerThreshold enables the circuit breaker, opening it after this many consecutive failed requests.
+	CircuitBreakerThreshold int `json:"circuitBreakerThreshold,omitempty"`
+	// CircuitBreakerCooldown is how long the circuit breaker stays open.
+	CircuitBreakerCooldown Duration `json:"circuitBreakerCooldown,omitempty"`
+	// ProxyURL sends every request through an HTTP proxy.
+	ProxyURL string `json:"proxyURL,omitempty"`
+	// CAFile is a PEM bundle of the CAs trusted when connecting to Loops, instead of the system ones.
+	CAFile string `json:"caFile,omitempty"`
+	// DefaultHeaders are sent on every request.
+	DefaultHeaders map[string]string `json:"defaultHeaders,omitempty"`
+	// Workspace is the Loops workspace the requests target, see WithWorkspace.
+	Workspace string `json:"workspace,omitempty"`
+	// DeadlineHeader sends the time left before the context deadline of each request in this header.
+	DeadlineHeader string `json:"deadlineHeader,omitempty"`
+	// ContactsImportPath enables ImportContacts to use the batch import endpoint at this path.
+	ContactsImportPath string `json:"contactsImportPath,omitempty"`
+	// MailingListUpdatePath enables UpdateMailingList to use the mailing list update endpoint under this path.
+	MailingListUpdatePath string `json:"mailingListUpdatePath,omitempty"`
+	// MailingListContactsPath enables ListContactsInMailingList to use the mailing list contacts endpoint under this
+	// path.
+	MailingListContactsPath string `json:"mailingListContactsPath,omitempty"`
+	// DeleteDryRun makes DeleteContact log the deletion without calling Loops.
+	DeleteDryRun bool `json:"deleteDryRun,omitempty"`
+	// MaxInFlightRequests bounds the number of concurrent requests.
+	MaxInFlightRequests int `json:"maxInFlightRequests,omitempty"`
+	// DebugBufferSize keeps this many of the last requests in memory for troubleshooting.
+	DebugBufferSize int `json:"debugBufferSize,omitempty"`
+}
+
+// NewSDKFromConfig creates a new Loops API client from a Config.
+func NewSDKFromConfig(cfg Config) (*Client, error) {
+	var opts []ClientOption
+	if cfg.BaseURL != "" {
+		opts = append(opts, WithBaseURL(cfg.BaseURL))
+	}
+	if cfg.Timeout.Duration > 0 {
+		opts = append(opts, WithHTTPClient(&http.Client{Timeout: cfg.Timeout.Duration}))
+	}
+	if cfg.RetryAttempts > 0 {
+		opts = append(opts, WithRetries(cfg.RetryAttempts, cfg.RetryBackoff.Duration))
+	}
+	if cfg.CircuitBreakerThreshold > 0 {
+		opts = append(opts, WithCircuitBreaker(cfg.CircuitBreakerThreshold, cfg.CircuitBreakerCooldown.Duration))
+	}
+	if cfg.ProxyURL != "" {
+		opts = append(opts, WithProxy(cfg.ProxyURL))
+	}
+	if cfg.CAFile != "" {
+		pool, err := LoadCACertPool(cfg.CAFile)
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, WithCACertPool(pool))
+	}
+	for key, value := range cfg.DefaultHeaders {
+		opts = append(opts, WithDefaultHeader(key, value))
+	}
+	if cfg.Workspace != "" {
+		opts = append(opts, WithWorkspace(cfg.Workspace))
+	}
+	if cfg.DeadlineHeader != "" {
+		opts = append(opts, WithDeadlineHeader(cfg.DeadlineHeader))
+	}
+	if cfg.ContactsImportPath != "" {
+		opts = append(opts, WithContactsImport(cfg.ContactsImportPath))
+	}
+	if cfg.MailingListUpdatePath != "" {
+		opts = append(opts, WithMailingListUpdate(cfg.MailingListUpdatePath))
+	}
+	if cfg.MailingListContactsPath != "" {
+		opts = append(opts, WithMailingListContacts(cfg.MailingListContactsPath))
+	}
+	if cfg.DeleteDryRun {
+		opts = append(opts, WithDeleteDryRun(true))
+	}
+	if cfg.MaxInFlightRequests > 0 {
+		opts = append(opts, WithConcurrencyLimiter(NewConcurrencyLimiter(cfg.MaxInFlightRequests)))
+	}
+	if cfg.DebugBufferSize > 0 {
+		opts = append(opts, WithDebugBuffer(cfg.DebugBufferSize))
+	}
+
+	return NewSDK(cfg.APIKey, opts...)
+}
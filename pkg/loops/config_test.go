@@ -0,0 +1,121 @@
+package loops
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestNewSDKFromConfig(t *testing.T) {
+	client, err := NewSDKFromConfig(Config{
+		APIKey:                  "test-key",
+		BaseURL:                 "https://loops.example.com/api/v1",
+		Timeout:                 Duration{5 * time.Second},
+		RetryAttempts:           3,
+		RetryBackoff:            Duration{time.Second},
+		CircuitBreakerThreshold: 5,
+		CircuitBreakerCooldown:  Duration{time.Minute},
+		ProxyURL:                "http://proxy.internal:3128",
+		DefaultHeaders:          map[string]string{"Loops-Beta-Feature": "on"},
+		DeadlineHeader:          "X-Deadline",
+		ContactsImportPath:      "/contacts/import",
+		DeleteDryRun:            true,
+	})
+	if err != nil {
+		t.Fatalf("NewSDKFromConfig() failed: %v", err)
+	}
+
+	if client.apiKey != "test-key" {
+		t.Errorf("Expected API key test-key, got %s", client.apiKey)
+	}
+	if client.baseURL != "https://loops.example.com/api/v1" {
+		t.Errorf("Expected base URL https://loops.example.com/api/v1, got %s", client.baseURL)
+	}
+	if client.httpClient.Timeout != 5*time.Second {
+		t.Errorf("Expected timeout 5s, got %s", client.httpClient.Timeout)
+	}
+	if client.maxRetries != 3 || client.retryBackoff != time.Second {
+		t.Errorf("Expected 3 retries with a 1s backoff, got %d with %s", client.maxRetries, client.retryBackoff)
+	}
+	if client.breaker == nil || client.breaker.threshold != 5 || client.breaker.cooldown != time.Minute {
+		t.Errorf("Expected a circuit breaker with threshold 5 and cooldown 1m, got %+v", client.breaker)
+	}
+	if client.proxyURL != "http://proxy.internal:3128" {
+		t.Errorf("Expected proxy http://proxy.internal:3128, got %s", client.proxyURL)
+	}
+	if client.defaultHeaders.Get("Loops-Beta-Feature") != "on" {
+		t.Errorf("Expected default header Loops-Beta-Feature: on, got %q", client.defaultHeaders.Get("Loops-Beta-Feature"))
+	}
+	if client.deadlineHeader != "X-Deadline" {
+		t.Errorf("Expected deadline header X-Deadline, got %q", client.deadlineHeader)
+	}
+	if client.importPath != "/contacts/import" {
+		t.Errorf("Expected import path /contacts/import, got %s", client.importPath)
+	}
+	if !client.deleteDryRun {
+		t.Error("Expected delete dry run to be enabled")
+	}
+}
+
+func TestNewSDKFromConfig_Minimal(t *testing.T) {
+	client, err := NewSDKFromConfig(Config{APIKey: "test-key"})
+	if err != nil {
+		t.Fatalf("NewSDKFromConfig() failed: %v", err)
+	}
+
+	if client.baseURL != DefaultBaseURL {
+		t.Errorf("Expected base URL %s, got %s", DefaultBaseURL, client.baseURL)
+	}
+	if client.httpClient.Timeout != 10*time.Second {
+		t.Errorf("Expected timeout 10s, got %s", client.httpClient.Timeout)
+	}
+	if client.maxRetries != 0 || client.breaker != nil || client.proxyURL != "" || client.deleteDryRun {
+		t.Errorf("Expected no optional behavior to be enabled, got %+v", client)
+	}
+}
+
+func TestNewSDKFromConfig_Invalid(t *testing.T) {
+	if _, err := NewSDKFromConfig(Config{}); err == nil {
+		t.Error("Expected an error for a missing API key, got none")
+	}
+	if _, err := NewSDKFromConfig(Config{APIKey: "test-key", ProxyURL: "ftp://proxy"}); err == nil {
+		t.Error("Expected an error for an invalid proxy URL, got none")
+	}
+}
+
+func TestConfig_DecodesDurationStrings(t *testing.T) {
+	var cfg Config
+	data := `{"apiKey":"test-key","timeout":"5s","retryBackoff":"250ms","circuitBreakerCooldown":"1m"}`
+	if err := json.Unmarshal([]byte(data), &cfg); err != nil {
+		t.Fatalf("Unmarshal() failed: %v", err)
+	}
+
+	if cfg.Timeout.Duration != 5*time.Second {
+		t.Errorf("Expected timeout 5s, got %s", cfg.Timeout)
+	}
+	if cfg.RetryBackoff.Duration != 250*time.Millisecond {
+		t.Errorf("Expected retry backoff 250ms, got %s", cfg.RetryBackoff)
+	}
+	if cfg.CircuitBreakerCooldown.Duration != time.Minute {
+		t.Errorf("Expected circuit breaker cooldown 1m, got %s", cfg.CircuitBreakerCooldown)
+	}
+
+	encoded, err := json.Marshal(cfg)
+	if err != nil {
+		t.Fatalf("Marshal() failed: %v", err)
+	}
+	var decoded Config
+	if err := json.Unmarshal(encoded, &decoded); err != nil {
+		t.Fatalf("Unmarshal() of %s failed: %v", encoded, err)
+	}
+	if decoded.Timeout != cfg.Timeout {
+		t.Errorf("Expected the timeout to round-trip, got %s", decoded.Timeout)
+	}
+
+	if err := json.Unmarshal([]byte(`{"timeout":"5 seconds"}`), &cfg); err == nil {
+		t.Error("Expected an error for an invalid duration, got none")
+	}
+	if err := json.Unmarshal([]byte(`{"timeout":5000000000}`), &cfg); err == nil {
+		t.Error("Expected an error for a numeric duration, got none")
+	}
+}
@@ -0,0 +1,182 @@
+package loops
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// maxDebugBodySize caps the size of the bodies kept by the debug buffer.
+	maxDebugBodySize = 4096
+	redactedValue    = "REDACTED"
+)
+
+// redactedBodyFields are the JSON fields, and query parameters, whose values are redacted from the requests kept by
+// the debug buffer. They are matched case-insensitively.
+var redactedBodyFields = map[string]bool{
+	"email":     true,
+	"firstname": true,
+	"lastname":  true,
+	"userid":    true,
+}
+
+// DebugEntry is a request to Loops, and its response, as kept by the debug buffer.
+type DebugEntry struct {
+	Time           time.Time   `json:"time"`
+	Method         string      `json:"method"`
+	Path           string      `json:"path"`
+	StatusCode     int         `json:"statusCode,omitempty"`
+	RequestHeaders http.Header `json:"requestHeaders,omitempty"`
+	RequestBody    string      `json:"requestBody,omitempty"`
+	ResponseBody   string      `json:"responseBody,omitempty"`
+	Error          string      `json:"error,omitempty"`
+}
+
+// WithDebugBuffer keeps the last size requests to Loops, and their responses, in memory for troubleshooting. The
+// Authorization header and the personal data in the bodies and query strings, see redactedBodyFields, are redacted. A
+// size of zero or less disables the buffer.
+func WithDebugBuffer(size int) ClientOption {
+	return func(c *Client) {
+		if size <= 0 {
+			c.debug = nil
+			return
+		}
+		c.debug = &debugBuffer{entries: make([]DebugEntry, 0, size), size: size}
+	}
+}
+
+// DebugEntries returns the requests kept by the debug buffer, oldest first, or nil if the buffer is disabled.
+func (c *Client) DebugEntries() []DebugEntry {
+	return c.debug.snapshot()
+}
+
+// DebugHandler serves the requests kept by the debug buffer as a JSON array, oldest first.
+func (c *Client) DebugHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		entries := c.DebugEntries()
+		if entries == nil {
+			entries = []DebugEntry{}
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(entries)
+	})
+}
+
+// debugBuffer is a ring buffer of the last requests to Loops. A nil buffer records nothing.
+type debugBuffer struct {
+	mu      sync.Mutex
+	entries []DebugEntry
+	size    int
+	// next is the index the next entry is written to once the buffer is full
+	next int
+}
+
+// record adds the request, and its response if any, to the buffer, evicting the oldest entry when it is full.
+func (b *debugBuffer) record(
+	method, path string, headers http.Header, reqBody []byte, statusCode int, respBody []byte, err error,
+) {
+	if b == nil {
+		return
+	}
+
+	entry := DebugEntry{
+		Time:           time.Now(),
+		Method:         method,
+		Path:           redactPath(path),
+		StatusCode:     statusCode,
+		RequestHeaders: redactHeaders(headers),
+		RequestBody:    redactBody(reqBody),
+		ResponseBody:   redactBody(respBody),
+	}
+	if err != nil {
+		entry.Error = err.Error()
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if len(b.entries) < b.size {
+		b.entries = append(b.entries, entry)
+		return
+	}
+	b.entries[b.next] = entry
+	b.next = (b.next + 1) % b.size
+}
+
+// snapshot returns a copy of the buffered entries, oldest first.
+func (b *debugBuffer) snapshot() []DebugEntry {
+	if b == nil {
+		return nil
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	entries := make([]DebugEntry, 0, len(b.entries))
+	entries = append(entries, b.entries[b.next:]...)
+	return append(entries, b.entries[:b.next]...)
+}
+
+// redactHeaders returns a copy of the headers with the Authorization header redacted.
+func redactHeaders(headers http.Header) http.Header {
+	redacted := headers.Clone()
+	if redacted.Get("Authorization") != "" {
+		redacted.Set("Authorization", redactedValue)
+	}
+	return redacted
+}
+
+// redactPath returns the path with the values of the query parameters in redactedBodyFields redacted.
+func redactPath(path string) string {
+	parsed, err := url.Parse(path)
+	if err != nil || parsed.RawQuery == "" {
+		return path
+	}
+	query := parsed.Query()
+	for key := range query {
+		if redactedBodyFields[strings.ToLower(key)] {
+			query[key] = []string{redactedValue}
+		}
+	}
+	parsed.RawQuery = query.Encode()
+	return parsed.String()
+}
+
+// redactBody returns the body with the values of redactedBodyFields redacted, truncated to maxDebugBodySize. Bodies
+// that are not JSON are only truncated.
+func redactBody(body []byte) string {
+	var decoded any
+	if err := json.Unmarshal(body, &decoded); err == nil {
+		if encoded, err := json.Marshal(redactValue(decoded)); err == nil {
+			body = encoded
+		}
+	}
+	if len(body) > maxDebugBodySize {
+		return string(body[:maxDebugBodySize]) + "...(truncated)"
+	}
+	return string(body)
+}
+
+func redactValue(value any) any {
+	switch v := value.(type) {
+	case map[string]any:
+		for key, field := range v {
+			if redactedBodyFields[strings.ToLower(key)] {
+				v[key] = redactedValue
+				continue
+			}
+			v[key] = redactValue(field)
+		}
+	case []any:
+		for i, item := range v {
+			v[i] = redactValue(item)
+		}
+	}
+	return value
+}
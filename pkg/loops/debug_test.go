@@ -0,0 +1,124 @@
+package loops
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestWithDebugBuffer(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/contacts/delete" {
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte(`{"success":false,"message":"Contact not found"}`))
+			return
+		}
+		if err := json.NewEncoder(w).Encode(APIResponse{Success: true, ID: "op-1"}); err != nil {
+			t.Errorf("Failed to write response: %v", err)
+		}
+	}))
+	defer ts.Close()
+
+	client, _ := NewSDK("test-key", WithBaseURL(ts.URL), WithDebugBuffer(2))
+
+	ctx := context.Background()
+	if _, err := client.UpsertContact(ctx, ContactRequest{Email: "first@example.com", UserID: "user-1"}); err != nil {
+		t.Fatalf("UpsertContact() failed: %v", err)
+	}
+	if _, err := client.UpsertContact(ctx, ContactRequest{
+		Email: "second@example.com", UserID: "user-2", FirstName: "Jane", LastName: "Doe", Source: "second",
+	}); err != nil {
+		t.Fatalf("UpsertContact() failed: %v", err)
+	}
+	if _, err := client.DeleteContact(ctx, "user-3"); err == nil {
+		t.Fatal("Expected an error, got none")
+	}
+
+	// The oldest request is evicted
+	entries := client.DebugEntries()
+	if len(entries) != 2 {
+		t.Fatalf("Expected 2 entries, got %d", len(entries))
+	}
+	if entries[0].Path != "/contacts/update" || !strings.Contains(entries[0].RequestBody, "second") {
+		t.Errorf("Expected the second upsert first, got %+v", entries[0])
+	}
+	if entries[1].Path != "/contacts/delete" || entries[1].StatusCode != http.StatusNotFound {
+		t.Errorf("Expected the failed deletion last, got %+v", entries[1])
+	}
+	if !strings.Contains(entries[1].ResponseBody, "Contact not found") {
+		t.Errorf("Expected the response body to be kept, got %q", entries[1].ResponseBody)
+	}
+
+	for _, entry := range entries {
+		if got := entry.RequestHeaders.Get("Authorization"); got != redactedValue {
+			t.Errorf("Expected Authorization header to be redacted, got %q", got)
+		}
+		for _, value := range []string{"@example.com", "user-", "Jane", "Doe"} {
+			if strings.Contains(entry.RequestBody, value) {
+				t.Errorf("Expected %q to be redacted, got %q", value, entry.RequestBody)
+			}
+		}
+	}
+}
+
+func TestRedactPath(t *testing.T) {
+	tests := []struct {
+		path     string
+		expected string
+	}{
+		{path: "/contacts/update", expected: "/contacts/update"},
+		{path: "/contacts/find?email=jane%40example.com", expected: "/contacts/find?email=REDACTED"},
+		{path: "/contacts/find?userId=user-1", expected: "/contacts/find?userId=REDACTED"},
+		{path: "/mailing-lists/list-1/contacts?cursor=abc", expected: "/mailing-lists/list-1/contacts?cursor=abc"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			if got := redactPath(tt.path); got != tt.expected {
+				t.Errorf("Expected %s, got %s", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestDebugHandler(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(APIResponse{Success: true})
+	}))
+	defer ts.Close()
+
+	tests := []struct {
+		name     string
+		opts     []ClientOption
+		expected int
+	}{
+		{name: "enabled", opts: []ClientOption{WithDebugBuffer(10)}, expected: 1},
+		{name: "disabled", expected: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client, _ := NewSDK("test-key", append([]ClientOption{WithBaseURL(ts.URL)}, tt.opts...)...)
+			if _, err := client.DeleteContact(context.Background(), "user-123"); err != nil {
+				t.Fatalf("DeleteContact() failed: %v", err)
+			}
+
+			rec := httptest.NewRecorder()
+			client.DebugHandler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/debug/loops", nil))
+			if rec.Code != http.StatusOK {
+				t.Fatalf("Expected status %d, got %d", http.StatusOK, rec.Code)
+			}
+
+			var entries []DebugEntry
+			if err := json.Unmarshal(rec.Body.Bytes(), &entries); err != nil {
+				t.Fatalf("Failed to decode entries: %v", err)
+			}
+			if len(entries) != tt.expected {
+				t.Errorf("Expected %d entries, got %d", tt.expected, len(entries))
+			}
+		})
+	}
+}
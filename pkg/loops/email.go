@@ -0,0 +1,9 @@
+package loops
+
+import "strings"
+
+// NormalizeEmail trims and lowercases an email address. Loops matches emails case-insensitively, so emails are
+// normalized before they are sent to Loops or compared with the ones Loops reports.
+func NormalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
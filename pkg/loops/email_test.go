@@ -0,0 +1,25 @@
+package loops
+
+import "testing"
+
+func TestNormalizeEmail(t *testing.T) {
+	tests := []struct {
+		name     string
+		email    string
+		expected string
+	}{
+		{name: "normalized", email: "user@example.com", expected: "user@example.com"},
+		{name: "mixed case", email: "User@Example.COM", expected: "user@example.com"},
+		{name: "whitespace", email: "  user@example.com\t\n", expected: "user@example.com"},
+		{name: "mixed case and whitespace", email: " User@Example.com ", expected: "user@example.com"},
+		{name: "empty", email: "", expected: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := NormalizeEmail(tt.email); got != tt.expected {
+				t.Errorf("Expected %q, got %q", tt.expected, got)
+			}
+		})
+	}
+}
@@ -1,21 +1,111 @@
 package loops
 
 import (
+	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"net"
 	"net/http"
+	"strconv"
+	"syscall"
+	"time"
 )
 
-// Error represents an error returned by the Loops API.
+// ErrAPIFailure matches the errors of requests that Loops answered with a 2xx status but a "success": false body.
+var ErrAPIFailure = errors.New("loops api reported a failure")
+
+// ErrInvalidAPIKey is returned by ValidateAPIKey when Loops rejects the API key.
+var ErrInvalidAPIKey = errors.New("loops api key is invalid")
+
+// ErrUnreachable is returned by ValidateAPIKey when Loops could not be reached or failed to answer, so the validity of
+// the API key is unknown.
+var ErrUnreachable = errors.New("loops api is unreachable")
+
+// ErrRateLimited matches the errors of requests that Loops answered with a 429 Too Many Requests.
+var ErrRateLimited = errors.New("loops api rate limit exceeded")
+
+// ErrCanceled matches the errors of requests whose context was canceled, e.g. on shutdown. The cancellation was
+// deliberate and says nothing about the health of Loops.
+var ErrCanceled = errors.New("loops api request canceled")
+
+// ErrTimeout matches the errors of requests that got no response in time, on the deadline of their context or a
+// timeout of the HTTP client.
+var ErrTimeout = errors.New("loops api request timed out")
+
+// ErrConnectionRefused matches the errors of requests whose connection to Loops was refused.
+var ErrConnectionRefused = errors.New("loops api connection refused")
+
+// classifyRequestError wraps an error of a request that got no response with ErrCanceled, ErrTimeout or
+// ErrConnectionRefused, when it is one of them. The original error is kept in the chain.
+func classifyRequestError(err error) error {
+	var netErr net.Error
+	switch {
+	case errors.Is(err, context.Canceled):
+		return fmt.Errorf("%w: %w", ErrCanceled, err)
+	case errors.Is(err, context.DeadlineExceeded), errors.As(err, &netErr) && netErr.Timeout():
+		return fmt.Errorf("%w: %w", ErrTimeout, err)
+	case errors.Is(err, syscall.ECONNREFUSED):
+		return fmt.Errorf("%w: %w", ErrConnectionRefused, err)
+	default:
+		return err
+	}
+}
+
+// Error represents an error returned by the Loops API. Its StatusCode is the 2xx status of the response when Loops
+// reported the failure in the body only.
 type Error struct {
 	StatusCode int
 	Body       string
+	// RetryAfter is the wait requested by the Retry-After header of the response, zero when it has none.
+	RetryAfter time.Duration
 }
 
 func (e *Error) Error() string {
 	return fmt.Sprintf("api request failed with status %d: %s", e.StatusCode, e.Body)
 }
 
+// Is makes errors.Is match ErrAPIFailure for failures reported with a 2xx status, and ErrRateLimited for 429s.
+func (e *Error) Is(target error) bool {
+	switch target {
+	case ErrAPIFailure:
+		return e.StatusCode < 300
+	case ErrRateLimited:
+		return e.StatusCode == http.StatusTooManyRequests
+	default:
+		return false
+	}
+}
+
+// parseRetryAfter returns the wait requested by a Retry-After header, given either as seconds or as an HTTP date.
+// It returns zero for an empty, invalid or past value.
+func parseRetryAfter(value string, now time.Time) time.Duration {
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(value); err == nil {
+		if seconds <= 0 {
+			return 0
+		}
+		return time.Duration(seconds) * time.Second
+	}
+	if date, err := http.ParseTime(value); err == nil && date.After(now) {
+		return date.Sub(now)
+	}
+	return 0
+}
+
+// reportsFailure returns true if the body of a response is a JSON object with "success": false.
+func reportsFailure(body []byte) bool {
+	var result struct {
+		Success *bool `json:"success"`
+	}
+	if err := json.Unmarshal(body, &result); err != nil {
+		return false
+	}
+	return result.Success != nil && !*result.Success
+}
+
 // IsErrorStatus checks if the error is a Loops API error with the given status code.
 func isErrorStatus(err error, status int) bool {
 	var apiErr *Error
@@ -30,12 +120,33 @@ func IsBadRequest(err error) bool {
 	return isErrorStatus(err, http.StatusBadRequest)
 }
 
+// IsUnauthorized checks if the error represents a 401 Unauthorized response.
+func IsUnauthorized(err error) bool {
+	return isErrorStatus(err, http.StatusUnauthorized)
+}
+
 // IsNotFound checks if the error represents a 404 Not Found response.
 func IsNotFound(err error) bool {
 	return isErrorStatus(err, http.StatusNotFound)
 }
 
+// IsGone checks if the error represents a 410 Gone response, e.g. for a contact that was purged from Loops.
+func IsGone(err error) bool {
+	return isErrorStatus(err, http.StatusGone)
+}
+
+// IsRateLimited checks if the error represents a 429 Too Many Requests response.
+func IsRateLimited(err error) bool {
+	return errors.Is(err, ErrRateLimited)
+}
+
 // IsConflict checks if the error represents a 409 Conflict response.
 func IsConflict(err error) bool {
 	return isErrorStatus(err, http.StatusConflict)
 }
+
+// IsPreconditionFailed checks if the error represents a 412 Precondition Failed response, i.e. a conditional
+// request whose ETag precondition did not hold.
+func IsPreconditionFailed(err error) bool {
+	return isErrorStatus(err, http.StatusPreconditionFailed)
+}
@@ -1,5 +1,17 @@
 package loops
 
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrInvalidWebhookEvent is wrapped by the errors returned by WebhookEvent.Validate.
+var ErrInvalidWebhookEvent = errors.New("invalid webhook event")
+
+// supportedWebhookSchemaMajorVersion is the major version of the Loops webhook schema the events are modeled after.
+const supportedWebhookSchemaMajorVersion = "1"
+
 // WebhookEvent represents the base structure for all Loops webhook events.
 type WebhookEvent struct {
 	EventName            string          `json:"eventName"`
@@ -8,6 +20,23 @@ type WebhookEvent struct {
 	ContactIdentity      ContactIdentity `json:"contactIdentity"`
 }
 
+// Validate checks that the event names its type and uses a recognized webhook schema version. It is a method rather
+// than a custom UnmarshalJSON, as the latter would be promoted to the events embedding WebhookEvent and shadow the
+// decoding of their own fields.
+func (e *WebhookEvent) Validate() error {
+	if e.EventName == "" {
+		return fmt.Errorf("%w: eventName is required", ErrInvalidWebhookEvent)
+	}
+	if e.WebhookSchemaVersion == "" {
+		return fmt.Errorf("%w: webhookSchemaVersion is required", ErrInvalidWebhookEvent)
+	}
+	if major, _, _ := strings.Cut(e.WebhookSchemaVersion, "."); major != supportedWebhookSchemaMajorVersion {
+		return fmt.Errorf("%w: unsupported webhookSchemaVersion %q, expected %s.x",
+			ErrInvalidWebhookEvent, e.WebhookSchemaVersion, supportedWebhookSchemaMajorVersion)
+	}
+	return nil
+}
+
 // ContactIdentity represents the contact information in webhook events.
 type ContactIdentity struct {
 	ID     string `json:"id"`
@@ -23,12 +52,27 @@ type MailingList struct {
 	IsPublic    bool   `json:"isPublic"`
 }
 
+// Validate checks that the mailing list of an event is identified.
+func (m *MailingList) Validate() error {
+	if m.ID == "" {
+		return fmt.Errorf("%w: mailingList.id is required", ErrInvalidWebhookEvent)
+	}
+	return nil
+}
+
 // MailingListSubscribedEvent represents the contact.mailingList.subscribed webhook event.
 type MailingListSubscribedEvent struct {
 	WebhookEvent
 	MailingList MailingList `json:"mailingList"`
 }
 
+// MailingListAddedEvent represents the contact.mailingList.added webhook event, sent when a contact is added to a
+// mailing list programmatically rather than by subscribing themselves.
+type MailingListAddedEvent struct {
+	WebhookEvent
+	MailingList MailingList `json:"mailingList"`
+}
+
 // MailingListUnsubscribedEvent represents the contact.mailingList.unsubscribed webhook event.
 type MailingListUnsubscribedEvent struct {
 	WebhookEvent
@@ -38,5 +82,6 @@ type MailingListUnsubscribedEvent struct {
 // EventName constants for webhook events.
 const (
 	EventNameMailingListSubscribed   = "contact.mailingList.subscribed"
+	EventNameMailingListAdded        = "contact.mailingList.added"
 	EventNameMailingListUnsubscribed = "contact.mailingList.unsubscribed"
 )
@@ -0,0 +1,56 @@
+package loops
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+)
+
+func TestWebhookEvent_Validate(t *testing.T) {
+	tests := []struct {
+		name    string
+		payload string
+		wantErr bool
+	}{
+		{
+			name:    "valid",
+			payload: `{"eventName":"contact.mailingList.subscribed","webhookSchemaVersion":"1.0.0"}`,
+		},
+		{
+			name:    "valid minor version",
+			payload: `{"eventName":"contact.mailingList.subscribed","webhookSchemaVersion":"1.2.0"}`,
+		},
+		{
+			name:    "missing eventName",
+			payload: `{"webhookSchemaVersion":"1.0.0"}`,
+			wantErr: true,
+		},
+		{
+			name:    "missing webhookSchemaVersion",
+			payload: `{"eventName":"contact.mailingList.subscribed"}`,
+			wantErr: true,
+		},
+		{
+			name:    "unsupported webhookSchemaVersion",
+			payload: `{"eventName":"contact.mailingList.subscribed","webhookSchemaVersion":"2.0.0"}`,
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var event WebhookEvent
+			if err := json.Unmarshal([]byte(tt.payload), &event); err != nil {
+				t.Fatalf("Unmarshal() failed: %v", err)
+			}
+
+			err := event.Validate()
+			if tt.wantErr && !errors.Is(err, ErrInvalidWebhookEvent) {
+				t.Errorf("Expected ErrInvalidWebhookEvent, got %v", err)
+			}
+			if !tt.wantErr && err != nil {
+				t.Errorf("Expected no error, got %v", err)
+			}
+		})
+	}
+}
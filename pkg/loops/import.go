@@ -0,0 +1,84 @@
+package loops
+
+import (
+	"context"
+	"net/http"
+)
+
+// ImportContactsRequest represents the payload for importing a batch of contacts.
+type ImportContactsRequest struct {
+	Contacts []ContactRequest `json:"contacts"`
+}
+
+// ImportResult represents the outcome of a batch import, with one record per imported contact in request order.
+type ImportResult struct {
+	Records []ImportRecordResult `json:"records"`
+}
+
+// ImportRecordResult represents the outcome of importing a single contact.
+type ImportRecordResult struct {
+	Email   string `json:"email,omitempty"`
+	UserID  string `json:"userId,omitempty"`
+	Success bool   `json:"success"`
+	Message string `json:"message,omitempty"`
+}
+
+// Failed returns the records that could not be imported.
+func (r *ImportResult) Failed() []ImportRecordResult {
+	var failed []ImportRecordResult
+	for _, record := range r.Records {
+		if !record.Success {
+			failed = append(failed, record)
+		}
+	}
+	return failed
+}
+
+// ImportContacts creates or updates a batch of contacts in Loops.
+//
+// API: POST <import path>, only when enabled with WithContactsImport. Falls back to UpsertContacts when the import
+// endpoint is not enabled or not available on the account.
+//
+// Idempotency: Idempotent
+//
+// Errors:
+//   - 400 Bad Request: If the batch payload is invalid.
+//
+// Per-contact failures do not fail the call, they are reported in the returned ImportResult.
+func (c *Client) ImportContacts(ctx context.Context, reqs []ContactRequest) (*ImportResult, error) {
+	if c.importPath == "" {
+		return c.UpsertContacts(ctx, reqs), nil
+	}
+
+	var result ImportResult
+	err := c.sendRequest(ctx, http.MethodPost, c.importPath, ImportContactsRequest{Contacts: reqs}, &result)
+	if err != nil {
+		if IsNotFound(err) || isErrorStatus(err, http.StatusMethodNotAllowed) {
+			return c.UpsertContacts(ctx, reqs), nil
+		}
+		return nil, err
+	}
+	return &result, nil
+}
+
+// UpsertContacts creates or updates a batch of contacts in Loops, one UpsertContact call per contact.
+//
+// Idempotency: Idempotent
+//
+// Per-contact failures are reported in the returned ImportResult.
+func (c *Client) UpsertContacts(ctx context.Context, reqs []ContactRequest) *ImportResult {
+	result := &ImportResult{Records: make([]ImportRecordResult, 0, len(reqs))}
+	for _, req := range reqs {
+		record := ImportRecordResult{
+			Email:   req.Email,
+			UserID:  req.UserID,
+			Success: true,
+		}
+		if _, err := c.UpsertContact(ctx, req); err != nil {
+			record.Success = false
+			record.Message = err.Error()
+		}
+		result.Records = append(result.Records, record)
+	}
+	return result
+}
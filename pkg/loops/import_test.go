@@ -0,0 +1,115 @@
+package loops
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestImportContacts(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			t.Errorf("Expected POST request, got %s", r.Method)
+		}
+		if r.URL.Path != "/contacts/import" {
+			t.Errorf("Expected path /contacts/import, got %s", r.URL.Path)
+		}
+
+		var req ImportContactsRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Errorf("Failed to decode request body: %v", err)
+		}
+		if len(req.Contacts) != 2 {
+			t.Errorf("Expected 2 contacts, got %d", len(req.Contacts))
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		if _, err := w.Write([]byte(`{"records":[` +
+			`{"userId":"user-1","success":true},` +
+			`{"userId":"user-2","success":false,"message":"Invalid email"}]}`)); err != nil {
+			t.Errorf("Failed to write response: %v", err)
+		}
+	}))
+	defer ts.Close()
+
+	client, _ := NewSDK("test-key", WithBaseURL(ts.URL), WithContactsImport("/contacts/import"))
+	result, err := client.ImportContacts(context.Background(), []ContactRequest{
+		{UserID: "user-1", Email: "one@example.com"},
+		{UserID: "user-2", Email: "invalid"},
+	})
+	if err != nil {
+		t.Fatalf("ImportContacts() failed: %v", err)
+	}
+
+	if len(result.Records) != 2 {
+		t.Fatalf("Expected 2 records, got %d", len(result.Records))
+	}
+	failed := result.Failed()
+	if len(failed) != 1 || failed[0].UserID != "user-2" || failed[0].Message != "Invalid email" {
+		t.Errorf("Expected user-2 to fail with Invalid email, got %+v", failed)
+	}
+}
+
+func TestImportContacts_Fallback(t *testing.T) {
+	tests := []struct {
+		name string
+		opts []ClientOption
+	}{
+		{
+			name: "Import not enabled",
+		},
+		{
+			name: "Import endpoint not available",
+			opts: []ClientOption{WithContactsImport("/contacts/import")},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path == "/contacts/import" {
+					w.WriteHeader(http.StatusNotFound)
+					return
+				}
+				if r.URL.Path != "/contacts/update" {
+					t.Errorf("Expected path /contacts/update, got %s", r.URL.Path)
+				}
+
+				var req ContactRequest
+				if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+					t.Errorf("Failed to decode request body: %v", err)
+				}
+				if req.UserID == "user-2" {
+					w.WriteHeader(http.StatusBadRequest)
+					if _, err := w.Write([]byte(`{"success":false,"message":"Invalid email"}`)); err != nil {
+						t.Errorf("Failed to write response: %v", err)
+					}
+					return
+				}
+				if err := json.NewEncoder(w).Encode(APIResponse{Success: true}); err != nil {
+					t.Errorf("Failed to write response: %v", err)
+				}
+			}))
+			defer ts.Close()
+
+			client, _ := NewSDK("test-key", append([]ClientOption{WithBaseURL(ts.URL)}, tt.opts...)...)
+			result, err := client.ImportContacts(context.Background(), []ContactRequest{
+				{UserID: "user-1", Email: "one@example.com"},
+				{UserID: "user-2", Email: "invalid"},
+			})
+			if err != nil {
+				t.Fatalf("ImportContacts() failed: %v", err)
+			}
+
+			if len(result.Records) != 2 || !result.Records[0].Success {
+				t.Fatalf("Expected user-1 to succeed, got %+v", result.Records)
+			}
+			failed := result.Failed()
+			if len(failed) != 1 || failed[0].UserID != "user-2" {
+				t.Errorf("Expected user-2 to fail, got %+v", failed)
+			}
+		})
+	}
+}
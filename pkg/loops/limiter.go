@@ -0,0 +1,50 @@
+package loops
+
+import (
+	"context"
+)
+
+// ConcurrencyLimiter bounds the number of requests to Loops in flight at once. A limiter can be shared by several
+// clients to bound their requests together. It is distinct from a rate limit: it caps concurrent connections, not
+// requests per second.
+type ConcurrencyLimiter struct {
+	slots chan struct{}
+}
+
+// NewConcurrencyLimiter returns a limiter allowing up to maxInFlight concurrent requests, or nil, which does not limit
+// requests, if maxInFlight is zero or less.
+func NewConcurrencyLimiter(maxInFlight int) *ConcurrencyLimiter {
+	if maxInFlight <= 0 {
+		return nil
+	}
+	return &ConcurrencyLimiter{slots: make(chan struct{}, maxInFlight)}
+}
+
+// WithConcurrencyLimiter makes the client wait for a slot of limiter before each request attempt. Retries release
+// their slot while backing off.
+func WithConcurrencyLimiter(limiter *ConcurrencyLimiter) ClientOption {
+	return func(c *Client) {
+		c.limiter = limiter
+	}
+}
+
+// acquire waits for a free slot, returning the context error if ctx is done first. A nil limiter returns immediately.
+func (l *ConcurrencyLimiter) acquire(ctx context.Context) error {
+	if l == nil {
+		return nil
+	}
+	select {
+	case l.slots <- struct{}{}:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// release frees a slot taken by acquire.
+func (l *ConcurrencyLimiter) release() {
+	if l == nil {
+		return
+	}
+	<-l.slots
+}
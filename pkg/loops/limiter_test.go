@@ -0,0 +1,87 @@
+package loops
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestWithConcurrencyLimiter(t *testing.T) {
+	var inFlight, maxInFlight atomic.Int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		current := inFlight.Add(1)
+		defer inFlight.Add(-1)
+		for {
+			observed := maxInFlight.Load()
+			if current <= observed || maxInFlight.CompareAndSwap(observed, current) {
+				break
+			}
+		}
+		time.Sleep(20 * time.Millisecond)
+		_, _ = w.Write([]byte(`{"success":true}`))
+	}))
+	defer ts.Close()
+
+	// Two clients sharing a limiter are bounded together
+	limiter := NewConcurrencyLimiter(2)
+	first, _ := NewSDK("test-key", WithBaseURL(ts.URL), WithConcurrencyLimiter(limiter))
+	second, _ := NewSDK("test-key", WithBaseURL(ts.URL), WithConcurrencyLimiter(limiter))
+
+	var wg sync.WaitGroup
+	for i := range 10 {
+		client := first
+		if i%2 == 1 {
+			client = second
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := client.DeleteContact(context.Background(), "user-123"); err != nil {
+				t.Errorf("DeleteContact() failed: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if got := maxInFlight.Load(); got > 2 {
+		t.Errorf("Expected at most 2 requests in flight, got %d", got)
+	}
+}
+
+func TestWithConcurrencyLimiter_ContextDone(t *testing.T) {
+	calls := 0
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		_, _ = w.Write([]byte(`{"success":true}`))
+	}))
+	defer ts.Close()
+
+	limiter := NewConcurrencyLimiter(1)
+	client, _ := NewSDK("test-key", WithBaseURL(ts.URL), WithConcurrencyLimiter(limiter))
+
+	// Hold the only slot
+	if err := limiter.acquire(context.Background()); err != nil {
+		t.Fatalf("acquire() failed: %v", err)
+	}
+	defer limiter.release()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, err := client.DeleteContact(ctx, "user-123"); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected context.DeadlineExceeded, got %v", err)
+	}
+	if calls != 0 {
+		t.Errorf("Expected no call to Loops, got %d", calls)
+	}
+}
+
+func TestNewConcurrencyLimiter_Unlimited(t *testing.T) {
+	if limiter := NewConcurrencyLimiter(0); limiter != nil {
+		t.Errorf("Expected no limiter, got %+v", limiter)
+	}
+}
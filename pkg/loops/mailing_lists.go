@@ -0,0 +1,112 @@
+package loops
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/url"
+	"strconv"
+)
+
+// ErrMailingListUpdateUnavailable is returned by UpdateMailingList when the mailing list update endpoint is not
+// enabled with WithMailingListUpdate.
+var ErrMailingListUpdateUnavailable = errors.New("loops mailing list update endpoint is not enabled")
+
+// ErrMailingListContactsUnavailable is returned by ListContactsInMailingList when the mailing list contacts endpoint
+// is not enabled with WithMailingListContacts.
+var ErrMailingListContactsUnavailable = errors.New("loops mailing list contacts endpoint is not enabled")
+
+// mailingListContactsPageSize is the number of contacts requested per page by ListContactsInMailingList.
+const mailingListContactsPageSize = 50
+
+// MailingListUpdate represents the payload for updating the metadata of a mailing list. Empty fields are left
+// untouched.
+type MailingListUpdate struct {
+	Name        string `json:"name,omitempty"`
+	Description string `json:"description,omitempty"`
+	IsPublic    *bool  `json:"isPublic,omitempty"`
+}
+
+// WithMailingListUpdate enables UpdateMailingList to use the mailing list update endpoint under the given path (e.g.
+// "/lists") for accounts where Loops offers it. The public Loops API only lists mailing lists, so UpdateMailingList
+// fails with ErrMailingListUpdateUnavailable without it.
+func WithMailingListUpdate(path string) ClientOption {
+	return func(c *Client) {
+		c.mailingListUpdatePath = path
+	}
+}
+
+// WithMailingListContacts enables ListContactsInMailingList to use the mailing list contacts endpoint under the given
+// path (e.g. "/lists") for accounts where Loops offers it. The public Loops API only looks contacts up one at a time,
+// so ListContactsInMailingList fails with ErrMailingListContactsUnavailable without it.
+func WithMailingListContacts(path string) ClientOption {
+	return func(c *Client) {
+		c.mailingListsPath = path
+	}
+}
+
+// UpdateMailingList updates the name, description or visibility of a mailing list.
+//
+// API: PUT <mailing list update path>/{id}, only when enabled with WithMailingListUpdate.
+//
+// Idempotency: Idempotent
+//
+// Errors:
+//   - 404 Not Found: If the mailing list does not exist.
+//   - 400 Bad Request: If the request payload is invalid.
+func (c *Client) UpdateMailingList(ctx context.Context, id string, req MailingListUpdate) (*APIResponse, error) {
+	if c.mailingListUpdatePath == "" {
+		return nil, ErrMailingListUpdateUnavailable
+	}
+
+	var resp APIResponse
+	path := c.mailingListUpdatePath + "/" + url.PathEscape(id)
+	err := c.sendRequest(withResponseHeader(ctx, &resp.Header), http.MethodPut, path, req, &resp)
+	if err != nil {
+		return nil, err
+	}
+	c.recordOperation(ctx, "UpdateMailingList", &resp)
+	return &resp, nil
+}
+
+// Pagination is the pagination of a Loops list response. NextCursor is empty on the last page.
+type Pagination struct {
+	NextCursor string `json:"nextCursor"`
+}
+
+// mailingListContactsPage is a page of the contacts of a mailing list.
+type mailingListContactsPage struct {
+	Data       []Contact  `json:"data"`
+	Pagination Pagination `json:"pagination"`
+}
+
+// ListContactsInMailingList returns the contacts subscribed to a mailing list, following the pagination until the
+// last page.
+//
+// API: GET <mailing list contacts path>/{id}/contacts?perPage=50&cursor={cursor}, only when enabled with
+// WithMailingListContacts.
+//
+// Idempotency: Idempotent
+//
+// Errors:
+//   - 404 Not Found: If the mailing list does not exist.
+//   - 400 Bad Request: If the request is invalid.
+func (c *Client) ListContactsInMailingList(ctx context.Context, listID string) ([]Contact, error) {
+	if c.mailingListsPath == "" {
+		return nil, ErrMailingListContactsUnavailable
+	}
+
+	return paginate(ctx, func(ctx context.Context, cursor string) ([]Contact, string, error) {
+		query := url.Values{"perPage": {strconv.Itoa(mailingListContactsPageSize)}}
+		if cursor != "" {
+			query.Set("cursor", cursor)
+		}
+
+		var page mailingListContactsPage
+		path := c.mailingListsPath + "/" + url.PathEscape(listID) + "/contacts?" + query.Encode()
+		if err := c.sendRequest(ctx, http.MethodGet, path, nil, &page); err != nil {
+			return nil, "", err
+		}
+		return page.Data, page.Pagination.NextCursor, nil
+	})
+}
@@ -0,0 +1,135 @@
+package loops
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+)
+
+func TestUpdateMailingList(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPut {
+			t.Errorf("Expected PUT request, got %s", r.Method)
+		}
+		if r.URL.Path != "/lists/list-abc" {
+			t.Errorf("Expected path /lists/list-abc, got %s", r.URL.Path)
+		}
+
+		var req map[string]any
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Errorf("Failed to decode request body: %v", err)
+		}
+		if req["name"] != "Newsletter" || req["isPublic"] != false {
+			t.Errorf("Expected name Newsletter and isPublic false, got %v", req)
+		}
+		if _, ok := req["description"]; ok {
+			t.Errorf("Expected empty description to be omitted, got %v", req["description"])
+		}
+
+		if err := json.NewEncoder(w).Encode(APIResponse{Success: true, ID: "list-abc"}); err != nil {
+			t.Errorf("Failed to write response: %v", err)
+		}
+	}))
+	defer ts.Close()
+
+	client, _ := NewSDK("test-key", WithBaseURL(ts.URL), WithMailingListUpdate("/lists"))
+	isPublic := false
+	resp, err := client.UpdateMailingList(context.Background(), "list-abc", MailingListUpdate{
+		Name:     "Newsletter",
+		IsPublic: &isPublic,
+	})
+	if err != nil {
+		t.Fatalf("UpdateMailingList() failed: %v", err)
+	}
+	if !resp.Success {
+		t.Error("UpdateMailingList() expected success true")
+	}
+}
+
+func TestUpdateMailingList_Unavailable(t *testing.T) {
+	client, _ := NewSDK("test-key", WithBaseURL("http://127.0.0.1:0"))
+	_, err := client.UpdateMailingList(context.Background(), "list-abc", MailingListUpdate{Name: "Newsletter"})
+	if !errors.Is(err, ErrMailingListUpdateUnavailable) {
+		t.Errorf("Expected ErrMailingListUpdateUnavailable, got %v", err)
+	}
+}
+
+func TestListContactsInMailingList(t *testing.T) {
+	pages := map[string]mailingListContactsPage{
+		"": {
+			Data:       []Contact{{UserID: "user-1"}, {UserID: "user-2"}},
+			Pagination: Pagination{NextCursor: "page-2"},
+		},
+		"page-2": {
+			Data:       []Contact{{UserID: "user-3"}},
+			Pagination: Pagination{NextCursor: "page-3"},
+		},
+		"page-3": {
+			Data: []Contact{{UserID: "user-4"}},
+		},
+	}
+
+	requests := 0
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if r.URL.Path != "/lists/list-abc/contacts" {
+			t.Errorf("Expected path /lists/list-abc/contacts, got %s", r.URL.Path)
+		}
+		if perPage := r.URL.Query().Get("perPage"); perPage != "50" {
+			t.Errorf("Expected perPage 50, got %q", perPage)
+		}
+
+		page, ok := pages[r.URL.Query().Get("cursor")]
+		if !ok {
+			t.Errorf("Unexpected cursor %q", r.URL.Query().Get("cursor"))
+		}
+		if err := json.NewEncoder(w).Encode(page); err != nil {
+			t.Errorf("Failed to write response: %v", err)
+		}
+	}))
+	defer ts.Close()
+
+	client, _ := NewSDK("test-key", WithBaseURL(ts.URL), WithMailingListContacts("/lists"))
+	contacts, err := client.ListContactsInMailingList(context.Background(), "list-abc")
+	if err != nil {
+		t.Fatalf("ListContactsInMailingList() failed: %v", err)
+	}
+
+	if requests != 3 {
+		t.Errorf("Expected 3 page requests, got %d", requests)
+	}
+	var userIDs []string
+	for _, contact := range contacts {
+		userIDs = append(userIDs, contact.UserID)
+	}
+	if expected := []string{"user-1", "user-2", "user-3", "user-4"}; !slices.Equal(userIDs, expected) {
+		t.Errorf("Expected contacts %v, got %v", expected, userIDs)
+	}
+}
+
+func TestListContactsInMailingList_RepeatedCursor(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		page := mailingListContactsPage{Pagination: Pagination{NextCursor: "page-2"}}
+		if err := json.NewEncoder(w).Encode(page); err != nil {
+			t.Errorf("Failed to write response: %v", err)
+		}
+	}))
+	defer ts.Close()
+
+	client, _ := NewSDK("test-key", WithBaseURL(ts.URL), WithMailingListContacts("/lists"))
+	if _, err := client.ListContactsInMailingList(context.Background(), "list-abc"); err == nil {
+		t.Error("Expected an error for a cursor looping back to an earlier page")
+	}
+}
+
+func TestListContactsInMailingList_Unavailable(t *testing.T) {
+	client, _ := NewSDK("test-key", WithBaseURL("http://127.0.0.1:0"))
+	_, err := client.ListContactsInMailingList(context.Background(), "list-abc")
+	if !errors.Is(err, ErrMailingListContactsUnavailable) {
+		t.Errorf("Expected ErrMailingListContactsUnavailable, got %v", err)
+	}
+}
@@ -0,0 +1,105 @@
+package loops
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/go-logr/logr"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	// operationsTotal counts the successful mutating calls made to Loops, labeled by SDK method name.
+	operationsTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "loops_operations_total",
+			Help: "Total number of successful mutating Loops API operations, by operation.",
+		},
+		[]string{"operation"},
+	)
+
+	// retriesTotal counts the retried requests, labeled by HTTP method and the reason of the retry.
+	retriesTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "loops_request_retries_total",
+			Help: "Total number of retried Loops API requests, by HTTP method and reason (5xx, 429, network, predicate).",
+		},
+		[]string{"method", "reason"},
+	)
+
+	// responseDecodeErrorsTotal counts the responses that could not be decoded, labeled by request path.
+	responseDecodeErrorsTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "loops_response_decode_errors_total",
+			Help: "Total number of Loops API responses that could not be decoded, by request path.",
+		},
+		[]string{"path"},
+	)
+
+	// circuitBreakerState reports the state of the circuit breaker of each client, labeled by client name.
+	circuitBreakerState = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "loops_circuit_breaker_state",
+			Help: "Current state of the Loops API circuit breaker, by client: 0 closed, 1 open, 2 half-open.",
+		},
+		[]string{"client"},
+	)
+)
+
+// WithMetricsRegisterer registers the metrics of the client, such as loops_operations_total, with reg, e.g. the
+// registry the process exposes. The metrics are shared by the clients of the process, so that several clients can be
+// registered with the same registry. Without it, the metrics are not registered anywhere.
+func WithMetricsRegisterer(reg prometheus.Registerer) ClientOption {
+	return func(c *Client) {
+		c.registerer = reg
+	}
+}
+
+// WithLogger sets the logger of the client, used for requests whose context carries no logger, see
+// logr.NewContext. Defaults to a logger discarding everything.
+func WithLogger(log logr.Logger) ClientOption {
+	return func(c *Client) {
+		c.log = log
+	}
+}
+
+// registerMetrics registers the metrics with reg, skipping the ones it already has.
+func registerMetrics(reg prometheus.Registerer) error {
+	for _, collector := range []prometheus.Collector{
+		operationsTotal, retriesTotal, responseDecodeErrorsTotal, circuitBreakerState,
+	} {
+		if err := reg.Register(collector); err != nil {
+			var alreadyRegistered prometheus.AlreadyRegisteredError
+			if errors.As(err, &alreadyRegistered) {
+				continue
+			}
+			return fmt.Errorf("failed to register Loops metrics: %w", err)
+		}
+	}
+	return nil
+}
+
+// logger returns the logger of the request context, so that the logs of a request are correlated with the caller's,
+// falling back to the logger of the client.
+func (c *Client) logger(ctx context.Context) logr.Logger {
+	if log, err := logr.FromContext(ctx); err == nil {
+		return log
+	}
+	return c.log
+}
+
+// recordDecodeError records a response that could not be decoded. The query string is dropped from the path, as it
+// carries per-contact values.
+func recordDecodeError(path string) {
+	path, _, _ = strings.Cut(path, "?")
+	responseDecodeErrorsTotal.WithLabelValues(path).Inc()
+}
+
+// recordOperation records a successful mutating call. The volatile operation ID returned by Loops is only logged at
+// debug level, so that controller actions can be correlated with Loops without blowing up metric cardinality.
+func (c *Client) recordOperation(ctx context.Context, operation string, resp *APIResponse) {
+	operationsTotal.WithLabelValues(operation).Inc()
+	c.logger(ctx).V(1).Info("Loops operation succeeded", "operation", operation, "operationID", resp.ID)
+}
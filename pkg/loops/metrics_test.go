@@ -0,0 +1,93 @@
+package loops
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-logr/logr"
+	"github.com/go-logr/logr/funcr"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestOperationsTotal(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := json.NewEncoder(w).Encode(APIResponse{Success: true, ID: "op-123"}); err != nil {
+			t.Errorf("Failed to write response: %v", err)
+		}
+	}))
+	defer ts.Close()
+
+	client, _ := NewSDK("test-key", WithBaseURL(ts.URL))
+
+	before := testutil.ToFloat64(operationsTotal.WithLabelValues("UpsertContact"))
+	if _, err := client.UpsertContact(context.Background(), ContactRequest{Email: "test@example.com"}); err != nil {
+		t.Fatalf("UpsertContact() failed: %v", err)
+	}
+	if got := testutil.ToFloat64(operationsTotal.WithLabelValues("UpsertContact")); got != before+1 {
+		t.Errorf("Expected loops_operations_total{operation=\"UpsertContact\"} to be %v, got %v", before+1, got)
+	}
+
+	// Convenience wrappers are recorded under their own method name
+	beforeAdd := testutil.ToFloat64(operationsTotal.WithLabelValues("AddToMailingList"))
+	if _, err := client.AddToMailingList(context.Background(), "user-123", "list-abc"); err != nil {
+		t.Fatalf("AddToMailingList() failed: %v", err)
+	}
+	if got := testutil.ToFloat64(operationsTotal.WithLabelValues("AddToMailingList")); got != beforeAdd+1 {
+		t.Errorf("Expected loops_operations_total{operation=\"AddToMailingList\"} to be %v, got %v", beforeAdd+1, got)
+	}
+	if got := testutil.ToFloat64(operationsTotal.WithLabelValues("UpsertContact")); got != before+1 {
+		t.Errorf("Expected loops_operations_total{operation=\"UpsertContact\"} to stay %v, got %v", before+1, got)
+	}
+}
+
+func TestWithMetricsRegisterer(t *testing.T) {
+	reg := prometheus.NewRegistry()
+
+	// Clients share the metrics, so registering them again with the same registry is not an error
+	for _, name := range []string{"first", "second"} {
+		if _, err := NewSDK("test-key", WithClientName(name), WithMetricsRegisterer(reg)); err != nil {
+			t.Fatalf("NewSDK() failed for client %s: %v", name, err)
+		}
+	}
+
+	operationsTotal.WithLabelValues("UpsertContact")
+	if count, err := testutil.GatherAndCount(reg, "loops_operations_total"); err != nil || count == 0 {
+		t.Errorf("Expected loops_operations_total to be registered, got %d series and error %v", count, err)
+	}
+}
+
+func TestWithLogger(t *testing.T) {
+	var logged []string
+	log := funcr.New(func(_, args string) { logged = append(logged, args) }, funcr.Options{Verbosity: 1})
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := json.NewEncoder(w).Encode(APIResponse{Success: true, ID: "op-123"}); err != nil {
+			t.Errorf("Failed to write response: %v", err)
+		}
+	}))
+	defer ts.Close()
+
+	client, _ := NewSDK("test-key", WithBaseURL(ts.URL), WithLogger(log))
+	if _, err := client.UpsertContact(context.Background(), ContactRequest{Email: "test@example.com"}); err != nil {
+		t.Fatalf("UpsertContact() failed: %v", err)
+	}
+	if len(logged) != 1 || !strings.Contains(logged[0], `"operationID"="op-123"`) {
+		t.Errorf("Expected the operation ID to be logged with the client logger, got %v", logged)
+	}
+
+	// The logger of the request context takes precedence
+	logged = nil
+	var fromContext []string
+	ctxLog := funcr.New(func(_, args string) { fromContext = append(fromContext, args) }, funcr.Options{Verbosity: 1})
+	ctx := logr.NewContext(context.Background(), ctxLog)
+	if _, err := client.UpsertContact(ctx, ContactRequest{Email: "test@example.com"}); err != nil {
+		t.Fatalf("UpsertContact() failed: %v", err)
+	}
+	if len(logged) != 0 || len(fromContext) != 1 {
+		t.Errorf("Expected the operation to be logged with the context logger only, got %v and %v", logged, fromContext)
+	}
+}
@@ -0,0 +1,34 @@
+package loops
+
+import (
+	"context"
+	"fmt"
+)
+
+// fetchPageFunc fetches the page at cursor, the empty cursor being the first page, and returns its items along with
+// the cursor of the next page, empty on the last page.
+type fetchPageFunc[T any] func(ctx context.Context, cursor string) ([]T, string, error)
+
+// paginate follows the cursors returned by fetch from the first page to the last one and returns the items of all
+// pages. It fails rather than loop forever if a cursor comes back twice.
+func paginate[T any](ctx context.Context, fetch fetchPageFunc[T]) ([]T, error) {
+	var items []T
+	seen := map[string]bool{}
+	cursor := ""
+	for {
+		page, next, err := fetch(ctx, cursor)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, page...)
+
+		if next == "" {
+			return items, nil
+		}
+		if seen[next] {
+			return nil, fmt.Errorf("loops returned the cursor %q twice", next)
+		}
+		seen[next] = true
+		cursor = next
+	}
+}
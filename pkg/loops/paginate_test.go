@@ -0,0 +1,75 @@
+package loops
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+)
+
+func TestPaginate(t *testing.T) {
+	pages := map[string]struct {
+		items []string
+		next  string
+	}{
+		"":       {items: []string{"a", "b"}, next: "page-2"},
+		"page-2": {items: []string{"c"}, next: "page-3"},
+		"page-3": {next: ""},
+	}
+
+	var cursors []string
+	items, err := paginate(context.Background(), func(_ context.Context, cursor string) ([]string, string, error) {
+		cursors = append(cursors, cursor)
+		page := pages[cursor]
+		return page.items, page.next, nil
+	})
+	if err != nil {
+		t.Fatalf("paginate() failed: %v", err)
+	}
+
+	if expected := []string{"", "page-2", "page-3"}; !slices.Equal(cursors, expected) {
+		t.Errorf("Expected cursors %v, got %v", expected, cursors)
+	}
+	if expected := []string{"a", "b", "c"}; !slices.Equal(items, expected) {
+		t.Errorf("Expected items %v, got %v", expected, items)
+	}
+}
+
+func TestPaginate_Errors(t *testing.T) {
+	fetchErr := errors.New("fetch failed")
+
+	tests := []struct {
+		name  string
+		fetch fetchPageFunc[string]
+		want  error
+	}{
+		{
+			name: "fetch error",
+			fetch: func(_ context.Context, cursor string) ([]string, string, error) {
+				if cursor == "page-2" {
+					return nil, "", fetchErr
+				}
+				return []string{"a"}, "page-2", nil
+			},
+			want: fetchErr,
+		},
+		{
+			name: "repeated cursor",
+			fetch: func(context.Context, string) ([]string, string, error) {
+				return []string{"a"}, "page-2", nil
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			items, err := paginate(context.Background(), tt.fetch)
+			if err == nil {
+				t.Fatalf("Expected an error, got items %v", items)
+			}
+			if tt.want != nil && !errors.Is(err, tt.want) {
+				t.Errorf("Expected %v, got %v", tt.want, err)
+			}
+		})
+	}
+}
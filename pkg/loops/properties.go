@@ -0,0 +1,98 @@
+package loops
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+// Types of the custom contact properties.
+const (
+	ContactPropertyTypeString  = "string"
+	ContactPropertyTypeNumber  = "number"
+	ContactPropertyTypeBoolean = "boolean"
+	ContactPropertyTypeDate    = "date"
+)
+
+// ContactPropertyRequest represents the payload for creating a custom contact property.
+type ContactPropertyRequest struct {
+	Name string `json:"name"`
+	Type string `json:"type"`
+}
+
+// CreateContactProperty declares a custom contact property, which Loops requires before the property is set on a
+// contact.
+//
+// API: POST /contacts/properties
+//
+// Idempotency: Not idempotent, so the request is sent once, whatever WithRetries allows
+//
+// Errors:
+//   - 400 Bad Request: If the name or type is invalid, or the property already exists.
+//   - 409 Conflict: If the property already exists.
+func (c *Client) CreateContactProperty(ctx context.Context, name, propertyType string) (*APIResponse, error) {
+	req := ContactPropertyRequest{Name: name, Type: propertyType}
+	var resp APIResponse
+	callCtx := withoutRetries(withResponseHeader(ctx, &resp.Header))
+	err := c.sendRequest(callCtx, http.MethodPost, "/contacts/properties", req, &resp)
+	if err != nil {
+		return nil, err
+	}
+	c.recordOperation(ctx, "CreateContactProperty", &resp)
+	return &resp, nil
+}
+
+// ContactPropertyCreator creates custom contact properties, e.g. a Client.
+type ContactPropertyCreator interface {
+	CreateContactProperty(ctx context.Context, name, propertyType string) (*APIResponse, error)
+}
+
+// ContactPropertyCache creates custom contact properties, for callers that set custom properties on contacts and must
+// declare them first. It remembers the properties known to exist, so that each one is created at most once per
+// process. It is safe for concurrent use. The controllers only sync the built-in contact properties and do not use it.
+type ContactPropertyCache struct {
+	creator ContactPropertyCreator
+
+	mu    sync.Mutex
+	known map[string]bool
+}
+
+// NewContactPropertyCache returns a ContactPropertyCache creating the missing properties with creator.
+func NewContactPropertyCache(creator ContactPropertyCreator) *ContactPropertyCache {
+	return &ContactPropertyCache{creator: creator, known: map[string]bool{}}
+}
+
+// Ensure creates the property unless it is already known to exist. A property Loops reports as existing already, with
+// a 409 Conflict or a 400 Bad Request saying so, is remembered as known too. The lock is not held during the call to
+// Loops, so concurrent calls for the same unknown property may both try to create it, the later one then finding it
+// existing.
+func (c *ContactPropertyCache) Ensure(ctx context.Context, name, propertyType string) error {
+	c.mu.Lock()
+	known := c.known[name]
+	c.mu.Unlock()
+	if known {
+		return nil
+	}
+
+	if _, err := c.creator.CreateContactProperty(ctx, name, propertyType); err != nil && !isPropertyExists(err) {
+		return err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.known[name] = true
+	return nil
+}
+
+// isPropertyExists returns true if err is the answer of Loops to the creation of a property that already exists,
+// either a 409 Conflict or a 400 Bad Request whose message says so.
+func isPropertyExists(err error) bool {
+	if IsConflict(err) {
+		return true
+	}
+	var apiErr *Error
+	return errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusBadRequest &&
+		strings.Contains(strings.ToLower(apiErr.Body), "already exists")
+}
@@ -0,0 +1,100 @@
+package loops
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestCreateContactProperty(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			t.Errorf("Expected POST request, got %s", r.Method)
+		}
+		if r.URL.Path != "/contacts/properties" {
+			t.Errorf("Expected path /contacts/properties, got %s", r.URL.Path)
+		}
+
+		var req ContactPropertyRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Errorf("Failed to decode request body: %v", err)
+		}
+		if req.Name != "plan" || req.Type != ContactPropertyTypeString {
+			t.Errorf("Expected a string property named plan, got %+v", req)
+		}
+
+		if err := json.NewEncoder(w).Encode(APIResponse{Success: true}); err != nil {
+			t.Errorf("Failed to write response: %v", err)
+		}
+	}))
+	defer ts.Close()
+
+	client, _ := NewSDK("test-key", WithBaseURL(ts.URL))
+	resp, err := client.CreateContactProperty(context.Background(), "plan", ContactPropertyTypeString)
+	if err != nil {
+		t.Fatalf("CreateContactProperty() failed: %v", err)
+	}
+	if !resp.Success {
+		t.Error("CreateContactProperty() expected success true")
+	}
+}
+
+func TestContactPropertyCache(t *testing.T) {
+	created := map[string]int{}
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req ContactPropertyRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Errorf("Failed to decode request body: %v", err)
+		}
+		created[req.Name]++
+
+		switch req.Name {
+		case "existing":
+			w.WriteHeader(http.StatusConflict)
+			_, _ = w.Write([]byte(`{"success":false,"message":"Property already exists"}`))
+		case "existing-bad-request":
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = w.Write([]byte(`{"success":false,"message":"A property with this name already exists"}`))
+		case "invalid":
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = w.Write([]byte(`{"success":false,"message":"Invalid type"}`))
+		default:
+			_, _ = w.Write([]byte(`{"success":true}`))
+		}
+	}))
+	defer ts.Close()
+
+	client, _ := NewSDK("test-key", WithBaseURL(ts.URL))
+	cache := NewContactPropertyCache(client)
+	ctx := context.Background()
+
+	tests := []struct {
+		name            string
+		property        string
+		wantErr         bool
+		expectedCreated int
+	}{
+		{name: "created", property: "plan", expectedCreated: 1},
+		{name: "cached after creation", property: "plan", expectedCreated: 1},
+		{name: "already existing", property: "existing", expectedCreated: 1},
+		{name: "cached after conflict", property: "existing", expectedCreated: 1},
+		{name: "already existing with a bad request", property: "existing-bad-request", expectedCreated: 1},
+		{name: "cached after bad request", property: "existing-bad-request", expectedCreated: 1},
+		{name: "failed", property: "invalid", wantErr: true, expectedCreated: 1},
+		{name: "retried after failure", property: "invalid", wantErr: true, expectedCreated: 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := cache.Ensure(ctx, tt.property, ContactPropertyTypeString)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Ensure() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if created[tt.property] != tt.expectedCreated {
+				t.Errorf("Expected %d creations of %s, got %d", tt.expectedCreated, tt.property, created[tt.property])
+			}
+		})
+	}
+}
@@ -0,0 +1,195 @@
+package loops
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Retry reasons, used as the reason label of loops_request_retries_total
+const (
+	retryReason5xx     = "5xx"
+	retryReason429     = "429"
+	retryReasonNetwork = "network"
+	// retryReasonPredicate is the reason of the retries the default policy would not make, decided by a RetryPredicate
+	retryReasonPredicate = "predicate"
+)
+
+// ErrCircuitOpen is returned without calling Loops while the circuit breaker is open.
+var ErrCircuitOpen = errors.New("loops circuit breaker is open")
+
+// WithRetries retries requests that fail with a network error, a 429 or a 5xx up to maxRetries times. The first
+// retry waits backoff, each subsequent one doubles the wait. Non-idempotent calls, such as DeleteContact, are never
+// retried, as the failed attempt may have gone through.
+func WithRetries(maxRetries int, backoff time.Duration) ClientOption {
+	return func(c *Client) {
+		c.maxRetries = maxRetries
+		c.retryBackoff = backoff
+	}
+}
+
+// RetryPredicate decides whether a failed request is retried. It is called with the response of a request Loops
+// answered with a status of 400 or more, whose body was already read, or with the error of a request that got no
+// response. Requests whose context is done are never retried.
+type RetryPredicate func(resp *http.Response, err error) bool
+
+// WithRetryPredicate makes the requests the predicate returns true for retried, instead of the ones failing with a
+// network error, a 429 or a 5xx. It only decides which requests are retried: how many times and how long to wait is
+// still configured with WithRetries. The circuit breaker counts the requests the predicate retries as failures.
+func WithRetryPredicate(predicate RetryPredicate) ClientOption {
+	return func(c *Client) {
+		c.retryPredicate = predicate
+	}
+}
+
+// WithCircuitBreaker stops calling Loops for cooldown once threshold consecutive requests have failed with a network
+// error, a 429 or a 5xx after their retries. Requests made while the circuit is open fail with ErrCircuitOpen. After
+// the cooldown a single request is let through, closing the circuit on success and opening it again on failure. The
+// state is reported by loops_circuit_breaker_state, labeled with the name of the client set by WithClientName.
+func WithCircuitBreaker(threshold int, cooldown time.Duration) ClientOption {
+	return func(c *Client) {
+		c.breaker = &circuitBreaker{
+			threshold: threshold,
+			cooldown:  cooldown,
+			now:       time.Now,
+		}
+	}
+}
+
+// retryReason returns why a request that ended with the given status code or transport error should be retried, or
+// an empty string if it should not.
+func retryReason(statusCode int, err error) string {
+	switch {
+	case err != nil:
+		return retryReasonNetwork
+	case statusCode == http.StatusTooManyRequests:
+		return retryReason429
+	case statusCode >= 500:
+		return retryReason5xx
+	default:
+		return ""
+	}
+}
+
+// retryReason returns why the request that got resp, or failed with err without a response, should be retried, or an
+// empty string if it should not. The retry predicate, if any, overrides the default policy.
+func (c *Client) retryReason(resp *http.Response, err error) string {
+	statusCode := 0
+	if resp != nil {
+		statusCode = resp.StatusCode
+	}
+	reason := retryReason(statusCode, err)
+	if c.retryPredicate == nil {
+		return reason
+	}
+	if !c.retryPredicate(resp, err) {
+		return ""
+	}
+	if reason == "" {
+		return retryReasonPredicate
+	}
+	return reason
+}
+
+// sleepContext waits for d, returning early with the context error if ctx is done first.
+func sleepContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// circuitState is the state of the circuit breaker, exposed as the value of loops_circuit_breaker_state.
+type circuitState int
+
+const (
+	circuitClosed circuitState = iota
+	circuitOpen
+	circuitHalfOpen
+)
+
+// circuitBreaker is a consecutive-failure circuit breaker. A nil circuitBreaker always allows requests.
+type circuitBreaker struct {
+	mu        sync.Mutex
+	client    string
+	threshold int
+	cooldown  time.Duration
+	now       func() time.Time
+
+	state    circuitState
+	failures int
+	openedAt time.Time
+	probing  bool
+}
+
+// allow returns ErrCircuitOpen if the request must not be sent.
+func (b *circuitBreaker) allow() error {
+	if b == nil {
+		return nil
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch b.state {
+	case circuitOpen:
+		if b.now().Sub(b.openedAt) < b.cooldown {
+			return ErrCircuitOpen
+		}
+		b.setState(circuitHalfOpen)
+		b.probing = true
+		return nil
+	case circuitHalfOpen:
+		// Only a single probe is let through until it completes
+		if b.probing {
+			return ErrCircuitOpen
+		}
+		b.probing = true
+		return nil
+	default:
+		return nil
+	}
+}
+
+// record records the outcome of an allowed request.
+func (b *circuitBreaker) record(success bool) {
+	if b == nil {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.probing = false
+	if success {
+		b.failures = 0
+		b.setState(circuitClosed)
+		return
+	}
+
+	b.failures++
+	if b.state == circuitHalfOpen || b.failures >= b.threshold {
+		b.openedAt = b.now()
+		b.setState(circuitOpen)
+	}
+}
+
+// release lets another request probe a half-open circuit without recording an outcome, e.g. when the caller
+// cancelled the request.
+func (b *circuitBreaker) release() {
+	if b == nil {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.probing = false
+}
+
+func (b *circuitBreaker) setState(state circuitState) {
+	b.state = state
+	circuitBreakerState.WithLabelValues(b.client).Set(float64(state))
+}
@@ -0,0 +1,252 @@
+package loops
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+// newFlakyServer returns a server answering the given status codes in order, then 200.
+func newFlakyServer(t *testing.T, statuses ...int) (*httptest.Server, *int32) {
+	t.Helper()
+	var calls int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		call := int(atomic.AddInt32(&calls, 1))
+		if call <= len(statuses) {
+			w.WriteHeader(statuses[call-1])
+			return
+		}
+		if err := json.NewEncoder(w).Encode(APIResponse{Success: true}); err != nil {
+			t.Errorf("Failed to write response: %v", err)
+		}
+	}))
+	t.Cleanup(ts.Close)
+	return ts, &calls
+}
+
+func TestSendRequest_Retries(t *testing.T) {
+	ts, calls := newFlakyServer(t, http.StatusServiceUnavailable, http.StatusTooManyRequests, http.StatusBadGateway)
+	client, _ := NewSDK("test-key", WithBaseURL(ts.URL), WithRetries(3, time.Millisecond))
+
+	before5xx := testutil.ToFloat64(retriesTotal.WithLabelValues(http.MethodPut, "5xx"))
+	before429 := testutil.ToFloat64(retriesTotal.WithLabelValues(http.MethodPut, "429"))
+
+	if _, err := client.UpsertContact(context.Background(), ContactRequest{Email: "test@example.com"}); err != nil {
+		t.Fatalf("UpsertContact() failed: %v", err)
+	}
+	if got := atomic.LoadInt32(calls); got != 4 {
+		t.Errorf("Expected 4 calls, got %d", got)
+	}
+	if got := testutil.ToFloat64(retriesTotal.WithLabelValues(http.MethodPut, "5xx")); got != before5xx+2 {
+		t.Errorf("Expected %v 5xx retries, got %v", before5xx+2, got)
+	}
+	if got := testutil.ToFloat64(retriesTotal.WithLabelValues(http.MethodPut, "429")); got != before429+1 {
+		t.Errorf("Expected %v 429 retries, got %v", before429+1, got)
+	}
+}
+
+func TestSendRequest_RetriesExhausted(t *testing.T) {
+	ts, calls := newFlakyServer(t, http.StatusInternalServerError, http.StatusInternalServerError)
+	client, _ := NewSDK("test-key", WithBaseURL(ts.URL), WithRetries(1, time.Millisecond))
+
+	_, err := client.UpsertContact(context.Background(), ContactRequest{Email: "test@example.com"})
+	var apiErr *Error
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusInternalServerError {
+		t.Fatalf("Expected a 500 error, got %v", err)
+	}
+	if got := atomic.LoadInt32(calls); got != 2 {
+		t.Errorf("Expected 2 calls, got %d", got)
+	}
+}
+
+func TestSendRequest_NoRetryOnClientError(t *testing.T) {
+	ts, calls := newFlakyServer(t, http.StatusBadRequest)
+	client, _ := NewSDK("test-key", WithBaseURL(ts.URL), WithRetries(3, time.Millisecond))
+
+	if _, err := client.UpsertContact(context.Background(), ContactRequest{Email: "test@example.com"}); err == nil {
+		t.Fatal("Expected an error, got none")
+	}
+	if got := atomic.LoadInt32(calls); got != 1 {
+		t.Errorf("Expected 1 call, got %d", got)
+	}
+}
+
+func TestSendRequest_NoRetryOfNonIdempotentRequests(t *testing.T) {
+	tests := []struct {
+		name string
+		call func(client *Client) error
+	}{
+		{
+			name: "DeleteContact",
+			call: func(client *Client) error {
+				_, err := client.DeleteContact(context.Background(), "user-123")
+				return err
+			},
+		},
+		{
+			name: "CreateContactProperty",
+			call: func(client *Client) error {
+				_, err := client.CreateContactProperty(context.Background(), "plan", ContactPropertyTypeString)
+				return err
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ts, calls := newFlakyServer(t, http.StatusBadGateway)
+			client, _ := NewSDK("test-key", WithBaseURL(ts.URL), WithRetries(3, time.Millisecond))
+
+			if err := tt.call(client); err == nil {
+				t.Fatal("Expected an error, got none")
+			}
+			if got := atomic.LoadInt32(calls); got != 1 {
+				t.Errorf("Expected 1 call, got %d", got)
+			}
+		})
+	}
+}
+
+func TestSendRequest_NetworkRetry(t *testing.T) {
+	ts, _ := newFlakyServer(t)
+	url := ts.URL
+	ts.Close()
+	client, _ := NewSDK("test-key", WithBaseURL(url), WithRetries(2, time.Millisecond))
+
+	before := testutil.ToFloat64(retriesTotal.WithLabelValues(http.MethodPut, "network"))
+	if _, err := client.UpsertContact(context.Background(), ContactRequest{Email: "test@example.com"}); err == nil {
+		t.Fatal("Expected an error, got none")
+	}
+	if got := testutil.ToFloat64(retriesTotal.WithLabelValues(http.MethodPut, "network")); got != before+2 {
+		t.Errorf("Expected %v network retries, got %v", before+2, got)
+	}
+}
+
+func TestSendRequest_RetryPredicate(t *testing.T) {
+	retryTeapots := func(resp *http.Response, err error) bool {
+		return resp != nil && resp.StatusCode == http.StatusTeapot
+	}
+
+	tests := []struct {
+		name          string
+		statuses      []int
+		expectedCalls int32
+		expectedErr   bool
+	}{
+		{
+			name:          "418 retried",
+			statuses:      []int{http.StatusTeapot, http.StatusTeapot},
+			expectedCalls: 3,
+		},
+		{
+			name:          "5xx no longer retried",
+			statuses:      []int{http.StatusServiceUnavailable},
+			expectedCalls: 1,
+			expectedErr:   true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ts, calls := newFlakyServer(t, tt.statuses...)
+			client, _ := NewSDK("test-key", WithBaseURL(ts.URL), WithRetries(3, time.Millisecond),
+				WithRetryPredicate(retryTeapots))
+
+			before := testutil.ToFloat64(retriesTotal.WithLabelValues(http.MethodPut, "predicate"))
+			_, err := client.UpsertContact(context.Background(), ContactRequest{Email: "test@example.com"})
+			if (err != nil) != tt.expectedErr {
+				t.Errorf("Expected error %t, got %v", tt.expectedErr, err)
+			}
+			if got := atomic.LoadInt32(calls); got != tt.expectedCalls {
+				t.Errorf("Expected %d calls, got %d", tt.expectedCalls, got)
+			}
+			expectedRetries := before + float64(tt.expectedCalls-1)
+			if got := testutil.ToFloat64(retriesTotal.WithLabelValues(http.MethodPut, "predicate")); got != expectedRetries {
+				t.Errorf("Expected %v predicate retries, got %v", expectedRetries, got)
+			}
+		})
+	}
+}
+
+func TestSendRequest_CircuitBreaker(t *testing.T) {
+	ts, calls := newFlakyServer(t, http.StatusInternalServerError, http.StatusInternalServerError)
+	client, _ := NewSDK("test-key", WithBaseURL(ts.URL), WithCircuitBreaker(2, time.Hour), WithClientName("breaker-test"))
+	now := time.Now()
+	client.breaker.now = func() time.Time { return now }
+
+	ctx := context.Background()
+	for i := 0; i < 2; i++ {
+		if _, err := client.UpsertContact(ctx, ContactRequest{Email: "test@example.com"}); err == nil {
+			t.Fatal("Expected an error, got none")
+		}
+	}
+	if got := testutil.ToFloat64(circuitBreakerState.WithLabelValues("breaker-test")); got != float64(circuitOpen) {
+		t.Errorf("Expected the circuit to be open, got state %v", got)
+	}
+
+	// Requests fail fast while the circuit is open
+	if _, err := client.UpsertContact(ctx, ContactRequest{Email: "test@example.com"}); !errors.Is(err, ErrCircuitOpen) {
+		t.Errorf("Expected ErrCircuitOpen, got %v", err)
+	}
+	if got := atomic.LoadInt32(calls); got != 2 {
+		t.Errorf("Expected 2 calls, got %d", got)
+	}
+
+	// After the cooldown a probe closes the circuit again
+	now = now.Add(time.Hour)
+	if _, err := client.UpsertContact(ctx, ContactRequest{Email: "test@example.com"}); err != nil {
+		t.Fatalf("UpsertContact() failed: %v", err)
+	}
+	if got := testutil.ToFloat64(circuitBreakerState.WithLabelValues("breaker-test")); got != float64(circuitClosed) {
+		t.Errorf("Expected the circuit to be closed, got state %v", got)
+	}
+}
+
+func TestSendRequest_RateLimitedRetryAfter(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Retry-After", "7")
+		w.WriteHeader(http.StatusTooManyRequests)
+	}))
+	defer ts.Close()
+	client, _ := NewSDK("test-key", WithBaseURL(ts.URL))
+
+	_, err := client.UpsertContact(context.Background(), ContactRequest{Email: "test@example.com"})
+	if !IsRateLimited(err) {
+		t.Fatalf("Expected a rate limit error, got %v", err)
+	}
+	var apiErr *Error
+	if !errors.As(err, &apiErr) || apiErr.RetryAfter != 7*time.Second {
+		t.Errorf("Expected a Retry-After of 7s, got %v", err)
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name     string
+		value    string
+		expected time.Duration
+	}{
+		{name: "empty", value: "", expected: 0},
+		{name: "seconds", value: "30", expected: 30 * time.Second},
+		{name: "negative seconds", value: "-1", expected: 0},
+		{name: "date", value: now.Add(time.Minute).Format(http.TimeFormat), expected: time.Minute},
+		{name: "past date", value: now.Add(-time.Minute).Format(http.TimeFormat), expected: 0},
+		{name: "invalid", value: "soon", expected: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := parseRetryAfter(tt.value, now); got != tt.expected {
+				t.Errorf("Expected %v, got %v", tt.expected, got)
+			}
+		})
+	}
+}
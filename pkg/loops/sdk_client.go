@@ -3,27 +3,69 @@ package loops
 import (
 	"bytes"
 	"context"
+	"crypto/tls"
+	"crypto/x509"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
+	"net/url"
+	"strconv"
 	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/prometheus/client_golang/prometheus"
+	"go.opentelemetry.io/otel/trace"
 )
 
 const (
-	defaultBaseURL = "https://app.loops.so/api/v1"
+	// DefaultBaseURL is the base URL of the Loops API, used unless WithBaseURL is given.
+	DefaultBaseURL = "https://app.loops.so/api/v1"
 )
 
 // Client is the Loops API client.
 type Client struct {
-	apiKey     string
-	baseURL    string
-	httpClient *http.Client
+	apiKey                string
+	baseURL               string
+	httpClient            *http.Client
+	defaultHeaders        http.Header
+	importPath            string
+	deleteDryRun          bool
+	maxRetries            int
+	retryBackoff          time.Duration
+	retryPredicate        RetryPredicate
+	breaker               *circuitBreaker
+	tracer                trace.Tracer
+	proxyURL              string
+	debug                 *debugBuffer
+	limiter               *ConcurrencyLimiter
+	mailingListUpdatePath string
+	mailingListsPath      string
+	caCertPool            *x509.CertPool
+	apiKeyProvider        APIKeyProvider
+	minTLSVersion         uint16
+	deadlineHeader        string
+	eventDedup            *eventDeduplicator
+	name                  string
+	registerer            prometheus.Registerer
+	log                   logr.Logger
 }
 
 // ClientOption defines a functional option for configuring the Client.
 type ClientOption func(*Client)
 
+// DefaultClientName is the name of the clients created without WithClientName.
+const DefaultClientName = "default"
+
+// WithClientName names the client in the client label of its metrics, such as loops_circuit_breaker_state, so that
+// the clients of one process are told apart. Defaults to DefaultClientName.
+func WithClientName(name string) ClientOption {
+	return func(c *Client) {
+		c.name = name
+	}
+}
+
 // WithBaseURL sets a custom base URL for the client.
 func WithBaseURL(url string) ClientOption {
 	return func(c *Client) {
@@ -38,39 +80,364 @@ func WithHTTPClient(client *http.Client) ClientOption {
 	}
 }
 
-// NewSDK creates a new Loops API client.
-func NewSDK(apiKey string, opts ...ClientOption) (*Client, error) {
-	if apiKey == "" {
-		return nil, fmt.Errorf("api key is required")
+// WithContactsImport enables ImportContacts to use the batch import endpoint at the given path (e.g.
+// "/contacts/import") for accounts where Loops offers it. Without it, ImportContacts falls back to UpsertContacts.
+func WithContactsImport(path string) ClientOption {
+	return func(c *Client) {
+		c.importPath = path
+	}
+}
+
+// WithDeleteDryRun makes DeleteContact log the deletion and return a synthetic success without calling Loops, as Loops
+// has no dry-run mode for deletions. Useful to verify what would be deleted before enabling hard deletes.
+func WithDeleteDryRun(enabled bool) ClientOption {
+	return func(c *Client) {
+		c.deleteDryRun = enabled
+	}
+}
+
+// WithDefaultHeader sets a header that is sent on every request, e.g. to opt into a Loops beta feature.
+// Default headers never overwrite the Authorization and Content-Type headers managed by the client.
+func WithDefaultHeader(key, value string) ClientOption {
+	return func(c *Client) {
+		if c.defaultHeaders == nil {
+			c.defaultHeaders = http.Header{}
+		}
+		c.defaultHeaders.Set(key, value)
+	}
+}
+
+// WithDeadlineHeader sends the time left before the context deadline of each request, in whole milliseconds, in the
+// named header (e.g. "X-Deadline"), so that gateways honoring it give up in sync with the client. The time is relative
+// rather than an absolute timestamp to be immune to clock skew, and is computed again on each retry. Requests whose
+// context has no deadline are sent without the header.
+func WithDeadlineHeader(name string) ClientOption {
+	return func(c *Client) {
+		c.deadlineHeader = name
+	}
+}
+
+// WithProxy sends every request through the HTTP proxy at proxyURL (e.g. "http://proxy.internal:3128"), overriding the
+// HTTP_PROXY and HTTPS_PROXY environment variables. The proxy URL is validated by NewSDK.
+func WithProxy(proxyURL string) ClientOption {
+	return func(c *Client) {
+		c.proxyURL = proxyURL
+	}
+}
+
+// WithCACertPool trusts the certificate authorities of pool, instead of the system ones, when connecting to Loops or
+// to the proxy, e.g. for an internal CA fronting Loops. It is applied by NewSDK.
+func WithCACertPool(pool *x509.CertPool) ClientOption {
+	return func(c *Client) {
+		c.caCertPool = pool
 	}
+}
 
+// WithMinTLSVersion sets the minimum TLS version of the connections to Loops, e.g. tls.VersionTLS13. It defaults to
+// TLS 1.2, which is enforced on the default transport and on any *http.Transport given to WithHTTPClient. An explicit
+// minimum version requires the transport of the HTTP client to be an *http.Transport. It is applied by NewSDK.
+func WithMinTLSVersion(version uint16) ClientOption {
+	return func(c *Client) {
+		c.minTLSVersion = version
+	}
+}
+
+// WorkspaceHeader is the header selecting the Loops workspace a request targets. The public Loops API scopes each API
+// key to a single team, so it only matters for accounts or gateways serving several workspaces behind one key.
+const WorkspaceHeader = "Loops-Workspace-Id"
+
+// WithWorkspace makes every request target the given Loops workspace, unless overridden per call with
+// WithRequestWorkspace.
+func WithWorkspace(id string) ClientOption {
+	return WithDefaultHeader(WorkspaceHeader, id)
+}
+
+// WithRequestWorkspace returns a copy of ctx targeting the given Loops workspace, so that one client can manage
+// contacts across workspaces. It takes precedence over WithWorkspace.
+func WithRequestWorkspace(ctx context.Context, id string) context.Context {
+	return WithRequestHeader(ctx, WorkspaceHeader, id)
+}
+
+type requestHeadersKey struct{}
+
+type responseHeaderKey struct{}
+
+// withResponseHeader returns a copy of ctx that stores the header of the last response to a request made with it in
+// header.
+func withResponseHeader(ctx context.Context, header *http.Header) context.Context {
+	return context.WithValue(ctx, responseHeaderKey{}, header)
+}
+
+type noRetriesKey struct{}
+
+// withoutRetries returns a copy of ctx whose requests are sent once, whatever WithRetries allows, for the requests
+// that are not safe to send twice.
+func withoutRetries(ctx context.Context) context.Context {
+	return context.WithValue(ctx, noRetriesKey{}, true)
+}
+
+// WithRequestHeader returns a copy of ctx carrying a header that is sent on the requests made with it.
+// Per-call headers take precedence over default headers and the headers managed by the client.
+func WithRequestHeader(ctx context.Context, key, value string) context.Context {
+	headers := http.Header{}
+	if existing, ok := ctx.Value(requestHeadersKey{}).(http.Header); ok {
+		headers = existing.Clone()
+	}
+	headers.Set(key, value)
+	return context.WithValue(ctx, requestHeadersKey{}, headers)
+}
+
+// NewSDK creates a new Loops API client. The API key may be empty when it comes from WithAPIKeyProvider.
+func NewSDK(apiKey string, opts ...ClientOption) (*Client, error) {
 	c := &Client{
 		apiKey:     apiKey,
-		baseURL:    defaultBaseURL,
+		baseURL:    DefaultBaseURL,
 		httpClient: &http.Client{Timeout: 10 * time.Second},
+		name:       DefaultClientName,
+		log:        logr.Discard(),
 	}
 
 	for _, opt := range opts {
 		opt(c)
 	}
 
+	if c.registerer != nil {
+		if err := registerMetrics(c.registerer); err != nil {
+			return nil, err
+		}
+	}
+
+	if c.breaker != nil {
+		c.breaker.client = c.name
+		c.breaker.setState(circuitClosed)
+	}
+
+	if c.currentAPIKey() == "" {
+		return nil, fmt.Errorf("api key is required")
+	}
+
 	if c.baseURL == "" {
 		return nil, fmt.Errorf("base url is required")
 	}
 
+	if c.proxyURL != "" {
+		if err := c.setProxy(c.proxyURL); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := c.configureTLS(); err != nil {
+		return nil, err
+	}
+
 	return c, nil
 }
 
+// configureTLS applies the minimum TLS version and the CA certificate pool to the transport of the HTTP client. The
+// default minimum version is skipped for transports other than *http.Transport, which manage their own TLS settings.
+func (c *Client) configureTLS() error {
+	switch c.minTLSVersion {
+	case 0, tls.VersionTLS10, tls.VersionTLS11, tls.VersionTLS12, tls.VersionTLS13:
+	default:
+		return fmt.Errorf("invalid minimum TLS version %#x", c.minTLSVersion)
+	}
+
+	feature := "a minimum TLS version"
+	if c.caCertPool != nil {
+		feature = "a CA certificate pool"
+	}
+	_, isTransport := c.httpClient.Transport.(*http.Transport)
+	if c.minTLSVersion == 0 && c.caCertPool == nil && c.httpClient.Transport != nil && !isTransport {
+		return nil
+	}
+
+	transport, err := c.cloneTransport(feature)
+	if err != nil {
+		return err
+	}
+	if transport.TLSClientConfig == nil {
+		transport.TLSClientConfig = &tls.Config{}
+	}
+	switch {
+	case c.minTLSVersion != 0:
+		transport.TLSClientConfig.MinVersion = c.minTLSVersion
+	case transport.TLSClientConfig.MinVersion < tls.VersionTLS12:
+		transport.TLSClientConfig.MinVersion = tls.VersionTLS12
+	}
+	if c.caCertPool != nil {
+		transport.TLSClientConfig.RootCAs = c.caCertPool
+	}
+	c.setTransport(transport)
+	return nil
+}
+
+// currentAPIKey returns the API key to send, from the API key provider when one is set.
+func (c *Client) currentAPIKey() string {
+	if c.apiKeyProvider != nil {
+		return c.apiKeyProvider.APIKey()
+	}
+	return c.apiKey
+}
+
+// setProxy validates the proxy URL and configures the transport of the HTTP client to use it.
+func (c *Client) setProxy(rawURL string) error {
+	proxy, err := url.Parse(rawURL)
+	if err != nil {
+		return fmt.Errorf("invalid proxy url: %w", err)
+	}
+	switch proxy.Scheme {
+	case "http", "https", "socks5":
+	default:
+		return fmt.Errorf("invalid proxy url %q: scheme must be one of http, https or socks5", rawURL)
+	}
+	if proxy.Host == "" {
+		return fmt.Errorf("invalid proxy url %q: host is required", rawURL)
+	}
+
+	transport, err := c.cloneTransport("a proxy")
+	if err != nil {
+		return err
+	}
+	transport.Proxy = http.ProxyURL(proxy)
+	c.setTransport(transport)
+	return nil
+}
+
+// cloneTransport returns a copy of the transport of the HTTP client to configure, failing for transports other than
+// *http.Transport. The feature is named in the error.
+func (c *Client) cloneTransport(feature string) (*http.Transport, error) {
+	switch t := c.httpClient.Transport.(type) {
+	case nil:
+		return http.DefaultTransport.(*http.Transport).Clone(), nil
+	case *http.Transport:
+		return t.Clone(), nil
+	default:
+		return nil, fmt.Errorf("%s requires the HTTP client transport to be an *http.Transport, got %T", feature, t)
+	}
+}
+
+// setTransport sets the transport of a copy of the HTTP client, leaving the client given to WithHTTPClient untouched.
+func (c *Client) setTransport(transport *http.Transport) {
+	httpClient := *c.httpClient
+	httpClient.Transport = transport
+	c.httpClient = &httpClient
+}
+
 // ContactRequest represents the payload for creating or updating a contact.
 type ContactRequest struct {
-	Email        string          `json:"email,omitempty"`
-	UserID       string          `json:"userId,omitempty"`
-	FirstName    string          `json:"firstName,omitempty"`
-	LastName     string          `json:"lastName,omitempty"`
-	Source       string          `json:"source,omitempty"`
-	Subscribed   *bool           `json:"subscribed,omitempty"`
-	UserGroup    string          `json:"userGroup,omitempty"`
-	MailingLists map[string]bool `json:"mailingLists,omitempty"`
+	Email        string         `json:"email,omitempty"`
+	UserID       string         `json:"userId,omitempty"`
+	FirstName    string         `json:"firstName,omitempty"`
+	LastName     string         `json:"lastName,omitempty"`
+	Source       string         `json:"source,omitempty"`
+	Subscribed   *bool          `json:"subscribed,omitempty"`
+	UserGroup    string         `json:"userGroup,omitempty"`
+	MailingLists MailingListOps `json:"mailingLists,omitempty"`
+
+	// ClearFields lists the contact properties, by JSON name, to empty in Loops. Empty fields are otherwise omitted
+	// from the payload and left untouched, while Loops empties a property when it receives a null value. A cleared
+	// field is sent as null even if it is also set.
+	ClearFields []string `json:"-"`
+
+	// IfMatch makes the update conditional on the contact still having this ETag, as returned by FindContact in
+	// Contact.ETag, so that a concurrent change is not overwritten. It is sent as the If-Match header.
+	IfMatch string `json:"-"`
+	// IfNoneMatch makes the update conditional on the contact not having this ETag, e.g. "*" to only create the
+	// contact. It is sent as the If-None-Match header.
+	IfNoneMatch string `json:"-"`
+}
+
+// Contact property names that can be cleared with ContactRequest.ClearFields.
+const (
+	ContactFieldFirstName = "firstName"
+	ContactFieldLastName  = "lastName"
+	ContactFieldUserGroup = "userGroup"
+)
+
+// MailingListOp is the change to apply to the subscription of a contact to a mailing list.
+type MailingListOp string
+
+const (
+	// MailingListAdd subscribes the contact to the mailing list.
+	MailingListAdd MailingListOp = "add"
+	// MailingListRemove unsubscribes the contact from the mailing list.
+	MailingListRemove MailingListOp = "remove"
+	// MailingListLeave leaves the subscription of the contact to the mailing list unchanged. Such entries are omitted
+	// from the payload, as Loops only touches the mailing lists it receives.
+	MailingListLeave MailingListOp = "leave"
+)
+
+// MailingListOps are the mailing list changes of a ContactRequest, keyed by mailing list ID. Loops expects a map of
+// mailing list IDs to booleans, where false unsubscribes the contact, so a partial boolean map built with the zero
+// value of missing entries would unsubscribe the contact from them. The ops make leaving a subscription unchanged
+// explicit.
+type MailingListOps map[string]MailingListOp
+
+// payload returns the mailing list map expected by Loops, or nil if no mailing list changes.
+func (o MailingListOps) payload() map[string]bool {
+	var mailingLists map[string]bool
+	for mailingListID, op := range o {
+		switch op {
+		case MailingListAdd, MailingListRemove:
+			if mailingLists == nil {
+				mailingLists = map[string]bool{}
+			}
+			mailingLists[mailingListID] = op == MailingListAdd
+		}
+	}
+	return mailingLists
+}
+
+// MarshalJSON encodes the ops as the mailing list map expected by Loops.
+func (o MailingListOps) MarshalJSON() ([]byte, error) {
+	payload := o.payload()
+	if payload == nil {
+		payload = map[string]bool{}
+	}
+	return json.Marshal(payload)
+}
+
+// UnmarshalJSON decodes the mailing list map expected by Loops into ops.
+func (o *MailingListOps) UnmarshalJSON(data []byte) error {
+	var mailingLists map[string]bool
+	if err := json.Unmarshal(data, &mailingLists); err != nil {
+		return err
+	}
+	if mailingLists == nil {
+		*o = nil
+		return nil
+	}
+	ops := MailingListOps{}
+	for mailingListID, subscribed := range mailingLists {
+		ops[mailingListID] = MailingListRemove
+		if subscribed {
+			ops[mailingListID] = MailingListAdd
+		}
+	}
+	*o = ops
+	return nil
+}
+
+// MarshalJSON encodes the request, sending the cleared fields as null.
+func (r ContactRequest) MarshalJSON() ([]byte, error) {
+	type contactRequest ContactRequest
+	if r.MailingLists.payload() == nil {
+		// Omit the mailingLists of a request that leaves every mailing list unchanged
+		r.MailingLists = nil
+	}
+	data, err := json.Marshal(contactRequest(r))
+	if err != nil || len(r.ClearFields) == 0 {
+		return data, err
+	}
+
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil, err
+	}
+	for _, field := range r.ClearFields {
+		fields[field] = json.RawMessage("null")
+	}
+	return json.Marshal(fields)
 }
 
 // APIResponse represents a generic response from the Loops API.
@@ -80,51 +447,149 @@ type ContactRequest struct {
 // persistent reference.
 //
 // On Failure: Returns Success=false and a Message describing the error.
+//
+// MailingLists is only set when Loops echoes the mailing list subscriptions of the contact, keyed by mailing list ID,
+// which it does not guarantee.
+//
+// Header is the header of the HTTP response, e.g. to read the rate limit or the request ID of the operation. It is nil
+// when no request was sent, as with WithDeleteDryRun.
 type APIResponse struct {
-	Success bool   `json:"success"`
-	Message string `json:"message,omitempty"`
-	ID      string `json:"id,omitempty"`
+	Success      bool            `json:"success"`
+	Message      string          `json:"message,omitempty"`
+	ID           string          `json:"id,omitempty"`
+	MailingLists map[string]bool `json:"mailingLists,omitempty"`
+	Header       http.Header     `json:"-"`
 }
 
-func (c *Client) sendRequest(ctx context.Context, method, path string, body interface{}, out interface{}) error {
-	var bodyReader io.Reader
+func (c *Client) sendRequest(ctx context.Context, method, path string, body interface{}, out interface{}) (err error) {
+	ctx, span := c.startSpan(ctx, method, path)
+	statusCode := 0
+	defer func() { endSpan(span, statusCode, err) }()
+
+	var data []byte
 	if body != nil {
-		data, err := json.Marshal(body)
+		data, err = json.Marshal(body)
 		if err != nil {
 			return fmt.Errorf("failed to marshal request body: %w", err)
 		}
+	}
+
+	if err := c.breaker.allow(); err != nil {
+		return err
+	}
+
+	maxRetries := c.maxRetries
+	if ctx.Value(noRetriesKey{}) != nil {
+		maxRetries = 0
+	}
+	for attempt := 0; ; attempt++ {
+		if err = c.limiter.acquire(ctx); err != nil {
+			c.breaker.release()
+			return fmt.Errorf("failed to wait for a request slot: %w", classifyRequestError(err))
+		}
+		var reason string
+		statusCode, reason, err = c.doRequest(ctx, method, path, data, out)
+		c.limiter.release()
+		if ctx.Err() != nil {
+			// The caller gave up, which says nothing about the health of Loops
+			c.breaker.release()
+			return err
+		}
+		if reason == "" || attempt >= maxRetries {
+			c.breaker.record(reason == "")
+			return err
+		}
+
+		retriesTotal.WithLabelValues(method, reason).Inc()
+		if sleepErr := sleepContext(ctx, c.retryBackoff<<attempt); sleepErr != nil {
+			c.breaker.release()
+			return err
+		}
+	}
+}
+
+// doRequest sends a single request. Alongside the error, it returns the response status code, if any, and the reason
+// to retry the request, if any.
+func (c *Client) doRequest(
+	ctx context.Context, method, path string, data []byte, out interface{},
+) (int, string, error) {
+	var bodyReader io.Reader
+	if data != nil {
 		bodyReader = bytes.NewReader(data)
 	}
 
 	req, err := http.NewRequestWithContext(ctx, method, fmt.Sprintf("%s%s", c.baseURL, path), bodyReader)
 	if err != nil {
-		return fmt.Errorf("failed to create request: %w", err)
+		return 0, "", fmt.Errorf("failed to create request: %w", err)
 	}
 
-	req.Header.Set("Authorization", "Bearer "+c.apiKey)
+	for key, values := range c.defaultHeaders {
+		req.Header[key] = values
+	}
+	req.Header.Set("Authorization", "Bearer "+c.currentAPIKey())
 	req.Header.Set("Content-Type", "application/json")
+	if headers, ok := ctx.Value(requestHeadersKey{}).(http.Header); ok {
+		for key, values := range headers {
+			req.Header[key] = values
+		}
+	}
+	if deadline, ok := ctx.Deadline(); ok && c.deadlineHeader != "" {
+		if remaining := time.Until(deadline).Milliseconds(); remaining > 0 {
+			req.Header.Set(c.deadlineHeader, strconv.FormatInt(remaining, 10))
+		}
+	}
+	c.injectTraceContext(ctx, req.Header)
 
 	resp, err := c.httpClient.Do(req)
 	if err != nil {
-		return fmt.Errorf("failed to execute request: %w", err)
+		c.debug.record(method, path, req.Header, data, 0, nil, err)
+		// A cancelled or expired context is not worth retrying
+		if ctx.Err() != nil {
+			return 0, "", fmt.Errorf("failed to execute request: %w", classifyRequestError(err))
+		}
+		return 0, c.retryReason(nil, err), fmt.Errorf("failed to execute request: %w", classifyRequestError(err))
 	}
 	defer func() { _ = resp.Body.Close() }()
+	if header, ok := ctx.Value(responseHeaderKey{}).(*http.Header); ok {
+		*header = resp.Header
+	}
+
+	respBody, readErr := io.ReadAll(resp.Body)
+	c.debug.record(method, path, req.Header, data, resp.StatusCode, respBody, readErr)
 
 	if resp.StatusCode >= 400 {
-		respBody, _ := io.ReadAll(resp.Body)
-		return &Error{
+		return resp.StatusCode, c.retryReason(resp, nil), &Error{
+			StatusCode: resp.StatusCode,
+			Body:       string(respBody),
+			RetryAfter: parseRetryAfter(resp.Header.Get("Retry-After"), time.Now()),
+		}
+	}
+
+	// Loops answers some failures with a 2xx status and "success": false
+	if readErr == nil && reportsFailure(respBody) {
+		return resp.StatusCode, "", &Error{
 			StatusCode: resp.StatusCode,
 			Body:       string(respBody),
 		}
 	}
 
 	if out != nil {
-		if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
-			return fmt.Errorf("failed to decode response: %w", err)
+		if readErr != nil {
+			return resp.StatusCode, "", fmt.Errorf("failed to read response: %w", readErr)
+		}
+
+		// Some endpoints answer with 204 or an empty body on success, there is nothing to decode then.
+		if len(bytes.TrimSpace(respBody)) == 0 {
+			return resp.StatusCode, "", nil
+		}
+
+		if err := json.Unmarshal(respBody, out); err != nil {
+			recordDecodeError(path)
+			return resp.StatusCode, "", fmt.Errorf("failed to decode response: %w", err)
 		}
 	}
 
-	return nil
+	return resp.StatusCode, "", nil
 }
 
 // UpsertContact creates or updates a contact in Loops.
@@ -135,12 +600,27 @@ func (c *Client) sendRequest(ctx context.Context, method, path string, body inte
 //
 // Errors:
 //   - 400 Bad Request: If the request payload is invalid.
+//   - 412 Precondition Failed: If IfMatch or IfNoneMatch is set and the contact does not satisfy it. See
+//     IsPreconditionFailed.
 func (c *Client) UpsertContact(ctx context.Context, req ContactRequest) (*APIResponse, error) {
+	return c.upsertContact(ctx, "UpsertContact", req)
+}
+
+// upsertContact sends the contact update, recording it under the given operation name.
+func (c *Client) upsertContact(ctx context.Context, operation string, req ContactRequest) (*APIResponse, error) {
+	if req.IfMatch != "" {
+		ctx = WithRequestHeader(ctx, "If-Match", req.IfMatch)
+	}
+	if req.IfNoneMatch != "" {
+		ctx = WithRequestHeader(ctx, "If-None-Match", req.IfNoneMatch)
+	}
+
 	var resp APIResponse
-	err := c.sendRequest(ctx, http.MethodPut, "/contacts/update", req, &resp)
+	err := c.sendRequest(withResponseHeader(ctx, &resp.Header), http.MethodPut, "/contacts/update", req, &resp)
 	if err != nil {
 		return nil, err
 	}
+	c.recordOperation(ctx, operation, &resp)
 	return &resp, nil
 }
 
@@ -153,21 +633,65 @@ type DeleteContactRequest struct {
 //
 // API: POST /contacts/delete
 //
-// Idempotency: Not idempotent
+// Idempotency: Not idempotent, so the request is sent once, whatever WithRetries allows
 //
 // Errors:
 //   - 404 Not Found: If the contact does not exist.
 //   - 400 Bad Request: If the request is invalid.
+//
+// With WithDeleteDryRun, no request is sent and a synthetic success is returned.
 func (c *Client) DeleteContact(ctx context.Context, userID string) (*APIResponse, error) {
+	if c.deleteDryRun {
+		c.logger(ctx).Info("Dry run, skipping Loops contact deletion", "userId", userID)
+		return &APIResponse{Success: true, Message: "dry run"}, nil
+	}
+
 	req := DeleteContactRequest{UserID: userID}
 	var resp APIResponse
-	err := c.sendRequest(ctx, http.MethodPost, "/contacts/delete", req, &resp)
+	callCtx := withoutRetries(withResponseHeader(ctx, &resp.Header))
+	err := c.sendRequest(callCtx, http.MethodPost, "/contacts/delete", req, &resp)
 	if err != nil {
 		return nil, err
 	}
+	c.recordOperation(ctx, "DeleteContact", &resp)
 	return &resp, nil
 }
 
+// Unsubscribe globally unsubscribes a contact from the emails sent by Loops, retaining the contact.
+//
+// Convenience wrapper around SetSubscribed with subscribed set to false.
+//
+// Idempotency: Idempotent
+//
+// Errors:
+//   - 400 Bad Request: If the request payload is invalid.
+func (c *Client) Unsubscribe(ctx context.Context, userID string) (*APIResponse, error) {
+	return c.setSubscribed(ctx, "Unsubscribe", userID, false)
+}
+
+// SetSubscribed sets the global subscription of a contact to the emails sent by Loops, retaining the contact.
+//
+// Convenience wrapper around UpsertContact. The update only carries the userId and the subscribed flag, so the
+// mailing list subscriptions and the other contact properties stored in Loops are not touched. Use AddToMailingList
+// and RemoveFromMailingList to change the subscription to a single mailing list instead.
+//
+// Idempotency: Idempotent
+//
+// Errors:
+//   - 400 Bad Request: If the request payload is invalid.
+func (c *Client) SetSubscribed(ctx context.Context, userID string, subscribed bool) (*APIResponse, error) {
+	return c.setSubscribed(ctx, "SetSubscribed", userID, subscribed)
+}
+
+// setSubscribed sends the minimal update of the subscribed flag, recording it under the given operation name.
+func (c *Client) setSubscribed(ctx context.Context, operation, userID string, subscribed bool) (*APIResponse, error) {
+	req := ContactRequest{
+		UserID:     userID,
+		Subscribed: &subscribed,
+	}
+	return c.upsertContact(ctx, operation, req)
+}
+
 // AddToMailingList adds a contact to a specific mailing list.
 //
 // Convenience wrapper around UpsertContact.
@@ -179,16 +703,19 @@ func (c *Client) DeleteContact(ctx context.Context, userID string) (*APIResponse
 func (c *Client) AddToMailingList(ctx context.Context, userID string, listID string) (*APIResponse, error) {
 	req := ContactRequest{
 		UserID: userID,
-		MailingLists: map[string]bool{
-			listID: true,
+		MailingLists: MailingListOps{
+			listID: MailingListAdd,
 		},
 	}
-	return c.UpsertContact(ctx, req)
+	return c.upsertContact(ctx, "AddToMailingList", req)
 }
 
 // RemoveFromMailingList removes a contact from a specific mailing list.
 //
-// Convenience wrapper around UpsertContact.
+// Convenience wrapper around UpsertContact. Loops does not expose a dedicated endpoint for removing a contact from a
+// mailing list, so the removal is expressed as an update carrying only the userId and a single MailingListRemove
+// entry. Every other ContactRequest field is left empty so that it is omitted from the payload and the remaining
+// contact properties stored in Loops are not touched.
 //
 // Idempotency: Idempotent
 //
@@ -197,9 +724,139 @@ func (c *Client) AddToMailingList(ctx context.Context, userID string, listID str
 func (c *Client) RemoveFromMailingList(ctx context.Context, userID string, listID string) (*APIResponse, error) {
 	req := ContactRequest{
 		UserID: userID,
-		MailingLists: map[string]bool{
-			listID: false,
+		MailingLists: MailingListOps{
+			listID: MailingListRemove,
 		},
 	}
-	return c.UpsertContact(ctx, req)
+	return c.upsertContact(ctx, "RemoveFromMailingList", req)
+}
+
+// Contact represents a contact stored in Loops.
+type Contact struct {
+	ID           string          `json:"id"`
+	Email        string          `json:"email"`
+	UserID       string          `json:"userId"`
+	FirstName    string          `json:"firstName"`
+	LastName     string          `json:"lastName"`
+	Source       string          `json:"source"`
+	Subscribed   bool            `json:"subscribed"`
+	UserGroup    string          `json:"userGroup"`
+	MailingLists map[string]bool `json:"mailingLists"`
+
+	// ETag is the ETag header of the response the contact was found with, empty if Loops sent none. It can be passed
+	// as ContactRequest.IfMatch to update the contact only if it did not change since.
+	ETag string `json:"-"`
+}
+
+// FindContactRequest is the query of FindContact. Loops looks contacts up by exactly one of email or userId.
+type FindContactRequest struct {
+	Email  string
+	UserID string
+}
+
+// FindContact returns the contact matching the query, or nil if Loops has no such contact.
+//
+// API: GET /contacts/find
+//
+// Idempotency: Idempotent
+//
+// Errors:
+//   - 400 Bad Request: If the request is invalid.
+func (c *Client) FindContact(ctx context.Context, req FindContactRequest) (*Contact, error) {
+	query := url.Values{}
+	switch {
+	case req.Email != "" && req.UserID != "":
+		return nil, fmt.Errorf("only one of email or userId can be used to find a contact")
+	case req.UserID != "":
+		query.Set("userId", req.UserID)
+	case req.Email != "":
+		query.Set("email", req.Email)
+	default:
+		return nil, fmt.Errorf("email or userId is required to find a contact")
+	}
+
+	var contacts []Contact
+	var header http.Header
+	path := "/contacts/find?" + query.Encode()
+	err := c.sendRequest(withResponseHeader(ctx, &header), http.MethodGet, path, nil, &contacts)
+	if err != nil {
+		return nil, err
+	}
+
+	// Loops answers with an empty list when no contact matches
+	if len(contacts) == 0 {
+		return nil, nil
+	}
+	contact := &contacts[0]
+	contact.ETag = header.Get("ETag")
+	return contact, nil
+}
+
+// GetContactMailingLists returns the mailing list subscriptions of a contact, keyed by mailing list ID.
+//
+// API: GET /contacts/find
+//
+// Idempotency: Idempotent
+//
+// Errors:
+//   - 404 Not Found: If the contact does not exist.
+//   - 400 Bad Request: If the request is invalid.
+func (c *Client) GetContactMailingLists(ctx context.Context, userID string) (map[string]bool, error) {
+	contact, err := c.FindContact(ctx, FindContactRequest{UserID: userID})
+	if err != nil {
+		return nil, err
+	}
+	if contact == nil {
+		return nil, &Error{
+			StatusCode: http.StatusNotFound,
+			Body:       fmt.Sprintf("contact with userId %s not found", userID),
+		}
+	}
+
+	mailingLists := contact.MailingLists
+	if mailingLists == nil {
+		mailingLists = map[string]bool{}
+	}
+	return mailingLists, nil
+}
+
+// Ping checks that Loops is reachable and that the API key is valid.
+//
+// API: GET /api-key
+//
+// Idempotency: Idempotent
+//
+// Errors:
+//   - 401 Unauthorized: If the API key is invalid.
+func (c *Client) Ping(ctx context.Context) error {
+	var resp APIResponse
+	return c.sendRequest(ctx, http.MethodGet, "/api-key", nil, &resp)
+}
+
+// ValidateAPIKey checks the API key against Loops, distinguishing an invalid key from a reachability problem.
+//
+// API: GET /api-key
+//
+// Idempotency: Idempotent
+//
+// Errors:
+//   - ErrInvalidAPIKey: If Loops answers 401 Unauthorized.
+//   - ErrUnreachable: If the request fails without a response, Loops answers 429 or 5xx, or the circuit breaker is
+//     open.
+//
+// Other responses are returned as an *Error.
+func (c *Client) ValidateAPIKey(ctx context.Context) error {
+	err := c.Ping(ctx)
+	if err == nil {
+		return nil
+	}
+	if IsUnauthorized(err) {
+		return fmt.Errorf("%w: %w", ErrInvalidAPIKey, err)
+	}
+
+	var apiErr *Error
+	if !errors.As(err, &apiErr) || apiErr.StatusCode == http.StatusTooManyRequests || apiErr.StatusCode >= 500 {
+		return fmt.Errorf("%w: %w", ErrUnreachable, err)
+	}
+	return err
 }
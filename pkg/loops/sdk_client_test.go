@@ -3,10 +3,15 @@ package loops
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"net/http"
 	"net/http/httptest"
+	"strconv"
+	"strings"
 	"testing"
 	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
 )
 
 func TestNewSDK(t *testing.T) {
@@ -95,6 +100,66 @@ func TestUpsertContact(t *testing.T) {
 	}
 }
 
+func TestClient_ResponseHeader(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("X-Request-Id", "req-"+r.URL.Path)
+		w.Header().Set("X-RateLimit-Remaining", "9")
+		if err := json.NewEncoder(w).Encode(APIResponse{Success: true}); err != nil {
+			t.Errorf("Failed to write response: %v", err)
+		}
+	}))
+	defer ts.Close()
+
+	client, _ := NewSDK("test-key", WithBaseURL(ts.URL), WithMailingListUpdate("/lists"))
+	ctx := context.Background()
+	tests := []struct {
+		name              string
+		call              func() (*APIResponse, error)
+		expectedRequestID string
+	}{
+		{
+			name: "UpsertContact",
+			call: func() (*APIResponse, error) {
+				return client.UpsertContact(ctx, ContactRequest{UserID: "user-123"})
+			},
+			expectedRequestID: "req-/contacts/update",
+		},
+		{
+			name:              "RemoveFromMailingList",
+			call:              func() (*APIResponse, error) { return client.RemoveFromMailingList(ctx, "user-123", "list-abc") },
+			expectedRequestID: "req-/contacts/update",
+		},
+		{
+			name:              "DeleteContact",
+			call:              func() (*APIResponse, error) { return client.DeleteContact(ctx, "user-123") },
+			expectedRequestID: "req-/contacts/delete",
+		},
+		{
+			name: "UpdateMailingList",
+			call: func() (*APIResponse, error) {
+				return client.UpdateMailingList(ctx, "list-abc", MailingListUpdate{})
+			},
+			expectedRequestID: "req-/lists/list-abc",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, err := tt.call()
+			if err != nil {
+				t.Fatalf("%s() failed: %v", tt.name, err)
+			}
+			if got := resp.Header.Get("X-Request-Id"); got != tt.expectedRequestID {
+				t.Errorf("Expected request ID %q, got %q", tt.expectedRequestID, got)
+			}
+			if got := resp.Header.Get("X-RateLimit-Remaining"); got != "9" {
+				t.Errorf("Expected rate limit remaining 9, got %q", got)
+			}
+		})
+	}
+}
+
 func TestDeleteContact(t *testing.T) {
 	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		if r.Method != http.MethodPost {
@@ -133,6 +198,22 @@ func TestDeleteContact(t *testing.T) {
 	}
 }
 
+func TestDeleteContact_DryRun(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Errorf("Expected no request in dry run, got %s %s", r.Method, r.URL.Path)
+	}))
+	defer ts.Close()
+
+	client, _ := NewSDK("test-key", WithBaseURL(ts.URL), WithDeleteDryRun(true))
+	resp, err := client.DeleteContact(context.Background(), "user-123")
+	if err != nil {
+		t.Fatalf("DeleteContact() failed: %v", err)
+	}
+	if !resp.Success {
+		t.Error("DeleteContact() expected success true")
+	}
+}
+
 func TestAddToMailingList(t *testing.T) {
 	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		var req ContactRequest
@@ -140,8 +221,8 @@ func TestAddToMailingList(t *testing.T) {
 			t.Errorf("Failed to decode request: %v", err)
 		}
 
-		if !req.MailingLists["list-abc"] {
-			t.Error("Expected mailing list list-abc to be true")
+		if req.MailingLists["list-abc"] != MailingListAdd {
+			t.Error("Expected mailing list list-abc to be added")
 		}
 		if req.UserID != "user-123" {
 			t.Errorf("Expected userId user-123, got %s", req.UserID)
@@ -167,8 +248,45 @@ func TestRemoveFromMailingList(t *testing.T) {
 			t.Errorf("Failed to decode request: %v", err)
 		}
 
-		if val, ok := req.MailingLists["list-abc"]; !ok || val {
-			t.Error("Expected mailing list list-abc to be false")
+		if req.MailingLists["list-abc"] != MailingListRemove {
+			t.Error("Expected mailing list list-abc to be removed")
+		}
+
+		if err := json.NewEncoder(w).Encode(APIResponse{Success: true}); err != nil {
+			t.Errorf("Failed to write response: %v", err)
+		}
+	}))
+	defer ts.Close()
+
+	client, _ := NewSDK("test-key", WithBaseURL(ts.URL))
+	_, err := client.RemoveFromMailingList(context.Background(), "user-123", "list-abc")
+	if err != nil {
+		t.Fatalf("RemoveFromMailingList() failed: %v", err)
+	}
+}
+
+func TestRemoveFromMailingList_OnlySendsMailingList(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPut {
+			t.Errorf("Expected PUT request, got %s", r.Method)
+		}
+		if r.URL.Path != "/contacts/update" {
+			t.Errorf("Expected path /contacts/update, got %s", r.URL.Path)
+		}
+
+		var payload map[string]json.RawMessage
+		if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+			t.Errorf("Failed to decode request: %v", err)
+		}
+
+		if len(payload) != 2 {
+			t.Errorf("Expected only userId and mailingLists to be sent, got %v", payload)
+		}
+		if string(payload["userId"]) != `"user-123"` {
+			t.Errorf("Expected userId user-123, got %s", payload["userId"])
+		}
+		if string(payload["mailingLists"]) != `{"list-abc":false}` {
+			t.Errorf("Expected mailingLists {\"list-abc\":false}, got %s", payload["mailingLists"])
 		}
 
 		if err := json.NewEncoder(w).Encode(APIResponse{Success: true}); err != nil {
@@ -234,6 +352,21 @@ func TestClient_Errors(t *testing.T) {
 		t.Errorf("Expected IsConflict to be true, got: %v", err409)
 	}
 
+	// Test IsGone
+	ts410 := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusGone)
+		if _, err := w.Write([]byte(`{"success":false,"message":"Contact purged"}`)); err != nil {
+			t.Errorf("Failed to write response: %v", err)
+		}
+	}))
+	defer ts410.Close()
+
+	client410, _ := NewSDK("test-key", WithBaseURL(ts410.URL))
+	_, err410 := client410.DeleteContact(context.Background(), "purged-user")
+	if err410 == nil || !IsGone(err410) || IsNotFound(err410) {
+		t.Errorf("Expected only IsGone to be true, got: %v", err410)
+	}
+
 	// Test Error String
 	apiErr := &Error{StatusCode: 418, Body: "I'm a teapot"}
 	if apiErr.Error() != "api request failed with status 418: I'm a teapot" {
@@ -258,6 +391,86 @@ func TestClient_NetworkErrors(t *testing.T) {
 	}
 }
 
+func TestClient_RequestErrorClassification(t *testing.T) {
+	sentinels := []error{ErrCanceled, ErrTimeout, ErrConnectionRefused}
+
+	tests := []struct {
+		name     string
+		ctx      func() (context.Context, context.CancelFunc)
+		opts     []ClientOption
+		closed   bool
+		expected error
+	}{
+		{
+			name: "canceled context",
+			ctx: func() (context.Context, context.CancelFunc) {
+				ctx, cancel := context.WithCancel(context.Background())
+				cancel()
+				return ctx, cancel
+			},
+			expected: ErrCanceled,
+		},
+		{
+			name: "context deadline",
+			ctx: func() (context.Context, context.CancelFunc) {
+				return context.WithTimeout(context.Background(), 10*time.Millisecond)
+			},
+			expected: ErrTimeout,
+		},
+		{
+			name:     "client timeout",
+			opts:     []ClientOption{WithHTTPClient(&http.Client{Timeout: 10 * time.Millisecond})},
+			expected: ErrTimeout,
+		},
+		{
+			name:     "connection refused",
+			closed:   true,
+			expected: ErrConnectionRefused,
+		},
+		{
+			name: "server error",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			release := make(chan struct{})
+			ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if tt.expected == nil {
+					w.WriteHeader(http.StatusInternalServerError)
+					return
+				}
+				// Hold the response until the request is given up
+				select {
+				case <-r.Context().Done():
+				case <-release:
+				}
+			}))
+			defer ts.Close()
+			defer close(release)
+			if tt.closed {
+				ts.Close()
+			}
+
+			ctx, cancel := context.Background(), context.CancelFunc(func() {})
+			if tt.ctx != nil {
+				ctx, cancel = tt.ctx()
+			}
+			defer cancel()
+			client, _ := NewSDK("test-key", append([]ClientOption{WithBaseURL(ts.URL)}, tt.opts...)...)
+			err := client.Ping(ctx)
+			if err == nil {
+				t.Fatal("Expected an error")
+			}
+			for _, sentinel := range sentinels {
+				if got := errors.Is(err, sentinel); got != (sentinel == tt.expected) {
+					t.Errorf("Expected errors.Is(err, %v) to be %t, got error %v", sentinel, sentinel == tt.expected, err)
+				}
+			}
+		})
+	}
+}
+
 func TestClient_DecodeErrors(t *testing.T) {
 	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Content-Type", "application/json")
@@ -268,10 +481,57 @@ func TestClient_DecodeErrors(t *testing.T) {
 	defer ts.Close()
 
 	client, _ := NewSDK("test-key", WithBaseURL(ts.URL))
+	before := testutil.ToFloat64(responseDecodeErrorsTotal.WithLabelValues("/contacts/update"))
 	_, err := client.UpsertContact(context.Background(), ContactRequest{})
 	if err == nil {
 		t.Error("Expected error for invalid JSON response")
 	}
+	if got := testutil.ToFloat64(responseDecodeErrorsTotal.WithLabelValues("/contacts/update")); got != before+1 {
+		t.Errorf("Expected %v decode errors, got %v", before+1, got)
+	}
+
+	// The query string of the path is not part of the label
+	before = testutil.ToFloat64(responseDecodeErrorsTotal.WithLabelValues("/contacts/find"))
+	if _, err := client.FindContact(context.Background(), FindContactRequest{UserID: "user-123"}); err == nil {
+		t.Error("Expected error for invalid JSON response")
+	}
+	if got := testutil.ToFloat64(responseDecodeErrorsTotal.WithLabelValues("/contacts/find")); got != before+1 {
+		t.Errorf("Expected %v decode errors, got %v", before+1, got)
+	}
+}
+
+func TestClient_EmptySuccessBody(t *testing.T) {
+	tests := []struct {
+		name   string
+		status int
+	}{
+		{
+			name:   "No Content",
+			status: http.StatusNoContent,
+		},
+		{
+			name:   "OK with empty body",
+			status: http.StatusOK,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(tt.status)
+			}))
+			defer ts.Close()
+
+			client, _ := NewSDK("test-key", WithBaseURL(ts.URL))
+			resp, err := client.DeleteContact(context.Background(), "user-123")
+			if err != nil {
+				t.Fatalf("DeleteContact() failed: %v", err)
+			}
+			if resp == nil {
+				t.Error("DeleteContact() returned nil response")
+			}
+		})
+	}
 }
 
 func TestWithHTTPClient(t *testing.T) {
@@ -284,3 +544,607 @@ func TestWithHTTPClient(t *testing.T) {
 		t.Error("SDK should not be nil")
 	}
 }
+
+func TestHeaders(t *testing.T) {
+	var got http.Header
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r.Header.Clone()
+		if err := json.NewEncoder(w).Encode(APIResponse{Success: true}); err != nil {
+			t.Errorf("Failed to write response: %v", err)
+		}
+	}))
+	defer ts.Close()
+
+	client, _ := NewSDK("test-key",
+		WithBaseURL(ts.URL),
+		WithDefaultHeader("Loops-Beta-Feature", "on"),
+		WithDefaultHeader("Authorization", "Bearer default-key"),
+	)
+
+	// Default headers are sent but do not overwrite client-managed headers
+	if _, err := client.UpsertContact(context.Background(), ContactRequest{}); err != nil {
+		t.Fatalf("UpsertContact() failed: %v", err)
+	}
+	if got.Get("Loops-Beta-Feature") != "on" {
+		t.Errorf("Expected default header Loops-Beta-Feature: on, got %q", got.Get("Loops-Beta-Feature"))
+	}
+	if got.Get("Authorization") != "Bearer test-key" {
+		t.Errorf("Expected Authorization header to be kept, got %q", got.Get("Authorization"))
+	}
+	if got.Get("Content-Type") != "application/json" {
+		t.Errorf("Expected Content-Type header to be kept, got %q", got.Get("Content-Type"))
+	}
+
+	// Per-call headers are sent and override defaults
+	ctx := WithRequestHeader(context.Background(), "Loops-Beta-Feature", "off")
+	ctx = WithRequestHeader(ctx, "X-Request-Id", "req-123")
+	if _, err := client.UpsertContact(ctx, ContactRequest{}); err != nil {
+		t.Fatalf("UpsertContact() failed: %v", err)
+	}
+	if got.Get("Loops-Beta-Feature") != "off" {
+		t.Errorf("Expected per-call header Loops-Beta-Feature: off, got %q", got.Get("Loops-Beta-Feature"))
+	}
+	if got.Get("X-Request-Id") != "req-123" {
+		t.Errorf("Expected per-call header X-Request-Id: req-123, got %q", got.Get("X-Request-Id"))
+	}
+}
+
+func TestWithWorkspace(t *testing.T) {
+	var got http.Header
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r.Header.Clone()
+		if err := json.NewEncoder(w).Encode(APIResponse{Success: true}); err != nil {
+			t.Errorf("Failed to write response: %v", err)
+		}
+	}))
+	defer ts.Close()
+
+	client, _ := NewSDK("test-key", WithBaseURL(ts.URL), WithWorkspace("workspace-a"))
+
+	if _, err := client.UpsertContact(context.Background(), ContactRequest{}); err != nil {
+		t.Fatalf("UpsertContact() failed: %v", err)
+	}
+	if workspace := got.Get(WorkspaceHeader); workspace != "workspace-a" {
+		t.Errorf("Expected workspace workspace-a, got %q", workspace)
+	}
+
+	// A per-call workspace overrides the client one
+	ctx := WithRequestWorkspace(context.Background(), "workspace-b")
+	if _, err := client.UpsertContact(ctx, ContactRequest{}); err != nil {
+		t.Fatalf("UpsertContact() failed: %v", err)
+	}
+	if workspace := got.Get(WorkspaceHeader); workspace != "workspace-b" {
+		t.Errorf("Expected workspace workspace-b, got %q", workspace)
+	}
+}
+
+func TestWithDeadlineHeader(t *testing.T) {
+	var got http.Header
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r.Header.Clone()
+		if err := json.NewEncoder(w).Encode(APIResponse{Success: true}); err != nil {
+			t.Errorf("Failed to write response: %v", err)
+		}
+	}))
+	defer ts.Close()
+
+	client, _ := NewSDK("test-key", WithBaseURL(ts.URL), WithDeadlineHeader("X-Deadline"))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if _, err := client.UpsertContact(ctx, ContactRequest{}); err != nil {
+		t.Fatalf("UpsertContact() failed: %v", err)
+	}
+	remaining, err := strconv.ParseInt(got.Get("X-Deadline"), 10, 64)
+	if err != nil {
+		t.Fatalf("Expected X-Deadline to hold milliseconds, got %q", got.Get("X-Deadline"))
+	}
+	if remaining <= 4000 || remaining > 5000 {
+		t.Errorf("Expected X-Deadline within (4000, 5000] milliseconds, got %d", remaining)
+	}
+
+	// A context without deadline sends no header
+	if _, err := client.UpsertContact(context.Background(), ContactRequest{}); err != nil {
+		t.Fatalf("UpsertContact() failed: %v", err)
+	}
+	if value := got.Get("X-Deadline"); value != "" {
+		t.Errorf("Expected no X-Deadline without a context deadline, got %q", value)
+	}
+}
+
+func TestGetContactMailingLists(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			t.Errorf("Expected GET request, got %s", r.Method)
+		}
+		if r.URL.Path != "/contacts/find" {
+			t.Errorf("Expected path /contacts/find, got %s", r.URL.Path)
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Query().Get("userId") {
+		case "user-123":
+			body := `[{"id":"c-1","userId":"user-123","mailingLists":{"list-abc":true,"list-def":false}}]`
+			if _, err := w.Write([]byte(body)); err != nil {
+				t.Errorf("Failed to write response: %v", err)
+			}
+		default:
+			if _, err := w.Write([]byte(`[]`)); err != nil {
+				t.Errorf("Failed to write response: %v", err)
+			}
+		}
+	}))
+	defer ts.Close()
+
+	client, _ := NewSDK("test-key", WithBaseURL(ts.URL))
+	lists, err := client.GetContactMailingLists(context.Background(), "user-123")
+	if err != nil {
+		t.Fatalf("GetContactMailingLists() failed: %v", err)
+	}
+	if len(lists) != 2 || !lists["list-abc"] || lists["list-def"] {
+		t.Errorf("Unexpected mailing lists: %v", lists)
+	}
+
+	_, err = client.GetContactMailingLists(context.Background(), "missing-user")
+	if !IsNotFound(err) {
+		t.Errorf("Expected IsNotFound to be true, got error: %v", err)
+	}
+}
+
+func TestFindContact_UserID(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/contacts/find" {
+			t.Errorf("Expected path /contacts/find, got %s", r.URL.Path)
+		}
+		if r.URL.Query().Has("email") {
+			t.Errorf("Expected no email query parameter, got %q", r.URL.Query().Get("email"))
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		body := `[]`
+		if r.URL.Query().Get("userId") == "user-123" {
+			body = `[{"id":"c-1","email":"jane@example.com","userId":"user-123"}]`
+		}
+		if _, err := w.Write([]byte(body)); err != nil {
+			t.Errorf("Failed to write response: %v", err)
+		}
+	}))
+	defer ts.Close()
+
+	client, _ := NewSDK("test-key", WithBaseURL(ts.URL))
+
+	tests := []struct {
+		name     string
+		userID   string
+		expected string
+	}{
+		{name: "one result", userID: "user-123", expected: "c-1"},
+		{name: "zero results", userID: "missing-user", expected: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			contact, err := client.FindContact(context.Background(), FindContactRequest{UserID: tt.userID})
+			if err != nil {
+				t.Fatalf("FindContact() failed: %v", err)
+			}
+			if tt.expected == "" {
+				if contact != nil {
+					t.Errorf("Expected no contact, got %+v", contact)
+				}
+				return
+			}
+			if contact == nil || contact.ID != tt.expected || contact.UserID != tt.userID {
+				t.Errorf("Expected contact %s, got %+v", tt.expected, contact)
+			}
+		})
+	}
+}
+
+func TestUpsertContact_ETagPreconditions(t *testing.T) {
+	const etag = `"v2"`
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/contacts/find":
+			w.Header().Set("ETag", etag)
+			_, _ = w.Write([]byte(`[{"id":"c-1","userId":"user-123"}]`))
+		case "/contacts/update":
+			if r.Header.Get("If-Match") != "" && r.Header.Get("If-Match") != etag {
+				w.WriteHeader(http.StatusPreconditionFailed)
+				_, _ = w.Write([]byte(`{"success":false,"message":"Contact was modified"}`))
+				return
+			}
+			if r.Header.Get("If-None-Match") == "*" {
+				w.WriteHeader(http.StatusPreconditionFailed)
+				_, _ = w.Write([]byte(`{"success":false,"message":"Contact exists"}`))
+				return
+			}
+			_, _ = w.Write([]byte(`{"success":true,"id":"c-1"}`))
+		}
+	}))
+	defer ts.Close()
+
+	client, _ := NewSDK("test-key", WithBaseURL(ts.URL))
+	ctx := context.Background()
+
+	contact, err := client.FindContact(ctx, FindContactRequest{UserID: "user-123"})
+	if err != nil {
+		t.Fatalf("FindContact() failed: %v", err)
+	}
+	if contact.ETag != etag {
+		t.Fatalf("Expected ETag %s, got %q", etag, contact.ETag)
+	}
+
+	tests := []struct {
+		name                   string
+		req                    ContactRequest
+		wantPreconditionFailed bool
+	}{
+		{name: "unconditional", req: ContactRequest{UserID: "user-123"}},
+		{name: "matching ETag", req: ContactRequest{UserID: "user-123", IfMatch: contact.ETag}},
+		{name: "stale ETag", req: ContactRequest{UserID: "user-123", IfMatch: `"v1"`}, wantPreconditionFailed: true},
+		{name: "create only", req: ContactRequest{UserID: "user-123", IfNoneMatch: "*"}, wantPreconditionFailed: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := client.UpsertContact(ctx, tt.req)
+			if got := IsPreconditionFailed(err); got != tt.wantPreconditionFailed {
+				t.Errorf("Expected IsPreconditionFailed %t, got %t (err: %v)", tt.wantPreconditionFailed, got, err)
+			}
+			if !tt.wantPreconditionFailed && err != nil {
+				t.Errorf("UpsertContact() failed: %v", err)
+			}
+		})
+	}
+}
+
+func TestFindContact_InvalidQuery(t *testing.T) {
+	client, _ := NewSDK("test-key", WithBaseURL("http://127.0.0.1:0"))
+
+	for _, req := range []FindContactRequest{{}, {Email: "jane@example.com", UserID: "user-123"}} {
+		if _, err := client.FindContact(context.Background(), req); err == nil {
+			t.Errorf("Expected an error for query %+v, got none", req)
+		}
+	}
+}
+
+func TestPing(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			t.Errorf("Expected GET request, got %s", r.Method)
+		}
+		if r.URL.Path != "/api-key" {
+			t.Errorf("Expected path /api-key, got %s", r.URL.Path)
+		}
+		if r.Header.Get("Authorization") != "Bearer test-key" {
+			w.WriteHeader(http.StatusUnauthorized)
+			if _, err := w.Write([]byte(`{"error":"Invalid API key"}`)); err != nil {
+				t.Errorf("Failed to write response: %v", err)
+			}
+			return
+		}
+		if _, err := w.Write([]byte(`{"success":true,"teamName":"Milo"}`)); err != nil {
+			t.Errorf("Failed to write response: %v", err)
+		}
+	}))
+	defer ts.Close()
+
+	client, _ := NewSDK("test-key", WithBaseURL(ts.URL))
+	if err := client.Ping(context.Background()); err != nil {
+		t.Errorf("Ping() failed: %v", err)
+	}
+
+	invalid, _ := NewSDK("wrong-key", WithBaseURL(ts.URL))
+	if err := invalid.Ping(context.Background()); !IsUnauthorized(err) {
+		t.Errorf("Expected IsUnauthorized to be true, got error: %v", err)
+	}
+}
+
+func TestValidateAPIKey(t *testing.T) {
+	tests := []struct {
+		name        string
+		status      int
+		unreachable bool
+		expected    error
+	}{
+		{name: "valid", status: http.StatusOK},
+		{name: "invalid", status: http.StatusUnauthorized, expected: ErrInvalidAPIKey},
+		{name: "server error", status: http.StatusServiceUnavailable, expected: ErrUnreachable},
+		{name: "rate limited", status: http.StatusTooManyRequests, expected: ErrUnreachable},
+		{name: "no response", unreachable: true, expected: ErrUnreachable},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(tt.status)
+				if _, err := w.Write([]byte(`{"success":true}`)); err != nil {
+					t.Errorf("Failed to write response: %v", err)
+				}
+			}))
+			baseURL := ts.URL
+			if tt.unreachable {
+				baseURL = "http://127.0.0.1:0"
+			}
+			defer ts.Close()
+
+			client, _ := NewSDK("test-key", WithBaseURL(baseURL))
+			err := client.ValidateAPIKey(context.Background())
+			if tt.expected == nil && err != nil {
+				t.Errorf("ValidateAPIKey() failed: %v", err)
+			}
+			if tt.expected != nil && !errors.Is(err, tt.expected) {
+				t.Errorf("Expected error %v, got %v", tt.expected, err)
+			}
+		})
+	}
+}
+
+func TestUpsertContact_ClearFields(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var payload map[string]json.RawMessage
+		if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+			t.Errorf("Failed to decode request: %v", err)
+		}
+
+		if string(payload["firstName"]) != "null" {
+			t.Errorf("Expected firstName to be null, got %s", payload["firstName"])
+		}
+		if string(payload["lastName"]) != `"Doe"` {
+			t.Errorf("Expected lastName Doe, got %s", payload["lastName"])
+		}
+		if string(payload["userId"]) != `"user-123"` {
+			t.Errorf("Expected userId user-123, got %s", payload["userId"])
+		}
+		if _, ok := payload["userGroup"]; ok {
+			t.Errorf("Expected userGroup to be omitted, got %s", payload["userGroup"])
+		}
+
+		if err := json.NewEncoder(w).Encode(APIResponse{Success: true}); err != nil {
+			t.Errorf("Failed to write response: %v", err)
+		}
+	}))
+	defer ts.Close()
+
+	client, _ := NewSDK("test-key", WithBaseURL(ts.URL))
+	_, err := client.UpsertContact(context.Background(), ContactRequest{
+		UserID:      "user-123",
+		LastName:    "Doe",
+		ClearFields: []string{ContactFieldFirstName},
+	})
+	if err != nil {
+		t.Fatalf("UpsertContact() failed: %v", err)
+	}
+}
+
+func TestContactRequest_MailingListOps(t *testing.T) {
+	tests := []struct {
+		name         string
+		mailingLists MailingListOps
+		expected     string
+	}{
+		{
+			name:         "Add subscribes",
+			mailingLists: MailingListOps{"list-abc": MailingListAdd},
+			expected:     `{"list-abc":true}`,
+		},
+		{
+			name:         "Remove unsubscribes",
+			mailingLists: MailingListOps{"list-abc": MailingListRemove},
+			expected:     `{"list-abc":false}`,
+		},
+		{
+			name:         "Leave is omitted",
+			mailingLists: MailingListOps{"list-abc": MailingListAdd, "list-def": MailingListLeave},
+			expected:     `{"list-abc":true}`,
+		},
+		{
+			name:         "Only leave omits the mailing lists",
+			mailingLists: MailingListOps{"list-def": MailingListLeave},
+		},
+		{
+			name: "No mailing lists",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, err := json.Marshal(ContactRequest{UserID: "user-123", MailingLists: tt.mailingLists})
+			if err != nil {
+				t.Fatalf("Marshal() failed: %v", err)
+			}
+			var payload map[string]json.RawMessage
+			if err := json.Unmarshal(data, &payload); err != nil {
+				t.Fatalf("Unmarshal() failed: %v", err)
+			}
+
+			if got := string(payload["mailingLists"]); got != tt.expected {
+				t.Errorf("Expected mailingLists %q, got %q", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestWithProxy(t *testing.T) {
+	var proxiedHost string
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// A proxy receives the absolute URL of the target
+		proxiedHost = r.URL.Host
+		if err := json.NewEncoder(w).Encode(APIResponse{Success: true}); err != nil {
+			t.Errorf("Failed to write response: %v", err)
+		}
+	}))
+	defer proxy.Close()
+
+	client, err := NewSDK("test-key", WithBaseURL("http://loops.invalid/api/v1"), WithProxy(proxy.URL))
+	if err != nil {
+		t.Fatalf("NewSDK() failed: %v", err)
+	}
+	if _, err := client.UpsertContact(context.Background(), ContactRequest{Email: "test@example.com"}); err != nil {
+		t.Fatalf("UpsertContact() failed: %v", err)
+	}
+	if proxiedHost != "loops.invalid" {
+		t.Errorf("Expected the request to loops.invalid to go through the proxy, got host %q", proxiedHost)
+	}
+}
+
+func TestWithProxy_Invalid(t *testing.T) {
+	tests := []struct {
+		name  string
+		proxy string
+		opts  []ClientOption
+	}{
+		{name: "unparsable", proxy: "http://proxy:port"},
+		{name: "unsupported scheme", proxy: "ftp://proxy.internal:3128"},
+		{name: "missing host", proxy: "http://"},
+		{
+			name:  "custom transport",
+			proxy: "http://proxy.internal:3128",
+			opts:  []ClientOption{WithHTTPClient(&http.Client{Transport: roundTripperFunc(nil)})},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := append(tt.opts, WithProxy(tt.proxy))
+			if _, err := NewSDK("test-key", opts...); err == nil {
+				t.Errorf("Expected an error for proxy %q, got none", tt.proxy)
+			}
+		})
+	}
+}
+
+// roundTripperFunc is an http.RoundTripper that is not an *http.Transport.
+type roundTripperFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(r *http.Request) (*http.Response, error) {
+	return f(r)
+}
+
+func TestUpsertContact_EchoedMailingLists(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"success":true,"id":"contact-123","mailingLists":{"list-abc":true,"list-def":false}}`))
+	}))
+	defer ts.Close()
+
+	client, _ := NewSDK("test-key", WithBaseURL(ts.URL))
+	resp, err := client.UpsertContact(context.Background(), ContactRequest{Email: "test@example.com"})
+	if err != nil {
+		t.Fatalf("UpsertContact() failed: %v", err)
+	}
+
+	if len(resp.MailingLists) != 2 || !resp.MailingLists["list-abc"] || resp.MailingLists["list-def"] {
+		t.Errorf("Expected mailing lists list-abc subscribed and list-def unsubscribed, got %v", resp.MailingLists)
+	}
+}
+
+func TestSendRequest_SuccessFalseWith200(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, err := w.Write([]byte(`{"success":false,"message":"Invalid userId"}`)); err != nil {
+			t.Errorf("Failed to write response: %v", err)
+		}
+	}))
+	defer ts.Close()
+
+	client, _ := NewSDK("test-key", WithBaseURL(ts.URL))
+
+	resp, err := client.UpsertContact(context.Background(), ContactRequest{Email: "test@example.com"})
+	if !errors.Is(err, ErrAPIFailure) {
+		t.Fatalf("Expected ErrAPIFailure, got %v", err)
+	}
+	if resp != nil {
+		t.Errorf("Expected no response, got %+v", resp)
+	}
+
+	var apiErr *Error
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusOK || !strings.Contains(apiErr.Body, "Invalid userId") {
+		t.Errorf("Expected an *Error with status 200 and the response body, got %v", err)
+	}
+
+	if _, err := client.AddToMailingList(context.Background(), "user-123", "list-abc"); !errors.Is(err, ErrAPIFailure) {
+		t.Errorf("Expected ErrAPIFailure from AddToMailingList, got %v", err)
+	}
+}
+
+func TestUnsubscribe(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPut {
+			t.Errorf("Expected PUT request, got %s", r.Method)
+		}
+		if r.URL.Path != "/contacts/update" {
+			t.Errorf("Expected path /contacts/update, got %s", r.URL.Path)
+		}
+
+		var payload map[string]json.RawMessage
+		if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+			t.Errorf("Failed to decode request: %v", err)
+		}
+
+		if len(payload) != 2 {
+			t.Errorf("Expected only userId and subscribed to be sent, got %v", payload)
+		}
+		if string(payload["userId"]) != `"user-123"` {
+			t.Errorf("Expected userId user-123, got %s", payload["userId"])
+		}
+		if string(payload["subscribed"]) != `false` {
+			t.Errorf("Expected subscribed false, got %s", payload["subscribed"])
+		}
+
+		if err := json.NewEncoder(w).Encode(APIResponse{Success: true}); err != nil {
+			t.Errorf("Failed to write response: %v", err)
+		}
+	}))
+	defer ts.Close()
+
+	client, _ := NewSDK("test-key", WithBaseURL(ts.URL))
+	if _, err := client.Unsubscribe(context.Background(), "user-123"); err != nil {
+		t.Fatalf("Unsubscribe() failed: %v", err)
+	}
+}
+
+func TestSetSubscribed(t *testing.T) {
+	tests := []struct {
+		name       string
+		subscribed bool
+		expected   string
+	}{
+		{name: "subscribe", subscribed: true, expected: "true"},
+		{name: "unsubscribe", subscribed: false, expected: "false"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.Method != http.MethodPut || r.URL.Path != "/contacts/update" {
+					t.Errorf("Expected PUT /contacts/update, got %s %s", r.Method, r.URL.Path)
+				}
+
+				var payload map[string]json.RawMessage
+				if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+					t.Errorf("Failed to decode request: %v", err)
+				}
+				if len(payload) != 2 {
+					t.Errorf("Expected only userId and subscribed to be sent, got %v", payload)
+				}
+				if string(payload["userId"]) != `"user-123"` {
+					t.Errorf("Expected userId user-123, got %s", payload["userId"])
+				}
+				if string(payload["subscribed"]) != tt.expected {
+					t.Errorf("Expected subscribed %s, got %s", tt.expected, payload["subscribed"])
+				}
+
+				if err := json.NewEncoder(w).Encode(APIResponse{Success: true}); err != nil {
+					t.Errorf("Failed to write response: %v", err)
+				}
+			}))
+			defer ts.Close()
+
+			client, _ := NewSDK("test-key", WithBaseURL(ts.URL))
+			if _, err := client.SetSubscribed(context.Background(), "user-123", tt.subscribed); err != nil {
+				t.Fatalf("SetSubscribed() failed: %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,129 @@
+package loops
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// IdempotencyKeyHeader is the header Loops deduplicates sent events on, for 24 hours.
+const IdempotencyKeyHeader = "Idempotency-Key"
+
+// EventRequest represents the payload for sending an event, which triggers the Loops loops listening to it. The
+// contact is identified by its email or userId.
+type EventRequest struct {
+	Email           string                 `json:"email,omitempty"`
+	UserID          string                 `json:"userId,omitempty"`
+	EventName       string                 `json:"eventName"`
+	EventProperties map[string]interface{} `json:"eventProperties,omitempty"`
+	MailingLists    MailingListOps         `json:"mailingLists,omitempty"`
+
+	// IdempotencyKey makes Loops send the event at most once per key. It is sent as the Idempotency-Key header, and
+	// keys the client-side deduplication of WithEventDeduplication.
+	IdempotencyKey string `json:"-"`
+}
+
+// WithEventDeduplication remembers the events sent successfully with an IdempotencyKey for ttl, so that sending the
+// same event again within ttl, e.g. when the caller sends it again after a reconciliation failing past SendEvent, is
+// suppressed without calling Loops. Events are identified by their userId, or email without one, their name and
+// their IdempotencyKey. Events without an IdempotencyKey are never deduplicated, nor are concurrent sends of the same
+// event. The retries of a single SendEvent call are not deduplicated by the client: the Idempotency-Key header they
+// carry makes Loops send the event at most once.
+func WithEventDeduplication(ttl time.Duration) ClientOption {
+	return func(c *Client) {
+		c.eventDedup = &eventDeduplicator{
+			ttl:  ttl,
+			now:  time.Now,
+			sent: map[eventKey]time.Time{},
+		}
+	}
+}
+
+// SendEvent sends an event for a contact, creating the contact if it does not exist.
+//
+// API: POST /events/send
+//
+// Idempotency: Idempotent with an IdempotencyKey, sent as the Idempotency-Key header on every attempt, so failed
+// attempts are retried. Not idempotent otherwise, so the request is sent once, whatever WithRetries allows.
+//
+// Errors:
+//   - 400 Bad Request: If the request payload is invalid.
+//   - 409 Conflict: If an event with the same IdempotencyKey was already sent.
+//
+// With WithEventDeduplication, no request is sent for an event already sent within the window and a synthetic success
+// is returned.
+func (c *Client) SendEvent(ctx context.Context, req EventRequest) (*APIResponse, error) {
+	key := newEventKey(req)
+	if c.eventDedup.seen(key) {
+		c.logger(ctx).Info("Event already sent, skipping", "eventName", req.EventName,
+			"idempotencyKey", req.IdempotencyKey)
+		return &APIResponse{Success: true, Message: "deduplicated"}, nil
+	}
+
+	if req.IdempotencyKey != "" {
+		ctx = WithRequestHeader(ctx, IdempotencyKeyHeader, req.IdempotencyKey)
+	} else {
+		ctx = withoutRetries(ctx)
+	}
+	var resp APIResponse
+	err := c.sendRequest(withResponseHeader(ctx, &resp.Header), http.MethodPost, "/events/send", req, &resp)
+	if err != nil {
+		return nil, err
+	}
+	c.eventDedup.record(key)
+	c.recordOperation(ctx, "SendEvent", &resp)
+	return &resp, nil
+}
+
+// eventKey identifies a sent event for the deduplication.
+type eventKey struct {
+	contact        string
+	eventName      string
+	idempotencyKey string
+}
+
+func newEventKey(req EventRequest) eventKey {
+	contact := req.UserID
+	if contact == "" {
+		contact = NormalizeEmail(req.Email)
+	}
+	return eventKey{contact: contact, eventName: req.EventName, idempotencyKey: req.IdempotencyKey}
+}
+
+// eventDeduplicator remembers the events sent within the last ttl. A nil eventDeduplicator remembers nothing.
+type eventDeduplicator struct {
+	ttl time.Duration
+	now func() time.Time
+
+	mu   sync.Mutex
+	sent map[eventKey]time.Time
+}
+
+// seen returns true if the event was sent within the last ttl.
+func (d *eventDeduplicator) seen(key eventKey) bool {
+	if d == nil || key.idempotencyKey == "" {
+		return false
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	now := d.now()
+	for k, sentAt := range d.sent {
+		if now.Sub(sentAt) >= d.ttl {
+			delete(d.sent, k)
+		}
+	}
+	_, ok := d.sent[key]
+	return ok
+}
+
+// record remembers the event as sent now.
+func (d *eventDeduplicator) record(key eventKey) {
+	if d == nil || key.idempotencyKey == "" {
+		return
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.sent[key] = d.now()
+}
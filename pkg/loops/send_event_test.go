@@ -0,0 +1,146 @@
+package loops
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestSendEvent(t *testing.T) {
+	var calls int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		if r.Method != http.MethodPost || r.URL.Path != "/events/send" {
+			t.Errorf("Expected POST /events/send, got %s %s", r.Method, r.URL.Path)
+		}
+		if got := r.Header.Get(IdempotencyKeyHeader); got != "signup-jane" {
+			t.Errorf("Expected idempotency key signup-jane, got %q", got)
+		}
+
+		var req EventRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Errorf("Failed to decode request body: %v", err)
+		}
+		if req.UserID != "user-123" || req.EventName != "signup" || req.EventProperties["plan"] != "pro" {
+			t.Errorf("Unexpected event %+v", req)
+		}
+
+		if err := json.NewEncoder(w).Encode(APIResponse{Success: true}); err != nil {
+			t.Errorf("Failed to write response: %v", err)
+		}
+	}))
+	defer ts.Close()
+
+	client, _ := NewSDK("test-key", WithBaseURL(ts.URL))
+	resp, err := client.SendEvent(context.Background(), EventRequest{
+		UserID:          "user-123",
+		EventName:       "signup",
+		EventProperties: map[string]interface{}{"plan": "pro"},
+		IdempotencyKey:  "signup-jane",
+	})
+	if err != nil {
+		t.Fatalf("SendEvent() failed: %v", err)
+	}
+	if !resp.Success {
+		t.Error("SendEvent() expected success true")
+	}
+	if got := atomic.LoadInt32(&calls); got != 1 {
+		t.Errorf("Expected 1 call, got %d", got)
+	}
+}
+
+func TestSendEvent_Deduplication(t *testing.T) {
+	var calls int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// The first send fails and is retried. Both attempts carry the Idempotency-Key header, which keeps Loops from
+		// sending the event twice when the failed attempt went through, e.g. on a lost response
+		n := atomic.AddInt32(&calls, 1)
+		if got := r.Header.Get(IdempotencyKeyHeader); n <= 2 && got != "signup-jane" {
+			t.Errorf("Expected idempotency key signup-jane on attempt %d, got %q", n, got)
+		}
+		if n == 1 {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		if err := json.NewEncoder(w).Encode(APIResponse{Success: true}); err != nil {
+			t.Errorf("Failed to write response: %v", err)
+		}
+	}))
+	defer ts.Close()
+
+	client, _ := NewSDK("test-key", WithBaseURL(ts.URL), WithRetries(1, time.Millisecond),
+		WithEventDeduplication(time.Minute))
+	now := time.Now()
+	client.eventDedup.now = func() time.Time { return now }
+	ctx := context.Background()
+	event := EventRequest{UserID: "user-123", EventName: "signup", IdempotencyKey: "signup-jane"}
+
+	if _, err := client.SendEvent(ctx, event); err != nil {
+		t.Fatalf("SendEvent() failed: %v", err)
+	}
+	if got := atomic.LoadInt32(&calls); got != 2 {
+		t.Fatalf("Expected 2 calls, got %d", got)
+	}
+
+	tests := []struct {
+		name          string
+		event         EventRequest
+		elapsed       time.Duration
+		expectedCalls int32
+	}{
+		{name: "retried send within the window suppressed", event: event, elapsed: 30 * time.Second},
+		{
+			name:          "other idempotency key sent",
+			event:         EventRequest{UserID: "user-123", EventName: "signup", IdempotencyKey: "signup-jane-2"},
+			elapsed:       30 * time.Second,
+			expectedCalls: 1,
+		},
+		{
+			name:          "no idempotency key sent",
+			event:         EventRequest{UserID: "user-123", EventName: "signup"},
+			elapsed:       30 * time.Second,
+			expectedCalls: 1,
+		},
+		{name: "retried send after the window sent", event: event, elapsed: time.Minute, expectedCalls: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client.eventDedup.now = func() time.Time { return now.Add(tt.elapsed) }
+			before := atomic.LoadInt32(&calls)
+
+			resp, err := client.SendEvent(ctx, tt.event)
+			if err != nil {
+				t.Fatalf("SendEvent() failed: %v", err)
+			}
+			if !resp.Success {
+				t.Error("SendEvent() expected success true")
+			}
+			if got := atomic.LoadInt32(&calls) - before; got != tt.expectedCalls {
+				t.Errorf("Expected %d calls, got %d", tt.expectedCalls, got)
+			}
+		})
+	}
+}
+
+func TestSendEvent_NoRetryWithoutIdempotencyKey(t *testing.T) {
+	var calls int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer ts.Close()
+
+	client, _ := NewSDK("test-key", WithBaseURL(ts.URL), WithRetries(3, time.Millisecond))
+	req := EventRequest{UserID: "user-123", EventName: "signup"}
+	if _, err := client.SendEvent(context.Background(), req); err == nil {
+		t.Fatal("Expected SendEvent() to fail")
+	}
+	if got := atomic.LoadInt32(&calls); got != 1 {
+		t.Errorf("Expected 1 call, as an event without idempotency key may have been sent, got %d", got)
+	}
+}
@@ -0,0 +1,55 @@
+package loops
+
+import (
+	"context"
+	"net/http"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const tracerName = "go.miloapis.com/email-provider-loops/pkg/loops"
+
+// WithTracerProvider records a span per Loops API call and propagates the trace context to Loops in the W3C
+// traceparent header. Without it, the client does not trace at all.
+func WithTracerProvider(provider trace.TracerProvider) ClientOption {
+	return func(c *Client) {
+		c.tracer = provider.Tracer(tracerName)
+	}
+}
+
+// startSpan starts the span of an API call. It is a no-op returning ctx and a nil span when tracing is disabled.
+func (c *Client) startSpan(ctx context.Context, method, path string) (context.Context, trace.Span) {
+	if c.tracer == nil {
+		return ctx, nil
+	}
+	return c.tracer.Start(ctx, "loops "+method, trace.WithSpanKind(trace.SpanKindClient), trace.WithAttributes(
+		attribute.String("http.request.method", method),
+		attribute.String("url.path", path),
+	))
+}
+
+// endSpan records the outcome of an API call and ends its span.
+func endSpan(span trace.Span, statusCode int, err error) {
+	if span == nil {
+		return
+	}
+	if statusCode != 0 {
+		span.SetAttributes(attribute.Int("http.response.status_code", statusCode))
+	}
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+// injectTraceContext propagates the trace context of ctx in the request headers when tracing is enabled.
+func (c *Client) injectTraceContext(ctx context.Context, header http.Header) {
+	if c.tracer == nil {
+		return
+	}
+	propagation.TraceContext{}.Inject(ctx, propagation.HeaderCarrier(header))
+}
@@ -0,0 +1,91 @@
+package loops
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestWithTracerProvider(t *testing.T) {
+	var traceparents []string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		traceparents = append(traceparents, r.Header.Get("traceparent"))
+		if r.URL.Path == "/contacts/delete" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		if err := json.NewEncoder(w).Encode(APIResponse{Success: true}); err != nil {
+			t.Errorf("Failed to write response: %v", err)
+		}
+	}))
+	defer ts.Close()
+
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	client, _ := NewSDK("test-key", WithBaseURL(ts.URL), WithTracerProvider(provider))
+
+	ctx := context.Background()
+	if _, err := client.UpsertContact(ctx, ContactRequest{Email: "test@example.com"}); err != nil {
+		t.Fatalf("UpsertContact() failed: %v", err)
+	}
+	if _, err := client.DeleteContact(ctx, "user-123"); err == nil {
+		t.Fatal("Expected an error, got none")
+	}
+
+	spans := recorder.Ended()
+	if len(spans) != 2 {
+		t.Fatalf("Expected 2 spans, got %d", len(spans))
+	}
+
+	expected := []struct {
+		method string
+		path   string
+		status int64
+	}{
+		{method: http.MethodPut, path: "/contacts/update", status: http.StatusOK},
+		{method: http.MethodPost, path: "/contacts/delete", status: http.StatusNotFound},
+	}
+	for i, want := range expected {
+		attrs := map[attribute.Key]attribute.Value{}
+		for _, kv := range spans[i].Attributes() {
+			attrs[kv.Key] = kv.Value
+		}
+		if got := attrs["http.request.method"].AsString(); got != want.method {
+			t.Errorf("Expected span %d method %s, got %s", i, want.method, got)
+		}
+		if got := attrs["url.path"].AsString(); got != want.path {
+			t.Errorf("Expected span %d path %s, got %s", i, want.path, got)
+		}
+		if got := attrs["http.response.status_code"].AsInt64(); got != want.status {
+			t.Errorf("Expected span %d status %d, got %d", i, want.status, got)
+		}
+
+		// The trace context of the span is propagated to Loops
+		if traceparents[i] == "" || traceparents[i][3:35] != spans[i].SpanContext().TraceID().String() {
+			t.Errorf("Expected traceparent of span %d to carry its trace ID, got %q", i, traceparents[i])
+		}
+	}
+}
+
+func TestWithoutTracerProvider(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("traceparent") != "" {
+			t.Errorf("Expected no traceparent header, got %q", r.Header.Get("traceparent"))
+		}
+		if err := json.NewEncoder(w).Encode(APIResponse{Success: true}); err != nil {
+			t.Errorf("Failed to write response: %v", err)
+		}
+	}))
+	defer ts.Close()
+
+	client, _ := NewSDK("test-key", WithBaseURL(ts.URL))
+	if _, err := client.UpsertContact(context.Background(), ContactRequest{Email: "test@example.com"}); err != nil {
+		t.Fatalf("UpsertContact() failed: %v", err)
+	}
+}